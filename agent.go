@@ -1,69 +1,175 @@
 package smolcode
 
 import (
-	"bufio"
 	"context"
 	_ "embed"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"os/signal"
+	"path/filepath"
 
 	// Used for string manipulation
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
+	"github.com/dhamidi/smolcode/codegen"
 	"github.com/dhamidi/smolcode/history"
 	"github.com/dhamidi/smolcode/mcp"
+	"golang.org/x/term"
 	"google.golang.org/genai"
 )
 
 //go:embed .smolcode/system.md
 var defaultSystemPrompt string
 
-func Code(conversationID string, modelName string, newConversationFlag bool, mcpServerConfigs []MCPServerConfig) error {
+// ErrMissingAPIKey is returned by Code if GEMINI_API_KEY is not set.
+var ErrMissingAPIKey = errors.New("GEMINI_API_KEY is not set: create an API key at https://aistudio.google.com/apikey and run 'export GEMINI_API_KEY=<your-key>'")
+
+// geminiAPIKey returns the API key for the Gemini API from the environment.
+func geminiAPIKey() (string, error) {
+	apiKey := strings.TrimSpace(os.Getenv("GEMINI_API_KEY"))
+	if apiKey == "" {
+		return "", ErrMissingAPIKey
+	}
+	return apiKey, nil
+}
+
+// Code runs an interactive session. Settings in overrides take precedence over all other configuration sources, see ResolveConfig.
+func Code(conversationID string, modelName string, newConversationFlag bool, mcpServerConfigs []MCPServerConfig, overrides *Config) error {
+	getUserMessage := NewUserMessageReader(os.Stdin, os.Stdout, InputHistoryPath)
+	agent, err := newSession(conversationID, modelName, newConversationFlag, mcpServerConfigs, overrides, getUserMessage)
+	if err != nil {
+		return err
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		// Users at a terminal already see what they typed.
+		agent.EchoUserMessages()
+	}
+	if err := agent.Run(context.Background()); err != nil {
+		fmt.Printf("Error running agent: %s\n", err.Error())
+		// Potentially return this error if Code() should propagate agent.Run errors
+	}
+	return nil // Successful completion of Code function
+}
+
+// CodeOnce sends prompt to the model without reading from stdin, runs the tools the model asks for
+// until it stops calling them, and returns the text of the final response.
+// The conversation is continued if conversationID is given and a new one is started otherwise;
+// either way it is saved to the database.
+func CodeOnce(conversationID string, modelName string, prompt string) (string, error) {
+	agent, err := newOneShotSession(conversationID, modelName, prompt, &Config{})
+	if err != nil {
+		return "", err
+	}
+	if err := agent.Run(context.Background()); err != nil {
+		return "", err
+	}
+	return finalResponseText(agent.history), nil
+}
+
+// newOneShotSession returns an agent that sends prompt as its only input, configured according to ResolveConfig(overrides).
+// It works on a new conversation unless conversationID is given.
+func newOneShotSession(conversationID string, modelName string, prompt string, overrides *Config) (*Agent, error) {
+	promptSent := false
+	getUserMessage := func() (string, bool) {
+		if promptSent {
+			return "", false
+		}
+		promptSent = true
+		return prompt, true
+	}
+	// Unlike an interactive session, a script shouldn't silently continue in another conversation.
+	overrides.StrictConversation = conversationID != ""
+	return newSession(conversationID, modelName, conversationID == "", nil, overrides, getUserMessage)
+}
+
+// finalResponseText returns the text of the last response of the model in conversation.
+// Only responses to the last user message are considered: if the model did not answer it with text,
+// e.g. because the request failed, an empty string is returned instead of an earlier answer.
+func finalResponseText(conversation []*genai.Content) string {
+	for i := len(conversation) - 1; i >= 0; i-- {
+		content := conversation[i]
+		if content != nil && content.Role == genai.RoleUser {
+			break
+		}
+		if content == nil || content.Role != genai.RoleModel {
+			continue
+		}
+		var text strings.Builder
+		for _, part := range content.Parts {
+			if part != nil && part.Text != "" && !part.Thought {
+				text.WriteString(part.Text)
+			}
+		}
+		if text.Len() > 0 {
+			return text.String()
+		}
+	}
+	return ""
+}
+
+// newSession loads or creates the conversation to work on and returns an agent for it,
+// configured according to ResolveConfig(overrides).
+func newSession(conversationID string, modelName string, newConversationFlag bool, mcpServerConfigs []MCPServerConfig, overrides *Config, getUserMessage func() (string, bool)) (*Agent, error) {
+	// Fail before anything else is set up, instead of on the first request.
+	apiKey, err := geminiAPIKey()
+	if err != nil {
+		return nil, err
+	}
+	config, sources, err := ResolveConfigSources(overrides)
+	if err != nil {
+		return nil, err
+	}
 	var loadedConv *history.Conversation
-	var err error
 	initialHistoryForAgent := []*genai.Content{}
 	var conversationWasNewlyCreated bool // Added to track if conversation is new
+	// notice records progress messages that are shown as part of the startup banner.
+	var notices []string
+	notice := func(format string, args ...any) {
+		notices = append(notices, fmt.Sprintf(format, args...))
+	}
 
 	if conversationID != "" {
 		// Attempt to load the specified conversation
-		fmt.Printf("Attempting to load conversation with ID: %s\n", conversationID)
+		notice("Attempting to load conversation with ID: %s", conversationID)
 		loadedConv, err = history.Load(conversationID)
+		if err != nil && config.StrictConversation {
+			return nil, fmt.Errorf("failed to load conversation %s: %w", conversationID, err)
+		}
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error loading conversation %s: %v. Starting a new conversation instead.\n", conversationID, err)
 			// Fall through to creating a new conversation
 			loadedConv, err = history.New()
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Fatal: Could not create new conversation: %v\n", err)
-				return err // Return the error
+				return nil, err // Return the error
 			}
 			conversationWasNewlyCreated = true
 		} else {
 			conversationWasNewlyCreated = false
-			// DEBUG: Dump loaded conversation
-			fmt.Printf("DEBUG Code(): Loaded conversation data: %s\n", AsJSON(loadedConv))
 		}
 	} else if newConversationFlag {
 		// Explicitly start a new conversation
 		loadedConv, err = history.New()
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Fatal: Could not create new conversation: %v\n", err)
-			return err // Return the error
+			return nil, err // Return the error
 		}
 		conversationWasNewlyCreated = true
 	} else {
 		// Attempt to load the latest conversation
-		fmt.Println("No conversation ID specified, attempting to load the latest conversation...")
-		convList, listErr := history.ListConversations(history.DefaultDatabasePath)
-		if listErr != nil {
-			fmt.Fprintf(os.Stderr, "Error listing conversations: %v. Starting a new conversation.\n", listErr)
+		notice("No conversation ID specified, attempting to load the latest conversation...")
+		latestID, latestErr := history.GetLatestConversationID()
+		if latestErr != nil && !errors.Is(latestErr, history.ErrConversationNotFound) {
+			fmt.Fprintf(os.Stderr, "Error listing conversations: %v. Starting a new conversation.\n", latestErr)
 		}
-		if len(convList) > 0 {
-			latestID := convList[0].ID // Assumes list is sorted by latest
+		if latestErr == nil {
 			// fmt.Printf("Found latest conversation with ID: %s. Attempting to load.\n", latestID) // Removed
 			loadedConv, err = history.Load(latestID)
 			if err != nil {
@@ -71,110 +177,151 @@ func Code(conversationID string, modelName string, newConversationFlag bool, mcp
 				// conversationWasNewlyCreated will be handled if a new one is created below
 			} else {
 				conversationWasNewlyCreated = false
-				// fmt.Printf("Successfully loaded latest conversation ID: %s\n", loadedConv.ID) // Removed
-				// DEBUG: Dump loaded conversation
-				fmt.Printf("DEBUG Code(): Loaded latest conversation data: %s\n", AsJSON(loadedConv))
 			}
 		} else {
-			fmt.Println("No existing conversations found.")
+			notice("No existing conversations found.")
 		}
 		// If loadedConv is still nil (no latest found or error loading it), create a new one
 		if loadedConv == nil {
-			fmt.Println("Starting a new conversation.")
+			notice("Starting a new conversation.")
 			loadedConv, err = history.New()
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Fatal: Could not create new conversation: %v\n", err)
-				return err // Return the error
+				return nil, err // Return the error
 			}
 			conversationWasNewlyCreated = true
 		}
 	}
 
+	if conversationWasNewlyCreated {
+		loadedConv.Meta = newConversationMeta()
+	}
+
 	// Populate initialHistoryForAgent from loadedConv.Messages
 	if loadedConv != nil && loadedConv.Messages != nil {
-		fmt.Fprintf(os.Stdout, "DEBUG Code(): Processing %d raw messages from loadedConv.Messages\n", len(loadedConv.Messages))
-		for i, msgWrapper := range loadedConv.Messages { // Added index i
-			if payloadBytes, ok := msgWrapper.Payload.([]byte); ok {
-				fmt.Fprintf(os.Stdout, "DEBUG Code(): [Msg %d/%d] Attempting to unmarshal payload (primary path). Bytes length: %d\n", i+1, len(loadedConv.Messages), len(payloadBytes))
-				var contentPart genai.Content
-				unmarshalErr := json.Unmarshal(payloadBytes, &contentPart)
-				if unmarshalErr != nil {
-					fmt.Fprintf(os.Stderr, "ERROR Code(): [Msg %d/%d] Primary unmarshal failed: %v. Raw payload for msg %d: %s\n", i+1, len(loadedConv.Messages), unmarshalErr, i+1, string(payloadBytes))
-					continue
-				}
-				fmt.Fprintf(os.Stdout, "DEBUG Code(): [Msg %d/%d] Primary unmarshal SUCCESS.\n", i+1, len(loadedConv.Messages))
-				initialHistoryForAgent = append(initialHistoryForAgent, &contentPart)
-			} else {
-				// This case implies that the payload stored in the DB (and loaded by history.Load)
-				fmt.Fprintf(os.Stderr, "WARNING Code(): [Msg %d/%d] Payload was NOT []byte (type: %T). Attempting fallback. Payload for msg %d: %+v\n", i+1, len(loadedConv.Messages), msgWrapper.Payload, i+1, msgWrapper.Payload)
-				var contentPart genai.Content
-				fallbackPayloadBytes, marshalErr := json.Marshal(msgWrapper.Payload) // marshal the map/value
-				if marshalErr != nil {
-					fmt.Fprintf(os.Stderr, "ERROR Code(): [Msg %d/%d] Fallback marshal failed for msg %d: %v\n", i+1, len(loadedConv.Messages), i+1, marshalErr)
-					continue
-				}
-				fmt.Fprintf(os.Stdout, "DEBUG Code(): [Msg %d/%d] Attempting to unmarshal payload (fallback path). Bytes length: %d\n", i+1, len(loadedConv.Messages), len(fallbackPayloadBytes))
-				unmarshalErr := json.Unmarshal(fallbackPayloadBytes, &contentPart) // unmarshal into typed struct
-				if unmarshalErr != nil {
-					fmt.Fprintf(os.Stderr, "ERROR Code(): [Msg %d/%d] Fallback unmarshal failed for msg %d: %v. Raw fallback payload: %s\n", i+1, len(loadedConv.Messages), i+1, unmarshalErr, string(fallbackPayloadBytes))
-					continue
-				}
-				fmt.Fprintf(os.Stdout, "DEBUG Code(): [Msg %d/%d] Fallback unmarshal SUCCESS.\n", i+1, len(loadedConv.Messages))
-				initialHistoryForAgent = append(initialHistoryForAgent, &contentPart)
-			}
-
-		}
-		fmt.Fprintf(os.Stdout, "DEBUG Code(): Finished processing messages. InitialHistoryForAgent length: %d\n", len(initialHistoryForAgent))
+		initialHistoryForAgent = contentsFromMessages(loadedConv.Messages)
 	}
-	ctx := context.Background()
-	client, err := genai.NewClient(ctx, &genai.ClientConfig{
-		APIKey:  os.Getenv("GEMINI_API_KEY"),
+	client, err := genai.NewClient(context.Background(), &genai.ClientConfig{
+		APIKey:  apiKey,
 		Backend: genai.BackendGeminiAPI,
 	})
 	if err != nil {
 		fmt.Printf("Error initializing genai client: %s\n", err.Error())
-		return err // Propagate error
+		return nil, err // Propagate error
 	}
 
-	scanner := bufio.NewScanner(os.Stdin)
-	getUserMessage := func() (string, bool) {
-		if !scanner.Scan() {
-			return "", false
-		}
-
-		return scanner.Text(), true
+	tools, err := BuiltinTools()
+	if err != nil {
+		return nil, err
 	}
-
-	tools := NewToolBox()
-	tools.
-		Add(ReadFileTool).
-		Add(ListFilesTool).
-		Add(EditFileTool).
-		Add(WriteFileTool).
-		Add(CreateCheckpointTool).
-		Add(ListChangesTool).
-		Add(RunCommandTool).
-		Add(SearchCodeTool).
-		Add(CreateMemoryTool).
-		Add(RecallMemoryTool).
-		Add(ForgetMemoryTool).
-		Add(PlannerTool).
-		Add(CodegenTool)
+	if err := tools.Remove(config.DisabledTools...); err != nil {
+		return nil, fmt.Errorf("failed to disable tools: %w", err)
+	}
+	if err := SetAllowedRoots(config.AllowedRoots); err != nil {
+		return nil, err
+	}
+	SetAllowSymlinkWrites(config.AllowSymlinkWrites)
+	SetMaxReadFileSize(int64(config.MaxReadFileSize))
 	systemPrompt, err := readFileContent(".smolcode/system.md")
 	if err != nil {
 		fmt.Printf("Error reading system.md: %s\n", err.Error())
-		return err // Propagate error
+		return nil, err // Propagate error
 	}
 
 	agent := NewAgent(client, getUserMessage, tools, systemPrompt, initialHistoryForAgent, loadedConv, "main", loadedConv.ID, len(initialHistoryForAgent), conversationWasNewlyCreated, mcpServerConfigs)
+	if err := errors.Join(agent.toolErrors...); err != nil {
+		for _, mcpServer := range agent.mcpActiveServers {
+			mcpServer.Close()
+		}
+		return nil, fmt.Errorf("failed to register tools: %w", err)
+	}
+	agent.disabledTools = config.DisabledTools
+	agent.configSources = sources
+	agent.startupNotices = notices
+	if modelName == "" {
+		// Resume with the model the conversation was last used with.
+		modelName = loadedConv.Model
+	}
+	if modelName == "" {
+		modelName = config.Model
+	}
 	if modelName != "" {
 		agent.ChooseModel(modelName)
 	}
-	if err := agent.Run(ctx); err != nil {
-		fmt.Printf("Error running agent: %s\n", err.Error())
-		// Potentially return this error if Code() should propagate agent.Run errors
+	if len(config.SafetySettings) > 0 {
+		agent.WithSafetySettings(config.SafetySettings)
 	}
-	return nil // Successful completion of Code function
+	if config.ThinkingBudget > 0 {
+		agent.WithThinkingBudget(config.ThinkingBudget)
+	}
+	if config.ContextWindow > 0 {
+		agent.WithContextWindow(config.ContextWindow)
+	}
+	if config.NoChangeSummary {
+		agent.DisableChangeSummary()
+	}
+	if config.ToolRateLimit > 0 {
+		agent.WithToolRateLimit(config.ToolRateLimit)
+	}
+	if config.GlobalToolRateLimit > 0 {
+		agent.WithGlobalToolRateLimit(config.GlobalToolRateLimit)
+	}
+	if config.MaxRetrySeconds > 0 {
+		agent.WithRetryConfig(RetryConfig{MaxRetryTime: time.Duration(config.MaxRetrySeconds) * time.Second})
+	}
+	switch {
+	case config.PromptTemplate != "":
+		agent.WithPromptTemplate(config.PromptTemplate)
+	case !term.IsTerminal(int(os.Stdout.Fd())):
+		agent.WithPromptTemplate(PlainPromptTemplate)
+	}
+	if config.Stream {
+		agent.EnableStreaming()
+	}
+	if config.Quiet {
+		agent.Quiet()
+	}
+	if config.Clarify {
+		agent.Clarify()
+	}
+	if config.AutoRecall {
+		agent.EnableAutoRecall(config.AutoRecallLimit, config.AutoRecallMinScore)
+	}
+	if config.Deterministic {
+		agent.EnableDeterministicGeneration()
+		// The code generation tool and /commit-msg use the codegen package.
+		codegen.SetDeterministic(true)
+	}
+	return agent, nil
+}
+
+// BuiltinTools returns a toolbox with every tool that is built into smolcode.
+func BuiltinTools() (ToolBox, error) {
+	tools := NewToolBox()
+	for _, tool := range []*ToolDefinition{
+		CachedTool(ReadFileTool, readOnlyToolCacheTTL),
+		CachedTool(ListFilesTool, readOnlyToolCacheTTL),
+		EditFileTool,
+		WriteFileTool,
+		WriteFilesTool,
+		CreateCheckpointTool,
+		NamedCheckpointTool,
+		CheckpointDiffTool,
+		ListChangesTool,
+		RunCommandTool,
+		CachedTool(SearchCodeTool, readOnlyToolCacheTTL),
+		CreateMemoryTool,
+		RecallMemoryTool,
+		ForgetMemoryTool,
+		PromoteToMemoryTool,
+		PlannerTool,
+		CodegenTool,
+	} {
+		if err := tools.AddChecked(tool); err != nil {
+			return nil, err
+		}
+	}
+	return tools, nil
 }
 
 func NewAgent(client *genai.Client, getUserMessage func() (string, bool), tools ToolBox, systemInstruction string, initialHistory []*genai.Content, convData *history.Conversation, name string, initialConvID string, initialLoadedMessages int, initialConvIsNew bool, mcpConfigs []MCPServerConfig) *Agent {
@@ -189,12 +336,12 @@ func NewAgent(client *genai.Client, getUserMessage func() (string, bool), tools
 		systemInstruction:     systemInstruction,
 		history:               initialHistory,
 		name:                  name,
-		modelName:             "gemini-2.5-pro-preview-03-25", // Default model
-		displayer:             &GlamourousTextDisplay{},       // Use GlamourousTextDisplay by default
-		initialConvID:         initialConvID,                  // Store passed-in value
-		initialLoadedMessages: initialLoadedMessages,          // Store passed-in value
-		initialConvIsNew:      initialConvIsNew,               // Store passed-in value
-		mcpConfigs:            mcpConfigs,                     // Store MCP server configurations
+		modelName:             DefaultModel,             // Default model
+		displayer:             &GlamourousTextDisplay{}, // Use GlamourousTextDisplay by default
+		initialConvID:         initialConvID,            // Store passed-in value
+		initialLoadedMessages: initialLoadedMessages,    // Store passed-in value
+		initialConvIsNew:      initialConvIsNew,         // Store passed-in value
+		mcpConfigs:            mcpConfigs,               // Store MCP server configurations
 		// cachedContent and systemPromptModTime are zero initially
 	}
 
@@ -214,6 +361,7 @@ func NewAgent(client *genai.Client, getUserMessage func() (string, bool), tools
 
 	// Initialize and start MCP servers
 	agent.mcpActiveServers = []*mcp.Server{}
+	agent.mcpToolCache = mcp.NewToolCache(mcp.DefaultToolCacheDir)
 	agent.mcpTools = []mcp.Tool{} // Holds a raw list of tools from all servers
 	agent.mcpToolExecutionMap = make(map[string]struct {
 		Server       *mcp.Server
@@ -229,6 +377,21 @@ func NewAgent(client *genai.Client, getUserMessage func() (string, bool), tools
 			agent.displayer.DisplayError("Error creating MCP server instance for ID %s (command: %s): NewServer returned nil", serverConfig.ID, serverConfig.Command)
 			continue
 		}
+		server.OnRestart = func(event mcp.RestartEvent) {
+			if event.Err != nil {
+				agent.displayer.DisplayError("MCP server %s stopped and could not be restarted: %v", event.ServerID, event.Err)
+				return
+			}
+			agent.displayer.DisplayMessage("MCP", "95", -1, "MCP server %s stopped and was restarted (restart %d of %d)", event.ServerID, event.Attempt, server.MaxRestarts)
+		}
+
+		if serverConfig.Lazy {
+			// Register the cached tools now, the server is started when one of them is called.
+			if catalog, err := agent.mcpToolCache.Load(serverConfig.Command); err == nil {
+				agent.registerMCPTools(server, catalog.Tools)
+				continue
+			}
+		}
 
 		// agent.displayer.DisplayMessage("MCP Init", "95", -1, "Attempting to start MCP server %s...", serverConfig.ID) // Conditional logging
 		// Using context.Background() for now, consider if a more specific context is needed
@@ -251,41 +414,11 @@ func NewAgent(client *genai.Client, getUserMessage func() (string, bool), tools
 			continue
 		}
 		// agent.displayer.DisplayMessage("MCP Init", "95", -1, "Fetched %d tools from MCP server %s", len(toolsFromServer), server.ID()) // Removed success message
-		agent.mcpTools = append(agent.mcpTools, toolsFromServer...)
-
-		// Populate agent's toolbox with these tools
-		for _, mcpT := range toolsFromServer { // mcpT is of type mcp.Tool
-			agentToolName := fmt.Sprintf("%s_%s", server.ID(), mcpT.Name)
 
-			paramSchema, schemaErr := DeserializeToolSchema(mcpT.RawInputSchema)
-			if schemaErr != nil {
-				agent.displayer.DisplayMessage("MCP Init", "95", -1, "Error processing tool %s from server %s", mcpT.Name, server.ID()) // Log only on error
-				agent.displayer.DisplayError("Error deserializing schema via adapter for MCP tool %s from server %s: %v", mcpT.Name, server.ID(), schemaErr)
-				continue // Skip this tool if schema is invalid
-			}
-
-			declaration := &genai.FunctionDeclaration{
-				Name:        agentToolName,
-				Description: mcpT.Description,
-				Parameters:  paramSchema,
-			}
-
-			// Add the tool to the agent's main toolbox, making it visible to the Gemini model.
-			mcpGenaiTool := &genai.Tool{
-				FunctionDeclarations: []*genai.FunctionDeclaration{declaration},
-			}
-			agent.tools.Add(&ToolDefinition{Tool: mcpGenaiTool, Function: nil}) // MCP tools are executed via RPC, not a local Go func
-			// agent.displayer.DisplayMessage("MCP Init", "95", -1, "Added MCP tool declaration to agent toolbox: %s", agentToolName) // Removed success message
-
-			// Store mapping for execution
-			agent.mcpToolExecutionMap[agentToolName] = struct {
-				Server       *mcp.Server
-				OriginalName string
-			}{
-				Server:       server,
-				OriginalName: mcpT.Name,
-			}
+		if serverConfig.Lazy {
+			agent.storeMCPToolCatalog(server, toolsFromServer)
 		}
+		agent.registerMCPTools(server, toolsFromServer)
 	}
 	// agent.displayer.DisplayMessage("MCP Init", "95", -1, "MCP server initialization complete. Active MCP servers: %d. Total MCP tools mapped: %d", len(agent.mcpActiveServers), len(agent.mcpToolExecutionMap)) // Removed summary message
 
@@ -296,6 +429,75 @@ func NewAgent(client *genai.Client, getUserMessage func() (string, bool), tools
 type MCPServerConfig struct {
 	ID      string
 	Command string
+	// Lazy registers the server's tools from the tool cache and starts the server
+	// only when one of its tools is called.
+	Lazy bool
+}
+
+// registerMCPTools adds the tools of server to the agent's toolbox, prefixed with the server ID.
+func (agent *Agent) registerMCPTools(server *mcp.Server, toolsFromServer mcp.Tools) {
+	agent.mcpTools = append(agent.mcpTools, toolsFromServer...)
+
+	// Populate agent's toolbox with these tools
+	for _, mcpT := range toolsFromServer { // mcpT is of type mcp.Tool
+		agentToolName := fmt.Sprintf("%s_%s", server.ID(), mcpT.Name)
+
+		paramSchema, schemaErr := DeserializeToolSchema(mcpT.RawInputSchema)
+		if schemaErr != nil {
+			agent.displayer.DisplayMessage("MCP Init", "95", -1, "Error processing tool %s from server %s", mcpT.Name, server.ID()) // Log only on error
+			agent.displayer.DisplayError("Error deserializing schema via adapter for MCP tool %s from server %s: %v", mcpT.Name, server.ID(), schemaErr)
+			continue // Skip this tool if schema is invalid
+		}
+
+		declaration := &genai.FunctionDeclaration{
+			Name:        agentToolName,
+			Description: mcpT.Description,
+			Parameters:  paramSchema,
+		}
+
+		// Add the tool to the agent's main toolbox, making it visible to the Gemini model.
+		mcpGenaiTool := &genai.Tool{
+			FunctionDeclarations: []*genai.FunctionDeclaration{declaration},
+		}
+		if err := agent.tools.AddChecked(&ToolDefinition{Tool: mcpGenaiTool, Function: nil}); err != nil { // MCP tools are executed via RPC, not a local Go func
+			agent.toolErrors = append(agent.toolErrors, fmt.Errorf("MCP server %s: %w", server.ID(), err))
+			continue
+		}
+		// agent.displayer.DisplayMessage("MCP Init", "95", -1, "Added MCP tool declaration to agent toolbox: %s", agentToolName) // Removed success message
+
+		// Store mapping for execution
+		agent.mcpToolExecutionMap[agentToolName] = struct {
+			Server       *mcp.Server
+			OriginalName string
+		}{
+			Server:       server,
+			OriginalName: mcpT.Name,
+		}
+	}
+}
+
+// startMCPServer starts a server whose tools were registered from the tool cache.
+// Once started, its catalog is listed again to refresh the cache for later sessions.
+func (agent *Agent) startMCPServer(ctx context.Context, server *mcp.Server) error {
+	if server.Started() {
+		return nil
+	}
+	if err := server.Start(ctx); err != nil {
+		return fmt.Errorf("failed to start MCP server %s: %w", server.ID(), err)
+	}
+	agent.mcpActiveServers = append(agent.mcpActiveServers, server)
+	if toolsFromServer, err := server.ListTools(ctx); err == nil {
+		agent.storeMCPToolCatalog(server, toolsFromServer)
+	}
+	return nil
+}
+
+// storeMCPToolCatalog caches the tools of a started server.
+func (agent *Agent) storeMCPToolCatalog(server *mcp.Server, toolsFromServer mcp.Tools) {
+	catalog := &mcp.ToolCatalog{Command: server.Command(), VersionHash: server.VersionHash(), Tools: toolsFromServer}
+	if err := agent.mcpToolCache.Store(catalog); err != nil {
+		agent.displayer.DisplayError("Error caching tools of MCP server %s: %v", server.ID(), err)
+	}
 }
 
 type Agent struct {
@@ -306,9 +508,11 @@ type Agent struct {
 		Server       *mcp.Server
 		OriginalName string
 	} // For executing MCP tools
-	initialConvID          string // Added to store initial conversation ID
-	initialLoadedMessages  int    // Added to store count of loaded messages
-	initialConvIsNew       bool   // Added to store if the conversation was new
+	mcpToolCache           *mcp.ToolCache // Tool catalogs of lazily started servers
+	initialConvID          string         // Added to store initial conversation ID
+	initialLoadedMessages  int            // Added to store count of loaded messages
+	initialConvIsNew       bool           // Added to store if the conversation was new
+	startupNotices         []string       // How the conversation was found, shown with the banner.
 	name                   string
 	client                 *genai.Client
 	getUserMessage         func() (string, bool)
@@ -317,6 +521,40 @@ type Agent struct {
 	systemInstruction      string
 	history                []*genai.Content
 	modelName              string
+	safetySettings         []*genai.SafetySetting
+	thinkingBudget         int
+	contextWindow          int
+	touchedFiles           map[string]bool
+	changeSummaryDisabled  bool
+	sessionSnapshot        string // Tree of the working tree before the first file was modified, see snapshotSession.
+	sessionSnapshotTaken   bool
+	toolErrors             []error // Problems registering tools, e.g. name collisions
+	toolRateLimit          float64 // Calls per second allowed for each tool; zero disables the limit
+	toolBuckets            map[string]*tokenBucket
+	globalToolRateLimit    float64 // Calls per second allowed for all tools together; zero disables the limit
+	globalToolBucket       *tokenBucket
+	deterministic          bool
+	echoUserMessages       bool
+	quiet                  bool // See Quiet.
+	clarify                bool // See Clarify.
+	autoRecall             bool // See EnableAutoRecall.
+	autoRecallLimit        int
+	autoRecallMinScore     float64
+	recalledMemories       map[string]bool // IDs of the memories added by auto-recall during this session.
+	sessionStart           time.Time       // When Run started, for the session summary.
+	turns                  int             // Messages sent by the user during this session.
+	toolCalls              map[string]int  // Tool calls during this session, keyed by tool name.
+	retryConfig            RetryConfig
+	clock                  clock // Time source for retries; nil uses the real clock.
+	streaming              bool
+	interruptMutex         sync.Mutex
+	lastInterrupt          time.Time // Time of the last Ctrl-C, see interruptible.
+	quitAfterInterrupt     bool
+	promptTemplate         string                // See WithPromptTemplate.
+	disabledTools          []string              // Built-in tools removed from the toolbox, kept for runtimeArgs.
+	configSources          map[string]string     // Where each setting came from, see ResolveConfigSources and runtimeArgs.
+	activePlan             string                // The plan last used with the manage_plan tool.
+	usage                  tokenUsage            // Token usage accumulated over all requests, see /tokens.
 	cachedContent          string                // Stores the resource name of the cached content
 	cachedHistoryCount     int                   // Number of history entries in cachedContent
 	persistentConversation *history.Conversation // For storing history in SQLite
@@ -329,6 +567,63 @@ func (agent *Agent) ChooseModel(modelName string) *Agent {
 	return agent
 }
 
+// switchModel changes the model for the rest of the session and records it with the conversation,
+// so that resuming the conversation later uses the same model.
+func (agent *Agent) switchModel(modelName string) {
+	if agent.cachedContent != "" {
+		// Cached content is bound to the model it was created for.
+		if _, err := agent.client.Caches.Delete(context.Background(), agent.cachedContent, nil); err != nil {
+			agent.trace("CacheDelete", map[string]string{"status": "error", "cacheName": agent.cachedContent, "agent": agent.name, "error": err.Error()})
+		}
+		agent.cachedContent = ""
+		agent.cachedHistoryCount = 0
+	}
+	agent.ChooseModel(modelName)
+	if err := agent.persistFullConversationToDB(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to persist conversation after switching model: %v\n", err)
+	}
+	agent.geminiMessage("Switched to model %s", agent.modelName)
+}
+
+// WithSafetySettings sets the safety settings sent with every request.
+// Passing nil restores the API defaults.
+func (agent *Agent) WithSafetySettings(settings []*genai.SafetySetting) *Agent {
+	agent.safetySettings = settings
+	return agent
+}
+
+// WithThinkingBudget sets the number of tokens the model may spend on reasoning.
+// A value of zero leaves the API default in place.
+// The budget is only sent to models that support thinking.
+func (agent *Agent) WithThinkingBudget(tokens int) *Agent {
+	agent.thinkingBudget = tokens
+	return agent
+}
+
+// WithContextWindow limits the number of most recent messages sent to the model.
+// Older messages stay in the history and are still persisted.
+// Since the window moves with every turn, the persistent cache is not used while a window is set.
+// A value of zero sends the full conversation.
+func (agent *Agent) WithContextWindow(messages int) *Agent {
+	agent.contextWindow = messages
+	return agent
+}
+
+// EnableDeterministicGeneration asks the model for reproducible output by sending
+// a temperature of zero and a fixed seed with every request.
+// Determinism is best-effort and depends on the model.
+func (agent *Agent) EnableDeterministicGeneration() *Agent {
+	agent.deterministic = true
+	return agent
+}
+
+// EchoUserMessages displays every accepted user message through the displayer,
+// so that transcripts contain both sides of the conversation even when the input is not shown on a terminal.
+func (agent *Agent) EchoUserMessages() *Agent {
+	agent.echoUserMessages = true
+	return agent
+}
+
 func (agent *Agent) EnableTracing() *Agent {
 	agent.tracingEnabled = true
 
@@ -342,6 +637,10 @@ func (agent *Agent) DisableTracing() *Agent {
 }
 
 func (agent *Agent) refreshCache(ctx context.Context) {
+	if agent.contextWindow > 0 {
+		// Caching the full history would send messages outside of the context window.
+		return
+	}
 	// Only refresh cache if there's history and (no cache exists or history has grown)
 	if len(agent.history) == 0 || (agent.cachedContent != "" && len(agent.history) == agent.cachedHistoryCount) {
 		// No history to cache, or cache is up-to-date
@@ -369,9 +668,7 @@ func (agent *Agent) refreshCache(ctx context.Context) {
 		// Model is part of Caches.Create call
 	}
 
-	if agent.systemInstruction != "" {
-		cacheConfig.SystemInstruction = agent.systemPrompt()
-	}
+	cacheConfig.SystemInstruction = agent.systemPrompt()
 	if len(agent.tools) > 0 {
 		cacheConfig.Tools = []*genai.Tool{agent.tools.List()}
 	}
@@ -418,15 +715,19 @@ func (agent *Agent) Run(ctx context.Context) error {
 	if agent.history == nil {
 		agent.history = []*genai.Content{}
 	}
+	agent.sessionStart = time.Now()
+	// Deferred, so that the summary is also saved when the session ends with an error.
+	defer agent.saveSessionSummary()
 
-	agent.displayer.Display(fmt.Sprintf("Chat with %s (use 'Ctrl-c' to quit)", agent.modelName))
-	agent.displayer.Display(fmt.Sprintf("Available tools: %s", strings.Join(agent.tools.Names(), ", ")))
+	agent.displayBanner()
 	readUserInput := true
+	safetyRetries := 0
+	turnStart := len(agent.history) // Length of the history before the current request, see discardTurn.
 	for {
 		if readUserInput {
 			agent.refreshCache(ctx) // Refresh cache before getting user input
 
-			agent.displayer.DisplayPrompt("\u001b[94mYou [%d]\u001b[0m: ", len(agent.history)) // Print prompt with history length
+			agent.displayer.DisplayPrompt("%s", agent.prompt())
 			userInput, ok := agent.getUserMessage()
 			if !ok {
 				break
@@ -440,6 +741,87 @@ func (agent *Agent) Run(ctx context.Context) error {
 				agent.DisableTracing()
 				continue
 			}
+			if fields := strings.Fields(userInput); len(fields) > 0 && fields[0] == "/clarify" {
+				enabled, ok := parseToggle(fields, agent.clarify)
+				if !ok {
+					agent.errorMessage("usage: /clarify [on|off]")
+					continue
+				}
+				agent.setClarify(enabled)
+				if agent.clarify {
+					agent.geminiMessage("Clarify mode is on: ambiguous requests are answered with a question.")
+				} else {
+					agent.geminiMessage("Clarify mode is off.")
+				}
+				continue
+			}
+			if fields := strings.Fields(userInput); len(fields) > 0 && fields[0] == "/auto-recall" {
+				enabled, ok := parseToggle(fields, agent.autoRecall)
+				if !ok {
+					agent.errorMessage("usage: /auto-recall [on|off]")
+					continue
+				}
+				if enabled {
+					agent.EnableAutoRecall(agent.autoRecallLimit, agent.autoRecallMinScore)
+					agent.geminiMessage("Auto-recall is on: up to %d relevant memories are added to each of your messages.", agent.autoRecallLimit)
+				} else {
+					agent.autoRecall = false
+					agent.geminiMessage("Auto-recall is off.")
+				}
+				continue
+			}
+			if fields := strings.Fields(userInput); len(fields) > 0 && fields[0] == "/remember" {
+				id, content, ok := parseRememberCommand(userInput)
+				if !ok {
+					agent.errorMessage("usage: /remember <id> <content>")
+					continue
+				}
+				if err := promoteToMemory(id, content); err != nil {
+					agent.errorMessage("Failed to remember '%s': %v", id, err)
+				} else {
+					agent.geminiMessage("Remembered '%s'.", id)
+				}
+				continue
+			}
+			if strings.TrimSpace(userInput) == "/tokens" {
+				agent.displayer.DisplayMessage("Usage", "90", -1, "%s", agent.usage.String())
+				continue
+			}
+			if strings.TrimSpace(userInput) == "/commit-msg" {
+				message, _, err := SuggestCommitMessage()
+				if err != nil {
+					agent.errorMessage("%v", err)
+				} else {
+					agent.geminiMessage("Suggested commit message:\n\n%s", message)
+				}
+				continue
+			}
+			if fields := strings.Fields(userInput); len(fields) > 0 && fields[0] == "/model" {
+				if len(fields) == 1 {
+					agent.geminiMessage("Current model: %s", agent.modelName)
+				} else {
+					agent.switchModel(fields[1])
+				}
+				continue
+			}
+			if strings.TrimSpace(userInput) == "/build" {
+				if err := agent.buildProject(); err != nil {
+					agent.errorMessage("%v", err)
+				}
+				continue
+			}
+			if fields := strings.Fields(userInput); len(fields) > 0 && fields[0] == "/edit" {
+				message, edited, err := editMessage(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(userInput), "/edit")))
+				if err != nil {
+					agent.errorMessage("%v", err)
+					continue
+				}
+				if !edited {
+					agent.skipMessage(SkipEditCancelled, "Editor closed without changes, message cancelled.")
+					continue
+				}
+				userInput = message
+			}
 			if strings.TrimSpace(userInput) == "/reload" {
 				err := agent.reload()
 				if err != nil {
@@ -451,29 +833,60 @@ func (agent *Agent) Run(ctx context.Context) error {
 			}
 			userMessage := genai.NewContentFromText(userInput, genai.RoleUser)
 			if isContentEmpty(userMessage) {
-				agent.skipMessage("User input is empty, not adding to history.")
+				agent.skipMessage(SkipEmptyUserInput, "User input is empty, not adding to history.")
 				// Continue to next iteration to re-prompt user, skip inference for empty input
 				readUserInput = true
 				continue
 			} else {
-				agent.history = append(agent.history, userMessage)
-				if err := agent.persistFullConversationToDB(); err != nil {
-					// Log error, but continue. The primary history is in memory.
-					fmt.Fprintf(os.Stderr, "Warning: failed to persist conversation after user message: %v\n", err)
-				}
+				turnStart = len(agent.history)
+				agent.addUserMessage(userInput)
+				agent.recallRelevantMemories(userInput)
 			}
 		}
 
-		response, err := agent.runInference(ctx, agent.history)
+		var response *genai.GenerateContentResponse
+		var err error
+		agent.interruptible(ctx, func(ctx context.Context) {
+			response, err = agent.runInference(ctx, agent.history)
+		})
+		if agent.quitRequested() {
+			break
+		}
+		if errors.Is(err, context.Canceled) && ctx.Err() == nil {
+			// Nothing of the cancelled request is kept, the user decides how to go on.
+			agent.discardTurn(turnStart)
+			agent.errorMessage("request cancelled")
+			safetyRetries = 0
+			readUserInput = true
+			continue
+		}
 		if err != nil {
 			// For any other error, return it to terminate the agent run
 			return err
 		}
 
+		if response.UsageMetadata != nil && agent.persistentConversation != nil {
+			agent.persistentConversation.TotalTokens += int(response.UsageMetadata.TotalTokenCount)
+		}
+
 		// Print usage metadata summary
 		agent.displayer.DisplayMessage("Usage", "90", -1, "%s", formatUsageMetadata(response.UsageMetadata))
 
-		if len(response.Candidates) == 0 {
+		if reason, retryable := blockedResponseReason(response); reason != "" {
+			if retryable && safetyRetries < maxSafetyRetries {
+				safetyRetries++
+				agent.errorMessage("response blocked: %s. Retrying (%d/%d)...", reason, safetyRetries, maxSafetyRetries)
+				readUserInput = false // The user message is already in history, just ask again.
+				continue
+			}
+			agent.errorMessage("response blocked: %s. Rephrase your request or adjust the safety settings.", reason)
+			safetyRetries = 0
+			readUserInput = true
+			continue
+		}
+		safetyRetries = 0
+
+		if len(response.Candidates) == 0 || response.Candidates[0].Content == nil {
 			agent.errorMessage("empty response received")
 			readUserInput = true
 			continue
@@ -485,23 +898,58 @@ func (agent *Agent) Run(ctx context.Context) error {
 			readUserInput = true
 			continue
 		}
+		responseAdded := false
 		if isContentEmpty(responseMessage) {
-			agent.skipMessage("Model response is empty, not adding to history.")
+			agent.skipMessage(SkipEmptyModelResponse, "Model response is empty, not adding to history.")
 		} else {
 			agent.history = append(agent.history, responseMessage)
+			responseAdded = true
 			if err := agent.persistFullConversationToDB(); err != nil {
 				fmt.Fprintf(os.Stderr, "Warning: failed to persist conversation after model response: %v\n", err)
 			}
 		}
+		if question, ok := clarifyingQuestion(responseMessage); agent.clarify && ok {
+			// The question is shown on its own and answered by the user's next message.
+			if !agent.streaming {
+				agent.questionMessage("%s", question)
+			}
+			readUserInput = true
+			continue
+		}
 		toolResults := []*genai.Content{}
 
-		for _, content := range responseMessage.Parts {
-			if content.Text != "" {
-				agent.geminiMessage("%s", content.Text)
-			} else if content.FunctionCall != nil {
-				response := agent.executeTool(content.FunctionCall)
-				toolResults = append(toolResults, response)
+		cancelled := false
+		agent.interruptible(ctx, func(ctx context.Context) {
+			for _, content := range responseMessage.Parts {
+				if content.Text != "" {
+					if !agent.streaming { // Streamed text has already been displayed.
+						agent.geminiMessage("%s", content.Text)
+					}
+				} else if content.FunctionCall != nil {
+					if ctx.Err() != nil {
+						break
+					}
+					response := agent.executeTool(ctx, content.FunctionCall)
+					toolResults = append(toolResults, response)
+				}
+			}
+			cancelled = ctx.Err() != nil
+		})
+		if cancelled {
+			// Results of the interrupted tool calls are discarded. The function calls are
+			// removed as well, so that the history doesn't contain calls without responses.
+			if responseAdded {
+				agent.history = agent.history[:len(agent.history)-1]
+				if err := agent.persistFullConversationToDB(); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: failed to persist conversation after cancelled tool calls: %v\n", err)
+				}
+			}
+			if agent.quitRequested() {
+				break
 			}
+			agent.errorMessage("tool calls cancelled")
+			readUserInput = true
+			continue
 		}
 
 		if len(toolResults) == 0 {
@@ -521,7 +969,7 @@ func (agent *Agent) Run(ctx context.Context) error {
 			}
 		}
 		if skippedToolResults > 0 {
-			agent.skipMessage("%d tool result(s) were empty and not added to history.", skippedToolResults)
+			agent.skipMessage(SkipEmptyToolResult, "%d tool result(s) were empty and not added to history.", skippedToolResults)
 		}
 		if len(validToolResults) > 0 {
 			agent.history = append(agent.history, validToolResults...)
@@ -532,18 +980,31 @@ func (agent *Agent) Run(ctx context.Context) error {
 	}
 
 	// Final save of conversation to database on exit
-	agent.displayer.Display("\nExiting... ensuring conversation is saved to database.")
+	if !agent.quiet {
+		agent.displayer.Display("\nExiting... ensuring conversation is saved to database.")
+	}
 	if err := agent.persistFullConversationToDB(); err != nil {
 		fmt.Fprintf(os.Stderr, "Warning: final attempt to persist conversation to DB failed: %v\n", err)
-	} else {
+	} else if !agent.quiet {
 		agent.displayer.Display("Conversation saved to database successfully.")
 	}
 
+	if !agent.changeSummaryDisabled {
+		if summary := agent.changeSummary(); summary != "" {
+			agent.displayer.Display(summary)
+		}
+	}
+
 	return nil
 }
 
-func (agent *Agent) executeTool(call *genai.FunctionCall) *genai.Content {
+func (agent *Agent) executeTool(ctx context.Context, call *genai.FunctionCall) *genai.Content {
 	agent.toolMessage("Tool call %s with parameters: %s", call.Name, AsJSON(call.Args))
+	agent.countToolCall(call.Name)
+	if !agent.allowToolCall(call.Name, time.Now()) {
+		agent.toolMessage("Tool %s rate limited", call.Name)
+		return genai.NewContentFromFunctionResponse(call.Name, map[string]any{"error": "rate limited: too many tool calls, wait a moment and retry"}, "tool")
+	}
 	// Check if it's an MCP tool first
 	if execDetails, isMCPTool := agent.mcpToolExecutionMap[call.Name]; isMCPTool {
 		// Ensure call.Args is not nil, as mcp.Server.Call expects map[string]any
@@ -551,37 +1012,37 @@ func (agent *Agent) executeTool(call *genai.FunctionCall) *genai.Content {
 		if argsToSend == nil {
 			argsToSend = make(map[string]any)
 		}
+		// The server outlives this call, so it is not started with ctx.
+		if err := agent.startMCPServer(context.Background(), execDetails.Server); err != nil {
+			agent.toolMessage("Tool %s execution error: %v", call.Name, err)
+			return genai.NewContentFromFunctionResponse(call.Name, map[string]any{"error": err.Error()}, "tool")
+		}
 
-		resultContents, err := execDetails.Server.Call(context.Background(), execDetails.OriginalName, argsToSend)
-		var responseData map[string]any
+		resultContents, err := execDetails.Server.Call(ctx, execDetails.OriginalName, argsToSend)
+		invalidateToolCaches()
 		if err != nil {
 			agent.toolMessage("Tool %s execution error: %v", call.Name, err)
+			responseData := map[string]any{"error": err.Error()}
 			// Try to include any partial content if the error indicates a tool-side error but still returned content
 			if len(resultContents) > 0 && resultContents[0].Type == "text" {
-				responseData = map[string]any{"error": err.Error(), "details": resultContents[0].Text}
-			} else {
-				responseData = map[string]any{"error": err.Error()}
-			}
-		} else {
-			// Process resultContents into a single map[string]any.
-			// This is a simplified conversion. A more robust one would handle multiple content parts, images, etc.
-			if len(resultContents) > 0 {
-				if resultContents[0].Type == "text" {
-					responseData = map[string]any{"output": resultContents[0].Text}
-					agent.toolMessage("Tool %s result: %s", call.Name, CropText(resultContents[0].Text, 70))
-				} else if resultContents[0].Type == "image" {
-					responseData = map[string]any{"output": fmt.Sprintf("[Image data received, mime: %s]", resultContents[0].MimeType)}
-					agent.toolMessage("Tool %s result: Image data (mime: %s)", call.Name, resultContents[0].MimeType)
-				} else {
-					responseData = map[string]any{"output": fmt.Sprintf("[Unknown MCP content type: %s]", resultContents[0].Type)}
-					agent.toolMessage("Tool %s result: Unknown content type %s", call.Name, resultContents[0].Type)
-				}
-			} else {
-				responseData = map[string]any{"output": "MCP tool executed successfully, no content returned."}
-				agent.toolMessage("Tool %s: No content returned", call.Name)
+				responseData["details"] = resultContents[0].Text
 			}
+			return genai.NewContentFromFunctionResponse(call.Name, responseData, "tool")
+		}
+		if len(resultContents) == 0 {
+			agent.toolMessage("Tool %s: No content returned", call.Name)
+			return genai.NewContentFromFunctionResponse(call.Name, map[string]any{"output": "MCP tool executed successfully, no content returned."}, "tool")
 		}
-		return genai.NewContentFromFunctionResponse(call.Name, responseData, "tool")
+		result := mcpToolResult(resultContents)
+		for _, attachment := range result.Attachments {
+			agent.toolMessage("Tool %s result: Image data (mime: %s)", call.Name, attachment.MIMEType)
+		}
+		agent.toolMessage("Tool %s result: %s", call.Name, CropText(AsJSON(result.Output), 70))
+		return result.Content(call.Name)
+	}
+
+	if planName, ok := call.Args["plan_name"].(string); ok && call.Name == PlannerTool.Name() {
+		agent.activePlan = planName
 	}
 
 	// If not an MCP tool, proceed with existing local tool execution logic
@@ -590,32 +1051,54 @@ func (agent *Agent) executeTool(call *genai.FunctionCall) *genai.Content {
 		agent.toolMessage("Tool %s not found", call.Name)
 		return genai.NewContentFromFunctionResponse(call.Name, map[string]any{"error": "tool not found"}, "tool")
 	}
-	result, err := tool.Function(call.Args)
+	if mutatesFiles(call.Name) {
+		agent.snapshotSession()
+	}
+	result, err := agent.callTool(tool, call.Args)
+	if !tool.cached {
+		invalidateToolCaches()
+	}
 	if err != nil {
 		agent.toolMessage("Tool %s execution error: %v", call.Name, err)
 		return genai.NewContentFromFunctionResponse(call.Name, map[string]any{"error": err.Error()}, "tool")
 	}
 
-	agent.toolMessage("Tool %s result: %s", call.Name, CropText(AsJSON(result), 70))
-	return genai.NewContentFromFunctionResponse(call.Name, result, "tool")
+	agent.recordTouchedFile(call, result.Output)
+	agent.toolMessage("Tool %s result: %s", call.Name, CropText(AsJSON(result.Output), 70))
+	return result.Content(call.Name)
+}
+
+// mcpToolResult converts the content returned by an MCP tool into a tool result.
+// Text content becomes the output, images are attached as inline data.
+func mcpToolResult(contents []mcp.ToolResultContent) *ToolResult {
+	result := &ToolResult{}
+	texts := []string{}
+	for _, content := range contents {
+		switch content.Type {
+		case "text":
+			texts = append(texts, content.Text)
+		case "image":
+			data, err := base64.StdEncoding.DecodeString(content.Data)
+			if err != nil {
+				texts = append(texts, fmt.Sprintf("[Invalid image data, mime: %s: %v]", content.MimeType, err))
+				continue
+			}
+			result.Attachments = append(result.Attachments, &genai.Blob{Data: data, MIMEType: content.MimeType})
+			texts = append(texts, fmt.Sprintf("[Image data attached, mime: %s]", content.MimeType))
+		default:
+			texts = append(texts, fmt.Sprintf("[Unknown MCP content type: %s]", content.Type))
+		}
+	}
+	result.Output = map[string]any{"output": strings.Join(texts, "\n")}
+	return result
 }
 
 func (agent *Agent) errorMessage(fmtStr string, value ...any) {
 	agent.displayer.DisplayError(fmtStr, value...)
 }
 
+// youMessage displays the user's latest message, which is expected to be the last entry of the history.
 func (agent *Agent) youMessage(fmtStr string, value ...any) {
-	// The history length for "You" messages is usually len(agent.history)-1 when it's about the *current* user input.
-	// However, the user prompt like "You [0]:" is handled separately in the Run loop.
-	// This specific youMessage function doesn't seem to be called in the current codebase provided.
-	// If it were to be used for general "You" messages, it would be:
-	// agent.displayer.DisplayMessage("You", "94", len(agent.history)-1, fmtStr, value...)
-	// For now, let's make it use the displayer but acknowledge its current non-use or specific context.
-	// It's safer to keep the history logic as it was, if it was specific.
-	// The primary prompt for user input is in the `Run` loop directly using fmt.Printf.
-	// Given this, DisplayMessage might not be the perfect fit if `len(agent.history)-1` is crucial and specific.
-	// However, to adhere to the refactoring goal, we'll use the displayer.
-	// The original fmt.Printf for the prompt in `Run` will be addressed separately.
 	agent.displayer.DisplayMessage("You", "94", len(agent.history)-1, fmtStr, value...)
 }
 
@@ -627,8 +1110,8 @@ func (agent *Agent) geminiMessage(fmtStr string, value ...any) {
 	agent.displayer.DisplayMessage("Gemini", "93", len(agent.history), fmtStr, value...)
 }
 
-func (agent *Agent) skipMessage(fmtStr string, value ...any) {
-	agent.displayer.DisplayMessage("Skip  ", "96", len(agent.history), fmtStr, value...)
+func (agent *Agent) skipMessage(reason SkipReason, fmtStr string, value ...any) {
+	agent.displayer.DisplaySkip(reason, len(agent.history), fmtStr, value...)
 }
 
 func (agent *Agent) trace(direction string, arg any) {
@@ -640,18 +1123,123 @@ func (agent *Agent) trace(direction string, arg any) {
 	fmt.Printf("\u001b[90mTrace [%d] %s\u001b[0m: %s\n", len(agent.history), direction, AsJSON(arg))
 }
 
+// addUserMessage adds text entered by the user to the conversation and persists it.
+func (agent *Agent) addUserMessage(text string) {
+	// Files may have changed since the last turn, so don't reuse results of read-only tools.
+	invalidateToolCaches()
+	agent.history = append(agent.history, genai.NewContentFromText(text, genai.RoleUser))
+	agent.turns++
+	if agent.echoUserMessages {
+		agent.youMessage("%s", text)
+	}
+	if err := agent.persistFullConversationToDB(); err != nil {
+		// Log error, but continue. The primary history is in memory.
+		fmt.Fprintf(os.Stderr, "Warning: failed to persist conversation after user message: %v\n", err)
+	}
+}
+
+// discardTurn removes everything added to the history since it had length turnStart,
+// e.g. the user message and recalled memories of a cancelled request, from memory and from the database.
+func (agent *Agent) discardTurn(turnStart int) {
+	if turnStart >= len(agent.history) {
+		return
+	}
+	agent.history = agent.history[:turnStart]
+	agent.turns--
+	if err := agent.persistFullConversationToDB(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to persist conversation after cancelling a request: %v\n", err)
+	}
+}
+
+// parseToggle interprets the arguments of a slash command that switches a mode, such as /clarify:
+// without argument it toggles current, "on" and "off" set it. ok is false for any other argument.
+func parseToggle(fields []string, current bool) (enabled bool, ok bool) {
+	switch {
+	case len(fields) == 1:
+		return !current, true
+	case len(fields) == 2 && fields[1] == "on":
+		return true, true
+	case len(fields) == 2 && fields[1] == "off":
+		return false, true
+	}
+	return current, false
+}
+
+// interruptWindow is how soon after an interrupt a second Ctrl-C ends the session.
+const interruptWindow = 2 * time.Second
+
+// interruptible runs fn in a context that is cancelled by Ctrl-C, so that an interrupt
+// aborts the current request instead of ending the session.
+// A second Ctrl-C within interruptWindow also asks Run to quit, see quitRequested.
+func (agent *Agent) interruptible(ctx context.Context, fn func(ctx context.Context)) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	defer signal.Stop(interrupts)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-interrupts:
+				agent.recordInterrupt(time.Now())
+				cancel()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	fn(ctx)
+}
+
+// recordInterrupt notes a Ctrl-C at now, requesting to quit if it follows another one closely.
+func (agent *Agent) recordInterrupt(now time.Time) {
+	agent.interruptMutex.Lock()
+	defer agent.interruptMutex.Unlock()
+	if !agent.lastInterrupt.IsZero() && now.Sub(agent.lastInterrupt) < interruptWindow {
+		agent.quitAfterInterrupt = true
+	}
+	agent.lastInterrupt = now
+}
+
+// quitRequested reports whether Ctrl-C was pressed twice within interruptWindow.
+func (agent *Agent) quitRequested() bool {
+	agent.interruptMutex.Lock()
+	defer agent.interruptMutex.Unlock()
+	return agent.quitAfterInterrupt
+}
+
 func (agent *Agent) runInference(ctx context.Context, conversation []*genai.Content) (*genai.GenerateContentResponse, error) {
 	agent.trace(">", conversation)
 
 	var response *genai.GenerateContentResponse
 	var err error
 
-	retryDelays := []time.Duration{5 * time.Second, 10 * time.Second, 15 * time.Second, 30 * time.Second}
-	maxRetries := 5
+	retry := agent.retryConfig.withDefaults()
+	clock := agent.clock
+	if clock == nil {
+		clock = realClock{}
+	}
+	start := clock.Now()
+	attempts := 0
 
-	for attempt := 0; attempt < maxRetries; attempt++ {
+	for attempt := 0; attempt < retry.MaxRetries; attempt++ {
+		attempts++
 		config := &genai.GenerateContentConfig{
 			MaxOutputTokens: 8 * 1024,
+			SafetySettings:  agent.safetySettings,
+		}
+		if agent.thinkingBudget > 0 && modelSupportsThinking(agent.modelName) {
+			budget := int32(agent.thinkingBudget)
+			config.ThinkingConfig = &genai.ThinkingConfig{ThinkingBudget: &budget}
+		}
+		if agent.deterministic {
+			temperature, seed := float32(0), int32(codegen.DeterministicSeed)
+			config.Temperature = &temperature
+			config.Seed = &seed
 		}
 
 		var conversationToSend []*genai.Content
@@ -670,53 +1258,52 @@ func (agent *Agent) runInference(ctx context.Context, conversation []*genai.Cont
 				config.Tools = []*genai.Tool{agent.tools.List()}
 			}
 			config.SystemInstruction = agent.systemPrompt()
-			conversationToSend = conversation // Send the full conversation
+			conversationToSend = windowConversation(conversation, agent.contextWindow)
 			agent.trace("CacheInfo", map[string]string{"status": "not_using_persistent_cache", "reason": "no valid cache or history not grown", "cachedContent": agent.cachedContent, "cachedHistoryCount": fmt.Sprintf("%d", agent.cachedHistoryCount), "currentHistoryCount": fmt.Sprintf("%d", len(conversation))})
 		}
 		agent.trace("GenerateContentConfig", config) // Log the config being used
 		// Pass conversationToSend instead of the original 'conversation'
-		response, err = agent.client.Models.GenerateContent(ctx, agent.modelName, conversationToSend, config)
+		response, err = agent.generateContent(ctx, conversationToSend, config)
 
 		if err == nil {
 			agent.trace("<", response)
+			agent.usage.Add(agent.modelName, response.UsageMetadata)
 			return response, nil // Success
 		}
 
-		// Check if the error is a 500 error or similar that might benefit from a retry
-		// This is a basic check; you might want to make it more specific
-		if strings.Contains(err.Error(), "An internal error has occurred") || strings.Contains(err.Error(), "server error") {
-			fmt.Fprintf(os.Stderr, "Attempt %d/%d: Encountered API error: %v\n", attempt+1, maxRetries, err)
-			if attempt < len(retryDelays) {
-				delay := retryDelays[attempt]
-				fmt.Fprintf(os.Stderr, "Retrying in %s...\n", delay)
-				time.Sleep(delay)
-			} else if attempt < maxRetries-1 {
-				// If we've exhausted specific delays but not max retries, use the last delay value
-				delay := retryDelays[len(retryDelays)-1]
+		// A 403 for cached content is handled regardless of the retry policy:
+		// the cache is invalidated and the request is retried without it.
+		if apiErr, ok := asAPIError(err); ok {
+			if apiErr.Code == 403 && strings.Contains(apiErr.Message, "CachedContent") {
+				agent.trace("CachedContentError", map[string]string{"status": "ignoring_403_cached_content", "code": fmt.Sprintf("%d", apiErr.Code), "message": apiErr.Message})
+				fmt.Fprintf(os.Stderr, "Encountered API Error Code %d with CachedContent: %s. Invalidating cache and retrying without cache for this attempt.\n", apiErr.Code, apiErr.Message)
+				agent.cachedContent = "" // Invalidate cache
+				agent.cachedHistoryCount = 0
+				// Continue the loop to retry without cache for this specific attempt.
+				// The next iteration of the loop in runInference will not use cache.
+				continue // This will go to the next attempt in the retry loop.
+			}
+		}
+
+		if retry.isRetryable(err) {
+			fmt.Fprintf(os.Stderr, "Attempt %d/%d: Encountered API error: %v\n", attempt+1, retry.MaxRetries, err)
+			if attempt < retry.MaxRetries-1 {
+				delay := retry.delay(attempt)
+				if clock.Now().Sub(start)+delay > retry.MaxRetryTime {
+					fmt.Fprintf(os.Stderr, "Not retrying: waiting %s more would exceed the retry time limit of %s.\n", delay, retry.MaxRetryTime)
+					break
+				}
 				fmt.Fprintf(os.Stderr, "Retrying in %s...\n", delay)
-				time.Sleep(delay)
+				if err := clock.Sleep(ctx, delay); err != nil {
+					return nil, err
+				}
 			} else {
 				// Last attempt failed
-				fmt.Fprintf(os.Stderr, "All %d retry attempts failed.\n", maxRetries)
+				fmt.Fprintf(os.Stderr, "All %d retry attempts failed.\n", retry.MaxRetries)
 				break
 			}
 		} else {
 			// Non-retryable error
-			// Check for specific 403 CachedContent error using structured error fields
-			var apiErr *genai.APIError
-			if errors.As(err, &apiErr) {
-				// instruction.md suggests genai.APIError has a Code field (int) and Message field (string).
-				if apiErr.Code == 403 && strings.Contains(apiErr.Message, "CachedContent") {
-					agent.trace("CachedContentError", map[string]string{"status": "ignoring_403_cached_content", "code": fmt.Sprintf("%d", apiErr.Code), "message": apiErr.Message})
-					fmt.Fprintf(os.Stderr, "Encountered API Error Code %d with CachedContent: %s. Invalidating cache and retrying without cache for this attempt.\n", apiErr.Code, apiErr.Message)
-					agent.cachedContent = "" // Invalidate cache
-					agent.cachedHistoryCount = 0
-					// Continue the loop to retry without cache for this specific attempt.
-					// The next iteration of the loop in runInference will not use cache.
-					continue // This will go to the next attempt in the retry loop.
-				}
-			}
-
 			agent.trace("<", response) // Trace the error response if any
 			return response, err
 		}
@@ -724,37 +1311,122 @@ func (agent *Agent) runInference(ctx context.Context, conversation []*genai.Cont
 
 	// If all retries fail, return the last error
 	agent.trace("<", response) // Trace the final error response if any
-	return response, fmt.Errorf("after %d attempts, last error: %w", maxRetries, err)
+	return response, fmt.Errorf("after %d attempts in %s, last error: %w", attempts, clock.Now().Sub(start).Round(time.Millisecond), err)
+}
+
+// windowConversation returns the most recent messages of conversation, at most window of them.
+// A window of zero or less returns the whole conversation.
+// The window never starts with a function response, as the model rejects
+// responses without their corresponding function call.
+func windowConversation(conversation []*genai.Content, window int) []*genai.Content {
+	if window <= 0 || len(conversation) <= window {
+		return conversation
+	}
+	windowed := conversation[len(conversation)-window:]
+	for len(windowed) > 0 && isFunctionResponse(windowed[0]) {
+		windowed = windowed[1:]
+	}
+	return windowed
+}
+
+// isFunctionResponse reports whether content carries a function response.
+func isFunctionResponse(content *genai.Content) bool {
+	if content == nil {
+		return false
+	}
+	for _, part := range content.Parts {
+		if part != nil && part.FunctionResponse != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// modelSupportsThinking reports whether the model accepts a thinking configuration.
+// Only the Gemini 2.5 family supports it at the moment.
+func modelSupportsThinking(modelName string) bool {
+	return strings.Contains(modelName, "gemini-2.5")
 }
 
 func (agent *Agent) systemPrompt() *genai.Content {
-	if strings.TrimSpace(agent.systemInstruction) == "" {
+	instruction := agent.systemInstruction
+	if agent.clarify {
+		instruction = strings.TrimSpace(instruction + "\n\n" + clarifyInstruction)
+	}
+	if strings.TrimSpace(instruction) == "" {
 		return nil
 	}
 
-	return genai.NewContentFromText(agent.systemInstruction, genai.RoleUser)
+	return genai.NewContentFromText(instruction, genai.RoleUser)
 }
 
-// buildProject executes a hardcoded build command.
-func (agent *Agent) buildProject() error {
-	agent.geminiMessage("Attempting to build the project...")
+// buildOutputPath is where buildProject places the smolcode binary, relative to the working directory.
+const buildOutputPath = "smolcode"
 
-	// Hardcoded build command
-	fullBuildCommand := "go build -tags fts5 -o smolcode cmd/smolcode/main.go"
+// BuildCommandPath is the file the command run by /build and /reload is read from.
+const BuildCommandPath = ".smolcode/build.txt"
 
-	agent.geminiMessage("Executing build command: %s", fullBuildCommand)
+// DefaultBuildCommand is run by /build and /reload if BuildCommandPath is missing or blank.
+const DefaultBuildCommand = "go build -tags fts5 -o " + buildOutputPath + " cmd/smolcode/main.go"
+
+// BuildOutputPathFile is the file the path of the binary built by a custom build command is read from,
+// so that /reload knows what to restart.
+const BuildOutputPathFile = ".smolcode/build-output.txt"
+
+// buildCommand returns the command configured in path, or DefaultBuildCommand if the file is missing or blank.
+func buildCommand(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return DefaultBuildCommand, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read build command from %s: %w", path, err)
+	}
+	if command := strings.TrimSpace(string(data)); command != "" {
+		return command, nil
+	}
+	return DefaultBuildCommand, nil
+}
 
-	parts := strings.Fields(fullBuildCommand)
-	if len(parts) == 0 {
-		return fmt.Errorf("buildProject: hardcoded build command is effectively empty after splitting")
+// builtBinaryPath returns the binary restarted by /reload, given the files configuring the build command and its output:
+// the path configured in outputFile if there is one, or buildOutputPath if the default build command is used.
+// Where a custom build command places the binary is unknown otherwise, so that is an error.
+func builtBinaryPath(commandFile, outputFile string) (string, error) {
+	data, err := os.ReadFile(outputFile)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("failed to read build output path from %s: %w", outputFile, err)
+	}
+	if path := strings.TrimSpace(string(data)); path != "" {
+		return path, nil
+	}
+	command, err := buildCommand(commandFile)
+	if err != nil {
+		return "", err
+	}
+	if command != DefaultBuildCommand {
+		return "", fmt.Errorf("cannot reload: the build command in %s is custom, write the path of the binary it builds to %s", commandFile, outputFile)
+	}
+	return buildOutputPath, nil
+}
+
+// buildProject runs the build command, see buildCommand, and shows the compiler output as it is written.
+// It does not touch the session, so a failed build can simply be fixed and retried.
+func (agent *Agent) buildProject() error {
+	agent.geminiMessage("Attempting to build the project...")
+
+	fullBuildCommand, err := buildCommand(BuildCommandPath)
+	if err != nil {
+		return fmt.Errorf("buildProject: %w", err)
 	}
-	cmdName := parts[0]
-	cmdArgs := parts[1:]
 
-	cmd := exec.Command(cmdName, cmdArgs...)
-	cmd.Stdout = os.Stdout // Pipe build output to agent's stdout
-	cmd.Stderr = os.Stderr // Pipe build errors to agent's stderr
+	agent.geminiMessage("Executing build command: %s", fullBuildCommand)
 
+	// Run through the shell, so that configured commands may use pipes, && and the like.
+	cmd := exec.Command("sh", "-c", fullBuildCommand)
+	// The same writer for both streams keeps their output in order.
+	output := &displayWriter{displayer: agent.displayer}
+	cmd.Stdout = output
+	cmd.Stderr = output
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("buildProject: build failed: %w", err)
 	}
@@ -764,6 +1436,12 @@ func (agent *Agent) buildProject() error {
 }
 
 func (agent *Agent) reload() error {
+	// Find out what to restart before building, so that a missing configuration doesn't waste a build.
+	binaryPath, err := builtBinaryPath(BuildCommandPath, BuildOutputPathFile)
+	if err != nil {
+		return err
+	}
+
 	// First, try to build the project
 	if err := agent.buildProject(); err != nil {
 		// If build fails, return the error and don't proceed with reload
@@ -778,28 +1456,20 @@ func (agent *Agent) reload() error {
 	agent.geminiMessage("Conversation state saved. Current conversation ID: %s", agent.persistentConversation.ID)
 
 	// Prepare arguments for the new process
-	goCmdPath, err := exec.LookPath("go")
+	commandPath, args, err := reloadCommand(binaryPath)
 	if err != nil {
-		return fmt.Errorf("failed to find 'go' executable: %w", err)
+		return err
 	}
-
-	// Ensure the path to main.go is correct relative to the execution context
-	mainGoPath := "cmd/smolcode/main.go"
-
-	args := []string{
-		"go",
-		"run",
-		"-tags",
-		"fts5",
-		mainGoPath,
+	args = append(args,
 		"-conversation-id",              // New flag
 		agent.persistentConversation.ID, // Pass the current conversation ID
-	}
+	)
+	args = append(args, agent.runtimeArgs()...)
 
 	// Use syscall.Exec to replace the current process
 	fmt.Printf("Reloading with command: %s\n", strings.Join(args, " "))
 	env := os.Environ()
-	err = syscall.Exec(goCmdPath, args, env)
+	err = syscall.Exec(commandPath, args, env)
 	if err != nil {
 		// If syscall.Exec returns, it means an error occurred.
 		return fmt.Errorf("failed to execute new process: %w", err)
@@ -809,6 +1479,116 @@ func (agent *Agent) reload() error {
 	return errors.New("syscall.Exec finished unexpectedly without error, which indicates a failure")
 }
 
+// reloadCommand returns the executable and the leading arguments used to restart smolcode,
+// given the binary built by buildProject, see builtBinaryPath.
+// If the default binary cannot be found it falls back to `go run`; a configured binary must exist.
+func reloadCommand(binary string) (string, []string, error) {
+	binaryPath, err := filepath.Abs(binary)
+	if err == nil {
+		if info, statErr := os.Stat(binaryPath); statErr == nil && info.Mode().IsRegular() && info.Mode().Perm()&0111 != 0 {
+			return binaryPath, []string{binaryPath}, nil
+		}
+	}
+	if binary != buildOutputPath {
+		return "", nil, fmt.Errorf("cannot reload: %s configured in %s is not an executable file", binary, BuildOutputPathFile)
+	}
+
+	goCmdPath, err := exec.LookPath("go")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to find 'go' executable: %w", err)
+	}
+	// Ensure the path to main.go is correct relative to the execution context
+	mainGoPath := "cmd/smolcode/main.go"
+	return goCmdPath, []string{"go", "run", "-tags", "fts5", mainGoPath}, nil
+}
+
+// runtimeArgs returns the command line flags that recreate the agent's current configuration,
+// so that a reloaded process comes up configured the same way.
+// Settings from configuration files or the environment are left out, see givenOnCommandLine.
+func (agent *Agent) runtimeArgs() []string {
+	args := []string{"-model", agent.modelName}
+	lazy := false
+	for _, config := range agent.mcpConfigs {
+		args = append(args, "-mcp", config.ID+":"+config.Command)
+		lazy = lazy || config.Lazy
+	}
+	if lazy {
+		args = append(args, "-mcp-lazy")
+	}
+	if isUnsafe(agent.safetySettings) && agent.givenOnCommandLine("safetySettings") {
+		args = append(args, "-unsafe")
+	}
+	if agent.thinkingBudget > 0 && agent.givenOnCommandLine("thinkingBudget") {
+		args = append(args, "-thinking-budget", fmt.Sprint(agent.thinkingBudget))
+	}
+	if agent.contextWindow > 0 && agent.givenOnCommandLine("contextWindow") {
+		args = append(args, "-context-window", fmt.Sprint(agent.contextWindow))
+	}
+	if agent.changeSummaryDisabled && agent.givenOnCommandLine("noChangeSummary") {
+		args = append(args, "-no-summary")
+	}
+	if agent.toolRateLimit > 0 && agent.givenOnCommandLine("toolRateLimit") {
+		args = append(args, "-tool-rate-limit", fmt.Sprint(agent.toolRateLimit))
+	}
+	if agent.globalToolRateLimit > 0 && agent.givenOnCommandLine("globalToolRateLimit") {
+		args = append(args, "-global-tool-rate-limit", fmt.Sprint(agent.globalToolRateLimit))
+	}
+	if agent.retryConfig.MaxRetryTime > 0 && agent.givenOnCommandLine("maxRetrySeconds") {
+		args = append(args, "-max-retry-seconds", fmt.Sprint(int(agent.retryConfig.MaxRetryTime.Seconds())))
+	}
+	if agent.streaming && agent.givenOnCommandLine("stream") {
+		args = append(args, "-stream")
+	}
+	if agent.givenOnCommandLine("disabledTools") {
+		for _, name := range agent.disabledTools {
+			args = append(args, "-disable-tool", name)
+		}
+	}
+	if agent.promptTemplate != "" && agent.givenOnCommandLine("promptTemplate") {
+		args = append(args, "-prompt-template", agent.promptTemplate)
+	}
+	if agent.deterministic && agent.givenOnCommandLine("deterministic") {
+		args = append(args, "-deterministic")
+	}
+	if agent.quiet && agent.givenOnCommandLine("quiet") {
+		args = append(args, "-quiet")
+	}
+	if agent.clarify && agent.givenOnCommandLine("clarify") {
+		args = append(args, "-clarify")
+	}
+	if agent.autoRecall && agent.givenOnCommandLine("autoRecall") {
+		args = append(args, "-auto-recall")
+		if agent.autoRecallLimit != DefaultAutoRecallLimit && agent.givenOnCommandLine("autoRecallLimit") {
+			args = append(args, "-auto-recall-limit", fmt.Sprint(agent.autoRecallLimit))
+		}
+		if agent.autoRecallMinScore != 0 && agent.givenOnCommandLine("autoRecallMinScore") {
+			args = append(args, "-auto-recall-min-score", fmt.Sprint(agent.autoRecallMinScore))
+		}
+	}
+	return args
+}
+
+// givenOnCommandLine reports whether the setting key, as in ConfigKeys, was given on the command line.
+// Settings of an agent not configured by newSession count as given on the command line.
+// The reloaded process reads the other settings from the configuration files and the environment again,
+// so that changes to them take effect.
+func (agent *Agent) givenOnCommandLine(key string) bool {
+	return agent.configSources == nil || agent.configSources[key] == SourceOverride
+}
+
+// isUnsafe reports whether settings disable blocking for every category, as set by --unsafe.
+func isUnsafe(settings []*genai.SafetySetting) bool {
+	if len(settings) != len(UnsafeSafetySettings()) {
+		return false
+	}
+	for _, setting := range settings {
+		if setting.Threshold != genai.HarmBlockThresholdBlockNone {
+			return false
+		}
+	}
+	return true
+}
+
 func readFileContent(filepath string) (string, error) {
 	content, err := os.ReadFile(filepath)
 	if err != nil {
@@ -882,6 +1662,7 @@ func (agent *Agent) persistFullConversationToDB() error {
 
 	}
 	agent.trace("PersistToDB", map[string]string{"status": "appending_history_as_bytes", "count": fmt.Sprintf("%d", len(agent.persistentConversation.Messages))})
+	agent.persistentConversation.Model = agent.modelName
 
 	// 3. Call history.Save(a.persistentConversation) to save to SQLite.
 	err := history.Save(agent.persistentConversation)
@@ -923,18 +1704,84 @@ func formatUsageMetadata(metadata *genai.GenerateContentResponseUsageMetadata) s
 		// If PromptTokenCount > 0 and CachedContentTokenCount is 0, it implies cache was not used for prompt tokens.
 		cacheInfo = " (Cache Miss/Not Used)"
 	}
+	thoughtsInfo := ""
+	if metadata.ThoughtsTokenCount > 0 {
+		thoughtsInfo = fmt.Sprintf(", Thoughts=%d", metadata.ThoughtsTokenCount)
+	}
 
 	return fmt.Sprintf(
-		"Token Usage: Prompt=%d/%d (%d%%)%s, Candidates=%d, Total=%d",
+		"Token Usage: Prompt=%d/%d (%d%%)%s, Candidates=%d%s, Total=%d",
 		metadata.PromptTokenCount,
 		limit,
 		(metadata.PromptTokenCount*100)/int32(limit), // Calculate percentage
 		cacheInfo,
 		metadata.CandidatesTokenCount,
+		thoughtsInfo,
 		metadata.TotalTokenCount,
 	)
 }
 
+// maxSafetyRetries is the number of times a response that was filtered for
+// safety reasons is regenerated before giving up and returning to the user.
+const maxSafetyRetries = 1
+
+// blockedResponseReason reports why a response was blocked by the content filters.
+// It returns an empty string if the response was not blocked.
+// The boolean result indicates whether asking again might succeed:
+// a blocked prompt will be blocked again, while a filtered candidate might not be.
+func blockedResponseReason(response *genai.GenerateContentResponse) (string, bool) {
+	if response == nil {
+		return "", false
+	}
+	if feedback := response.PromptFeedback; feedback != nil && feedback.BlockReason != "" {
+		reason := fmt.Sprintf("prompt blocked (%s)", feedback.BlockReason)
+		if feedback.BlockReasonMessage != "" {
+			reason += ": " + feedback.BlockReasonMessage
+		}
+		return reason + formatSafetyRatings(feedback.SafetyRatings), false
+	}
+	if len(response.Candidates) == 0 || response.Candidates[0] == nil {
+		return "", false
+	}
+
+	candidate := response.Candidates[0]
+	switch candidate.FinishReason {
+	case genai.FinishReasonSafety, genai.FinishReasonRecitation:
+		return describeFinishReason(candidate), true
+	case genai.FinishReasonBlocklist, genai.FinishReasonProhibitedContent, genai.FinishReasonSPII:
+		return describeFinishReason(candidate), false
+	}
+	return "", false
+}
+
+// describeFinishReason renders a candidate's finish reason together with the
+// finish message and any safety ratings that triggered the block.
+func describeFinishReason(candidate *genai.Candidate) string {
+	reason := fmt.Sprintf("finish reason %s", candidate.FinishReason)
+	if candidate.FinishMessage != "" {
+		reason += ": " + candidate.FinishMessage
+	}
+	return reason + formatSafetyRatings(candidate.SafetyRatings)
+}
+
+// formatSafetyRatings lists the harm categories that were blocked or rated
+// with at least a medium probability, e.g. " [HARM_CATEGORY_HARASSMENT=HIGH]".
+func formatSafetyRatings(ratings []*genai.SafetyRating) string {
+	var flagged []string
+	for _, rating := range ratings {
+		if rating == nil {
+			continue
+		}
+		if rating.Blocked || rating.Probability == genai.HarmProbabilityMedium || rating.Probability == genai.HarmProbabilityHigh {
+			flagged = append(flagged, fmt.Sprintf("%s=%s", rating.Category, rating.Probability))
+		}
+	}
+	if len(flagged) == 0 {
+		return ""
+	}
+	return " [" + strings.Join(flagged, ", ") + "]"
+}
+
 func CropText(in string, width int) string {
 	if len(in) <= width {
 		return in
@@ -0,0 +1,434 @@
+package smolcode
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/dhamidi/smolcode/history"
+	"github.com/dhamidi/smolcode/mcp"
+	"google.golang.org/genai"
+)
+
+func TestWindowConversationExcludesOlderMessages(t *testing.T) {
+	var sent []*genai.Content
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Contents []*genai.Content `json:"contents"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		sent = request.Contents
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"candidates":[{"content":{"role":"model","parts":[{"text":"fifth"}]}}]}`)
+	}))
+	defer server.Close()
+	client, err := genai.NewClient(context.Background(), &genai.ClientConfig{
+		APIKey:      "test-key",
+		Backend:     genai.BackendGeminiAPI,
+		HTTPOptions: genai.HTTPOptions{BaseURL: server.URL},
+	})
+	if err != nil {
+		t.Fatalf("genai.NewClient failed: %v", err)
+	}
+	agent := (&Agent{client: client, displayer: &recordingDisplay{}}).ChooseModel("gemini-2.5-flash").WithContextWindow(2)
+	agent.history = []*genai.Content{
+		genai.NewContentFromText("first", genai.RoleUser),
+		genai.NewContentFromText("second", genai.RoleModel),
+		genai.NewContentFromText("third", genai.RoleUser),
+		genai.NewContentFromText("fourth", genai.RoleModel),
+	}
+
+	if _, err := agent.runInference(context.Background(), agent.history); err != nil {
+		t.Fatalf("runInference failed: %v", err)
+	}
+
+	if len(sent) != 2 {
+		t.Fatalf("expected 2 messages to be sent, got %d", len(sent))
+	}
+	if sent[0].Parts[0].Text != "third" || sent[1].Parts[0].Text != "fourth" {
+		t.Errorf("expected the most recent messages to be sent, got %q and %q", sent[0].Parts[0].Text, sent[1].Parts[0].Text)
+	}
+	if len(agent.history) != 4 {
+		t.Errorf("expected history to retain all 4 messages, got %d", len(agent.history))
+	}
+}
+
+func TestWindowConversationDoesNotStartWithFunctionResponse(t *testing.T) {
+	conversation := []*genai.Content{
+		genai.NewContentFromText("read main.go", genai.RoleUser),
+		genai.NewContentFromFunctionCall("read_file", map[string]any{"path": "main.go"}, genai.RoleModel),
+		genai.NewContentFromFunctionResponse("read_file", map[string]any{"content": "package main"}, "tool"),
+		genai.NewContentFromText("It is the main package.", genai.RoleModel),
+	}
+
+	sent := windowConversation(conversation, 2)
+
+	if len(sent) != 1 || sent[0].Parts[0].Text != "It is the main package." {
+		t.Errorf("expected the dangling function response to be dropped, got %s", AsJSON(sent))
+	}
+}
+
+func TestWindowConversationZeroSendsEverything(t *testing.T) {
+	conversation := []*genai.Content{
+		genai.NewContentFromText("first", genai.RoleUser),
+		genai.NewContentFromText("second", genai.RoleModel),
+	}
+	if sent := windowConversation(conversation, 0); len(sent) != len(conversation) {
+		t.Errorf("expected %d messages, got %d", len(conversation), len(sent))
+	}
+}
+
+func TestRuntimeArgsRecreateConfiguration(t *testing.T) {
+	agent := (&Agent{mcpConfigs: []MCPServerConfig{{ID: "docs", Command: "./docs-server --stdio"}}}).
+		ChooseModel("gemini-2.5-flash").
+		WithSafetySettings(UnsafeSafetySettings()).
+		WithThinkingBudget(1024).
+		WithContextWindow(20).
+		DisableChangeSummary().
+		EnableDeterministicGeneration()
+
+	got := strings.Join(agent.runtimeArgs(), " ")
+
+	want := "-model gemini-2.5-flash -mcp docs:./docs-server --stdio -unsafe -thinking-budget 1024 -context-window 20 -no-summary -deterministic"
+	if got != want {
+		t.Errorf("expected args %q, got %q", want, got)
+	}
+}
+
+func TestRuntimeArgsOmitSettingsFromConfiguration(t *testing.T) {
+	agent := (&Agent{configSources: map[string]string{
+		"thinkingBudget": SourceOverride,
+		"toolRateLimit":  DefaultConfigPath,
+		"quiet":          SourceEnvironment,
+	}}).
+		ChooseModel("gemini-2.5-flash").
+		WithThinkingBudget(1024).
+		WithToolRateLimit(2).
+		Quiet()
+
+	got := strings.Join(agent.runtimeArgs(), " ")
+
+	want := "-model gemini-2.5-flash -thinking-budget 1024"
+	if got != want {
+		t.Errorf("expected args %q, got %q", want, got)
+	}
+}
+
+func TestRuntimeArgsOmitDefaults(t *testing.T) {
+	agent := (&Agent{}).ChooseModel("gemini-2.5-pro")
+
+	got := agent.runtimeArgs()
+
+	if len(got) != 2 || got[0] != "-model" || got[1] != "gemini-2.5-pro" {
+		t.Errorf("expected only the model to be passed, got %q", got)
+	}
+}
+
+func TestMCPToolResultAttachesImages(t *testing.T) {
+	result := mcpToolResult([]mcp.ToolResultContent{
+		{Type: "text", Text: "chart rendered"},
+		{Type: "image", Data: base64.StdEncoding.EncodeToString([]byte("png-bytes")), MimeType: "image/png"},
+	})
+
+	if len(result.Attachments) != 1 || string(result.Attachments[0].Data) != "png-bytes" || result.Attachments[0].MIMEType != "image/png" {
+		t.Fatalf("expected the decoded image to be attached, got %+v", result.Attachments)
+	}
+	if output := result.Output["output"].(string); !strings.HasPrefix(output, "chart rendered\n") {
+		t.Errorf("expected the text content in the output, got %q", output)
+	}
+}
+
+func TestRealClockSleepReturnsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	start := time.Now()
+	err := realClock{}.Sleep(ctx, time.Minute)
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("expected to return without waiting for the delay")
+	}
+}
+
+// recordingDisplay is a TextDisplayer that remembers the messages it displays.
+type recordingDisplay struct {
+	RawTextDisplay
+	messages []string
+	skips    []SkipReason
+	lines    []string
+	prompts  []string
+}
+
+func (d *recordingDisplay) DisplayPrompt(format string, args ...interface{}) {
+	d.prompts = append(d.prompts, fmt.Sprintf(format, args...))
+}
+
+func (d *recordingDisplay) Display(content string) error {
+	d.lines = append(d.lines, content)
+	return nil
+}
+
+func (d *recordingDisplay) DisplayMessage(role string, colorCode string, historyCount int, format string, args ...interface{}) {
+	d.messages = append(d.messages, fmt.Sprintf("%s [%d]: ", role, historyCount)+fmt.Sprintf(format, args...))
+}
+
+func (d *recordingDisplay) DisplaySkip(reason SkipReason, historyCount int, format string, args ...interface{}) {
+	d.skips = append(d.skips, reason)
+}
+
+func TestRunReportsSkipReasons(t *testing.T) {
+	display := &recordingDisplay{}
+	inputs := []string{""}
+	agent := &Agent{displayer: display, getUserMessage: func() (string, bool) {
+		if len(inputs) == 0 {
+			return "", false
+		}
+		input := inputs[0]
+		inputs = inputs[1:]
+		return input, true
+	}}
+
+	if err := agent.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if len(display.skips) != 1 || display.skips[0] != SkipEmptyUserInput {
+		t.Errorf("expected a skip for empty user input, got %q", display.skips)
+	}
+}
+
+func TestAddUserMessageEchoesThroughDisplayer(t *testing.T) {
+	history.SetStore(history.NewMemoryStore())
+	t.Cleanup(func() { history.SetStore(nil) })
+	conv, err := history.New()
+	if err != nil {
+		t.Fatalf("history.New failed: %v", err)
+	}
+	display := &recordingDisplay{}
+	agent := &Agent{persistentConversation: conv, displayer: display}
+
+	agent.addUserMessage("not echoed")
+	agent.EchoUserMessages().addUserMessage("hello %s")
+
+	want := []string{"You [1]: hello %s"}
+	if len(display.messages) != len(want) || display.messages[0] != want[0] {
+		t.Errorf("expected displayed messages %q, got %q", want, display.messages)
+	}
+	if len(agent.history) != 2 {
+		t.Errorf("expected both messages in the history, got %d", len(agent.history))
+	}
+}
+
+func TestLazyMCPServerRegistersCachedTools(t *testing.T) {
+	t.Chdir(t.TempDir())
+	command := "./missing-docs-server --stdio"
+	catalog := &mcp.ToolCatalog{Command: command, Tools: mcp.Tools{{Name: "search", Description: "Search the docs", RawInputSchema: json.RawMessage(`{"type":"object"}`)}}}
+	if err := mcp.NewToolCache(mcp.DefaultToolCacheDir).Store(catalog); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	conv, _ := history.New()
+
+	agent := NewAgent(nil, nil, ToolBox{}, "", nil, conv, "main", conv.ID, 0, true, []MCPServerConfig{{ID: "docs", Command: command, Lazy: true}})
+	agent.displayer = &recordingDisplay{}
+
+	if _, found := agent.tools.Get("docs_search"); !found {
+		t.Fatalf("expected the cached tool to be registered, got %v", agent.tools.Names())
+	}
+	if len(agent.mcpActiveServers) != 0 {
+		t.Errorf("expected the server not to be started before a tool is called")
+	}
+	response := agent.executeTool(context.Background(), &genai.FunctionCall{Name: "docs_search"})
+	if errorMessage, _ := response.Parts[0].FunctionResponse.Response["error"].(string); !strings.Contains(errorMessage, "failed to start MCP server docs") {
+		t.Errorf("expected the call to start the server, got %v", response.Parts[0].FunctionResponse.Response)
+	}
+}
+
+func TestCodeFailsEarlyWithoutAPIKey(t *testing.T) {
+	t.Setenv("GEMINI_API_KEY", "")
+	history.SetStore(history.NewMemoryStore())
+	t.Cleanup(func() { history.SetStore(nil) })
+
+	err := Code("", "", true, nil, nil)
+
+	if !errors.Is(err, ErrMissingAPIKey) {
+		t.Fatalf("expected ErrMissingAPIKey, got %v", err)
+	}
+	if conversations, _ := history.List(); len(conversations) != 0 {
+		t.Errorf("expected no conversation to be created, got %d", len(conversations))
+	}
+}
+
+func TestRunDiscardsCancelledRequest(t *testing.T) {
+	history.SetStore(history.NewMemoryStore())
+	t.Cleanup(func() { history.SetStore(nil) })
+	conv, err := history.New()
+	if err != nil {
+		t.Fatalf("history.New failed: %v", err)
+	}
+	cancelled := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, ":generateContent") {
+			http.Error(w, "not supported", http.StatusNotFound)
+			return
+		}
+		// Press Ctrl-C while the model is thinking.
+		syscall.Kill(os.Getpid(), syscall.SIGINT)
+		select {
+		case <-r.Context().Done():
+		case <-cancelled:
+		}
+	}))
+	defer server.Close()
+	defer close(cancelled)
+	client, err := genai.NewClient(context.Background(), &genai.ClientConfig{
+		APIKey:      "test-key",
+		Backend:     genai.BackendGeminiAPI,
+		HTTPOptions: genai.HTTPOptions{BaseURL: server.URL},
+	})
+	if err != nil {
+		t.Fatalf("genai.NewClient failed: %v", err)
+	}
+	inputs := []string{"never answered"}
+	agent := (&Agent{client: client, persistentConversation: conv, displayer: &recordingDisplay{}, getUserMessage: func() (string, bool) {
+		if len(inputs) == 0 {
+			return "", false
+		}
+		input := inputs[0]
+		inputs = inputs[1:]
+		return input, true
+	}}).ChooseModel("gemini-2.5-flash").Quiet()
+	agent.history = []*genai.Content{
+		genai.NewContentFromText("first", genai.RoleUser),
+		genai.NewContentFromText("answer", genai.RoleModel),
+	}
+	if err := agent.persistFullConversationToDB(); err != nil {
+		t.Fatalf("persistFullConversationToDB failed: %v", err)
+	}
+
+	if err := agent.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if len(agent.history) != 2 || agent.history[1].Parts[0].Text != "answer" {
+		t.Errorf("expected the history to be unchanged, got %s", AsJSON(agent.history))
+	}
+	if agent.turns != 0 {
+		t.Errorf("expected the cancelled request not to count as a turn, got %d", agent.turns)
+	}
+	stored, err := history.Load(conv.ID)
+	if err != nil {
+		t.Fatalf("history.Load failed: %v", err)
+	}
+	if len(stored.Messages) != 2 {
+		t.Errorf("expected the stored conversation to be unchanged, got %d messages", len(stored.Messages))
+	}
+}
+
+func TestSecondInterruptWithinWindowRequestsQuit(t *testing.T) {
+	agent := &Agent{}
+	start := time.Now()
+
+	agent.recordInterrupt(start)
+	agent.recordInterrupt(start.Add(interruptWindow + time.Second))
+	if agent.quitRequested() {
+		t.Fatalf("expected interrupts further apart than the window to only cancel requests")
+	}
+
+	agent.recordInterrupt(start.Add(interruptWindow + 2*time.Second))
+	if !agent.quitRequested() {
+		t.Errorf("expected a second interrupt within the window to request quitting")
+	}
+}
+
+func TestFinalResponseTextSkipsToolCallsAndThoughts(t *testing.T) {
+	conversation := []*genai.Content{
+		genai.NewContentFromText("fix the build", genai.RoleUser),
+		{Role: genai.RoleModel, Parts: []*genai.Part{{Text: "Done."}, {Text: " The build passes."}}},
+		{Role: genai.RoleModel, Parts: []*genai.Part{{FunctionCall: &genai.FunctionCall{Name: "run_command"}}}},
+		{Role: genai.RoleModel, Parts: []*genai.Part{{Text: "thinking", Thought: true}}},
+	}
+
+	if got, want := finalResponseText(conversation), "Done. The build passes."; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	unanswered := append(conversation, genai.NewContentFromText("now run the tests", genai.RoleUser))
+	if got := finalResponseText(unanswered); got != "" {
+		t.Errorf("expected no text for an unanswered user message, got %q", got)
+	}
+	toolCallsOnly := append(unanswered, &genai.Content{Role: genai.RoleModel, Parts: []*genai.Part{{FunctionCall: &genai.FunctionCall{Name: "run_command"}}}})
+	if got := finalResponseText(toolCallsOnly); got != "" {
+		t.Errorf("expected no text when the model only called tools since the last user message, got %q", got)
+	}
+}
+
+func TestBuildProjectStreamsOutputThroughDisplayer(t *testing.T) {
+	t.Chdir(t.TempDir())
+	os.Mkdir(".smolcode", 0755)
+	if err := os.WriteFile(BuildCommandPath, []byte("echo compiling; echo 'main.go:1: syntax error' >&2; exit 1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	display := &recordingDisplay{}
+	agent := &Agent{displayer: display}
+
+	if err := agent.buildProject(); err == nil {
+		t.Fatal("expected the build to fail")
+	}
+
+	if got, want := strings.Join(display.prompts, ""), "compiling\nmain.go:1: syntax error\n"; got != want {
+		t.Errorf("expected the build output %q to be displayed, got %q", want, got)
+	}
+}
+
+func TestBuiltBinaryPath(t *testing.T) {
+	dir := t.TempDir()
+	custom := filepath.Join(dir, "build.txt")
+	output := filepath.Join(dir, "build-output.txt")
+	missing := filepath.Join(dir, "missing.txt")
+	os.WriteFile(custom, []byte("make smolcode\n"), 0644)
+	os.WriteFile(output, []byte("bin/smolcode\n"), 0644)
+
+	if got, err := builtBinaryPath(missing, missing); err != nil || got != buildOutputPath {
+		t.Errorf("expected %q for the default build command, got %q, %v", buildOutputPath, got, err)
+	}
+	if got, err := builtBinaryPath(custom, output); err != nil || got != "bin/smolcode" {
+		t.Errorf("expected the configured output path, got %q, %v", got, err)
+	}
+	if _, err := builtBinaryPath(custom, missing); err == nil || !strings.Contains(err.Error(), "missing.txt") {
+		t.Errorf("expected an error naming the output path file for a custom build command, got %v", err)
+	}
+}
+
+func TestBuildCommand(t *testing.T) {
+	dir := t.TempDir()
+	blank := filepath.Join(dir, "blank.txt")
+	configured := filepath.Join(dir, "build.txt")
+	os.WriteFile(blank, []byte(" \n\t\n"), 0644)
+	os.WriteFile(configured, []byte("make smolcode\n"), 0644)
+
+	for path, want := range map[string]string{
+		filepath.Join(dir, "missing.txt"): DefaultBuildCommand,
+		blank:                             DefaultBuildCommand,
+		configured:                        "make smolcode",
+	} {
+		got, err := buildCommand(path)
+		if err != nil || got != want {
+			t.Errorf("buildCommand(%s) = %q, %v, want %q", filepath.Base(path), got, err, want)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package smolcode
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ErrPathNotAllowed is returned by file tools for paths outside the allowed roots, see SetAllowedRoots.
+var ErrPathNotAllowed = errors.New("permission denied: path is outside the directories smolcode may access")
+
+// ErrSymlinkWrite is returned by file tools that write through a symbolic link, see SetAllowSymlinkWrites.
+var ErrSymlinkWrite = errors.New("permission denied: refusing to write through a symbolic link")
+
+var (
+	allowedRootsMutex  sync.RWMutex
+	allowedRoots       []string // Resolved absolute paths; empty means the working directory.
+	allowSymlinkWrites bool
+)
+
+// SetAllowedRoots confines the file tools to the directories roots and everything below them.
+// Relative roots are resolved against the working directory.
+// An empty list restores the default, which is the working directory.
+func SetAllowedRoots(roots []string) error {
+	resolved := make([]string, 0, len(roots))
+	for _, root := range roots {
+		abs, err := resolvePath(root)
+		if err != nil {
+			return fmt.Errorf("invalid allowed root %q: %w", root, err)
+		}
+		resolved = append(resolved, abs)
+	}
+	allowedRootsMutex.Lock()
+	defer allowedRootsMutex.Unlock()
+	allowedRoots = resolved
+	return nil
+}
+
+// SetAllowSymlinkWrites controls whether the file tools may write through symbolic links below an allowed root.
+// The link target must lie within an allowed root either way.
+func SetAllowSymlinkWrites(allow bool) {
+	allowedRootsMutex.Lock()
+	defer allowedRootsMutex.Unlock()
+	allowSymlinkWrites = allow
+}
+
+// allowedPath returns path with symbolic links resolved, or an error wrapping ErrPathNotAllowed
+// unless it lies within an allowed root.
+// Paths are compared after cleaning ".." and resolving symbolic links, so neither can be used to escape.
+// Callers should operate on the returned path, so that the file they check is the file they access.
+func allowedPath(path string) (string, error) {
+	resolved, err := resolvePath(path)
+	if err != nil {
+		return "", err
+	}
+	roots, err := currentAllowedRoots()
+	if err != nil {
+		return "", err
+	}
+	for _, root := range roots {
+		if isWithin(resolved, root) {
+			return resolved, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrPathNotAllowed, path)
+}
+
+// allowedWritePath works like allowedPath, and additionally returns an error wrapping ErrSymlinkWrite
+// if path passes through a symbolic link below an allowed root, unless SetAllowSymlinkWrites permits it.
+// Symbolic links above the roots, like a symlinked home directory, are fine.
+func allowedWritePath(path string) (string, error) {
+	resolved, err := allowedPath(path)
+	if err != nil {
+		return "", err
+	}
+	allowedRootsMutex.RLock()
+	allow := allowSymlinkWrites
+	allowedRootsMutex.RUnlock()
+	if allow {
+		return resolved, nil
+	}
+	roots, err := currentAllowedRoots()
+	if err != nil {
+		return "", err
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	// Follow the path from the top, resolving links on the way, so that a link is judged by the directory
+	// it really is in, even if it points back at a root or above it.
+	volume := filepath.VolumeName(abs)
+	current := volume + string(filepath.Separator)
+	for _, name := range strings.Split(abs[len(current):], string(filepath.Separator)) {
+		next := filepath.Join(current, name)
+		info, err := os.Lstat(next)
+		if err != nil {
+			break // The rest of the path doesn't exist yet, so it holds no links.
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			if withinRoots(current, roots) {
+				return "", fmt.Errorf("%w: %s", ErrSymlinkWrite, next)
+			}
+			if next, err = filepath.EvalSymlinks(next); err != nil {
+				break
+			}
+		}
+		current = next
+	}
+	return resolved, nil
+}
+
+// withinRoots reports whether path is one of roots or lies below one of them.
+func withinRoots(path string, roots []string) bool {
+	for _, root := range roots {
+		if isWithin(path, root) {
+			return true
+		}
+	}
+	return false
+}
+
+// currentAllowedRoots returns the roots set by SetAllowedRoots, or the working directory if there are none.
+func currentAllowedRoots() ([]string, error) {
+	allowedRootsMutex.RLock()
+	roots := allowedRoots
+	allowedRootsMutex.RUnlock()
+	if len(roots) > 0 {
+		return roots, nil
+	}
+	workingDirectory, err := resolvePath(".")
+	if err != nil {
+		return nil, err
+	}
+	return []string{workingDirectory}, nil
+}
+
+// isWithin reports whether path is dir or lies below it. Both must be clean absolute paths.
+func isWithin(path, dir string) bool {
+	return path == dir || strings.HasPrefix(path, strings.TrimSuffix(dir, string(filepath.Separator))+string(filepath.Separator))
+}
+
+// resolvePath returns the absolute, cleaned form of path with symbolic links resolved.
+// Parts of the path that don't exist yet are kept as they are.
+func resolvePath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	existing, missing := abs, ""
+	for {
+		resolved, err := filepath.EvalSymlinks(existing)
+		if err == nil {
+			return filepath.Join(resolved, missing), nil
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			return abs, nil
+		}
+		missing = filepath.Join(filepath.Base(existing), missing)
+		existing = parent
+	}
+}
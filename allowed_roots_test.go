@@ -0,0 +1,139 @@
+package smolcode
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAllowedPathRejectsEscapes(t *testing.T) {
+	outside := t.TempDir()
+	project := filepath.Join(t.TempDir(), "project")
+	os.MkdirAll(filepath.Join(project, "src"), 0755)
+	os.Symlink(outside, filepath.Join(project, "link"))
+	t.Chdir(project)
+
+	for _, path := range []string{
+		"..",
+		"../secret.txt",
+		"src/../../secret.txt",
+		filepath.Join(outside, "secret.txt"),
+		"link/secret.txt",
+		"/etc/passwd",
+	} {
+		if _, err := allowedPath(path); !errors.Is(err, ErrPathNotAllowed) {
+			t.Errorf("allowedPath(%q) = %v, want ErrPathNotAllowed", path, err)
+		}
+	}
+	for _, path := range []string{".", "src", "src/../main.go", "new/dir/file.txt"} {
+		if _, err := allowedPath(path); err != nil {
+			t.Errorf("allowedPath(%q) = %v, want nil", path, err)
+		}
+	}
+}
+
+func TestSetAllowedRoots(t *testing.T) {
+	shared := t.TempDir()
+	t.Chdir(t.TempDir())
+	if err := SetAllowedRoots([]string{".", shared}); err != nil {
+		t.Fatalf("SetAllowedRoots failed: %v", err)
+	}
+	t.Cleanup(func() { SetAllowedRoots(nil) })
+
+	if _, err := allowedPath(filepath.Join(shared, "lib.go")); err != nil {
+		t.Errorf("expected paths in an additional root to be allowed, got %v", err)
+	}
+	if _, err := allowedPath(filepath.Dir(shared)); !errors.Is(err, ErrPathNotAllowed) {
+		t.Errorf("expected the parent of a root to be refused, got %v", err)
+	}
+}
+
+func TestFileToolsRefusePathsOutsideRoots(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	for name, call := range map[string]func() (map[string]any, error){
+		"read_file": func() (map[string]any, error) {
+			return ReadFileTool.Function(map[string]any{"filepath": "../secret.txt"})
+		},
+		"list_files": func() (map[string]any, error) { return ListFilesTool.Function(map[string]any{"filepath": "../"}) },
+		"write_file": func() (map[string]any, error) {
+			return writeFile(map[string]any{"filepath": "../escaped.txt", "content": "x"})
+		},
+		"edit_file": func() (map[string]any, error) {
+			return editFile(map[string]any{"filepath": "../escaped.txt", "old_str": "", "new_str": "x"})
+		},
+	} {
+		if _, err := call(); !errors.Is(err, ErrPathNotAllowed) {
+			t.Errorf("%s: expected ErrPathNotAllowed, got %v", name, err)
+		}
+	}
+	if _, err := os.Stat("../escaped.txt"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected no file to be written outside the root")
+	}
+}
+
+func TestFileToolsRefuseSymlinksOutsideRoots(t *testing.T) {
+	outside := t.TempDir()
+	os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0644)
+	t.Chdir(t.TempDir())
+	os.Symlink(filepath.Join(outside, "secret.txt"), "secret.txt")
+
+	if _, err := ReadFileTool.Function(map[string]any{"filepath": "secret.txt"}); !errors.Is(err, ErrPathNotAllowed) {
+		t.Errorf("read_file: expected ErrPathNotAllowed, got %v", err)
+	}
+	if _, err := writeFile(map[string]any{"filepath": "secret.txt", "content": "x"}); !errors.Is(err, ErrPathNotAllowed) {
+		t.Errorf("write_file: expected ErrPathNotAllowed, got %v", err)
+	}
+	if contents, _ := os.ReadFile(filepath.Join(outside, "secret.txt")); string(contents) != "secret" {
+		t.Errorf("expected the file outside the root to be unchanged, got %q", contents)
+	}
+}
+
+func TestWritesThroughSymlinksRequirePermission(t *testing.T) {
+	t.Chdir(t.TempDir())
+	os.Mkdir("src", 0755)
+	os.WriteFile("src/main.go", []byte("package main"), 0644)
+	os.Symlink("main.go", "src/link.go")
+	os.Symlink("src", "alias")
+
+	for _, path := range []string{"src/link.go", "alias/new.go"} {
+		if _, err := writeFile(map[string]any{"filepath": path, "content": "x"}); !errors.Is(err, ErrSymlinkWrite) {
+			t.Errorf("write_file(%q): expected ErrSymlinkWrite, got %v", path, err)
+		}
+		if _, err := editFile(map[string]any{"filepath": path, "old_str": "package", "new_str": "x"}); !errors.Is(err, ErrSymlinkWrite) {
+			t.Errorf("edit_file(%q): expected ErrSymlinkWrite, got %v", path, err)
+		}
+	}
+	if _, err := ReadFileTool.Function(map[string]any{"filepath": "src/link.go"}); err != nil {
+		t.Errorf("expected reading through a symlink within the root to work, got %v", err)
+	}
+
+	SetAllowSymlinkWrites(true)
+	t.Cleanup(func() { SetAllowSymlinkWrites(false) })
+	if _, err := writeFile(map[string]any{"filepath": "src/link.go", "content": "package link"}); err != nil {
+		t.Fatalf("expected writing through a symlink to be permitted, got %v", err)
+	}
+	if contents, _ := os.ReadFile("src/main.go"); string(contents) != "package link" {
+		t.Errorf("expected the link target to be written, got %q", contents)
+	}
+}
+
+func TestWritesThroughSymlinksToTheRootRequirePermission(t *testing.T) {
+	project := filepath.Join(t.TempDir(), "project")
+	os.Mkdir(project, 0755)
+	t.Chdir(project)
+	os.Mkdir("src", 0755)
+	os.WriteFile("main.go", []byte("package main"), 0644)
+	os.Symlink(".", "self")
+	os.Symlink("..", "src/up")
+
+	for _, path := range []string{"self/main.go", "self/self/new.go", "src/up/project/main.go"} {
+		if _, err := writeFile(map[string]any{"filepath": path, "content": "x"}); !errors.Is(err, ErrSymlinkWrite) {
+			t.Errorf("write_file(%q): expected ErrSymlinkWrite, got %v", path, err)
+		}
+	}
+	if contents, _ := os.ReadFile("main.go"); string(contents) != "package main" {
+		t.Errorf("expected main.go to be unchanged, got %q", contents)
+	}
+}
@@ -0,0 +1,83 @@
+package smolcode
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dhamidi/smolcode/memory"
+	"google.golang.org/genai"
+)
+
+// DefaultAutoRecallLimit is the number of memories auto-recall adds for a message, unless configured otherwise.
+const DefaultAutoRecallLimit = 3
+
+// autoRecallPrefix starts the messages added by auto-recall.
+const autoRecallPrefix = "Relevant stored knowledge (recalled automatically from memory, use it if it helps):"
+
+// EnableAutoRecall searches the memory for every message of the user and adds the best matches
+// to the conversation before asking the model, so that it doesn't need to call recall_memory.
+// At most limit memories are added per message, only those with a score of at least minScore,
+// and every memory at most once per session.
+// A limit of zero or less uses DefaultAutoRecallLimit.
+func (agent *Agent) EnableAutoRecall(limit int, minScore float64) *Agent {
+	if limit <= 0 {
+		limit = DefaultAutoRecallLimit
+	}
+	agent.autoRecall = true
+	agent.autoRecallLimit = limit
+	agent.autoRecallMinScore = minScore
+	return agent
+}
+
+// recallRelevantMemories adds the memories matching the user's message to the history, see EnableAutoRecall.
+// Problems with the memory database are reported, but don't stop the conversation.
+func (agent *Agent) recallRelevantMemories(message string) {
+	if !agent.autoRecall || strings.TrimSpace(message) == "" {
+		return
+	}
+	mgr, err := memory.New(memoryDBPath)
+	if err != nil {
+		agent.errorMessage("auto-recall: failed to open memory: %v", err)
+		return
+	}
+	defer mgr.Close()
+
+	// Any of the words may be relevant; ranking puts memories matching more of them first.
+	hits, err := mgr.SearchMemoryHits(message, memory.SearchOptions{Any: true})
+	if err != nil {
+		agent.errorMessage("auto-recall: failed to search memory: %v", err)
+		return
+	}
+	if agent.recalledMemories == nil {
+		agent.recalledMemories = map[string]bool{}
+	}
+	var recalled []*memory.MemoryHit
+	for _, hit := range hits {
+		if len(recalled) == agent.autoRecallLimit {
+			break
+		}
+		if hit.Score < agent.autoRecallMinScore || agent.recalledMemories[hit.ID] {
+			continue
+		}
+		agent.recalledMemories[hit.ID] = true
+		recalled = append(recalled, hit)
+	}
+	if len(recalled) == 0 {
+		return
+	}
+
+	var text strings.Builder
+	text.WriteString(autoRecallPrefix)
+	ids := make([]string, len(recalled))
+	for i, hit := range recalled {
+		fmt.Fprintf(&text, "\n- %s: %s", hit.ID, hit.Content)
+		ids[i] = hit.ID
+	}
+	agent.history = append(agent.history, genai.NewContentFromText(text.String(), genai.RoleUser))
+	agent.displayer.DisplayMessage("Memory", "90", len(agent.history)-1, "Recalled %s", strings.Join(ids, ", "))
+	agent.trace("AutoRecall", recalled)
+	if err := agent.persistFullConversationToDB(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to persist conversation after recalling memories: %v\n", err)
+	}
+}
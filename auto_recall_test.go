@@ -0,0 +1,79 @@
+package smolcode
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dhamidi/smolcode/memory"
+)
+
+func TestRecallRelevantMemories(t *testing.T) {
+	t.Chdir(t.TempDir())
+	mgr, err := memory.New(memoryDBPath)
+	if err != nil {
+		t.Fatalf("memory.New failed: %v", err)
+	}
+	for id, content := range map[string]string{
+		"db":      "the history database is sqlite",
+		"db-path": "the sqlite database lives in .smolcode",
+		"fts":     "memories are searched with sqlite full-text search",
+		"flags":   "flags are declared in cmd_default.go",
+	} {
+		if err := mgr.AddMemory(id, content); err != nil {
+			t.Fatalf("AddMemory failed: %v", err)
+		}
+	}
+	mgr.Close()
+
+	display := &recordingDisplay{}
+	agent := (&Agent{displayer: display}).EnableAutoRecall(2, 0)
+
+	agent.recallRelevantMemories("where is the sqlite database?")
+	if len(agent.history) != 1 {
+		t.Fatalf("expected one message with recalled memories, got %d", len(agent.history))
+	}
+	text := agent.history[0].Parts[0].Text
+	if !strings.HasPrefix(text, autoRecallPrefix) || strings.Count(text, "\n- ") != 2 {
+		t.Errorf("expected the 2 best memories, got %q", text)
+	}
+	if strings.Contains(text, "flags") {
+		t.Errorf("expected unrelated memories to be left out, got %q", text)
+	}
+
+	// Memories already added in this session are not added again.
+	agent.recallRelevantMemories("where is the sqlite database?")
+	if len(agent.history) != 2 || !strings.Contains(agent.history[1].Parts[0].Text, "\n- fts: ") {
+		t.Fatalf("expected only the remaining memory to be added, got %d messages", len(agent.history))
+	}
+	agent.recallRelevantMemories("where is the sqlite database?")
+	if len(agent.history) != 2 {
+		t.Errorf("expected nothing to be added once all matches were recalled, got %d messages", len(agent.history))
+	}
+
+	strict := (&Agent{displayer: display}).EnableAutoRecall(2, 1000)
+	strict.recallRelevantMemories("sqlite")
+	if len(strict.history) != 0 {
+		t.Errorf("expected no memories below the minimum score, got %d messages", len(strict.history))
+	}
+}
+
+func TestParseToggle(t *testing.T) {
+	tests := []struct {
+		input   string
+		current bool
+		want    bool
+		ok      bool
+	}{
+		{"/clarify", false, true, true},
+		{"/clarify", true, false, true},
+		{"/clarify on", true, true, true},
+		{"/clarify off", true, false, true},
+		{"/clarify maybe", true, true, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseToggle(strings.Fields(tt.input), tt.current)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseToggle(%q, %v) = %v, %v; want %v, %v", tt.input, tt.current, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package smolcode
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Banner describes the session, shown when Run starts.
+type Banner struct {
+	ConversationID  string   `json:"conversationId,omitempty"`
+	NewConversation bool     `json:"newConversation"`
+	LoadedMessages  int      `json:"loadedMessages"`
+	Model           string   `json:"model"`
+	Tools           []string `json:"tools"`
+	Notices         []string `json:"notices,omitempty"` // How the conversation was loaded or created
+}
+
+// BannerDisplayer is implemented by displayers that present the startup banner themselves,
+// e.g. as a structured event, instead of as lines of text.
+type BannerDisplayer interface {
+	DisplayBanner(banner Banner)
+}
+
+// Quiet suppresses the startup banner, so that only errors and model output are displayed.
+func (agent *Agent) Quiet() *Agent {
+	agent.quiet = true
+	return agent
+}
+
+// banner returns the banner describing the agent's session.
+func (agent *Agent) banner() Banner {
+	return Banner{
+		ConversationID:  agent.initialConvID,
+		NewConversation: agent.initialConvIsNew,
+		LoadedMessages:  agent.initialLoadedMessages,
+		Model:           agent.modelName,
+		Tools:           agent.tools.Names(),
+		Notices:         agent.startupNotices,
+	}
+}
+
+// displayBanner shows the banner through the displayer, unless the agent is quiet.
+func (agent *Agent) displayBanner() {
+	if agent.quiet {
+		return
+	}
+	banner := agent.banner()
+	if displayer, ok := agent.displayer.(BannerDisplayer); ok {
+		displayer.DisplayBanner(banner)
+		return
+	}
+	for _, notice := range banner.Notices {
+		agent.displayer.Display(notice)
+	}
+	agent.displayer.Display(fmt.Sprintf("Chat with %s (use 'Ctrl-c' to quit)", banner.Model))
+	agent.displayer.Display(fmt.Sprintf("Available tools: %s", strings.Join(banner.Tools, ", ")))
+}
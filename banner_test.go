@@ -0,0 +1,96 @@
+package smolcode
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// bannerRecordingDisplay receives the banner as a structured event.
+type bannerRecordingDisplay struct {
+	recordingDisplay
+	banners []Banner
+}
+
+func (d *bannerRecordingDisplay) DisplayBanner(banner Banner) {
+	d.banners = append(d.banners, banner)
+}
+
+func endOfInput() (string, bool) { return "", false }
+
+func TestRunDisplaysBanner(t *testing.T) {
+	display := &recordingDisplay{}
+	agent := (&Agent{displayer: display, getUserMessage: endOfInput, tools: NewToolBox()}).ChooseModel("gemini-2.5-pro")
+
+	if err := agent.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	want := []string{"Chat with gemini-2.5-pro (use 'Ctrl-c' to quit)", "Available tools: "}
+	if len(display.lines) < len(want) || !reflect.DeepEqual(display.lines[:len(want)], want) {
+		t.Errorf("expected banner %q, got %q", want, display.lines)
+	}
+}
+
+func TestRunDisplaysStartupNoticesBeforeBanner(t *testing.T) {
+	display := &recordingDisplay{}
+	agent := (&Agent{
+		displayer:      display,
+		getUserMessage: endOfInput,
+		tools:          NewToolBox(),
+		startupNotices: []string{"No existing conversations found.", "Starting a new conversation."},
+	}).ChooseModel("gemini-2.5-pro")
+
+	if err := agent.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	want := []string{"No existing conversations found.", "Starting a new conversation.", "Chat with gemini-2.5-pro (use 'Ctrl-c' to quit)"}
+	if len(display.lines) < len(want) || !reflect.DeepEqual(display.lines[:len(want)], want) {
+		t.Errorf("expected notices before the banner %q, got %q", want, display.lines)
+	}
+}
+
+func TestQuietSuppressesBanner(t *testing.T) {
+	display := &bannerRecordingDisplay{}
+	agent := (&Agent{displayer: display, getUserMessage: endOfInput, tools: NewToolBox(), startupNotices: []string{"Starting a new conversation."}}).Quiet()
+
+	if err := agent.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if len(display.lines) != 0 || len(display.banners) != 0 {
+		t.Errorf("expected no banner, got lines %q and banners %+v", display.lines, display.banners)
+	}
+	if args := agent.runtimeArgs(); args[len(args)-1] != "-quiet" {
+		t.Errorf("expected -quiet to be passed on reload, got %q", args)
+	}
+}
+
+func TestBannerDisplayerReceivesStructuredBanner(t *testing.T) {
+	display := &bannerRecordingDisplay{}
+	tools := NewToolBox()
+	tools.Add(ReadFileTool)
+	agent := (&Agent{
+		displayer:             display,
+		getUserMessage:        endOfInput,
+		tools:                 tools,
+		initialConvID:         "conv-1",
+		initialLoadedMessages: 4,
+	}).ChooseModel("gemini-2.5-flash")
+
+	if err := agent.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	want := []Banner{{ConversationID: "conv-1", LoadedMessages: 4, Model: "gemini-2.5-flash", Tools: []string{"read_file"}}}
+	if !reflect.DeepEqual(display.banners, want) {
+		t.Errorf("expected banners %+v, got %+v", want, display.banners)
+	}
+	for _, line := range display.lines {
+		if strings.HasPrefix(line, "Chat with") || strings.HasPrefix(line, "Available tools") {
+			t.Errorf("expected no text banner, got %q", line)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package smolcode
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/dhamidi/smolcode/history"
+)
+
+// BenchmarkRun holds the measurements of a single run made by Benchmark.
+type BenchmarkRun struct {
+	Latency          time.Duration
+	PromptTokens     int64
+	CandidatesTokens int64
+}
+
+// TokensPerSecond returns the number of candidate tokens generated per second of latency.
+func (run BenchmarkRun) TokensPerSecond() float64 {
+	if run.Latency <= 0 {
+		return 0
+	}
+	return float64(run.CandidatesTokens) / run.Latency.Seconds()
+}
+
+// Benchmark sends prompt to modelName n times, each time in a new conversation without tools,
+// and measures how long it takes until the model's final response.
+// Conversations are kept in memory instead of the history database.
+func Benchmark(modelName string, prompt string, n int) ([]BenchmarkRun, error) {
+	builtinTools, err := BuiltinTools()
+	if err != nil {
+		return nil, err
+	}
+	history.SetStore(history.NewMemoryStore())
+	defer history.SetStore(nil)
+
+	runs := make([]BenchmarkRun, 0, n)
+	for i := 0; i < n; i++ {
+		agent, err := newOneShotSession("", modelName, prompt, &Config{DisabledTools: builtinTools.Names(), NoChangeSummary: true})
+		if err != nil {
+			return runs, err
+		}
+		started := time.Now()
+		if err := agent.Run(context.Background()); err != nil {
+			return runs, fmt.Errorf("run %d failed: %w", i+1, err)
+		}
+		runs = append(runs, BenchmarkRun{
+			Latency:          time.Since(started),
+			PromptTokens:     agent.usage.PromptTokens,
+			CandidatesTokens: agent.usage.CandidatesTokens,
+		})
+	}
+	return runs, nil
+}
+
+// BenchmarkSummary describes the distribution of one measurement across benchmark runs.
+type BenchmarkSummary struct {
+	Min, Max, Mean, P50, P95 float64
+}
+
+// SummarizeBenchmark computes the summary of values. Percentiles use the nearest-rank method.
+func SummarizeBenchmark(values []float64) BenchmarkSummary {
+	if len(values) == 0 {
+		return BenchmarkSummary{}
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	sum := 0.0
+	for _, value := range sorted {
+		sum += value
+	}
+	percentile := func(p float64) float64 {
+		rank := int(math.Ceil(p * float64(len(sorted))))
+		return sorted[max(rank, 1)-1]
+	}
+	return BenchmarkSummary{
+		Min:  sorted[0],
+		Max:  sorted[len(sorted)-1],
+		Mean: sum / float64(len(sorted)),
+		P50:  percentile(0.5),
+		P95:  percentile(0.95),
+	}
+}
+
+// WriteBenchmarkCSV writes runs to w as CSV, with a header and one row per run.
+func WriteBenchmarkCSV(w io.Writer, runs []BenchmarkRun) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"run", "latency_ms", "prompt_tokens", "candidates_tokens", "tokens_per_second"})
+	for i, run := range runs {
+		out.Write([]string{
+			strconv.Itoa(i + 1),
+			strconv.FormatInt(run.Latency.Milliseconds(), 10),
+			strconv.FormatInt(run.PromptTokens, 10),
+			strconv.FormatInt(run.CandidatesTokens, 10),
+			strconv.FormatFloat(run.TokensPerSecond(), 'f', 2, 64),
+		})
+	}
+	out.Flush()
+	return out.Error()
+}
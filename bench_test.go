@@ -0,0 +1,38 @@
+package smolcode
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSummarizeBenchmark(t *testing.T) {
+	values := []float64{}
+	for i := 20; i >= 1; i-- {
+		values = append(values, float64(i))
+	}
+
+	summary := SummarizeBenchmark(values)
+
+	want := BenchmarkSummary{Min: 1, Max: 20, Mean: 10.5, P50: 10, P95: 19}
+	if summary != want {
+		t.Errorf("expected %+v, got %+v", want, summary)
+	}
+	if single := SummarizeBenchmark([]float64{3}); single != (BenchmarkSummary{Min: 3, Max: 3, Mean: 3, P50: 3, P95: 3}) {
+		t.Errorf("unexpected summary of a single value: %+v", single)
+	}
+}
+
+func TestWriteBenchmarkCSV(t *testing.T) {
+	var out strings.Builder
+	runs := []BenchmarkRun{{Latency: 2 * time.Second, PromptTokens: 120, CandidatesTokens: 50}}
+
+	if err := WriteBenchmarkCSV(&out, runs); err != nil {
+		t.Fatalf("WriteBenchmarkCSV failed: %v", err)
+	}
+
+	want := "run,latency_ms,prompt_tokens,candidates_tokens,tokens_per_second\n1,2000,120,50,25.00\n"
+	if out.String() != want {
+		t.Errorf("expected %q, got %q", want, out.String())
+	}
+}
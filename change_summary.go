@@ -0,0 +1,167 @@
+package smolcode
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+// mutatingTools maps the names of local tools that modify files to the argument holding the file path.
+var mutatingTools = map[string]string{
+	EditFileTool.Name():  "filepath",
+	WriteFileTool.Name(): "filepath",
+}
+
+// mutatesFiles reports whether the local tool with the given name modifies files.
+func mutatesFiles(name string) bool {
+	_, ok := mutatingTools[name]
+	return ok || name == WriteFilesTool.Name()
+}
+
+// recordTouchedFile remembers the files modified by a successful call to a mutating tool that returned output.
+func (agent *Agent) recordTouchedFile(call *genai.FunctionCall, output map[string]any) {
+	if call.Name == WriteFilesTool.Name() {
+		written, _ := output["written"].([]string)
+		for _, path := range written {
+			agent.touchFile(path)
+		}
+		return
+	}
+	argName, ok := mutatingTools[call.Name]
+	if !ok {
+		return
+	}
+	path, ok := call.Args[argName].(string)
+	if !ok || path == "" {
+		return
+	}
+	agent.touchFile(path)
+}
+
+// touchFile adds path to the files listed in the change summary.
+func (agent *Agent) touchFile(path string) {
+	if agent.touchedFiles == nil {
+		agent.touchedFiles = map[string]bool{}
+	}
+	agent.touchedFiles[path] = true
+}
+
+// DisableChangeSummary suppresses the summary of modified files printed when Run exits.
+func (agent *Agent) DisableChangeSummary() *Agent {
+	agent.changeSummaryDisabled = true
+	return agent
+}
+
+// snapshotSession remembers the state of the working tree before the first file is modified in this session,
+// see changeSummary. It is only taken once, and not at all when the summary is disabled,
+// so that sessions that don't modify files don't pay for snapshotting large repositories.
+// Outside a git repository there is no snapshot.
+func (agent *Agent) snapshotSession() {
+	if agent.changeSummaryDisabled || agent.sessionSnapshotTaken {
+		return
+	}
+	agent.sessionSnapshotTaken = true
+	agent.sessionSnapshot, _ = snapshotWorkingTree()
+}
+
+// changeSummary describes the files touched during this session together with
+// their line delta since the session started, e.g. "M agent.go (+10 -2)".
+// The delta is taken between snapshots of the working tree before the session modified files and now,
+// so that changes made before the session, committed or not, are not counted.
+// Without a snapshot, e.g. outside a git repository, the files are listed without a delta.
+func (agent *Agent) changeSummary() string {
+	if len(agent.touchedFiles) == 0 {
+		return ""
+	}
+	paths := make([]string, 0, len(agent.touchedFiles))
+	for path := range agent.touchedFiles {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	changes, root, prefix, err := agent.sessionChanges()
+	var summary strings.Builder
+	summary.WriteString("Files changed in this session:\n")
+	for _, path := range paths {
+		if err != nil {
+			fmt.Fprintf(&summary, "  ? %s\n", path)
+			continue
+		}
+		change, ok := changes[repositoryPath(root, prefix, path)]
+		if !ok {
+			fmt.Fprintf(&summary, "  = %s (no net change)\n", path)
+			continue
+		}
+		fmt.Fprintf(&summary, "  %s %s (+%d -%d)\n", change.status, path, change.added, change.deleted)
+	}
+	return summary.String()
+}
+
+// fileDelta is how a file changed during the session.
+type fileDelta struct {
+	status         string // See FileChange.
+	added, deleted int
+}
+
+// sessionChanges returns the files changed since the session started, keyed by their path relative to the
+// root of the repository, along with the root and the working directory relative to it, see repositoryRoot.
+func (agent *Agent) sessionChanges() (changes map[string]fileDelta, root, prefix string, err error) {
+	if agent.sessionSnapshot == "" {
+		return nil, "", "", fmt.Errorf("no snapshot of the working tree at the start of the session")
+	}
+	if root, prefix, err = repositoryRoot(); err != nil {
+		return nil, "", "", err
+	}
+	current, err := snapshotWorkingTree()
+	if err != nil {
+		return nil, "", "", err
+	}
+	files, err := diffTrees(agent.sessionSnapshot, current)
+	if err != nil {
+		return nil, "", "", err
+	}
+	out, err := runGit(nil, "diff-tree", "-r", "-z", "--no-renames", "--numstat", agent.sessionSnapshot, current)
+	if err != nil {
+		return nil, "", "", err
+	}
+	numstat := parseNumstat(out)
+	changes = map[string]fileDelta{}
+	for _, file := range files {
+		delta := numstat[file.Path]
+		changes[file.Path] = fileDelta{status: file.Status, added: delta[0], deleted: delta[1]}
+	}
+	return changes, root, prefix, nil
+}
+
+// repositoryPath returns path, relative to the working directory or absolute, relative to the root of the repository,
+// given the root and the working directory relative to it, see repositoryRoot.
+func repositoryPath(root, prefix, path string) string {
+	if filepath.IsAbs(path) {
+		if rel, err := filepath.Rel(root, path); err == nil {
+			return filepath.ToSlash(rel)
+		}
+		return path
+	}
+	return filepath.ToSlash(filepath.Join(prefix, path))
+}
+
+// parseNumstat parses the output of git diff-tree -z --numstat into added and deleted line counts per path.
+// Binary files, reported as "-", count as zero lines.
+func parseNumstat(output string) map[string][2]int {
+	result := map[string][2]int{}
+	// Without renames, every entry is "added<TAB>deleted<TAB>path" terminated by NUL.
+	for _, entry := range strings.Split(output, "\x00") {
+		fields := strings.SplitN(entry, "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		added, _ := strconv.Atoi(fields[0])
+		deleted, _ := strconv.Atoi(fields[1])
+		result[fields[2]] = [2]int{added, deleted}
+	}
+	return result
+}
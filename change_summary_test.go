@@ -0,0 +1,59 @@
+package smolcode
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestChangeSummaryCountsOnlyChangesDuringTheSession(t *testing.T) {
+	testRepository(t, map[string]string{"edited.txt": "one\n"})
+	os.Mkdir("sub", 0755)
+	os.WriteFile("edited.txt", []byte("one\ntwo\n"), 0644)
+	agent := &Agent{touchedFiles: map[string]bool{}}
+	agent.snapshotSession()
+
+	os.WriteFile("edited.txt", []byte("one\nthree\n"), 0644)
+	os.WriteFile("sub/added.txt", []byte("a\nb\n"), 0644)
+	t.Chdir("sub")
+	agent.touchedFiles["../edited.txt"] = true
+	agent.touchedFiles["added.txt"] = true
+	agent.touchedFiles["../untouched.txt"] = true
+
+	summary := agent.changeSummary()
+	for _, want := range []string{
+		"  M ../edited.txt (+1 -1)\n",
+		"  A added.txt (+2 -0)\n",
+		"  = ../untouched.txt (no net change)\n",
+	} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("summary is missing %q:\n%s", want, summary)
+		}
+	}
+}
+
+func TestSnapshotSessionIsTakenOnceAndOnlyWhenEnabled(t *testing.T) {
+	testRepository(t, map[string]string{"file.txt": "one\n"})
+
+	disabled := (&Agent{}).DisableChangeSummary()
+	disabled.snapshotSession()
+	if disabled.sessionSnapshot != "" {
+		t.Errorf("expected no snapshot when the change summary is disabled")
+	}
+
+	agent := &Agent{}
+	agent.snapshotSession()
+	first := agent.sessionSnapshot
+	os.WriteFile("file.txt", []byte("two\n"), 0644)
+	agent.snapshotSession()
+	if first == "" || agent.sessionSnapshot != first {
+		t.Errorf("expected the first snapshot to be kept, got %q and then %q", first, agent.sessionSnapshot)
+	}
+}
+
+func TestChangeSummaryWithoutSnapshotListsFiles(t *testing.T) {
+	agent := &Agent{touchedFiles: map[string]bool{"main.go": true}}
+	if got, want := agent.changeSummary(), "Files changed in this session:\n  ? main.go\n"; got != want {
+		t.Errorf("changeSummary() = %q, want %q", got, want)
+	}
+}
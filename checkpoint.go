@@ -0,0 +1,268 @@
+package smolcode
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// checkpointRefPrefix is the namespace of the git refs named checkpoints are stored under.
+const checkpointRefPrefix = "refs/smolcode/checkpoints/"
+
+// ErrCheckpointNotFound is returned when there is no checkpoint with the requested name.
+var ErrCheckpointNotFound = errors.New("checkpoint not found")
+
+// validCheckpointName matches the names accepted for checkpoints.
+var validCheckpointName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Checkpoint is a named snapshot of the working tree.
+//
+// Checkpoints are stored as git commits under refs/smolcode/checkpoints/, so creating
+// or restoring one neither moves HEAD nor touches the index. They include untracked
+// files, but not files ignored by git, smolcode's own .smolcode directory with its
+// databases, or the smolcode binary built by /build.
+type Checkpoint struct {
+	Name    string    `json:"name"`
+	Commit  string    `json:"commit"`
+	Created time.Time `json:"created"`
+}
+
+// FileChange is a file that differs between two states of the working tree.
+type FileChange struct {
+	Status string `json:"status"` // "A" for added, "M" for modified, "D" for deleted.
+	Path   string `json:"path"`   // Relative to the root of the repository.
+}
+
+// CreateCheckpoint snapshots the working tree under name, replacing an existing checkpoint with the same name.
+func CreateCheckpoint(name string) (*Checkpoint, error) {
+	if !validCheckpointName.MatchString(name) {
+		return nil, fmt.Errorf("invalid checkpoint name %q: use letters, digits, '.', '_' and '-'", name)
+	}
+	tree, err := snapshotWorkingTree()
+	if err != nil {
+		return nil, err
+	}
+	commitArgs := []string{"commit-tree", tree, "-m", "smolcode checkpoint " + name}
+	if head, err := runGit(nil, "rev-parse", "--verify", "--quiet", "HEAD"); err == nil {
+		commitArgs = append(commitArgs, "-p", strings.TrimSpace(head))
+	}
+	// Checkpoints are internal snapshots, so they don't depend on the user's git identity.
+	identity := []string{"GIT_AUTHOR_NAME=smolcode", "GIT_AUTHOR_EMAIL=smolcode@localhost", "GIT_COMMITTER_NAME=smolcode", "GIT_COMMITTER_EMAIL=smolcode@localhost"}
+	commit, err := runGit(identity, commitArgs...)
+	if err != nil {
+		return nil, err
+	}
+	commit = strings.TrimSpace(commit)
+	if _, err := runGit(nil, "update-ref", checkpointRefPrefix+name, commit); err != nil {
+		return nil, err
+	}
+	return &Checkpoint{Name: name, Commit: commit, Created: time.Now()}, nil
+}
+
+// ListCheckpoints returns all checkpoints, most recent first.
+func ListCheckpoints() ([]Checkpoint, error) {
+	out, err := runGit(nil, "for-each-ref", "--sort=-creatordate", "--format=%(refname:strip=3) %(objectname) %(creatordate:unix)", checkpointRefPrefix)
+	if err != nil {
+		return nil, err
+	}
+	checkpoints := []Checkpoint{}
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+		var seconds int64
+		fmt.Sscan(fields[2], &seconds)
+		checkpoints = append(checkpoints, Checkpoint{Name: fields[0], Commit: fields[1], Created: time.Unix(seconds, 0)})
+	}
+	return checkpoints, nil
+}
+
+// RestoreCheckpoint changes the working tree back to the checkpoint name and returns the files it changed.
+// Files created since the checkpoint are deleted, unless git ignores them.
+func RestoreCheckpoint(name string) ([]FileChange, error) {
+	commit, err := checkpointCommit(name)
+	if err != nil {
+		return nil, err
+	}
+	current, err := snapshotWorkingTree()
+	if err != nil {
+		return nil, err
+	}
+	changes, err := diffTrees(current, commit)
+	if err != nil {
+		return nil, err
+	}
+	// Paths reported by git are relative to the root of the repository, not the working directory.
+	root, _, err := repositoryRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	var restore []string
+	for _, change := range changes {
+		if change.Status == "D" {
+			if err := os.Remove(filepath.Join(root, change.Path)); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return nil, fmt.Errorf("failed to remove %s: %w", change.Path, err)
+			}
+		} else {
+			restore = append(restore, change.Path)
+		}
+	}
+	if len(restore) > 0 {
+		err := withTemporaryIndex(false, func(env []string) error {
+			if _, err := runGit(env, "read-tree", commit); err != nil {
+				return err
+			}
+			_, err := runGitIn(root, env, append([]string{"checkout-index", "--force", "--"}, restore...)...)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	invalidateToolCaches()
+	return changes, nil
+}
+
+// CheckpointDiff returns a unified diff from the checkpoint name to the current working tree.
+// Untracked files are included, like in the checkpoint itself.
+func CheckpointDiff(name string) (string, error) {
+	commit, err := checkpointCommit(name)
+	if err != nil {
+		return "", err
+	}
+	current, err := snapshotWorkingTree()
+	if err != nil {
+		return "", err
+	}
+	return runGit(nil, "diff", "--no-color", commit, current)
+}
+
+// checkpointCommit returns the commit the checkpoint name is stored in.
+func checkpointCommit(name string) (string, error) {
+	if !validCheckpointName.MatchString(name) {
+		return "", fmt.Errorf("%w: %s", ErrCheckpointNotFound, name)
+	}
+	commit, err := runGit(nil, "rev-parse", "--verify", "--quiet", checkpointRefPrefix+name)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrCheckpointNotFound, name)
+	}
+	return strings.TrimSpace(commit), nil
+}
+
+// snapshotWorkingTree writes the working tree, including untracked files, to the git object
+// database and returns the tree's hash. The index is not modified.
+// The .smolcode directory and the binary built by /build in the working directory are left out,
+// so that restoring a checkpoint never rolls back or deletes conversations, plans and memories.
+func snapshotWorkingTree() (string, error) {
+	root, prefix, err := repositoryRoot()
+	if err != nil {
+		return "", err
+	}
+	var tree string
+	err = withTemporaryIndex(true, func(env []string) error {
+		args := []string{"add", "--all", "--", ".", ":(exclude)" + prefix + ".smolcode", ":(exclude)" + prefix + buildOutputPath}
+		if _, err := runGitIn(root, env, args...); err != nil {
+			return err
+		}
+		out, err := runGit(env, "write-tree")
+		tree = strings.TrimSpace(out)
+		return err
+	})
+	return tree, err
+}
+
+// repositoryRoot returns the root of the repository containing the working directory,
+// and the path of the working directory relative to it, ending in a slash unless it is empty.
+func repositoryRoot() (root, prefix string, err error) {
+	out, err := runGit(nil, "rev-parse", "--show-toplevel", "--show-prefix")
+	if err != nil {
+		return "", "", err
+	}
+	lines := strings.SplitN(strings.TrimSuffix(out, "\n"), "\n", 2)
+	if len(lines) == 2 {
+		prefix = lines[1]
+	}
+	return lines[0], prefix, nil
+}
+
+// diffTrees lists the files that differ between the trees (or commits) from and to.
+func diffTrees(from, to string) ([]FileChange, error) {
+	out, err := runGit(nil, "diff-tree", "-r", "-z", "--no-renames", "--name-status", from, to)
+	if err != nil {
+		return nil, err
+	}
+	changes := []FileChange{}
+	fields := strings.Split(strings.TrimSuffix(out, "\x00"), "\x00")
+	for i := 0; i+1 < len(fields); i += 2 {
+		status := fields[i]
+		if status == "T" {
+			status = "M"
+		}
+		changes = append(changes, FileChange{Status: status, Path: fields[i+1]})
+	}
+	return changes, nil
+}
+
+// withTemporaryIndex runs fn with an environment that makes git use a temporary index file.
+// If seeded, the temporary index starts as a copy of the repository's index, which lets git
+// skip hashing files that haven't changed.
+func withTemporaryIndex(seeded bool, fn func(env []string) error) error {
+	dir, err := os.MkdirTemp("", "smolcode-index-")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary index: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	indexPath := filepath.Join(dir, "index")
+	if seeded {
+		if repoIndex, err := runGit(nil, "rev-parse", "--path-format=absolute", "--git-path", "index"); err == nil {
+			copyFile(strings.TrimSpace(repoIndex), indexPath) // Without a copy, git starts from an empty index.
+		}
+	}
+	return fn([]string{"GIT_INDEX_FILE=" + indexPath})
+}
+
+// copyFile copies the file at src to dst.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// runGit runs git with args and the additional environment variables env, returning its standard output.
+func runGit(env []string, args ...string) (string, error) {
+	return runGitIn("", env, args...)
+}
+
+// runGitIn is like runGit, but runs git in dir; an empty dir is the working directory.
+func runGitIn(dir string, env []string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s failed: %w (%s)", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return string(out), nil
+}
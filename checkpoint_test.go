@@ -0,0 +1,163 @@
+package smolcode
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// testRepository changes to a new git repository containing files.
+func testRepository(t *testing.T, files map[string]string) {
+	t.Helper()
+	t.Chdir(t.TempDir())
+	if output, err := exec.Command("git", "init", "--quiet").CombinedOutput(); err != nil {
+		t.Fatalf("git init failed: %v (%s)", err, output)
+	}
+	for path, contents := range files {
+		if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+	}
+}
+
+func TestRestoreCheckpointUndoesChanges(t *testing.T) {
+	testRepository(t, map[string]string{"kept.txt": "original\n", "removed.txt": "removed later\n"})
+	if _, err := CreateCheckpoint("before-edit"); err != nil {
+		t.Fatalf("CreateCheckpoint failed: %v", err)
+	}
+	os.WriteFile("kept.txt", []byte("changed\n"), 0644)
+	os.Remove("removed.txt")
+	os.WriteFile("added.txt", []byte("new\n"), 0644)
+
+	changes, err := RestoreCheckpoint("before-edit")
+	if err != nil {
+		t.Fatalf("RestoreCheckpoint failed: %v", err)
+	}
+
+	want := []FileChange{{Status: "D", Path: "added.txt"}, {Status: "M", Path: "kept.txt"}, {Status: "A", Path: "removed.txt"}}
+	if diff := cmp.Diff(want, changes); diff != "" {
+		t.Errorf("changes mismatch (-want +got):\n%s", diff)
+	}
+	if data, _ := os.ReadFile("kept.txt"); string(data) != "original\n" {
+		t.Errorf("expected kept.txt to be restored, got %q", data)
+	}
+	if data, _ := os.ReadFile("removed.txt"); string(data) != "removed later\n" {
+		t.Errorf("expected removed.txt to be restored, got %q", data)
+	}
+	if _, err := os.Stat("added.txt"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected added.txt to be deleted, got %v", err)
+	}
+	if status, _ := exec.Command("git", "status", "--porcelain").Output(); string(status) != "?? kept.txt\n?? removed.txt\n" {
+		t.Errorf("expected the index to be untouched, got status %q", status)
+	}
+}
+
+func TestRestoreCheckpointKeepsSmolcodeData(t *testing.T) {
+	testRepository(t, map[string]string{"file.txt": "original\n"})
+	os.Mkdir(".smolcode", 0755)
+	os.WriteFile(".smolcode/memory.db", []byte("before"), 0644)
+	if _, err := CreateCheckpoint("start"); err != nil {
+		t.Fatalf("CreateCheckpoint failed: %v", err)
+	}
+	os.WriteFile(".smolcode/history.db", []byte("conversation"), 0644)
+	os.WriteFile(".smolcode/memory.db", []byte("after"), 0644)
+	os.WriteFile(buildOutputPath, []byte("binary"), 0755)
+	os.WriteFile("file.txt", []byte("changed\n"), 0644)
+
+	changes, err := RestoreCheckpoint("start")
+	if err != nil {
+		t.Fatalf("RestoreCheckpoint failed: %v", err)
+	}
+
+	if diff := cmp.Diff([]FileChange{{Status: "M", Path: "file.txt"}}, changes); diff != "" {
+		t.Errorf("changes mismatch (-want +got):\n%s", diff)
+	}
+	for path, want := range map[string]string{".smolcode/history.db": "conversation", ".smolcode/memory.db": "after", buildOutputPath: "binary"} {
+		if data, err := os.ReadFile(path); err != nil || string(data) != want {
+			t.Errorf("expected %s to survive the restore with %q, got %q, %v", path, want, data, err)
+		}
+	}
+}
+
+func TestRestoreCheckpointFromSubdirectory(t *testing.T) {
+	testRepository(t, map[string]string{"top.txt": "original\n"})
+	os.Mkdir("sub", 0755)
+	os.WriteFile("sub/file.txt", []byte("original\n"), 0644)
+	t.Chdir("sub")
+	if _, err := CreateCheckpoint("start"); err != nil {
+		t.Fatalf("CreateCheckpoint failed: %v", err)
+	}
+	os.WriteFile("file.txt", []byte("changed\n"), 0644)
+	os.WriteFile("added.txt", []byte("new\n"), 0644)
+	os.WriteFile("../top.txt", []byte("changed\n"), 0644)
+
+	changes, err := RestoreCheckpoint("start")
+	if err != nil {
+		t.Fatalf("RestoreCheckpoint failed: %v", err)
+	}
+
+	want := []FileChange{{Status: "D", Path: "sub/added.txt"}, {Status: "M", Path: "sub/file.txt"}, {Status: "M", Path: "top.txt"}}
+	if diff := cmp.Diff(want, changes); diff != "" {
+		t.Errorf("changes mismatch (-want +got):\n%s", diff)
+	}
+	for path, want := range map[string]string{"file.txt": "original\n", "../top.txt": "original\n"} {
+		if data, _ := os.ReadFile(path); string(data) != want {
+			t.Errorf("expected %s to be restored, got %q", path, data)
+		}
+	}
+	if _, err := os.Stat("added.txt"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected added.txt to be deleted, got %v", err)
+	}
+}
+
+func TestListCheckpoints(t *testing.T) {
+	testRepository(t, map[string]string{"file.txt": "contents\n"})
+	for _, name := range []string{"first", "second"} {
+		if _, err := CreateCheckpoint(name); err != nil {
+			t.Fatalf("CreateCheckpoint(%q) failed: %v", name, err)
+		}
+	}
+
+	checkpoints, err := ListCheckpoints()
+	if err != nil {
+		t.Fatalf("ListCheckpoints failed: %v", err)
+	}
+
+	if len(checkpoints) != 2 {
+		t.Fatalf("expected 2 checkpoints, got %+v", checkpoints)
+	}
+}
+
+func TestRestoreUnknownCheckpoint(t *testing.T) {
+	testRepository(t, nil)
+
+	_, err := RestoreCheckpoint("missing")
+
+	if !errors.Is(err, ErrCheckpointNotFound) {
+		t.Errorf("expected ErrCheckpointNotFound, got %v", err)
+	}
+}
+
+func TestCheckpointDiff(t *testing.T) {
+	testRepository(t, map[string]string{"file.txt": "before\n"})
+	if _, err := CreateCheckpoint("start"); err != nil {
+		t.Fatalf("CreateCheckpoint failed: %v", err)
+	}
+	os.WriteFile("file.txt", []byte("after\n"), 0644)
+
+	diff, err := CheckpointDiff("start")
+	if err != nil {
+		t.Fatalf("CheckpointDiff failed: %v", err)
+	}
+
+	if !strings.Contains(diff, "-before\n+after\n") {
+		t.Errorf("expected the diff to show the change, got:\n%s", diff)
+	}
+	if _, err := CheckpointDiff("missing"); !errors.Is(err, ErrCheckpointNotFound) {
+		t.Errorf("expected ErrCheckpointNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,50 @@
+package smolcode
+
+import (
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+// clarifyInstruction is added to the system prompt in clarify mode.
+const clarifyInstruction = `If a request is ambiguous, or could reasonably be understood in more than one way, do not guess and do not call any tools.
+Instead, reply with a single, concise clarifying question and nothing else, ending with a question mark.
+Once the request is clear, go ahead without asking for further confirmation.`
+
+// Clarify instructs the model to ask a clarifying question instead of guessing when a request is ambiguous.
+// Such questions are presented to the user as questions, see clarifyingQuestion.
+func (agent *Agent) Clarify() *Agent {
+	agent.setClarify(true)
+	return agent
+}
+
+// setClarify enables or disables clarify mode, as done by /clarify.
+func (agent *Agent) setClarify(enabled bool) {
+	if agent.clarify == enabled {
+		return
+	}
+	agent.clarify = enabled
+	// The system prompt changed, so the cached content must be recreated before the next request.
+	agent.cachedHistoryCount = -1
+}
+
+// clarifyingQuestion returns the text of content if it is purely a question:
+// text ending in a question mark, without any function calls. Thoughts are ignored.
+func clarifyingQuestion(content *genai.Content) (string, bool) {
+	text := ""
+	for _, part := range content.Parts {
+		if part.FunctionCall != nil {
+			return "", false
+		}
+		if !part.Thought {
+			text += part.Text
+		}
+	}
+	text = strings.TrimSpace(text)
+	return text, strings.HasSuffix(text, "?")
+}
+
+// questionMessage displays a clarifying question of the model.
+func (agent *Agent) questionMessage(fmtStr string, value ...any) {
+	agent.displayer.DisplayMessage("Question", "96", len(agent.history), fmtStr, value...)
+}
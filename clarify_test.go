@@ -0,0 +1,106 @@
+package smolcode
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/genai"
+)
+
+func TestClarifyingQuestion(t *testing.T) {
+	tests := []struct {
+		name    string
+		content *genai.Content
+		want    bool
+	}{
+		{"question", genai.NewContentFromText("Which file do you mean? ", genai.RoleModel), true},
+		{"statement", genai.NewContentFromText("I changed the file.", genai.RoleModel), false},
+		{"question with tool call", &genai.Content{Role: genai.RoleModel, Parts: []*genai.Part{
+			{Text: "Shall I look at main.go?"},
+			{FunctionCall: &genai.FunctionCall{Name: "read_file"}},
+		}}, false},
+		{"question after thought", &genai.Content{Role: genai.RoleModel, Parts: []*genai.Part{
+			{Text: "The user was vague.", Thought: true},
+			{Text: "Do you mean the CLI or the library?"},
+		}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, got := clarifyingQuestion(tt.content); got != tt.want {
+				t.Errorf("clarifyingQuestion() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClarifyExtendsSystemPrompt(t *testing.T) {
+	agent := &Agent{systemInstruction: "You are smolcode."}
+
+	agent.Clarify()
+	prompt := agent.systemPrompt().Parts[0].Text
+	if !strings.HasPrefix(prompt, "You are smolcode.") || !strings.Contains(prompt, clarifyInstruction) {
+		t.Errorf("expected the clarify instruction after the system prompt, got %q", prompt)
+	}
+
+	agent.setClarify(false)
+	if prompt := agent.systemPrompt().Parts[0].Text; prompt != "You are smolcode." {
+		t.Errorf("expected the plain system prompt after disabling clarify mode, got %q", prompt)
+	}
+}
+
+func TestRunPresentsClarifyingQuestion(t *testing.T) {
+	t.Chdir(t.TempDir())
+	var requestBodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requestBodies = append(requestBodies, string(body))
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"candidates":[{"content":{"role":"model","parts":[{"text":"Which bug do you mean?"}]},"finishReason":"STOP"}]}`)
+	}))
+	defer server.Close()
+	client, err := genai.NewClient(context.Background(), &genai.ClientConfig{
+		APIKey:      "test-key",
+		Backend:     genai.BackendGeminiAPI,
+		HTTPOptions: genai.HTTPOptions{BaseURL: server.URL},
+	})
+	if err != nil {
+		t.Fatalf("genai.NewClient failed: %v", err)
+	}
+
+	display := &recordingDisplay{}
+	inputs := []string{"/clarify", "fix the bug"}
+	agent := (&Agent{client: client, displayer: display, getUserMessage: func() (string, bool) {
+		if len(inputs) == 0 {
+			return "", false
+		}
+		input := inputs[0]
+		inputs = inputs[1:]
+		return input, true
+	}}).ChooseModel("gemini-2.5-flash").WithContextWindow(10).Quiet()
+
+	if err := agent.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if len(requestBodies) != 1 || !strings.Contains(requestBodies[0], "clarifying question") {
+		t.Errorf("expected one request with the clarify instruction, got %q", requestBodies)
+	}
+	joined := strings.Join(display.messages, "\n")
+	if !strings.Contains(joined, "Clarify mode is on") {
+		t.Errorf("expected /clarify to report the new mode, got %q", display.messages)
+	}
+	if !strings.Contains(joined, "Question [2]: Which bug do you mean?") {
+		t.Errorf("expected the question to be presented as such, got %q", display.messages)
+	}
+	if strings.Contains(joined, "Gemini [2]: Which bug") {
+		t.Errorf("expected the question not to be displayed as a regular response, got %q", display.messages)
+	}
+	if len(agent.history) != 2 {
+		t.Errorf("expected the question to be kept in the history, got %d messages", len(agent.history))
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dhamidi/smolcode"
+	"github.com/dhamidi/smolcode/history"
+	"github.com/dhamidi/smolcode/memory"
+)
+
+func TestBackupRoundTrip(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	mgr, err := memory.New(memoryDBPath)
+	if err != nil {
+		t.Fatalf("memory.New failed: %v", err)
+	}
+	if err := mgr.AddMemory("greeting", "hello from the backup"); err != nil {
+		t.Fatalf("AddMemory failed: %v", err)
+	}
+	mgr.Close()
+
+	conversation, err := history.New()
+	if err != nil {
+		t.Fatalf("history.New failed: %v", err)
+	}
+	conversation.Append(map[string]string{"text": "hi"})
+	if err := history.SaveTo(conversation, history.DefaultDatabasePath); err != nil {
+		t.Fatalf("SaveTo failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(".smolcode", "system.md"), []byte("be helpful"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(smolcode.PreferencesPath, []byte(`{"theme":"dark"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var archive bytes.Buffer
+	manifest, err := writeBackup(&archive)
+	if err != nil {
+		t.Fatalf("writeBackup failed: %v", err)
+	}
+	wantFiles := []string{memoryDBPath, history.DefaultDatabasePath, ".smolcode/system.md", smolcode.PreferencesPath}
+	if strings.Join(manifest.Files, ",") != strings.Join(wantFiles, ",") {
+		t.Errorf("expected files %v, got %v", wantFiles, manifest.Files)
+	}
+	if _, ok := manifest.SchemaVersions[history.DefaultDatabasePath]; !ok {
+		t.Errorf("expected a schema version for the history database, got %v", manifest.SchemaVersions)
+	}
+
+	t.Chdir(t.TempDir())
+	if _, err := restoreBackup(bytes.NewReader(archive.Bytes()), false); err != nil {
+		t.Fatalf("restoreBackup failed: %v", err)
+	}
+
+	mgr, err = memory.New(memoryDBPath)
+	if err != nil {
+		t.Fatalf("memory.New on restored database failed: %v", err)
+	}
+	defer mgr.Close()
+	mem, err := mgr.GetMemoryByID("greeting")
+	if err != nil {
+		t.Fatalf("restored memory not found: %v", err)
+	}
+	if mem.Content != "hello from the backup" {
+		t.Errorf("unexpected restored memory content %q", mem.Content)
+	}
+	restored, err := history.LoadFrom(conversation.ID, history.DefaultDatabasePath)
+	if err != nil {
+		t.Fatalf("restored conversation not found: %v", err)
+	}
+	if len(restored.Messages) != 1 {
+		t.Errorf("expected 1 restored message, got %d", len(restored.Messages))
+	}
+	systemPrompt, err := os.ReadFile(".smolcode/system.md")
+	if err != nil || string(systemPrompt) != "be helpful" {
+		t.Errorf("expected the system prompt to be restored, got %q (%v)", systemPrompt, err)
+	}
+	preferences, err := os.ReadFile(smolcode.PreferencesPath)
+	if err != nil || string(preferences) != `{"theme":"dark"}` {
+		t.Errorf("expected the preferences to be restored, got %q (%v)", preferences, err)
+	}
+
+	_, err = restoreBackup(bytes.NewReader(archive.Bytes()), false)
+	if err == nil || !strings.Contains(err.Error(), "--force") {
+		t.Errorf("expected restore over existing files to be refused, got %v", err)
+	}
+	if _, err := restoreBackup(bytes.NewReader(archive.Bytes()), true); err != nil {
+		t.Errorf("restore with force failed: %v", err)
+	}
+}
+
+func TestRestoreRejectsInvalidArchive(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if _, err := restoreBackup(strings.NewReader("not an archive"), false); err == nil {
+		t.Error("expected an error for a file that is not a backup")
+	}
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/dhamidi/smolcode"
+	"github.com/dhamidi/smolcode/history"
+	"github.com/dhamidi/smolcode/memory"
+	"github.com/dhamidi/smolcode/planner"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// backupManifestName is the name of the manifest in a backup archive.
+const backupManifestName = "manifest.json"
+
+// backupManifest describes the contents of a backup archive.
+type backupManifest struct {
+	CreatedAt time.Time `json:"createdAt"`
+	Files     []string  `json:"files"`
+	// SchemaVersions maps every database in Files to its schema version (its user_version) at the time of the backup.
+	SchemaVersions map[string]int `json:"schemaVersions"`
+}
+
+// backupDatabases are the databases included in a backup, together with the function that upgrades them to the current schema.
+var backupDatabases = []struct {
+	path    string
+	migrate func(path string) error
+}{
+	{planStoragePath, migratePlanDatabase},
+	{memoryDBPath, migrateMemoryDatabase},
+	{history.DefaultDatabasePath, history.Migrate},
+}
+
+// backupFiles are the other files included in a backup.
+var backupFiles = []string{
+	".smolcode/system.md",
+	smolcode.DefaultConfigPath,
+	smolcode.PreferencesPath,
+}
+
+// migratePlanDatabase upgrades the plan database at path to the current schema.
+func migratePlanDatabase(path string) error {
+	p, err := planner.New(path)
+	if err != nil {
+		return err
+	}
+	return p.Close()
+}
+
+// migrateMemoryDatabase upgrades the memory database at path to the current schema.
+func migrateMemoryDatabase(path string) error {
+	mgr, err := memory.New(path)
+	if err != nil {
+		return err
+	}
+	return mgr.Close()
+}
+
+// schemaVersion returns the user_version of the SQLite database at path.
+func schemaVersion(path string) (int, error) {
+	db, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to read schema version of %s: %w", path, err)
+	}
+	return version, nil
+}
+
+// writeBackup writes a gzipped tarball of all databases and files that exist to w,
+// starting with a manifest of its contents.
+func writeBackup(w io.Writer) (*backupManifest, error) {
+	manifest := &backupManifest{CreatedAt: time.Now().UTC(), SchemaVersions: map[string]int{}}
+	contents := map[string][]byte{}
+	add := func(path string) (bool, error) {
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		manifest.Files = append(manifest.Files, path)
+		contents[path] = data
+		return true, nil
+	}
+	for _, database := range backupDatabases {
+		found, err := add(database.path)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			continue
+		}
+		version, err := schemaVersion(database.path)
+		if err != nil {
+			return nil, err
+		}
+		manifest.SchemaVersions[database.path] = version
+	}
+	for _, path := range backupFiles {
+		if _, err := add(path); err != nil {
+			return nil, err
+		}
+	}
+
+	encodedManifest, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	gz := gzip.NewWriter(w)
+	tarFS := NewTarballWriterFS(gz)
+	if err := tarFS.WriteFile(backupManifestName, encodedManifest, 0644); err != nil {
+		return nil, err
+	}
+	for _, path := range manifest.Files {
+		if err := tarFS.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, err
+		}
+		if err := tarFS.WriteFile(path, contents[path], 0644); err != nil {
+			return nil, err
+		}
+	}
+	if err := tarFS.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish backup: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish backup: %w", err)
+	}
+	return manifest, nil
+}
+
+// readBackup reads the manifest and the files listed in it from a backup written by writeBackup.
+func readBackup(r io.Reader) (*backupManifest, map[string][]byte, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("not a gzip file: %w", err)
+	}
+	defer gz.Close()
+
+	var manifest *backupManifest
+	contents := map[string][]byte{}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read backup: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read %s from backup: %w", hdr.Name, err)
+		}
+		if hdr.Name == backupManifestName {
+			manifest = &backupManifest{}
+			if err := json.Unmarshal(data, manifest); err != nil {
+				return nil, nil, fmt.Errorf("invalid manifest: %w", err)
+			}
+			continue
+		}
+		contents[hdr.Name] = data
+	}
+	if manifest == nil {
+		return nil, nil, fmt.Errorf("backup has no %s", backupManifestName)
+	}
+	for _, path := range manifest.Files {
+		if !isBackupPath(path) {
+			return nil, nil, fmt.Errorf("backup contains unexpected file %s", path)
+		}
+		if _, ok := contents[path]; !ok {
+			return nil, nil, fmt.Errorf("backup is missing %s listed in its manifest", path)
+		}
+	}
+	return manifest, contents, nil
+}
+
+// isBackupPath reports whether path is one of the files writeBackup includes,
+// so that a restore never writes anywhere else.
+func isBackupPath(path string) bool {
+	if slices.Contains(backupFiles, path) {
+		return true
+	}
+	for _, database := range backupDatabases {
+		if database.path == path {
+			return true
+		}
+	}
+	return false
+}
+
+// restoreBackup unpacks a backup written by writeBackup and upgrades the restored databases to the current schema.
+// Unless force is set, it refuses to overwrite any existing file.
+func restoreBackup(r io.Reader, force bool) (*backupManifest, error) {
+	manifest, contents, err := readBackup(r)
+	if err != nil {
+		return nil, err
+	}
+	if !force {
+		for _, path := range manifest.Files {
+			if _, err := os.Stat(path); err == nil {
+				return nil, fmt.Errorf("%s already exists, use --force to overwrite it", path)
+			}
+		}
+	}
+	for _, path := range manifest.Files {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create directory for %s: %w", path, err)
+		}
+		if err := os.WriteFile(path, contents[path], 0644); err != nil {
+			return nil, fmt.Errorf("failed to restore %s: %w", path, err)
+		}
+	}
+	for _, database := range backupDatabases {
+		if _, ok := manifest.SchemaVersions[database.path]; !ok || database.migrate == nil {
+			continue
+		}
+		if err := database.migrate(database.path); err != nil {
+			return nil, fmt.Errorf("failed to migrate restored %s: %w", database.path, err)
+		}
+	}
+	return manifest, nil
+}
+
+func handleBackupCommand(args []string) {
+	backupCmd := flag.NewFlagSet("backup", flag.ExitOnError)
+	backupCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: smolcode backup <file.tar.gz>\n")
+		fmt.Fprintf(os.Stderr, "Bundles the plans, memory and history databases, the system prompt, the configuration and the preferences into one archive.\n")
+	}
+	backupCmd.Parse(args)
+	if backupCmd.NArg() != 1 {
+		backupCmd.Usage()
+		log.Fatal("Error: 'backup' requires exactly one argument: <file.tar.gz>")
+	}
+
+	target := backupCmd.Arg(0)
+	file, err := os.Create(target)
+	if err != nil {
+		die("Error creating %s: %v", target, err)
+	}
+	manifest, err := writeBackup(file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(target)
+		die("Error writing backup: %v", err)
+	}
+	fmt.Printf("Backed up %d files to %s\n", len(manifest.Files), target)
+}
+
+func handleRestoreCommand(args []string) {
+	restoreCmd := flag.NewFlagSet("restore", flag.ExitOnError)
+	force := restoreCmd.Bool("force", false, "Overwrite existing files")
+	restoreCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: smolcode restore [--force] <file.tar.gz>\n")
+		fmt.Fprintf(os.Stderr, "Unpacks an archive created by 'smolcode backup' and upgrades the restored databases.\n")
+		restoreCmd.PrintDefaults()
+	}
+	positional := parseInterspersed(restoreCmd, args)
+	if len(positional) != 1 {
+		restoreCmd.Usage()
+		log.Fatal("Error: 'restore' requires exactly one argument: <file.tar.gz>")
+	}
+
+	source := positional[0]
+	file, err := os.Open(source)
+	if err != nil {
+		die("Error opening %s: %v", source, err)
+	}
+	defer file.Close()
+	manifest, err := restoreBackup(file, *force)
+	if err != nil {
+		die("Error restoring %s: %v", source, err)
+	}
+	fmt.Printf("Restored %d files from backup created at %s\n", len(manifest.Files), manifest.CreatedAt.Local().Format(time.DateTime))
+	for _, database := range backupDatabases {
+		before, ok := manifest.SchemaVersions[database.path]
+		if !ok {
+			continue
+		}
+		if after, err := schemaVersion(database.path); err == nil && after != before {
+			fmt.Printf("Upgraded %s from schema version %d to %d\n", database.path, before, after)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/dhamidi/smolcode"
+)
+
+// handleBenchCommand runs a prompt several times and reports latency and token throughput.
+func handleBenchCommand(args []string) {
+	benchCmd := flag.NewFlagSet("bench", flag.ExitOnError)
+	var modelName, promptPath, csvPath string
+	var runs int
+	benchCmd.StringVar(&modelName, "model", "", "The name of the model to benchmark (defaults to the configured model)")
+	benchCmd.StringVar(&modelName, "m", "", "The name of the model to benchmark (shorthand)")
+	benchCmd.StringVar(&promptPath, "prompt", "", "File containing the prompt to send")
+	benchCmd.IntVar(&runs, "n", 5, "Number of runs")
+	benchCmd.StringVar(&csvPath, "csv", "", "File to write the measurements of every run to, as CSV")
+	benchCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: smolcode bench [--model <name>] --prompt <file> [--n 5] [--csv <file>]\n")
+		fmt.Fprintf(os.Stderr, "Sends a prompt to a model several times, without tools, and reports latency and token throughput.\n")
+		benchCmd.PrintDefaults()
+	}
+	benchCmd.Parse(args)
+
+	if promptPath == "" {
+		benchCmd.Usage()
+		log.Fatal("Error: --prompt flag is required for 'bench'")
+	}
+	if runs < 1 {
+		log.Fatal("Error: --n must be at least 1")
+	}
+	if benchCmd.NArg() != 0 {
+		benchCmd.Usage()
+		log.Fatal("Error: 'bench' does not take positional arguments")
+	}
+	prompt, err := os.ReadFile(promptPath)
+	if err != nil {
+		log.Fatalf("Error reading prompt: %v", err)
+	}
+
+	results, err := smolcode.Benchmark(modelName, string(prompt), runs)
+	if err != nil {
+		die("Error running benchmark: %v", err)
+	}
+
+	var latencies, promptTokens, candidatesTokens, throughput []float64
+	fmt.Printf("\n%-4s %12s %14s %18s %12s\n", "Run", "Latency", "Prompt tokens", "Candidates tokens", "Tokens/sec")
+	for i, run := range results {
+		fmt.Printf("%-4d %12s %14d %18d %12.2f\n", i+1, run.Latency.Round(time.Millisecond), run.PromptTokens, run.CandidatesTokens, run.TokensPerSecond())
+		latencies = append(latencies, float64(run.Latency.Milliseconds()))
+		promptTokens = append(promptTokens, float64(run.PromptTokens))
+		candidatesTokens = append(candidatesTokens, float64(run.CandidatesTokens))
+		throughput = append(throughput, run.TokensPerSecond())
+	}
+
+	fmt.Printf("\n%-18s %10s %10s %10s %10s %10s\n", "", "Min", "Max", "Mean", "P50", "P95")
+	for _, row := range []struct {
+		name   string
+		values []float64
+	}{
+		{"Latency (ms)", latencies},
+		{"Prompt tokens", promptTokens},
+		{"Candidates tokens", candidatesTokens},
+		{"Tokens/sec", throughput},
+	} {
+		summary := smolcode.SummarizeBenchmark(row.values)
+		fmt.Printf("%-18s %10.2f %10.2f %10.2f %10.2f %10.2f\n", row.name, summary.Min, summary.Max, summary.Mean, summary.P50, summary.P95)
+	}
+
+	if csvPath != "" {
+		f, err := os.Create(csvPath)
+		if err != nil {
+			log.Fatalf("Error creating CSV file '%s': %v", csvPath, err)
+		}
+		defer f.Close()
+		if err := smolcode.WriteBenchmarkCSV(f, results); err != nil {
+			log.Fatalf("Error writing CSV file '%s': %v", csvPath, err)
+		}
+		fmt.Printf("\nMeasurements written to %s\n", csvPath)
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/dhamidi/smolcode"
+)
+
+func handleCheckpointListCommand(args []string) {
+	listCmd := flag.NewFlagSet("list", flag.ExitOnError)
+	listCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: smolcode checkpoint list\n")
+		fmt.Fprintf(os.Stderr, "Lists all named checkpoints, most recent first.\n")
+	}
+	listCmd.Parse(args)
+	if listCmd.NArg() != 0 {
+		listCmd.Usage()
+		log.Fatal("Error: 'list' does not take any arguments")
+	}
+
+	checkpoints, err := smolcode.ListCheckpoints()
+	if err != nil {
+		log.Fatalf("Error listing checkpoints: %v", err)
+	}
+	if len(checkpoints) == 0 {
+		fmt.Println("No checkpoints found.")
+		return
+	}
+	fmt.Println("Checkpoints:")
+	for _, checkpoint := range checkpoints {
+		fmt.Printf("  %s (created %s)\n", checkpoint.Name, checkpoint.Created.Format(time.RFC3339))
+	}
+}
+
+func handleCheckpointCreateCommand(args []string) {
+	createCmd := flag.NewFlagSet("create", flag.ExitOnError)
+	createCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: smolcode checkpoint create <name>\n")
+		fmt.Fprintf(os.Stderr, "Saves the current state of all files under name, replacing an existing checkpoint with that name.\n")
+	}
+	createCmd.Parse(args)
+	if createCmd.NArg() != 1 {
+		createCmd.Usage()
+		log.Fatal("Error: 'create' requires exactly one checkpoint name")
+	}
+
+	checkpoint, err := smolcode.CreateCheckpoint(createCmd.Arg(0))
+	if err != nil {
+		log.Fatalf("Error creating checkpoint: %v", err)
+	}
+	fmt.Printf("Checkpoint %s created.\n", checkpoint.Name)
+}
+
+func handleCheckpointRestoreCommand(args []string) {
+	restoreCmd := flag.NewFlagSet("restore", flag.ExitOnError)
+	restoreCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: smolcode checkpoint restore <name>\n")
+		fmt.Fprintf(os.Stderr, "Changes all files back to the checkpoint name. Files created since then are deleted.\n")
+	}
+	restoreCmd.Parse(args)
+	if restoreCmd.NArg() != 1 {
+		restoreCmd.Usage()
+		log.Fatal("Error: 'restore' requires exactly one checkpoint name")
+	}
+
+	name := restoreCmd.Arg(0)
+	changes, err := smolcode.RestoreCheckpoint(name)
+	if err != nil {
+		log.Fatalf("Error restoring checkpoint '%s': %v", name, err)
+	}
+	if len(changes) == 0 {
+		fmt.Printf("Checkpoint %s restored, no files changed.\n", name)
+		return
+	}
+	fmt.Printf("Checkpoint %s restored, %d files changed:\n", name, len(changes))
+	for _, change := range changes {
+		fmt.Printf("  %s %s\n", change.Status, change.Path)
+	}
+}
+
+func handleCheckpointDiffCommand(args []string) {
+	diffCmd := flag.NewFlagSet("diff", flag.ExitOnError)
+	diffCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: smolcode checkpoint diff <name>\n")
+		fmt.Fprintf(os.Stderr, "Prints a unified diff from the checkpoint name to the current files.\n")
+	}
+	diffCmd.Parse(args)
+	if diffCmd.NArg() != 1 {
+		diffCmd.Usage()
+		log.Fatal("Error: 'diff' requires exactly one checkpoint name")
+	}
+
+	name := diffCmd.Arg(0)
+	diff, err := smolcode.CheckpointDiff(name)
+	if err != nil {
+		log.Fatalf("Error comparing with checkpoint '%s': %v", name, err)
+	}
+	fmt.Print(diff)
+}
+
+// handleCheckpointCommand processes subcommands for named checkpoints.
+func handleCheckpointCommand(args []string) {
+	if len(args) < 1 {
+		fmt.Println("Usage: smolcode checkpoint <subcommand> [arguments]")
+		log.Fatal("Error: No checkpoint subcommand provided.")
+	}
+
+	subcommand := args[0]
+	remainingArgs := args[1:]
+
+	switch subcommand {
+	case "list":
+		handleCheckpointListCommand(remainingArgs)
+
+	case "create":
+		handleCheckpointCreateCommand(remainingArgs)
+
+	case "restore":
+		handleCheckpointRestoreCommand(remainingArgs)
+
+	case "diff":
+		handleCheckpointDiffCommand(remainingArgs)
+
+	default:
+		fmt.Fprintf(os.Stderr, "Usage: smolcode checkpoint <subcommand> [arguments]\n")
+		log.Fatalf("Error: Unknown checkpoint subcommand '%s'", subcommand)
+	}
+}
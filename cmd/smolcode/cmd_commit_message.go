@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+
+	"github.com/dhamidi/smolcode"
+)
+
+// handleCommitMessageCommand suggests a commit message for the current changes.
+func handleCommitMessageCommand(args []string) {
+	commitMsgCmd := flag.NewFlagSet("commit-message", flag.ExitOnError)
+	var commit bool
+	commitMsgCmd.BoolVar(&commit, "commit", false, "Commit the staged changes with the suggested message")
+	commitMsgCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: smolcode commit-message [--commit]\n")
+		fmt.Fprintf(os.Stderr, "Suggests a commit message for the staged changes, or the working tree if nothing is staged.\n")
+		commitMsgCmd.PrintDefaults()
+	}
+	commitMsgCmd.Parse(args)
+
+	if commitMsgCmd.NArg() != 0 {
+		commitMsgCmd.Usage()
+		log.Fatal("Error: 'commit-message' does not take positional arguments")
+	}
+
+	message, staged, err := smolcode.SuggestCommitMessage()
+	if errors.Is(err, smolcode.ErrNothingToCommit) {
+		fmt.Println("Nothing to commit: there are no staged or unstaged changes.")
+		return
+	}
+	if err != nil {
+		log.Fatalf("Error suggesting commit message: %v", err)
+	}
+	fmt.Println(message)
+
+	if !commit {
+		return
+	}
+	if !staged {
+		log.Fatal("Error: --commit requires staged changes; stage them with 'git add' first")
+	}
+	gitCommit := exec.Command("git", "commit", "-m", message)
+	gitCommit.Stdout = os.Stdout
+	gitCommit.Stderr = os.Stderr
+	if err := gitCommit.Run(); err != nil {
+		log.Fatalf("Error running git commit: %v", err)
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/dhamidi/smolcode"
+)
+
+// formatConfigSetting formats the effective value of key together with the source it came from.
+func formatConfigSetting(config *smolcode.Config, sources map[string]string, key string) (string, error) {
+	value, err := config.Get(key)
+	if err != nil {
+		return "", err
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode value of %s: %w", key, err)
+	}
+	source := sources[key]
+	if source == smolcode.SourceEnvironment {
+		source = fmt.Sprintf("%s, %s", source, smolcode.ConfigEnvVar(key))
+	}
+	return fmt.Sprintf("%s = %s (%s)", key, encoded, source), nil
+}
+
+func handleConfigListCommand(args []string) {
+	listCmd := flag.NewFlagSet("list", flag.ExitOnError)
+	listCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: smolcode config list\n")
+		fmt.Fprintf(os.Stderr, "Prints the effective value of every setting and where it comes from.\n")
+	}
+	listCmd.Parse(args)
+	if listCmd.NArg() != 0 {
+		listCmd.Usage()
+		log.Fatal("Error: 'list' does not take any arguments")
+	}
+
+	config, sources, err := smolcode.ResolveConfigSources(nil)
+	if err != nil {
+		log.Fatalf("Error loading configuration: %v", err)
+	}
+	for _, key := range smolcode.ConfigKeys() {
+		line, err := formatConfigSetting(config, sources, key)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		fmt.Println(line)
+	}
+}
+
+func handleConfigGetCommand(args []string) {
+	getCmd := flag.NewFlagSet("get", flag.ExitOnError)
+	getCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: smolcode config get <key>\n")
+		fmt.Fprintf(os.Stderr, "Prints the effective value of a setting and where it comes from.\n")
+	}
+	getCmd.Parse(args)
+	if getCmd.NArg() != 1 {
+		getCmd.Usage()
+		log.Fatal("Error: 'get' requires exactly one key")
+	}
+
+	config, sources, err := smolcode.ResolveConfigSources(nil)
+	if err != nil {
+		log.Fatalf("Error loading configuration: %v", err)
+	}
+	line, err := formatConfigSetting(config, sources, getCmd.Arg(0))
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	fmt.Println(line)
+}
+
+func handleConfigSetCommand(args []string) {
+	setCmd := flag.NewFlagSet("set", flag.ExitOnError)
+	var project bool
+	setCmd.BoolVar(&project, "project", false, fmt.Sprintf("Store the setting in %s instead of %s", smolcode.DefaultConfigPath, smolcode.PreferencesPath))
+	setCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: smolcode config set [--project] <key> <value>\n")
+		fmt.Fprintf(os.Stderr, "Stores a setting in %s.\n", smolcode.PreferencesPath)
+		fmt.Fprintf(os.Stderr, "Strings, booleans and numbers are given as is, other settings as JSON.\n")
+		setCmd.PrintDefaults()
+	}
+	positional := parseInterspersed(setCmd, args)
+	if len(positional) != 2 {
+		setCmd.Usage()
+		log.Fatal("Error: 'set' requires a key and a value")
+	}
+	key, value := positional[0], positional[1]
+
+	path := smolcode.PreferencesPath
+	if project {
+		path = smolcode.DefaultConfigPath
+	}
+	config, err := smolcode.LoadConfig(path)
+	if err != nil {
+		log.Fatalf("Error loading configuration: %v", err)
+	}
+	if err := config.Set(key, value); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	if err := smolcode.SaveConfig(config, path); err != nil {
+		log.Fatalf("Error saving configuration: %v", err)
+	}
+	fmt.Printf("Set %s in %s\n", key, path)
+}
+
+func handleConfigCommand(args []string) {
+	if len(args) < 1 {
+		fmt.Println("Usage: smolcode config <subcommand> [arguments]")
+		log.Fatal("Error: No config subcommand provided.")
+	}
+
+	subcommand := args[0]
+	remainingArgs := args[1:]
+
+	switch subcommand {
+	case "list":
+		handleConfigListCommand(remainingArgs)
+
+	case "get":
+		handleConfigGetCommand(remainingArgs)
+
+	case "set":
+		handleConfigSetCommand(remainingArgs)
+
+	default:
+		fmt.Fprintf(os.Stderr, "Usage: smolcode config <subcommand> [arguments]\n")
+		log.Fatalf("Error: Unknown config subcommand '%s'", subcommand)
+	}
+}
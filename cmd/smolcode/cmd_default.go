@@ -49,47 +49,213 @@ func handleDefaultCommand(args []string) {
 	defaultCmd.StringVar(&continueConvOpt, "continue", continueFlagNotSet, "Continue a conversation. Provide an ID, 'latest', or pass flag without value to use the latest conversation.")
 	defaultCmd.StringVar(&continueConvOpt, "c", continueFlagNotSet, "Continue a conversation. Provide an ID, 'latest', or pass flag without value to use the latest conversation. (shorthand)")
 	// Old BoolVar for continue removed
+	var continueNth int
+	defaultCmd.IntVar(&continueNth, "continue-nth", 0, "Continue the N-th most recent conversation; 1 is the latest")
 	defaultCmd.StringVar(&modelName, "model", "", "The name of the model to use")
 	defaultCmd.StringVar(&modelName, "m", "", "The name of the model to use (shorthand)")
 
+	var unsafe bool
+	defaultCmd.BoolVar(&unsafe, "unsafe", false, "Disable content blocking for all safety categories (sets every threshold to BLOCK_NONE)")
+
+	var thinkingBudget int
+	defaultCmd.IntVar(&thinkingBudget, "thinking-budget", 0, "Number of tokens the model may spend on reasoning (0 uses the API default)")
+
+	var contextWindow int
+	defaultCmd.IntVar(&contextWindow, "context-window", 0, "Only send the most recent N messages to the model (0 sends everything)")
+
+	var noSummary bool
+	defaultCmd.BoolVar(&noSummary, "no-summary", false, "Do not print the files changed during the session on exit")
+
+	var strictConversation bool
+	defaultCmd.BoolVar(&strictConversation, "strict-conversation", false, "Exit with an error if the conversation given by --conversation-id or --continue cannot be loaded, instead of starting a new one")
+
+	var toolRateLimit, globalToolRateLimit float64
+	defaultCmd.Float64Var(&toolRateLimit, "tool-rate-limit", 0, "Maximum calls per second to each tool (0 disables the limit)")
+	defaultCmd.Float64Var(&globalToolRateLimit, "global-tool-rate-limit", 0, "Maximum calls per second to all tools together (0 disables the limit)")
+
+	var maxRetrySeconds int
+	defaultCmd.IntVar(&maxRetrySeconds, "max-retry-seconds", 0, "Stop retrying a failed request to the model after this many seconds (0 uses the default of 120)")
+
+	var deterministic bool
+	defaultCmd.BoolVar(&deterministic, "deterministic", false, "Ask the models for reproducible output (temperature 0 and a fixed seed); best-effort and model-dependent")
+
+	var promptTemplate string
+	defaultCmd.StringVar(&promptTemplate, "prompt-template", "", "Prompt shown before reading input; may contain {count}, {model} and {plan}")
+
+	var disabledTools stringSliceFlag
+	defaultCmd.Var(&disabledTools, "disable-tool", "Do not offer a built-in tool to the model. Can be used multiple times.")
+
+	var stream bool
+	defaultCmd.BoolVar(&stream, "stream", false, "Display the model's responses as they are generated")
+
+	var quiet bool
+	defaultCmd.BoolVar(&quiet, "quiet", false, "Do not print the startup banner; only errors and model output are displayed")
+
+	var clarify bool
+	defaultCmd.BoolVar(&clarify, "clarify", false, "Ask the model to answer ambiguous requests with a clarifying question instead of guessing")
+
+	var autoRecall bool
+	defaultCmd.BoolVar(&autoRecall, "auto-recall", false, "Add memories relevant to each of your messages to the conversation")
+	var autoRecallLimit int
+	defaultCmd.IntVar(&autoRecallLimit, "auto-recall-limit", 0, fmt.Sprintf("Maximum number of memories auto-recall adds per message (0 uses the default of %d)", smolcode.DefaultAutoRecallLimit))
+	var autoRecallMinScore float64
+	defaultCmd.Float64Var(&autoRecallMinScore, "auto-recall-min-score", 0, "Relevance score a memory needs to be added by auto-recall")
+
 	var mcpConfigs mcpServerConfigFlag
 	defaultCmd.Var(&mcpConfigs, "mcp", "Register an MCP server. Format: id:command. Can be used multiple times.")
+	var mcpLazy bool
+	defaultCmd.BoolVar(&mcpLazy, "mcp-lazy", false, "Register MCP tools from the tool cache and start servers only when one of their tools is called")
 
 	// Important: Parse only the arguments passed to this handler
 	defaultCmd.Parse(args)
 
+	// Ensure no subcommands like 'plan' are accidentally processed here
+	// if they weren't caught by the main dispatcher.
+	// This check might be redundant if main dispatcher is robust.
+	if defaultCmd.NArg() > 0 {
+		argAfterFlags := defaultCmd.Arg(0)
+		// List of known top-level commands that should not be processed by default.
+		knownCommands := map[string]bool{"plan": true, "memory": true, "history": true, "generate": true, "resume": true, "commit-message": true, "tools": true, "config": true}
+		if _, isKnownCommand := knownCommands[argAfterFlags]; isKnownCommand {
+			// This case should ideally be handled by the main dispatcher.
+			// If we reach here, it means os.Args[1] was not a known command,
+			// but after parsing flags, a known command appeared as a positional argument.
+			// This could happen with `smolcode -cid 123 plan new myplan` if not handled carefully.
+			// The original main.go had a check: `if defaultCmd.Arg(0) == "plan"`
+			// We should ensure that the main dispatcher prevents this from being routed to default.
+			// For safety, one might print a general usage error or a specific one.
+			fmt.Fprintf(os.Stderr, "Error: Unexpected subcommand '%s' after flags for default operation.\n", argAfterFlags)
+			fmt.Fprintf(os.Stderr, "Usage for default operation: smolcode [flags]\n")
+			fmt.Fprintf(os.Stderr, "For subcommands: smolcode <command> [subcommand_args...]\n")
+			os.Exit(1)
+		}
+		// If it's not a known command, it might be an older way of passing instructions,
+		// or an unexpected argument. For now, smolcode.Code doesn't take direct instruction args.
+		// If there are non-flag arguments, it's likely an error for the default command.
+		fmt.Fprintf(os.Stderr, "Error: Unexpected positional arguments for default operation: %v\n", defaultCmd.Args())
+		defaultCmd.Usage()
+		os.Exit(1)
+
+	}
+
+	// Flags take precedence over the configuration files and environment.
+	config := &smolcode.Config{}
+	if unsafe {
+		config.SafetySettings = smolcode.UnsafeSafetySettings()
+	}
+	if thinkingBudget > 0 {
+		config.ThinkingBudget = thinkingBudget
+	}
+	if contextWindow > 0 {
+		config.ContextWindow = contextWindow
+	}
+	if noSummary {
+		config.NoChangeSummary = true
+	}
+	if strictConversation {
+		config.StrictConversation = true
+	}
+	if toolRateLimit > 0 {
+		config.ToolRateLimit = toolRateLimit
+	}
+	if globalToolRateLimit > 0 {
+		config.GlobalToolRateLimit = globalToolRateLimit
+	}
+	if maxRetrySeconds > 0 {
+		config.MaxRetrySeconds = maxRetrySeconds
+	}
+	if deterministic {
+		config.Deterministic = true
+	}
+	if stream {
+		config.Stream = true
+	}
+	if quiet {
+		config.Quiet = true
+	}
+	if clarify {
+		config.Clarify = true
+	}
+	if autoRecall {
+		config.AutoRecall = true
+	}
+	if autoRecallLimit > 0 {
+		config.AutoRecallLimit = autoRecallLimit
+	}
+	if autoRecallMinScore != 0 {
+		config.AutoRecallMinScore = autoRecallMinScore
+	}
+	if promptTemplate != "" {
+		config.PromptTemplate = promptTemplate
+	}
+	if len(disabledTools) > 0 {
+		config.DisabledTools = disabledTools
+	}
+
+	// Quiet may also be set in the configuration files or the environment.
+	if resolved, err := smolcode.ResolveConfig(config); err == nil {
+		quiet = resolved.Quiet
+	}
+
 	var conversationIDForAgent string
 	var forceNewForAgent bool
 
+	continueNthSet := false
+	defaultCmd.Visit(func(f *flag.Flag) { continueNthSet = continueNthSet || f.Name == "continue-nth" })
+	if continueNthSet && continueConvOpt != continueFlagNotSet {
+		die("Error: --continue-nth cannot be combined with --continue (-c)")
+	}
+
 	// Determine how to handle conversation loading based on flags
 	if specificIDToLoad != "" {
 		conversationIDForAgent = specificIDToLoad
 		forceNewForAgent = false
-		if continueConvOpt != continueFlagNotSet {
-			fmt.Fprintln(os.Stderr, "Warning: Both --conversation-id (-cid) and --continue (-c) were provided. Prioritizing --conversation-id.")
+		if (continueConvOpt != continueFlagNotSet || continueNthSet) && !quiet {
+			fmt.Fprintln(os.Stderr, "Warning: --conversation-id (-cid) was combined with --continue (-c) or --continue-nth. Prioritizing --conversation-id.")
+		}
+	} else if continueNthSet {
+		if continueNth < 1 {
+			die("Error: --continue-nth must be at least 1 (the latest conversation), got %d", continueNth)
+		}
+		nthID, err := history.GetNthLatestConversationID(continueNth)
+		if err != nil {
+			die("Error: cannot continue conversation %d: %v", continueNth, err)
 		}
+		if !quiet {
+			log.Printf("Continuing conversation %d: %s", continueNth, nthID)
+		}
+		conversationIDForAgent = nthID
+		forceNewForAgent = false
 	} else if continueConvOpt != continueFlagNotSet { // --continue or -c was used
 		if continueConvOpt == "" || continueConvOpt == "latest" { // --continue or --continue=latest
-			latestID, err := history.GetLatestConversationID(history.DefaultDatabasePath)
+			latestID, err := history.GetLatestConversationID()
 			if err != nil {
 				if err == history.ErrConversationNotFound {
-					log.Println("No conversations found in history. Starting a new conversation.")
+					if !quiet {
+						log.Println("No conversations found in history. Starting a new conversation.")
+					}
 					conversationIDForAgent = ""
 					forceNewForAgent = true // No latest, so force new
 				} else {
 					// For other errors, log it and fall back to a new conversation for robustness.
-					log.Printf("Error fetching latest conversation ID: %v. Starting a new conversation.", err)
+					if !quiet {
+						log.Printf("Error fetching latest conversation ID: %v. Starting a new conversation.", err)
+					}
 					conversationIDForAgent = ""
 					forceNewForAgent = true
 				}
 			} else {
-				log.Printf("Continuing latest conversation: %s", latestID)
+				if !quiet {
+					log.Printf("Continuing latest conversation: %s", latestID)
+				}
 				conversationIDForAgent = latestID
 				forceNewForAgent = false
 			}
 		} else {
 			// Request to load specific ID via --continue <id>
-			log.Printf("Attempting to continue conversation with ID: %s", continueConvOpt)
+			if !quiet {
+				log.Printf("Attempting to continue conversation with ID: %s", continueConvOpt)
+			}
 			conversationIDForAgent = continueConvOpt
 			forceNewForAgent = false
 		}
@@ -99,36 +265,12 @@ func handleDefaultCommand(args []string) {
 		forceNewForAgent = true
 	}
 
-	// Ensure no subcommands like 'plan' are accidentally processed here
-	// if they weren't caught by the main dispatcher.
-	// This check might be redundant if main dispatcher is robust.
-	if defaultCmd.NArg() > 0 {
-		argAfterFlags := defaultCmd.Arg(0)
-		// List of known top-level commands that should not be processed by default.
-		knownCommands := map[string]bool{"plan": true, "memory": true, "history": true, "generate": true}
-		if _, isKnownCommand := knownCommands[argAfterFlags]; isKnownCommand {
-			// This case should ideally be handled by the main dispatcher.
-			// If we reach here, it means os.Args[1] was not a known command,
-			// but after parsing flags, a known command appeared as a positional argument.
-			// This could happen with `smolcode -cid 123 plan new myplan` if not handled carefully.
-			// The original main.go had a check: `if defaultCmd.Arg(0) == "plan"`
-			// We should ensure that the main dispatcher prevents this from being routed to default.
-			// For safety, one might print a general usage error or a specific one.
-			fmt.Fprintf(os.Stderr, "Error: Unexpected subcommand '%s' after flags for default operation.\n", argAfterFlags)
-			fmt.Fprintf(os.Stderr, "Usage for default operation: smolcode [flags]\n")
-			fmt.Fprintf(os.Stderr, "For subcommands: smolcode <command> [subcommand_args...]\n")
-			os.Exit(1)
+	if mcpLazy {
+		for i := range mcpConfigs {
+			mcpConfigs[i].Lazy = true
 		}
-		// If it's not a known command, it might be an older way of passing instructions,
-		// or an unexpected argument. For now, smolcode.Code doesn't take direct instruction args.
-		// If there are non-flag arguments, it's likely an error for the default command.
-		fmt.Fprintf(os.Stderr, "Error: Unexpected positional arguments for default operation: %v\n", defaultCmd.Args())
-		defaultCmd.Usage()
-		os.Exit(1)
-
 	}
-
-	if err := smolcode.Code(conversationIDForAgent, modelName, forceNewForAgent, mcpConfigs); err != nil {
+	if err := smolcode.Code(conversationIDForAgent, modelName, forceNewForAgent, mcpConfigs, config); err != nil {
 		die("Error running smol-agent: %v", err) // die needs to be accessible
 	}
 }
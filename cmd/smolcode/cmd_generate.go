@@ -7,11 +7,11 @@ import (
 	"os"
 	"strings"
 
+	"github.com/dhamidi/smolcode"
 	"github.com/dhamidi/smolcode/codegen"
 )
 
 // stringSliceFlag is a custom flag type for accumulating multiple string values.
-// It's moved here as it's specific to the generate command.
 type stringSliceFlag []string
 
 func (s *stringSliceFlag) String() string {
@@ -27,11 +27,16 @@ func (s *stringSliceFlag) Set(value string) error {
 func handleGenerateCommand(args []string) {
 	genCmd := flag.NewFlagSet("generate", flag.ExitOnError)
 	archiveOutput := genCmd.Bool("archive", false, "Output a tar archive to stdout instead of writing files to disk.")
+	dryRun := genCmd.Bool("dry-run", false, "Print a unified diff between the existing files and the generated files to stdout instead of writing files to disk.")
 	var existingFilePaths stringSliceFlag
 	genCmd.Var(&existingFilePaths, "existing-file", "Path to an existing file to provide as context (can be specified multiple times).")
 	genCmd.Var(&existingFilePaths, "f", "Shorthand for --existing-file.")
 	var desiredFileSpecs stringSliceFlag
 	genCmd.Var(&desiredFileSpecs, "desired", "Desired file to generate, format 'filepath:description' (can be specified multiple times).")
+	deterministic := genCmd.Bool("deterministic", false, "Ask the model for reproducible output (temperature 0 and a fixed seed); best-effort and model-dependent.")
+	model := genCmd.String("model", codegen.DefaultModel, "Model to generate code with.")
+	temperature := genCmd.Float64("temperature", 0, "Sampling temperature to send with each request (default: the API's default).")
+	baseURL := genCmd.String("base-url", "", "Base URL of an OpenAI-compatible API to generate code with (default: $"+codegen.BaseURLEnv+" or the Inception Labs API).")
 
 	genCmd.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Usage: smolcode generate [flags] <instruction>\n")
@@ -48,8 +53,22 @@ func handleGenerateCommand(args []string) {
 		log.Fatal("Error: Instruction argument is required for 'generate' command.")
 	}
 	instruction := strings.Join(genCmd.Args(), " ")
+	if *dryRun && *archiveOutput {
+		log.Fatal("Error: --dry-run cannot be combined with --archive.")
+	}
 
-	generator := codegen.New(os.Getenv("INCEPTION_API_KEY"))
+	config, err := smolcode.ResolveConfig(&smolcode.Config{Deterministic: *deterministic})
+	if err != nil {
+		log.Fatalf("Error loading configuration: %v", err)
+	}
+	codegen.SetDeterministic(config.Deterministic)
+
+	generator := codegen.New(os.Getenv("INCEPTION_API_KEY")).WithModel(*model).WithBaseURL(*baseURL)
+	genCmd.Visit(func(f *flag.Flag) {
+		if f.Name == "temperature" {
+			generator.WithTemperature(*temperature)
+		}
+	})
 
 	var existingFilesToPass []codegen.File
 	for _, path := range existingFilePaths {
@@ -72,18 +91,34 @@ func handleGenerateCommand(args []string) {
 	}
 
 	fmt.Fprintf(os.Stderr, "Generating code with instruction: %s...\n", instruction)
-	generatedFiles, err := generator.GenerateCode(instruction, existingFilesToPass, desiredFiles)
-	if err != nil {
-		log.Fatalf("Error generating code: %v", err)
-	}
-	fmt.Fprintf(os.Stderr, "Code generation complete. Received %d file(s).\n", len(generatedFiles))
-
+	generatedFiles, generateErr := generator.GenerateCode(instruction, existingFilesToPass, desiredFiles)
 	var generatedFilePtrs []*codegen.File
-	for i := range generatedFiles {
-		generatedFilePtrs = append(generatedFilePtrs, &generatedFiles[i])
+	for _, f := range generatedFiles {
+		if f != nil {
+			generatedFilePtrs = append(generatedFilePtrs, f)
+		}
+	}
+	if generateErr != nil {
+		fmt.Fprintf(os.Stderr, "Error generating code:\n%v\n", generateErr)
+		if len(generatedFilePtrs) == 0 {
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "Keeping the %d file(s) generated successfully.\n", len(generatedFilePtrs))
+		// Report the failure once the successfully generated files are written.
+		defer os.Exit(1)
 	}
+	fmt.Fprintf(os.Stderr, "Code generation complete. Received %d file(s).\n", len(generatedFilePtrs))
 
-	if *archiveOutput {
+	if *dryRun {
+		diff, err := generator.Diff(generatedFilePtrs)
+		if err != nil {
+			log.Fatalf("Error comparing generated files: %v", err)
+		}
+		if diff == "" {
+			fmt.Fprintf(os.Stderr, "The generated files match the files on disk.\n")
+		}
+		fmt.Print(diff)
+	} else if *archiveOutput {
 		fmt.Fprintf(os.Stderr, "Outputting to tar archive on stdout...\n")
 		// Assuming NewTarballWriterFS is accessible or moved to a shared utility package.
 		// For now, this will cause a compile error if NewTarballWriterFS is not defined in this package
@@ -103,11 +138,11 @@ func handleGenerateCommand(args []string) {
 		fmt.Fprintf(os.Stderr, "Tar archive written to stdout successfully.\n")
 	} else {
 		fmt.Fprintf(os.Stderr, "Writing files to disk...\n")
-		if err := generator.Write(generatedFiles); err != nil {
+		if err := generator.Write(generatedFilePtrs); err != nil {
 			log.Fatalf("Error writing files to disk: %v", err)
 		}
 		fmt.Fprintf(os.Stderr, "Files written to disk successfully:\n")
-		for _, f := range generatedFiles {
+		for _, f := range generatedFilePtrs {
 			fmt.Fprintf(os.Stderr, "  - %s\n", f.Path)
 		}
 	}
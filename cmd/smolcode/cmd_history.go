@@ -5,9 +5,13 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"maps"
 	"os"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/dhamidi/smolcode"
 	"github.com/dhamidi/smolcode/history"
 )
 
@@ -84,7 +88,7 @@ func handleHistoryListCommand(args []string) {
 		log.Fatal("Error: 'list' does not take any arguments")
 	}
 
-	conversations, err := history.ListConversations(history.DefaultDatabasePath)
+	conversations, err := history.List()
 	if err != nil {
 		log.Fatalf("Error listing conversations: %v", err)
 	}
@@ -94,9 +98,13 @@ func handleHistoryListCommand(args []string) {
 	} else {
 		fmt.Println("Conversations:")
 		for _, conv := range conversations {
-			fmt.Printf("  ID: %s, Created: %s, Last Message: %s, Messages: %d\n",
-				conv.ID, conv.CreatedAt.Format(time.RFC3339),
-				conv.LatestMessageTime.Format(time.RFC3339), conv.MessageCount)
+			title := ""
+			if conv.Title != "" {
+				title = fmt.Sprintf(", Title: %q", conv.Title)
+			}
+			fmt.Printf("  ID: %s%s, Created: %s, Last Activity: %s, Messages: %d, Tokens: %d\n",
+				conv.ID, title, conv.CreatedAt.Format(time.RFC3339),
+				conv.LatestMessageTime.Format(time.RFC3339), conv.MessageCount, conv.TotalTokens)
 		}
 	}
 }
@@ -140,6 +148,303 @@ func handleHistoryShowCommand(args []string) {
 	}
 }
 
+func handleHistoryExportArchiveCommand(args []string) {
+	exportCmd := flag.NewFlagSet("export-archive", flag.ExitOnError)
+	var conversationID string
+	var outputPath string
+	exportCmd.StringVar(&conversationID, "id", "", "ID of the conversation to export")
+	exportCmd.StringVar(&outputPath, "output", "", "File to write the archive to (defaults to stdout)")
+	exportCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: smolcode history export-archive --id <conversation-id> [--output <file>]\n")
+		fmt.Fprintf(os.Stderr, "Exports a conversation with all messages and metadata as a single JSON archive.\n")
+		exportCmd.PrintDefaults()
+	}
+	exportCmd.Parse(args)
+
+	if conversationID == "" {
+		exportCmd.Usage()
+		log.Fatal("Error: --id flag is required for 'export-archive'")
+	}
+	if exportCmd.NArg() != 0 {
+		exportCmd.Usage()
+		log.Fatal("Error: 'export-archive' does not take positional arguments")
+	}
+
+	out := os.Stdout
+	if outputPath != "" {
+		f, err := os.Create(outputPath)
+		if err != nil {
+			log.Fatalf("Error creating archive file '%s': %v", outputPath, err)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	if err := history.ExportArchive(conversationID, out); err != nil {
+		log.Fatalf("Error exporting conversation '%s': %v", conversationID, err)
+	}
+	if outputPath != "" {
+		fmt.Printf("Conversation %s exported to %s\n", conversationID, outputPath)
+	}
+}
+
+func handleHistoryExportCommand(args []string) {
+	exportCmd := flag.NewFlagSet("export", flag.ExitOnError)
+	var outputPath string
+	exportCmd.StringVar(&outputPath, "out", "", "File to write the transcript to (defaults to stdout)")
+	exportCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: smolcode history export <conversation-id> [--out <file.md>]\n")
+		fmt.Fprintf(os.Stderr, "Exports a conversation as a Markdown transcript.\n")
+		exportCmd.PrintDefaults()
+	}
+	exportCmd.Parse(args)
+	if exportCmd.NArg() < 1 {
+		exportCmd.Usage()
+		log.Fatal("Error: 'export' requires exactly one conversation ID")
+	}
+	// Flags may also follow the conversation ID.
+	conversationID := exportCmd.Arg(0)
+	exportCmd.Parse(exportCmd.Args()[1:])
+	if exportCmd.NArg() != 0 {
+		exportCmd.Usage()
+		log.Fatal("Error: 'export' requires exactly one conversation ID")
+	}
+
+	out := os.Stdout
+	if outputPath != "" {
+		f, err := os.Create(outputPath)
+		if err != nil {
+			log.Fatalf("Error creating transcript file '%s': %v", outputPath, err)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	if err := history.ExportMarkdown(conversationID, out); err != nil {
+		log.Fatalf("Error exporting conversation '%s': %v", conversationID, err)
+	}
+	if outputPath != "" {
+		fmt.Printf("Conversation %s exported to %s\n", conversationID, outputPath)
+	}
+}
+
+func handleHistoryImportArchiveCommand(args []string) {
+	importCmd := flag.NewFlagSet("import-archive", flag.ExitOnError)
+	importCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: smolcode history import-archive <file>\n")
+		fmt.Fprintf(os.Stderr, "Imports a conversation archive. Use '-' to read from stdin.\n")
+	}
+	importCmd.Parse(args)
+
+	if importCmd.NArg() != 1 {
+		importCmd.Usage()
+		log.Fatal("Error: 'import-archive' requires exactly one file argument")
+	}
+
+	in := os.Stdin
+	if path := importCmd.Arg(0); path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			log.Fatalf("Error opening archive file '%s': %v", path, err)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	id, err := history.ImportArchive(in)
+	if err != nil {
+		log.Fatalf("Error importing archive: %v", err)
+	}
+	fmt.Printf("Conversation imported with ID: %s\n", id)
+}
+
+func handleHistoryVerifyCommand(args []string) {
+	verifyCmd := flag.NewFlagSet("verify", flag.ExitOnError)
+	verifyCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: smolcode history verify [<conversation-id>]\n")
+		fmt.Fprintf(os.Stderr, "Checks that every message of a conversation, or of all conversations, can be restored.\n")
+		fmt.Fprintf(os.Stderr, "Exits with a non-zero status if any message is invalid.\n")
+	}
+	verifyCmd.Parse(args)
+
+	var conversationIDs []string
+	switch verifyCmd.NArg() {
+	case 0:
+		conversations, err := history.List()
+		if err != nil {
+			log.Fatalf("Error listing conversations: %v", err)
+		}
+		for _, conv := range conversations {
+			conversationIDs = append(conversationIDs, conv.ID)
+		}
+	case 1:
+		conversationIDs = []string{verifyCmd.Arg(0)}
+	default:
+		verifyCmd.Usage()
+		log.Fatal("Error: 'verify' takes at most one conversation ID")
+	}
+
+	invalid := 0
+	for _, conversationID := range conversationIDs {
+		conv, err := history.Load(conversationID)
+		if err != nil {
+			fmt.Printf("%s: failed to load: %v\n", conversationID, err)
+			invalid++
+			continue
+		}
+		problems := smolcode.VerifyConversation(conv)
+		if len(problems) == 0 {
+			fmt.Printf("%s: ok (%d messages)\n", conversationID, len(conv.Messages))
+			continue
+		}
+		fmt.Printf("%s: %d of %d messages are invalid\n", conversationID, len(problems), len(conv.Messages))
+		for _, problem := range problems {
+			fmt.Printf("  %v\n", problem)
+		}
+		invalid += len(problems)
+	}
+	if invalid > 0 {
+		os.Exit(1)
+	}
+}
+
+func handleHistoryDeleteCommand(args []string) {
+	deleteCmd := flag.NewFlagSet("delete", flag.ExitOnError)
+	deleteCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: smolcode history delete <conversation-id>\n")
+		fmt.Fprintf(os.Stderr, "Deletes a conversation and all its messages.\n")
+	}
+	deleteCmd.Parse(args)
+	if deleteCmd.NArg() != 1 {
+		deleteCmd.Usage()
+		log.Fatal("Error: 'delete' requires exactly one conversation ID")
+	}
+
+	conversationID := deleteCmd.Arg(0)
+	if err := history.Delete(conversationID); err != nil {
+		log.Fatalf("Error deleting conversation '%s': %v", conversationID, err)
+	}
+	fmt.Printf("Conversation %s deleted.\n", conversationID)
+}
+
+func handleHistoryRenameCommand(args []string) {
+	renameCmd := flag.NewFlagSet("rename", flag.ExitOnError)
+	renameCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: smolcode history rename <conversation-id> <title>\n")
+		fmt.Fprintf(os.Stderr, "Sets the title shown when listing conversations.\n")
+	}
+	renameCmd.Parse(args)
+	if renameCmd.NArg() < 2 {
+		renameCmd.Usage()
+		log.Fatal("Error: 'rename' requires a conversation ID and a title")
+	}
+
+	conversationID := renameCmd.Arg(0)
+	title := strings.Join(renameCmd.Args()[1:], " ")
+	if err := history.SetTitle(conversationID, title); err != nil {
+		log.Fatalf("Error renaming conversation '%s': %v", conversationID, err)
+	}
+	fmt.Printf("Conversation %s renamed to %q.\n", conversationID, title)
+}
+
+func handleHistoryMetaCommand(args []string) {
+	metaCmd := flag.NewFlagSet("meta", flag.ExitOnError)
+	metaCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: smolcode history meta set <conversation-id> <key> <value>\n")
+		fmt.Fprintf(os.Stderr, "       smolcode history meta get <conversation-id> <key>\n")
+		fmt.Fprintf(os.Stderr, "       smolcode history meta list <conversation-id>\n")
+		fmt.Fprintf(os.Stderr, "Manages key/value metadata of a conversation. Setting an empty value removes the key.\n")
+	}
+	metaCmd.Parse(args)
+	wantArgs := map[string]int{"set": 4, "get": 3, "list": 2}
+	if metaCmd.NArg() == 0 || wantArgs[metaCmd.Arg(0)] != metaCmd.NArg() {
+		metaCmd.Usage()
+		log.Fatal("Error: 'meta' requires 'set', 'get' or 'list' and their arguments")
+	}
+
+	conversationID := metaCmd.Arg(1)
+	switch metaCmd.Arg(0) {
+	case "set":
+		key, value := metaCmd.Arg(2), metaCmd.Arg(3)
+		if err := history.SetMeta(conversationID, key, value); err != nil {
+			log.Fatalf("Error setting %s of conversation '%s': %v", key, conversationID, err)
+		}
+	case "get":
+		key := metaCmd.Arg(2)
+		value, err := history.GetMeta(conversationID, key)
+		if err != nil {
+			log.Fatalf("Error getting %s of conversation '%s': %v", key, conversationID, err)
+		}
+		fmt.Println(value)
+	case "list":
+		meta, err := history.Meta(conversationID)
+		if err != nil {
+			log.Fatalf("Error listing metadata of conversation '%s': %v", conversationID, err)
+		}
+		for _, key := range slices.Sorted(maps.Keys(meta)) {
+			fmt.Printf("%s\t%s\n", key, meta[key])
+		}
+	}
+}
+
+func handleHistorySummaryCommand(args []string) {
+	summaryCmd := flag.NewFlagSet("summary", flag.ExitOnError)
+	summaryCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: smolcode history summary <conversation-id>\n")
+		fmt.Fprintf(os.Stderr, "Prints the summaries of all sessions on a conversation as JSON.\n")
+	}
+	summaryCmd.Parse(args)
+	if summaryCmd.NArg() != 1 {
+		summaryCmd.Usage()
+		log.Fatal("Error: 'summary' requires exactly one conversation ID")
+	}
+
+	conversationID := summaryCmd.Arg(0)
+	summaries, err := history.SessionSummaries(conversationID)
+	if err != nil {
+		log.Fatalf("Error loading session summaries of conversation '%s': %v", conversationID, err)
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(summaries); err != nil {
+		log.Fatalf("Error encoding session summaries: %v", err)
+	}
+}
+
+func handleHistoryPruneCommand(args []string) {
+	pruneCmd := flag.NewFlagSet("prune", flag.ExitOnError)
+	var before string
+	pruneCmd.StringVar(&before, "before", "", "Delete conversations without activity since this date (YYYY-MM-DD or RFC 3339)")
+	pruneCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: smolcode history prune --before <date>\n")
+		fmt.Fprintf(os.Stderr, "Deletes all conversations whose latest message is older than date.\n")
+		pruneCmd.PrintDefaults()
+	}
+	pruneCmd.Parse(args)
+
+	if before == "" {
+		pruneCmd.Usage()
+		log.Fatal("Error: --before flag is required for 'prune'")
+	}
+	if pruneCmd.NArg() != 0 {
+		pruneCmd.Usage()
+		log.Fatal("Error: 'prune' does not take positional arguments")
+	}
+	cutoff, err := time.ParseInLocation(time.DateOnly, before, time.Local)
+	if err != nil {
+		cutoff, err = time.Parse(time.RFC3339, before)
+	}
+	if err != nil {
+		log.Fatalf("Error: invalid --before date %q, expected YYYY-MM-DD or RFC 3339", before)
+	}
+
+	pruned, err := history.PruneOlderThan(cutoff)
+	if err != nil {
+		log.Fatalf("Error pruning conversations: %v", err)
+	}
+	fmt.Printf("Deleted %d conversations without activity since %s.\n", pruned, cutoff.Format(time.RFC3339))
+}
+
 // handleHistoryCommand processes subcommands for the 'history' feature.
 func handleHistoryCommand(args []string) {
 	if len(args) < 1 {
@@ -163,6 +468,33 @@ func handleHistoryCommand(args []string) {
 	case "show":
 		handleHistoryShowCommand(remainingArgs)
 
+	case "export":
+		handleHistoryExportCommand(remainingArgs)
+
+	case "export-archive":
+		handleHistoryExportArchiveCommand(remainingArgs)
+
+	case "verify":
+		handleHistoryVerifyCommand(remainingArgs)
+
+	case "delete":
+		handleHistoryDeleteCommand(remainingArgs)
+
+	case "prune":
+		handleHistoryPruneCommand(remainingArgs)
+
+	case "rename":
+		handleHistoryRenameCommand(remainingArgs)
+
+	case "summary":
+		handleHistorySummaryCommand(remainingArgs)
+
+	case "meta":
+		handleHistoryMetaCommand(remainingArgs)
+
+	case "import-archive":
+		handleHistoryImportArchiveCommand(remainingArgs)
+
 	default:
 		fmt.Fprintf(os.Stderr, "Usage: smolcode history <subcommand> [arguments]\n")
 		log.Fatalf("Error: Unknown history subcommand '%s'", subcommand)
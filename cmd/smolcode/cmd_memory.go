@@ -7,7 +7,9 @@ import (
 	"os"
 	"os/exec"
 	"strings"
+	"time"
 
+	"github.com/dhamidi/smolcode"
 	"github.com/dhamidi/smolcode/memory"
 )
 
@@ -18,18 +20,27 @@ const (
 
 func handleMemoryAddCommand(mgr *memory.MemoryManager, args []string) {
 	addCmd := flag.NewFlagSet("add", flag.ExitOnError)
+	var tags stringSliceFlag
+	addCmd.Var(&tags, "tag", "Tag the memory, replacing its existing tags. Can be used multiple times.")
 	addCmd.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: smolcode memory add <id> <content>\n")
+		fmt.Fprintf(os.Stderr, "Usage: smolcode memory add [--tag <tag>]... <id> <content>\n")
 		fmt.Fprintf(os.Stderr, "Adds or updates a memory.\n")
+		addCmd.PrintDefaults()
 	}
-	addCmd.Parse(args)
-	if addCmd.NArg() != 2 {
+	positional := parseInterspersed(addCmd, args)
+	if len(positional) != 2 {
 		addCmd.Usage()
 		log.Fatal("Error: 'add' requires exactly two arguments: <id> <content>")
 	}
-	memID := addCmd.Arg(0)
-	memContent := addCmd.Arg(1)
-	if err := mgr.AddMemory(memID, memContent); err != nil {
+	memID := positional[0]
+	memContent := positional[1]
+	var err error
+	if len(tags) > 0 {
+		err = mgr.AddMemoryWithTags(memID, memContent, tags)
+	} else {
+		err = mgr.AddMemory(memID, memContent)
+	}
+	if err != nil {
 		log.Fatalf("Error adding memory '%s': %v", memID, err)
 	}
 	fmt.Printf("Memory '%s' added/updated successfully.\n", memID)
@@ -51,31 +62,82 @@ func handleMemoryGetCommand(mgr *memory.MemoryManager, args []string) {
 	if err != nil {
 		log.Fatalf("Error retrieving memory '%s': %v", memID, err)
 	}
-	fmt.Printf("ID: %s\nContent: %s\n", mem.ID, mem.Content)
+	fmt.Printf("ID: %s\n", mem.ID)
+	if len(mem.Tags) > 0 {
+		fmt.Printf("Tags: %s\n", strings.Join(mem.Tags, ", "))
+	}
+	fmt.Printf("Content: %s\n", mem.Content)
+}
+
+func handleMemoryListCommand(mgr *memory.MemoryManager, args []string) {
+	listCmd := flag.NewFlagSet("list", flag.ExitOnError)
+	listCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: smolcode memory list\n")
+		fmt.Fprintf(os.Stderr, "Lists all memories, most recently updated first.\n")
+	}
+	listCmd.Parse(args)
+	if listCmd.NArg() != 0 {
+		listCmd.Usage()
+		log.Fatal("Error: 'list' does not take any arguments")
+	}
+
+	memories, err := mgr.ListAll()
+	if err != nil {
+		log.Fatalf("Error listing memories: %v", err)
+	}
+	for _, mem := range memories {
+		fmt.Printf("%s\t%s\t%s\n", mem.ID, mem.UpdatedAt.Local().Format(time.DateTime), mem.Content)
+	}
 }
 
 func handleMemorySearchCommand(mgr *memory.MemoryManager, args []string) {
 	searchCmd := flag.NewFlagSet("search", flag.ExitOnError)
+	var opts memory.SearchOptions
+	searchCmd.BoolVar(&opts.Prefix, "prefix", false, "Match terms as prefixes, e.g. 'config' also matches 'configuration'")
+	searchCmd.BoolVar(&opts.Any, "any", false, "Match memories containing any of the terms instead of all of them")
+	searchCmd.BoolVar(&opts.Boolean, "boolean", false, "Treat AND, OR and NOT in the query as operators")
+	searchCmd.IntVar(&opts.Limit, "limit", 0, "Maximum number of results, best matches first (0 for no limit)")
+	searchCmd.StringVar(&opts.Tag, "tag", "", "Only match memories with this tag")
 	searchCmd.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: smolcode memory search <query>\n")
-		fmt.Fprintf(os.Stderr, "Searches memories by query.\n")
+		fmt.Fprintf(os.Stderr, "Usage: smolcode memory search <query> [--prefix] [--any] [--boolean] [--limit N] [--tag <tag>]\n")
+		fmt.Fprintf(os.Stderr, "       smolcode memory search --tag <tag>\n")
+		fmt.Fprintf(os.Stderr, "Searches memories by query, by tag, or both.\n")
+		searchCmd.PrintDefaults()
 	}
-	searchCmd.Parse(args)
-	if searchCmd.NArg() != 1 {
+	positional := parseInterspersed(searchCmd, args)
+	if len(positional) > 1 || (len(positional) == 0 && opts.Tag == "") {
 		searchCmd.Usage()
-		log.Fatal("Error: 'search' requires exactly one argument: <query>")
+		log.Fatal("Error: 'search' requires exactly one argument: <query>, or --tag")
 	}
-	query := searchCmd.Arg(0)
-	mems, err := mgr.SearchMemory(query)
-	if err != nil {
-		log.Fatalf("Error searching memory with query '%s': %v", query, err)
+	var mems []*memory.Memory
+	var scores []float64 // Only set when searching by query.
+	var err error
+	if len(positional) == 0 {
+		mems, err = mgr.SearchMemoryByTag(opts.Tag)
+		if err != nil {
+			log.Fatalf("Error searching memory with tag '%s': %v", opts.Tag, err)
+		}
+	} else {
+		query := positional[0]
+		hits, err := mgr.SearchMemoryHits(query, opts)
+		if err != nil {
+			log.Fatalf("Error searching memory with query '%s': %v", query, err)
+		}
+		for _, hit := range hits {
+			mems = append(mems, hit.Memory)
+			scores = append(scores, hit.Score)
+		}
 	}
 	if len(mems) == 0 {
 		fmt.Println("No memories found matching your query.")
 	} else {
 		fmt.Printf("Found %d memory/memories:\n", len(mems))
-		for _, mem := range mems {
-			fmt.Printf("---\nID: %s\nContent: %s\n", mem.ID, mem.Content)
+		for i, mem := range mems {
+			fmt.Printf("---\nID: %s\n", mem.ID)
+			if scores != nil {
+				fmt.Printf("Score: %.3f\n", scores[i])
+			}
+			fmt.Printf("Content: %s\n", mem.Content)
 		}
 	}
 }
@@ -176,6 +238,146 @@ func handleMemoryTestCommand(args []string) {
 	os.Remove(execPath)
 }
 
+// handleMemoryImportLinesCommand adds or updates the memories read from a TSV, CSV or JSON file in a single batch.
+func handleMemoryImportLinesCommand(mgr *memory.MemoryManager, args []string) {
+	importCmd := flag.NewFlagSet("import-lines", flag.ExitOnError)
+	var format string
+	importCmd.StringVar(&format, "format", "tsv", "Input format: tsv (id<TAB>content per line), csv or json")
+	importCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: smolcode memory import-lines [--format tsv|csv|json] <file>\n")
+		fmt.Fprintf(os.Stderr, "Adds or updates many memories at once. Use '-' to read from stdin.\n")
+		importCmd.PrintDefaults()
+	}
+	importCmd.Parse(args)
+	if importCmd.NArg() != 1 {
+		importCmd.Usage()
+		log.Fatal("Error: 'import-lines' requires exactly one argument: <file>")
+	}
+
+	in := os.Stdin
+	if path := importCmd.Arg(0); path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			log.Fatalf("Error opening '%s': %v", path, err)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	memories, err := memory.ParseEntries(in, format)
+	if err != nil {
+		log.Fatalf("Error reading memories: %v", err)
+	}
+	result, err := mgr.AddMemories(memories)
+	if err != nil {
+		log.Fatalf("Error importing memories: %v", err)
+	}
+	fmt.Printf("Imported %d memories: %d added, %d updated, %d skipped (unchanged).\n",
+		len(memories), result.Added, result.Updated, result.Skipped)
+}
+
+func handleMemoryFromConversationCommand(mgr *memory.MemoryManager, args []string) {
+	fromConvCmd := flag.NewFlagSet("from-conversation", flag.ExitOnError)
+	var dryRun bool
+	fromConvCmd.BoolVar(&dryRun, "dry-run", false, "Print the extracted memories without storing them")
+	fromConvCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: smolcode memory from-conversation [--dry-run] <conversation-id>\n")
+		fmt.Fprintf(os.Stderr, "Asks the model for the durable facts of a conversation and stores each of them as a memory.\n")
+		fromConvCmd.PrintDefaults()
+	}
+	fromConvCmd.Parse(args)
+	if fromConvCmd.NArg() != 1 {
+		fromConvCmd.Usage()
+		log.Fatal("Error: 'from-conversation' requires exactly one argument: <conversation-id>")
+	}
+	conversationID := fromConvCmd.Arg(0)
+
+	existing, err := mgr.ListAll()
+	if err != nil {
+		log.Fatalf("Error listing memories: %v", err)
+	}
+	memories, err := smolcode.ExtractConversationMemories(conversationID, existing)
+	if err != nil {
+		log.Fatalf("Error extracting memories: %v", err)
+	}
+	if len(memories) == 0 {
+		fmt.Printf("No durable facts found in conversation '%s'.\n", conversationID)
+		return
+	}
+	for _, mem := range memories {
+		fmt.Printf("%s\t%s\n", mem.ID, mem.Content)
+	}
+	if dryRun {
+		return
+	}
+	result, err := mgr.AddMemories(memories)
+	if err != nil {
+		log.Fatalf("Error storing memories: %v", err)
+	}
+	fmt.Printf("Stored %d memories: %d added, %d updated, %d skipped (unchanged).\n",
+		len(memories), result.Added, result.Updated, result.Skipped)
+}
+
+func handleMemoryStatsCommand(mgr *memory.MemoryManager, args []string) {
+	statsCmd := flag.NewFlagSet("stats", flag.ExitOnError)
+	statsCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: smolcode memory stats\n")
+		fmt.Fprintf(os.Stderr, "Displays statistics about the memory store.\n")
+	}
+	statsCmd.Parse(args)
+	if statsCmd.NArg() != 0 {
+		statsCmd.Usage()
+		log.Fatal("Error: 'stats' does not take any arguments")
+	}
+
+	stats, err := mgr.Stats()
+	if err != nil {
+		log.Fatalf("Error computing memory statistics: %v", err)
+	}
+	fmt.Printf("Total entries: %d\n", stats.TotalEntries)
+	fmt.Printf("Total content size: %d bytes\n", stats.TotalContentBytes)
+	if stats.TotalEntries == 0 {
+		return
+	}
+	fmt.Printf("Average content size: %.1f bytes\n", stats.AverageBytes)
+	fmt.Printf("Smallest/largest content: %d/%d bytes\n", stats.SmallestBytes, stats.LargestBytes)
+	fmt.Printf("Oldest memory: %s\n", stats.OldestID)
+	fmt.Printf("Newest memory: %s\n", stats.NewestID)
+	if len(stats.Tags) == 0 {
+		return
+	}
+	fmt.Println("Tags:")
+	for _, tag := range stats.Tags {
+		fmt.Printf("  %s: %d\n", tag.Tag, tag.Count)
+	}
+}
+
+func handleMemoryReindexCommand(mgr *memory.MemoryManager, args []string) {
+	reindexCmd := flag.NewFlagSet("reindex", flag.ExitOnError)
+	reindexCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: smolcode memory reindex\n")
+		fmt.Fprintf(os.Stderr, "Checks the full-text search index and rebuilds it from the stored memories.\n")
+	}
+	reindexCmd.Parse(args)
+	if reindexCmd.NArg() != 0 {
+		reindexCmd.Usage()
+		log.Fatal("Error: 'reindex' does not take any arguments")
+	}
+
+	if err := mgr.CheckIndex(); err != nil {
+		fmt.Printf("Index problem detected: %v\n", err)
+	} else {
+		fmt.Println("Index integrity check passed.")
+	}
+	if err := mgr.RebuildIndex(); err != nil {
+		log.Fatalf("Error rebuilding index: %v", err)
+	}
+	if err := mgr.CheckIndex(); err != nil {
+		log.Fatalf("Index is still inconsistent after rebuilding: %v", err)
+	}
+	fmt.Println("Index rebuilt successfully.")
+}
+
 // handleMemoryCommand processes subcommands for the 'memory' feature.
 func handleMemoryCommand(args []string) {
 	mgr, err := memory.New(memoryDBPath)
@@ -203,12 +405,27 @@ func handleMemoryCommand(args []string) {
 	case "get":
 		handleMemoryGetCommand(mgr, remainingArgs)
 
+	case "list":
+		handleMemoryListCommand(mgr, remainingArgs)
+
 	case "search":
 		handleMemorySearchCommand(mgr, remainingArgs)
 
 	case "forget":
 		handleMemoryForgetCommand(mgr, remainingArgs)
 
+	case "import-lines":
+		handleMemoryImportLinesCommand(mgr, remainingArgs)
+
+	case "from-conversation":
+		handleMemoryFromConversationCommand(mgr, remainingArgs)
+
+	case "stats":
+		handleMemoryStatsCommand(mgr, remainingArgs)
+
+	case "reindex":
+		handleMemoryReindexCommand(mgr, remainingArgs)
+
 	case "test":
 		handleMemoryTestCommand(remainingArgs)
 
@@ -1,10 +1,13 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"sort"
 	"strings"
 
 	"github.com/dhamidi/smolcode/planner"
@@ -17,17 +20,26 @@ const (
 
 func handlePlanNewCommand(plans *planner.Planner, args []string) {
 	newCmd := flag.NewFlagSet("new", flag.ExitOnError)
+	var templateName string
+	newCmd.StringVar(&templateName, "template", "", "Create the plan with the steps of a template from "+planner.TemplateDir)
 	newCmd.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: smolcode plan new <plan-name>\n")
-		fmt.Fprintf(os.Stderr, "Creates a new, empty plan file.\n")
+		fmt.Fprintf(os.Stderr, "Usage: smolcode plan new <plan-name> [--template <template>]\n")
+		fmt.Fprintf(os.Stderr, "Creates a new plan, which is empty unless created from a template.\n")
+		newCmd.PrintDefaults()
 	}
-	newCmd.Parse(args)
-	if newCmd.NArg() != 1 {
+	positional := parseInterspersed(newCmd, args)
+	if len(positional) != 1 {
 		newCmd.Usage()
 		log.Fatal("Error: 'new' requires exactly one argument: <plan-name>")
 	}
-	planName := newCmd.Arg(0)
-	plan, err := plans.Create(planName)
+	planName := positional[0]
+	var plan *planner.Plan
+	var err error
+	if templateName != "" {
+		plan, err = plans.CreateFromTemplate(planName, templateName)
+	} else {
+		plan, err = plans.Create(planName)
+	}
 	if err != nil {
 		log.Fatalf("Error creating new plan '%s': %v", planName, err)
 	}
@@ -37,6 +49,36 @@ func handlePlanNewCommand(plans *planner.Planner, args []string) {
 	fmt.Printf("Plan '%s' created successfully.\n", planName)
 }
 
+func handlePlanTemplatesCommand(plans *planner.Planner, args []string) {
+	templatesCmd := flag.NewFlagSet("templates", flag.ExitOnError)
+	templatesCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: smolcode plan templates\n")
+		fmt.Fprintf(os.Stderr, "Lists the plan templates in %s.\n", planner.TemplateDir)
+	}
+	templatesCmd.Parse(args)
+	if templatesCmd.NArg() != 0 {
+		templatesCmd.Usage()
+		log.Fatal("Error: 'templates' does not take any arguments")
+	}
+
+	templates, err := plans.Templates()
+	if err != nil {
+		log.Fatalf("Error listing plan templates: %v", err)
+	}
+	if len(templates) == 0 {
+		fmt.Printf("No plan templates found in %s.\n", planner.TemplateDir)
+		return
+	}
+	fmt.Println("Available plan templates:")
+	for _, template := range templates {
+		fmt.Printf("- %s (%d steps)", template.Name, len(template.Steps))
+		if template.Description != "" {
+			fmt.Printf(": %s", template.Description)
+		}
+		fmt.Println()
+	}
+}
+
 func handlePlanInspectCommand(plans *planner.Planner, args []string) {
 	inspectCmd := flag.NewFlagSet("inspect", flag.ExitOnError)
 	inspectCmd.Usage = func() {
@@ -58,9 +100,12 @@ func handlePlanInspectCommand(plans *planner.Planner, args []string) {
 
 func handlePlanNextStepCommand(plans *planner.Planner, args []string) {
 	nextStepCmd := flag.NewFlagSet("next-step", flag.ExitOnError)
+	var byPriority bool
+	nextStepCmd.BoolVar(&byPriority, "by-priority", false, "Display the ready step with the highest priority instead of the first one in plan order")
 	nextStepCmd.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: smolcode plan next-step <plan-name>\n")
-		fmt.Fprintf(os.Stderr, "Displays the next incomplete step of the plan.\n")
+		fmt.Fprintf(os.Stderr, "Usage: smolcode plan next-step [--by-priority] <plan-name>\n")
+		fmt.Fprintf(os.Stderr, "Displays the next incomplete step of the plan whose dependencies are complete.\n")
+		nextStepCmd.PrintDefaults()
 	}
 	nextStepCmd.Parse(args)
 	if nextStepCmd.NArg() != 1 {
@@ -72,20 +117,111 @@ func handlePlanNextStepCommand(plans *planner.Planner, args []string) {
 	if err != nil {
 		die("Error loading plan '%s': %v\n", planName, err) // die needs to be accessible
 	}
-	next := plan.NextStep()
-	if next == nil {
+	next, err := plan.NextReadyStep()
+	if byPriority && err == nil {
+		next = plan.NextStepByPriority()
+	}
+	if errors.Is(err, planner.ErrPlanCompleted) {
 		fmt.Println("Plan is already complete!")
+	} else if err != nil {
+		die("Error: no step of plan '%s' can be worked on: %v\n", planName, err)
 	} else {
-		fmt.Printf("Next Step (%s):\n", next.ID())
-		fmt.Printf("  Status: %s\n", next.Status())
-		fmt.Printf("  Description: %s\n", next.Description())
-		if len(next.AcceptanceCriteria()) > 0 {
-			fmt.Println("  Acceptance Criteria:")
-			for _, crit := range next.AcceptanceCriteria() {
-				fmt.Printf("    - %s\n", crit)
-			}
+		printNextStep(next)
+	}
+}
+
+// printNextStep prints the details of the next step of a plan.
+func printNextStep(next *planner.Step) {
+	fmt.Printf("Next Step (%s):\n", next.ID())
+	fmt.Printf("  Status: %s\n", next.Status())
+	if next.Priority() != 0 {
+		fmt.Printf("  Priority: %d\n", next.Priority())
+	}
+	fmt.Printf("  Description: %s\n", next.Description())
+	if len(next.AcceptanceCriteria()) > 0 {
+		fmt.Println("  Acceptance Criteria:")
+		for _, crit := range next.AcceptanceCriteria() {
+			fmt.Printf("    - %s\n", crit)
 		}
 	}
+	if len(next.DependsOn()) > 0 {
+		fmt.Printf("  Depends on: %s\n", strings.Join(next.DependsOn(), ", "))
+	}
+}
+
+func handlePlanAdvanceCommand(plans *planner.Planner, args []string) {
+	advanceCmd := flag.NewFlagSet("advance", flag.ExitOnError)
+	advanceCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: smolcode plan advance <plan-name> [step-id]\n")
+		fmt.Fprintf(os.Stderr, "Marks the given step (or the current next step) as DONE and displays the new next step.\n")
+	}
+	advanceCmd.Parse(args)
+	if advanceCmd.NArg() < 1 || advanceCmd.NArg() > 2 {
+		advanceCmd.Usage()
+		log.Fatal("Error: 'advance' requires <plan-name> and an optional <step-id>")
+	}
+	planName := advanceCmd.Arg(0)
+	stepID := advanceCmd.Arg(1)
+
+	plan, err := plans.Get(planName)
+	if err != nil {
+		die("Error loading plan '%s': %v\n", planName, err)
+	}
+	if stepID == "" && plan.NextStep() != nil {
+		stepID = plan.NextStep().ID()
+	}
+
+	next, err := plan.Advance(stepID)
+	if errors.Is(err, planner.ErrPlanCompleted) {
+		fmt.Printf("Plan '%s' is already complete, there is no step to advance.\n", planName)
+		return
+	}
+	if err != nil {
+		log.Fatalf("Error advancing step '%s' in plan '%s': %v", stepID, planName, err)
+	}
+
+	if err := plans.Save(plan); err != nil {
+		log.Fatalf("Error saving updated plan '%s': %v", planName, err)
+	}
+	fmt.Printf("Step '%s' in plan '%s' marked as DONE.\n", stepID, planName)
+	if next == nil {
+		fmt.Println("Plan is now complete!")
+	} else {
+		printNextStep(next)
+	}
+}
+
+func handlePlanGraphCommand(plans *planner.Planner, args []string) {
+	graphCmd := flag.NewFlagSet("graph", flag.ExitOnError)
+	var format string
+	graphCmd.StringVar(&format, "format", "dot", "Output format: dot or mermaid")
+	graphCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: smolcode plan graph [--format dot|mermaid] <plan-name>\n")
+		fmt.Fprintf(os.Stderr, "Renders the steps of a plan as a graph, colored by status.\n")
+		graphCmd.PrintDefaults()
+	}
+	graphCmd.Parse(args)
+	if graphCmd.NArg() != 1 {
+		graphCmd.Usage()
+		log.Fatal("Error: 'graph' requires exactly one argument: <plan-name>")
+	}
+	planName := graphCmd.Arg(0)
+
+	var graph string
+	var err error
+	switch format {
+	case "dot":
+		graph, err = plans.RenderGraph(planName)
+	case "mermaid":
+		graph, err = plans.RenderMermaid(planName)
+	default:
+		graphCmd.Usage()
+		log.Fatalf("Error: Invalid format '%s'. Must be dot or mermaid.", format)
+	}
+	if err != nil {
+		die("Error rendering plan '%s': %v\n", planName, err)
+	}
+	fmt.Print(graph)
 }
 
 func handlePlanSetCommand(plans *planner.Planner, args []string) {
@@ -131,9 +267,14 @@ func handlePlanSetCommand(plans *planner.Planner, args []string) {
 func handlePlanAddStepCommand(plans *planner.Planner, args []string) {
 	addStepCmd := flag.NewFlagSet("add-step", flag.ExitOnError)
 	addStepCmd.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: smolcode plan add-step <plan-name> <step-id> <description> [acceptance-criteria...]\n")
+		fmt.Fprintf(os.Stderr, "Usage: smolcode plan add-step [--depends-on <step-id>]... [--priority <n>] <plan-name> <step-id> <description> [acceptance-criteria...]\n")
 		fmt.Fprintf(os.Stderr, "Adds a new step to the end of the plan.\n")
+		addStepCmd.PrintDefaults()
 	}
+	var dependsOn stringSliceFlag
+	addStepCmd.Var(&dependsOn, "depends-on", "ID of a step that must be DONE before this one (repeatable)")
+	var priority int
+	addStepCmd.IntVar(&priority, "priority", 0, "Priority of the step; 'next-step --by-priority' picks higher priorities first")
 	addStepCmd.Parse(args)
 	if addStepCmd.NArg() < 3 {
 		addStepCmd.Usage()
@@ -149,7 +290,8 @@ func handlePlanAddStepCommand(plans *planner.Planner, args []string) {
 		die("Error loading plan '%s': %v\n", planName, err) // die needs to be accessible
 	}
 
-	plan.AddStep(stepID, description, acceptanceCriteria)
+	plan.AddStep(stepID, description, acceptanceCriteria, dependsOn...)
+	plan.SetPriority(stepID, priority)
 
 	if err := plans.Save(plan); err != nil {
 		log.Fatalf("Error saving updated plan '%s': %v", planName, err)
@@ -157,11 +299,118 @@ func handlePlanAddStepCommand(plans *planner.Planner, args []string) {
 	fmt.Printf("Step '%s' added to plan '%s'.\n", stepID, planName)
 }
 
+func handlePlanNoteCommand(plans *planner.Planner, args []string) {
+	noteCmd := flag.NewFlagSet("note", flag.ExitOnError)
+	noteCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: smolcode plan note <plan-name> <step-id> <text...>\n")
+		fmt.Fprintf(os.Stderr, "Adds a progress note to a step without changing its description.\n")
+	}
+	noteCmd.Parse(args)
+	if noteCmd.NArg() < 3 {
+		noteCmd.Usage()
+		log.Fatal("Error: 'note' requires at least three arguments: <plan-name> <step-id> <text>")
+	}
+	planName := noteCmd.Arg(0)
+	stepID := noteCmd.Arg(1)
+	note := strings.Join(noteCmd.Args()[2:], " ")
+
+	if err := plans.AddNote(planName, stepID, note); err != nil {
+		log.Fatalf("Error adding note: %v", err)
+	}
+	fmt.Printf("Note added to step '%s' in plan '%s'.\n", stepID, planName)
+}
+
+func handlePlanUndoCommand(plans *planner.Planner, args []string) {
+	undoCmd := flag.NewFlagSet("undo", flag.ExitOnError)
+	undoCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: smolcode plan undo <plan-name>\n")
+		fmt.Fprintf(os.Stderr, "Reverts the last status change, removal or reordering of steps in the plan.\n")
+	}
+	undoCmd.Parse(args)
+	if undoCmd.NArg() != 1 {
+		undoCmd.Usage()
+		log.Fatal("Error: 'undo' requires exactly one argument: <plan-name>")
+	}
+	planName := undoCmd.Arg(0)
+
+	if err := plans.Undo(planName); errors.Is(err, planner.ErrNothingToUndo) {
+		fmt.Printf("Nothing to undo in plan '%s'.\n", planName)
+		return
+	} else if err != nil {
+		log.Fatalf("Error undoing the last change of plan '%s': %v", planName, err)
+	}
+	fmt.Printf("Reverted the last change of plan '%s'.\n", planName)
+}
+
+func handlePlanExportCommand(plans *planner.Planner, args []string) {
+	exportCmd := flag.NewFlagSet("export", flag.ExitOnError)
+	exportCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: smolcode plan export <plan-name>\n")
+		fmt.Fprintf(os.Stderr, "Prints the plan as JSON, to be imported with 'smolcode plan import'.\n")
+	}
+	exportCmd.Parse(args)
+	if exportCmd.NArg() != 1 {
+		exportCmd.Usage()
+		log.Fatal("Error: 'export' requires exactly one argument: <plan-name>")
+	}
+	planName := exportCmd.Arg(0)
+
+	data, err := plans.ExportJSON(planName)
+	if err != nil {
+		die("Error exporting plan '%s': %v\n", planName, err)
+	}
+	fmt.Println(string(data))
+}
+
+func handlePlanImportCommand(plans *planner.Planner, args []string) {
+	importCmd := flag.NewFlagSet("import", flag.ExitOnError)
+	var overwrite bool
+	importCmd.BoolVar(&overwrite, "overwrite", false, "Replace an existing plan with the same name")
+	importCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: smolcode plan import [--overwrite] <file>\n")
+		fmt.Fprintf(os.Stderr, "Stores a plan exported with 'smolcode plan export'. Use '-' to read from stdin.\n")
+		importCmd.PrintDefaults()
+	}
+	importCmd.Parse(args)
+	if importCmd.NArg() != 1 {
+		importCmd.Usage()
+		log.Fatal("Error: 'import' requires exactly one argument: <file>")
+	}
+
+	var data []byte
+	var err error
+	if path := importCmd.Arg(0); path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		log.Fatalf("Error reading plan: %v", err)
+	}
+
+	var plan *planner.Plan
+	if overwrite {
+		plan, err = plans.ImportJSONOverwrite(data)
+	} else {
+		plan, err = plans.ImportJSON(data)
+	}
+	if errors.Is(err, planner.ErrPlanExists) {
+		die("Error: %v; use --overwrite to replace it\n", err)
+	}
+	if err != nil {
+		log.Fatalf("Error importing plan: %v", err)
+	}
+	fmt.Printf("Plan '%s' imported with %d steps.\n", plan.ID, len(plan.Steps))
+}
+
 func handlePlanListCommand(plans *planner.Planner, args []string) {
 	listCmd := flag.NewFlagSet("list", flag.ExitOnError)
+	var sortBy string
+	listCmd.StringVar(&sortBy, "sort", "name", "Sort plans by 'name' or by 'completion' ratio, most complete first")
 	listCmd.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: smolcode plan list\n")
+		fmt.Fprintf(os.Stderr, "Usage: smolcode plan list [--sort name|completion]\n")
 		fmt.Fprintf(os.Stderr, "Lists all available plans.\n")
+		listCmd.PrintDefaults()
 	}
 	listCmd.Parse(args)
 	if listCmd.NArg() != 0 {
@@ -173,12 +422,21 @@ func handlePlanListCommand(plans *planner.Planner, args []string) {
 	if err != nil {
 		log.Fatalf("Error listing plans: %v", err)
 	}
+	switch sortBy {
+	case "name":
+		sort.SliceStable(planNames, func(i, j int) bool { return planNames[i].Name < planNames[j].Name })
+	case "completion":
+		planner.SortByCompletion(planNames)
+	default:
+		listCmd.Usage()
+		log.Fatalf("Error: unknown sort order '%s', expected 'name' or 'completion'", sortBy)
+	}
 	if len(planNames) == 0 {
 		fmt.Println("No plans found.")
 	} else {
 		fmt.Println("Available plans:")
 		for _, name := range planNames {
-			fmt.Printf("- %s (%s, %d/%d tasks)\n", name.Name, name.Status, name.CompletedTasks, name.TotalTasks)
+			fmt.Printf("- %s %s %s (%s)\n", name.Name, planner.ProgressBar(name.CompletedTasks, name.TotalTasks, 20), planner.FormatProgress(name.CompletedTasks, name.TotalTasks), name.Status)
 		}
 	}
 }
@@ -273,18 +531,39 @@ func handlePlanCommand(args []string) {
 	case "new":
 		handlePlanNewCommand(plans, remainingArgs)
 
+	case "templates":
+		handlePlanTemplatesCommand(plans, remainingArgs)
+
 	case "inspect":
 		handlePlanInspectCommand(plans, remainingArgs)
 
 	case "next-step":
 		handlePlanNextStepCommand(plans, remainingArgs)
 
+	case "advance":
+		handlePlanAdvanceCommand(plans, remainingArgs)
+
 	case "set":
 		handlePlanSetCommand(plans, remainingArgs)
 
+	case "graph":
+		handlePlanGraphCommand(plans, remainingArgs)
+
 	case "add-step":
 		handlePlanAddStepCommand(plans, remainingArgs)
 
+	case "note":
+		handlePlanNoteCommand(plans, remainingArgs)
+
+	case "undo":
+		handlePlanUndoCommand(plans, remainingArgs)
+
+	case "export":
+		handlePlanExportCommand(plans, remainingArgs)
+
+	case "import":
+		handlePlanImportCommand(plans, remainingArgs)
+
 	case "list":
 		handlePlanListCommand(plans, remainingArgs)
 
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dhamidi/smolcode"
+	"github.com/dhamidi/smolcode/history"
+)
+
+// handleResumeCommand searches conversations for a query and continues the matching one.
+func handleResumeCommand(args []string) {
+	resumeCmd := flag.NewFlagSet("resume", flag.ExitOnError)
+	var titlesOnly bool
+	var modelName string
+	resumeCmd.BoolVar(&titlesOnly, "titles-only", false, "Only match conversation titles, not message contents")
+	resumeCmd.StringVar(&modelName, "model", "", "The name of the model to use")
+	resumeCmd.StringVar(&modelName, "m", "", "The name of the model to use (shorthand)")
+	resumeCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: smolcode resume [--titles-only] [-m <model>] <query>\n")
+		fmt.Fprintf(os.Stderr, "Searches conversations for <query> and continues the matching conversation.\n")
+		fmt.Fprintf(os.Stderr, "If several conversations match, you are asked to pick one.\n")
+		resumeCmd.PrintDefaults()
+	}
+	resumeCmd.Parse(args)
+
+	if resumeCmd.NArg() == 0 {
+		resumeCmd.Usage()
+		log.Fatal("Error: 'resume' requires a search query")
+	}
+	query := strings.Join(resumeCmd.Args(), " ")
+
+	matches, err := history.SearchConversations(query, titlesOnly)
+	if err != nil {
+		log.Fatalf("Error searching conversations: %v", err)
+	}
+
+	var conversationID string
+	switch len(matches) {
+	case 0:
+		fmt.Printf("No conversations match %q.\n", query)
+		return
+	case 1:
+		conversationID = matches[0].ID
+	default:
+		conversationID, err = pickConversation(matches, os.Stdin, os.Stdout)
+		if err != nil {
+			log.Fatalf("Error selecting conversation: %v", err)
+		}
+	}
+
+	if err := smolcode.Code(conversationID, modelName, false, nil, nil); err != nil {
+		die("Error running smol-agent: %v", err)
+	}
+}
+
+// pickConversation lists conversations on out and reads the number of the chosen one from in.
+func pickConversation(conversations []history.ConversationMetadata, in io.Reader, out io.Writer) (string, error) {
+	fmt.Fprintf(out, "%d conversations match:\n", len(conversations))
+	for i, conv := range conversations {
+		fmt.Fprintf(out, "  [%d] %s (Last Message: %s, Messages: %d)\n",
+			i+1, conv.ID, conv.LatestMessageTime.Format(time.RFC3339), conv.MessageCount)
+	}
+
+	scanner := bufio.NewScanner(in)
+	for {
+		fmt.Fprintf(out, "Select a conversation [1-%d]: ", len(conversations))
+		if !scanner.Scan() {
+			if err := scanner.Err(); err != nil {
+				return "", err
+			}
+			return "", fmt.Errorf("no conversation selected")
+		}
+		choice, err := strconv.Atoi(strings.TrimSpace(scanner.Text()))
+		if err != nil || choice < 1 || choice > len(conversations) {
+			fmt.Fprintf(out, "Please enter a number between 1 and %d.\n", len(conversations))
+			continue
+		}
+		return conversations[choice-1].ID, nil
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/dhamidi/smolcode"
+)
+
+func handleToolsListCommand(args []string) {
+	listCmd := flag.NewFlagSet("list", flag.ExitOnError)
+	var asJSON bool
+	listCmd.BoolVar(&asJSON, "json", false, "Print the catalog as JSON, including each tool's input schema")
+	listCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: smolcode tools list [--json]\n")
+		fmt.Fprintf(os.Stderr, "Lists the tools built into smolcode.\n")
+		listCmd.PrintDefaults()
+	}
+	listCmd.Parse(args)
+	if listCmd.NArg() != 0 {
+		listCmd.Usage()
+		log.Fatal("Error: 'list' does not take positional arguments")
+	}
+
+	tools, err := smolcode.BuiltinTools()
+	if err != nil {
+		log.Fatalf("Error registering tools: %v", err)
+	}
+	catalog, err := tools.Catalog()
+	if err != nil {
+		log.Fatalf("Error building tool catalog: %v", err)
+	}
+
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(catalog); err != nil {
+			log.Fatalf("Error encoding tool catalog: %v", err)
+		}
+		return
+	}
+
+	fmt.Println("Tools:")
+	for _, tool := range catalog {
+		description, _, _ := strings.Cut(tool.Description, "\n")
+		fmt.Printf("  %s: %s\n", tool.Name, description)
+	}
+}
+
+func handleToolsCommand(args []string) {
+	if len(args) < 1 {
+		fmt.Println("Usage: smolcode tools <subcommand> [arguments]")
+		log.Fatal("Error: No tools subcommand provided.")
+	}
+
+	subcommand := args[0]
+	remainingArgs := args[1:]
+
+	switch subcommand {
+	case "list":
+		handleToolsListCommand(remainingArgs)
+
+	default:
+		fmt.Fprintf(os.Stderr, "Usage: smolcode tools <subcommand> [arguments]\n")
+		log.Fatalf("Error: Unknown tools subcommand '%s'", subcommand)
+	}
+}
@@ -22,6 +22,22 @@ func main() {
 		handleHistoryCommand(args)
 	case "generate":
 		handleGenerateCommand(args)
+	case "resume":
+		handleResumeCommand(args)
+	case "commit-message":
+		handleCommitMessageCommand(args)
+	case "tools":
+		handleToolsCommand(args)
+	case "config":
+		handleConfigCommand(args)
+	case "checkpoint":
+		handleCheckpointCommand(args)
+	case "bench":
+		handleBenchCommand(args)
+	case "backup":
+		handleBackupCommand(args)
+	case "restore":
+		handleRestoreCommand(args)
 	default:
 		// If the first arg is not a known command, it might be a flag for the default command,
 		// or an unknown command. handleDefaultCommand expects all args including potential flags.
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
 	"strings"
@@ -15,6 +16,21 @@ func die(format string, a ...interface{}) {
 	os.Exit(1)
 }
 
+// parseInterspersed parses flags that may appear before, between or after positional arguments,
+// so that both "search --prefix query" and "search query --prefix" work.
+// It returns the positional arguments.
+func parseInterspersed(fs *flag.FlagSet, args []string) []string {
+	var positional []string
+	for {
+		fs.Parse(args)
+		if fs.NArg() == 0 {
+			return positional
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
 // executeSmolcodeCommand - if this is to be a shared utility.
 // The version in cmd_memory.go is executeSmolcodeCommandInternal(execPath string, args ...string)
 // The original in main.go was func executeSmolcodeCommand(args ...string) (string, error)
@@ -1,12 +1,10 @@
 package codegen
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
 	"strings"
+	"sync/atomic"
 )
 
 var (
@@ -24,8 +22,23 @@ type APIRequestMessage struct {
 // APIRequest represents the request body for the chat completions API.
 
 type APIRequest struct {
-	Model    string              `json:"model"`
-	Messages []APIRequestMessage `json:"messages"`
+	Model       string              `json:"model"`
+	Messages    []APIRequestMessage `json:"messages"`
+	Temperature *float64            `json:"temperature,omitempty"`
+	Seed        *int64              `json:"seed,omitempty"`
+	Stream      bool                `json:"stream,omitempty"`
+}
+
+// DeterministicSeed is the seed sent with every request while deterministic generation is enabled.
+const DeterministicSeed = 42
+
+var deterministic atomic.Bool
+
+// SetDeterministic controls whether requests ask for reproducible output by setting
+// the temperature to zero and the seed to DeterministicSeed.
+// Determinism is best-effort: it depends on the model, and the same request may still produce different output.
+func SetDeterministic(enabled bool) {
+	deterministic.Store(enabled)
 }
 
 // APIResponseChoice represents a choice in the API response.
@@ -53,7 +66,12 @@ type APIErrorDetail struct {
 
 // makeChatCompletionsRequest sends a request to the Inceptionlabs API for a single file generation.
 // It constructs the prompt as per docs.md and returns the deserialized APIResponse.
-func makeChatCompletionsRequest(apiKey, instruction string, existingFiles []File, allDesiredFiles []DesiredFile, currentFileToGenerate DesiredFile) (*APIResponse, error) {
+func makeChatCompletionsRequest(g *Generator, instruction string, existingFiles []File, allDesiredFiles []DesiredFile, currentFileToGenerate DesiredFile) (*APIResponse, error) {
+	return g.sendChatCompletionsRequest(g.fileRequest(instruction, existingFiles, allDesiredFiles, currentFileToGenerate))
+}
+
+// fileRequest returns the request asking for the contents of currentFileToGenerate, with the prompt described in docs.md.
+func (g *Generator) fileRequest(instruction string, existingFiles []File, allDesiredFiles []DesiredFile, currentFileToGenerate DesiredFile) APIRequest {
 	var userMessageBuilder strings.Builder
 
 	// Overall instruction
@@ -82,50 +100,73 @@ func makeChatCompletionsRequest(apiKey, instruction string, existingFiles []File
 
 	userContent := userMessageBuilder.String()
 
-	reqBody := APIRequest{
-		Model: "mercury-coder-small", // As per instruction.md
-		Messages: []APIRequestMessage{
-			{Role: "system", Content: "You are a helpful assistant that generates code. You will be given an overall instruction, a set of existing reference files, a list of all files to be generated with their descriptions, and the specific file you need to generate now. Your response MUST ONLY be the complete text content for the requested file. Do NOT include any other explanatory text, markdown formatting, or any preamble. Only the raw file content."},
-			{Role: "user", Content: userContent},
-		},
+	return g.newRequest(
+		APIRequestMessage{Role: "system", Content: "You are a helpful assistant that generates code. You will be given an overall instruction, a set of existing reference files, a list of all files to be generated with their descriptions, and the specific file you need to generate now. Your response MUST ONLY be the complete text content for the requested file. Do NOT include any other explanatory text, markdown formatting, or any preamble. Only the raw file content."},
+		APIRequestMessage{Role: "user", Content: userContent},
+	)
+}
+
+// newRequest returns a request for messages using the generator's model and temperature.
+func (g *Generator) newRequest(messages ...APIRequestMessage) APIRequest {
+	return APIRequest{
+		Model:       g.model(),
+		Messages:    messages,
+		Temperature: g.Temperature,
 	}
+}
 
-	jsonData, err := json.Marshal(reqBody)
+// sendChatCompletionsRequest posts reqBody to the chat completions endpoint and returns the deserialized APIResponse.
+// Transient failures are retried as configured by WithRetries.
+func (g *Generator) sendChatCompletionsRequest(reqBody APIRequest) (*APIResponse, error) {
+	endpoint, err := g.endpoint()
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal API request: %w", err)
+		return nil, err
+	}
+	jsonData, err := encodeRequest(reqBody)
+	if err != nil {
+		return nil, err
 	}
 
-	req, err := http.NewRequest("POST", chatCompletionsEndpoint, bytes.NewBuffer(jsonData))
+	statusCode, bodyBytes, err := g.post(endpoint, jsonData)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+		return nil, err
 	}
 
-	req.Header.Set("Authorization", "Bearer "+apiKey)
-	req.Header.Set("Content-Type", "application/json")
+	if statusCode >= 400 {
+		return nil, statusError(statusCode, bodyBytes)
+	}
+	return decodeResponse(bodyBytes)
+}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send HTTP request: %w", err)
+// encodeRequest returns the JSON encoding of reqBody, asking for reproducible output if deterministic generation is enabled.
+func encodeRequest(reqBody APIRequest) ([]byte, error) {
+	if deterministic.Load() {
+		temperature, seed := 0.0, int64(DeterministicSeed)
+		reqBody.Temperature = &temperature
+		reqBody.Seed = &seed
 	}
-	defer resp.Body.Close()
 
-	bodyBytes, err := io.ReadAll(resp.Body)
+	jsonData, err := json.Marshal(reqBody)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return nil, fmt.Errorf("failed to marshal API request: %w", err)
 	}
+	return jsonData, nil
+}
 
-	if resp.StatusCode >= 400 {
-		// Attempt to parse an error response
-		var errResp APIResponse
-		parseErr := json.Unmarshal(bodyBytes, &errResp)
-		if parseErr == nil && errResp.Error != nil {
-			return nil, fmt.Errorf("API error: %s (Type: %s, Code: %v, HTTP Status: %d)", errResp.Error.Message, errResp.Error.Type, errResp.Error.Code, resp.StatusCode)
-		}
-		// Fallback error message if JSON parsing fails or error structure is different
-		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(bodyBytes))
+// statusError returns the error for a response with a status code of 400 or above and the given body.
+func statusError(statusCode int, bodyBytes []byte) error {
+	// Attempt to parse an error response
+	var errResp APIResponse
+	parseErr := json.Unmarshal(bodyBytes, &errResp)
+	if parseErr == nil && errResp.Error != nil {
+		return fmt.Errorf("API error: %s (Type: %s, Code: %v, HTTP Status: %d)", errResp.Error.Message, errResp.Error.Type, errResp.Error.Code, statusCode)
 	}
+	// Fallback error message if JSON parsing fails or error structure is different
+	return fmt.Errorf("API request failed with status %d: %s", statusCode, string(bodyBytes))
+}
 
+// decodeResponse deserializes the body of a successful response.
+func decodeResponse(bodyBytes []byte) (*APIResponse, error) {
 	var apiResp APIResponse
 	if err := json.Unmarshal(bodyBytes, &apiResp); err != nil {
 		// If unmarshalling fails, but there was an API error message in a parsable format in the body, prioritize that.
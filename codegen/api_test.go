@@ -93,7 +93,7 @@ func TestMakeChatCompletionsRequest_Success(t *testing.T) {
 	}
 	currentFileToGenerate := DesiredFile{Path: "new_func.go", Description: "A new Go function"}
 
-	resp, err := makeChatCompletionsRequest("test-key", "Create a new Go function.", existingFiles, allDesiredFiles, currentFileToGenerate)
+	resp, err := makeChatCompletionsRequest(New("test-key"), "Create a new Go function.", existingFiles, allDesiredFiles, currentFileToGenerate)
 	if err != nil {
 		t.Fatalf("makeChatCompletionsRequest failed: %v", err)
 	}
@@ -164,7 +164,7 @@ func TestMakeChatCompletionsRequest_ErrorStatus(t *testing.T) {
 				chatCompletionsEndpoint = originalChatEndpoint
 			}()
 
-			_, err := makeChatCompletionsRequest("test-key", "test instruction", nil, nil, DesiredFile{})
+			_, err := makeChatCompletionsRequest(New("test-key").WithRetries(0, 0), "test instruction", nil, nil, DesiredFile{})
 			if err == nil {
 				t.Fatalf("makeChatCompletionsRequest was expected to fail, but it did not")
 			}
@@ -188,7 +188,7 @@ func TestMakeChatCompletionsRequest_MalformedResponse(t *testing.T) {
 		chatCompletionsEndpoint = originalChatEndpoint
 	}()
 
-	_, err := makeChatCompletionsRequest("test-key", "test instruction", nil, nil, DesiredFile{})
+	_, err := makeChatCompletionsRequest(New("test-key"), "test instruction", nil, nil, DesiredFile{})
 	if err == nil {
 		t.Fatal("Expected an error due to malformed JSON response, got nil")
 	}
@@ -211,7 +211,7 @@ func TestMakeChatCompletionsRequest_NoChoicesInResponse(t *testing.T) {
 		chatCompletionsEndpoint = originalChatEndpoint
 	}()
 
-	apiResp, err := makeChatCompletionsRequest("test-key", "test instruction", nil, nil, DesiredFile{})
+	apiResp, err := makeChatCompletionsRequest(New("test-key"), "test instruction", nil, nil, DesiredFile{})
 	if err != nil { // Should not error at this stage
 		t.Fatalf("makeChatCompletionsRequest failed unexpectedly: %v", err)
 	}
@@ -224,3 +224,70 @@ func TestMakeChatCompletionsRequest_NoChoicesInResponse(t *testing.T) {
 		t.Errorf("Expected 0 choices in this test scenario, got %d", len(apiResp.Choices))
 	}
 }
+
+func TestMakeChatCompletionsRequest_Deterministic(t *testing.T) {
+	var received APIRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&received)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(APIResponse{})
+	}))
+	defer server.Close()
+
+	originalChatEndpoint := chatCompletionsEndpoint
+	chatCompletionsEndpoint = server.URL + "/v1/chat/completions"
+	defer func() {
+		chatCompletionsEndpoint = originalChatEndpoint
+	}()
+	SetDeterministic(true)
+	defer SetDeterministic(false)
+
+	if _, err := makeChatCompletionsRequest(New("test-key"), "test instruction", nil, nil, DesiredFile{}); err != nil {
+		t.Fatalf("makeChatCompletionsRequest failed: %v", err)
+	}
+
+	if received.Temperature == nil || *received.Temperature != 0 {
+		t.Errorf("Expected temperature 0, got %v", received.Temperature)
+	}
+	if received.Seed == nil || *received.Seed != DeterministicSeed {
+		t.Errorf("Expected seed %d, got %v", DeterministicSeed, received.Seed)
+	}
+}
+
+func TestMakeChatCompletionsRequest_ModelAndTemperature(t *testing.T) {
+	var received map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = nil
+		json.NewDecoder(r.Body).Decode(&received)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(APIResponse{})
+	}))
+	defer server.Close()
+
+	originalChatEndpoint := chatCompletionsEndpoint
+	chatCompletionsEndpoint = server.URL + "/v1/chat/completions"
+	defer func() {
+		chatCompletionsEndpoint = originalChatEndpoint
+	}()
+
+	if _, err := makeChatCompletionsRequest(New("test-key"), "test instruction", nil, nil, DesiredFile{}); err != nil {
+		t.Fatalf("makeChatCompletionsRequest failed: %v", err)
+	}
+	if received["model"] != DefaultModel {
+		t.Errorf("Expected default model %q, got %v", DefaultModel, received["model"])
+	}
+	if _, ok := received["temperature"]; ok {
+		t.Errorf("Expected no temperature by default, got %v", received["temperature"])
+	}
+
+	generator := New("test-key").WithModel("other-model").WithTemperature(0.7)
+	if _, err := makeChatCompletionsRequest(generator, "test instruction", nil, nil, DesiredFile{}); err != nil {
+		t.Fatalf("makeChatCompletionsRequest failed: %v", err)
+	}
+	if received["model"] != "other-model" {
+		t.Errorf("Expected model 'other-model', got %v", received["model"])
+	}
+	if received["temperature"] != 0.7 {
+		t.Errorf("Expected temperature 0.7, got %v", received["temperature"])
+	}
+}
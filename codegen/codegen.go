@@ -1,11 +1,13 @@
 package codegen
 
 import (
+	"errors"
 	"fmt"
 	"io/fs"
 	"os"
 	"path/filepath" // Added for filepath.Dir
 	"sync"
+	"time"
 )
 
 // WriteableFileSystem defines the necessary methods for a file system that can be written to.
@@ -28,20 +30,78 @@ type DesiredFile struct {
 	Description string `json:"description"` // Human language description of desired contents
 }
 
+// DefaultModel is the model requested by a Generator without a Model.
+const DefaultModel = "mercury-coder-small"
+
 // Generator is responsible for generating code.
 type Generator struct {
 	apiKey string
+	// Model is the model to request completions from; DefaultModel is used if it is empty.
+	Model string
+	// Temperature is sent with every request if set, otherwise the API's default applies.
+	// Deterministic generation (see SetDeterministic) overrides it with zero.
+	Temperature *float64
+	// MaxRetries is how often a request failing with a transient error is retried, see WithRetries.
+	MaxRetries int
+	// RetryBaseDelay is the delay before the first retry, doubling with every retry.
+	RetryBaseDelay time.Duration
+	// MaxConcurrency is the maximum number of files GenerateCode requests at the same time.
+	// Zero or less does not limit the number of requests.
+	MaxConcurrency int
+	// BaseURL is the base URL of the OpenAI-compatible API to use, see WithBaseURL.
+	// If it is empty, the base URL in BaseURLEnv or the Inceptionlabs API is used.
+	BaseURL string
 }
 
-// New creates a new Generator.
+// DefaultMaxConcurrency is the number of files a Generator requests at the same time by default.
+const DefaultMaxConcurrency = 4
+
+// New creates a new Generator that retries failed requests DefaultMaxRetries times
+// and requests up to DefaultMaxConcurrency files at the same time.
 func New(apiKey string) *Generator {
-	return &Generator{apiKey: apiKey}
+	return &Generator{
+		apiKey:         apiKey,
+		MaxRetries:     DefaultMaxRetries,
+		RetryBaseDelay: DefaultRetryBaseDelay,
+		MaxConcurrency: DefaultMaxConcurrency,
+	}
+}
+
+// WithMaxConcurrency sets the maximum number of files GenerateCode requests at the same time.
+// Zero or less does not limit the number of requests.
+func (g *Generator) WithMaxConcurrency(maxConcurrency int) *Generator {
+	g.MaxConcurrency = maxConcurrency
+	return g
+}
+
+// WithModel sets the model to request completions from.
+// An empty model selects DefaultModel.
+func (g *Generator) WithModel(model string) *Generator {
+	g.Model = model
+	return g
+}
+
+// WithTemperature sets the sampling temperature sent with every request.
+func (g *Generator) WithTemperature(temperature float64) *Generator {
+	g.Temperature = &temperature
+	return g
+}
+
+// model returns the model to request completions from.
+func (g *Generator) model() string {
+	if g.Model == "" {
+		return DefaultModel
+	}
+	return g.Model
 }
 
 // Write writes the generated files to disk.
-// It overwrites existing files.
-func (g *Generator) Write(files []File) error {
+// It overwrites existing files and skips nil entries, such as files GenerateCode failed to generate.
+func (g *Generator) Write(files []*File) error {
 	for _, file := range files {
+		if file == nil {
+			continue
+		}
 		err := os.WriteFile(file.Path, file.Contents, 0644)
 		if err != nil {
 			return fmt.Errorf("error writing file %s: %w", file.Path, err)
@@ -55,7 +115,7 @@ func (g *Generator) Write(files []File) error {
 func (g *Generator) WriteTo(files []*File, destFS WriteableFileSystem) error {
 	for _, file := range files {
 		if file == nil {
-			continue // Should not happen with well-formed input, but good for robustness
+			continue // Not generated, see GenerateCode
 		}
 		// Get the directory part of the path
 		dir := filepath.Dir(file.Path)
@@ -77,58 +137,54 @@ func (g *Generator) WriteTo(files []*File, destFS WriteableFileSystem) error {
 }
 
 // GenerateCode concurrently generates multiple files based on an instruction, existing files, and desired output files.
-func (g *Generator) GenerateCode(instruction string, existingFiles []File, desiredOutputFiles []DesiredFile) ([]File, error) {
+// At most MaxConcurrency files are requested at the same time.
+//
+// The generated files are returned in the order of desiredOutputFiles.
+// If some files fail to generate, their entries are nil and the error joins the errors of all failed files,
+// so callers must check for nil entries when using a partial result.
+func (g *Generator) GenerateCode(instruction string, existingFiles []File, desiredOutputFiles []DesiredFile) ([]*File, error) {
 	if len(desiredOutputFiles) == 0 {
-		return []File{}, nil
+		return []*File{}, nil
 	}
 
-	generatedFiles := make([]File, len(desiredOutputFiles))
-	// Using a channel to collect errors from goroutines.
-	// A buffered channel matching the number of goroutines to prevent blocking.
-	errs := make(chan error, len(desiredOutputFiles))
+	return g.generateFiles(desiredOutputFiles, func(df DesiredFile) (*File, error) {
+		return g.generateSingleFile(instruction, existingFiles, desiredOutputFiles, df)
+	})
+}
+
+// generateFiles calls generate for each desired file concurrently, with at most MaxConcurrency calls at the same time.
+// It returns the results and errors like GenerateCode.
+func (g *Generator) generateFiles(desiredOutputFiles []DesiredFile, generate func(df DesiredFile) (*File, error)) ([]*File, error) {
+	generatedFiles := make([]*File, len(desiredOutputFiles))
+	// Each goroutine reports its error in the slot of its file, so errors are reported in the order of desiredOutputFiles.
+	errs := make([]error, len(desiredOutputFiles))
 	var wg sync.WaitGroup
+	// Semaphore bounding the number of requests in flight.
+	concurrency := g.MaxConcurrency
+	if concurrency <= 0 {
+		concurrency = len(desiredOutputFiles)
+	}
+	slots := make(chan struct{}, concurrency)
 
 	for i, desiredFile := range desiredOutputFiles {
 		wg.Add(1)
 		go func(idx int, df DesiredFile) {
 			defer wg.Done()
-			// Placeholder for the actual call to the new private method
-			// file, err := g.generateSingleFile(instruction, existingFiles, desiredOutputFiles, df)
-			// For now, let's simulate a successful generation for structure
-			// This will be replaced in the next step when generateSingleFile is implemented
-
-			// Simulating a call to a method that will be implemented in a later step.
-			// This method is expected to call makeChatCompletionsRequest (api.go)
-			// and process its response to return a File object or an error.
-			// file, err := g.generateSingleFile(instruction, existingFiles, desiredOutputFiles, df)
-
-			// Temporarily, we pass only the necessary parts to makeAPIRequest for this step's structure,
-			// knowing it will be replaced by g.generateSingleFile.
-			// The makeAPIRequest will also be updated in a later step.
-			// This is a structural placeholder.
-
-			// Calling the new private method (to be fully implemented in the next step)
-			file, err := g.generateSingleFile(instruction, existingFiles, desiredOutputFiles, df)
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			file, err := generate(df)
 			if err != nil {
-				errs <- fmt.Errorf("error generating file %s: %w", df.Path, err)
+				errs[idx] = fmt.Errorf("error generating file %s: %w", df.Path, err)
 				return
 			}
-			generatedFiles[idx] = *file // Store a copy
+			generatedFiles[idx] = file
 		}(i, desiredFile)
 	}
 
 	wg.Wait()
-	close(errs)
-
-	// Check for errors
-	// TODO: Aggregate multiple errors if necessary. For now, return the first one.
-	for err := range errs {
-		if err != nil {
-			return nil, err // Return the first error encountered
-		}
-	}
 
-	return generatedFiles, nil
+	return generatedFiles, errors.Join(errs...)
 }
 
 // internal variable for testing purposes
@@ -137,7 +193,7 @@ var makeChatCompletionsRequestFunc = makeChatCompletionsRequest
 // It constructs the necessary parameters and processes the API response.
 func (g *Generator) generateSingleFile(instruction string, existingFiles []File, allDesiredFiles []DesiredFile, currentFileToGenerate DesiredFile) (*File, error) {
 	// Call makeChatCompletionsRequest (from api.go) - this anticipates signature changes in api.go
-	apiResp, err := makeChatCompletionsRequestFunc(g.apiKey, instruction, existingFiles, allDesiredFiles, currentFileToGenerate)
+	apiResp, err := makeChatCompletionsRequestFunc(g, instruction, existingFiles, allDesiredFiles, currentFileToGenerate)
 	if err != nil {
 		return nil, fmt.Errorf("API request failed for %s: %w", currentFileToGenerate.Path, err)
 	}
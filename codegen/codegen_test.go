@@ -1,3 +1,86 @@
 package codegen
 
-// TODO: Add tests
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeChatCompletionsRequest replaces makeChatCompletionsRequestFunc with fn for the duration of the test.
+func fakeChatCompletionsRequest(t *testing.T, fn func(g *Generator, instruction string, existingFiles []File, allDesiredFiles []DesiredFile, currentFileToGenerate DesiredFile) (*APIResponse, error)) {
+	t.Helper()
+	original := makeChatCompletionsRequestFunc
+	makeChatCompletionsRequestFunc = fn
+	t.Cleanup(func() { makeChatCompletionsRequestFunc = original })
+}
+
+func TestGenerateCode_LimitsConcurrency(t *testing.T) {
+	var inFlight, maxInFlight atomic.Int32
+	fakeChatCompletionsRequest(t, func(g *Generator, instruction string, existingFiles []File, allDesiredFiles []DesiredFile, currentFileToGenerate DesiredFile) (*APIResponse, error) {
+		current := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			seen := maxInFlight.Load()
+			if current <= seen || maxInFlight.CompareAndSwap(seen, current) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		return &APIResponse{Choices: []APIResponseChoice{{Message: APIRequestMessage{Content: "contents of " + currentFileToGenerate.Path}}}}, nil
+	})
+
+	var desiredFiles []DesiredFile
+	for i := 0; i < 12; i++ {
+		desiredFiles = append(desiredFiles, DesiredFile{Path: fmt.Sprintf("file%d.go", i)})
+	}
+	files, err := New("test-key").WithMaxConcurrency(3).GenerateCode("instruction", nil, desiredFiles)
+	if err != nil {
+		t.Fatalf("GenerateCode failed: %v", err)
+	}
+
+	if maxInFlight.Load() > 3 {
+		t.Errorf("Expected at most 3 requests in flight, got %d", maxInFlight.Load())
+	}
+	if maxInFlight.Load() < 2 {
+		t.Errorf("Expected requests to run concurrently, got at most %d in flight", maxInFlight.Load())
+	}
+	for i, file := range files {
+		if expected := "contents of " + desiredFiles[i].Path; string(file.Contents) != expected {
+			t.Errorf("Expected file %d to contain %q, got %q", i, expected, file.Contents)
+		}
+	}
+}
+
+func TestGenerateCode_ReturnsPartialResultAndAllErrors(t *testing.T) {
+	fakeChatCompletionsRequest(t, func(g *Generator, instruction string, existingFiles []File, allDesiredFiles []DesiredFile, currentFileToGenerate DesiredFile) (*APIResponse, error) {
+		if strings.HasPrefix(currentFileToGenerate.Path, "bad") {
+			return nil, fmt.Errorf("rate limited")
+		}
+		return &APIResponse{Choices: []APIResponseChoice{{Message: APIRequestMessage{Content: "ok"}}}}, nil
+	})
+
+	desiredFiles := []DesiredFile{{Path: "good1.go"}, {Path: "bad1.go"}, {Path: "good2.go"}, {Path: "bad2.go"}, {Path: "bad3.go"}}
+	files, err := New("test-key").GenerateCode("instruction", nil, desiredFiles)
+	if err == nil {
+		t.Fatal("Expected an error, got nil")
+	}
+	for _, path := range []string{"bad1.go", "bad2.go", "bad3.go"} {
+		if !strings.Contains(err.Error(), "error generating file "+path) {
+			t.Errorf("Expected the error to mention %s, got: %v", path, err)
+		}
+	}
+	if len(files) != len(desiredFiles) {
+		t.Fatalf("Expected %d entries, got %d", len(desiredFiles), len(files))
+	}
+	for i, file := range files {
+		failed := strings.HasPrefix(desiredFiles[i].Path, "bad")
+		if failed && file != nil {
+			t.Errorf("Expected a nil entry for %s, got %+v", desiredFiles[i].Path, file)
+		}
+		if !failed && (file == nil || file.Path != desiredFiles[i].Path) {
+			t.Errorf("Expected %s to be generated, got %+v", desiredFiles[i].Path, file)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package codegen
+
+import (
+	"fmt"
+	"strings"
+)
+
+// commitMessageSystemPrompt instructs the model to only produce the commit message.
+const commitMessageSystemPrompt = "You write git commit messages. You will be given a diff. Write a concise conventional-commit message for it: a subject line of at most 72 characters in the form 'type(scope): summary', optionally followed by a blank line and a short body. Respond ONLY with the commit message, without markdown formatting or any preamble."
+
+// CommitMessage asks the model for a commit message describing diff.
+func (g *Generator) CommitMessage(diff string) (string, error) {
+	if strings.TrimSpace(diff) == "" {
+		return "", fmt.Errorf("cannot write a commit message for an empty diff")
+	}
+
+	reqBody := g.newRequest(
+		APIRequestMessage{Role: "system", Content: commitMessageSystemPrompt},
+		APIRequestMessage{Role: "user", Content: diff},
+	)
+
+	apiResp, err := g.sendChatCompletionsRequest(reqBody)
+	if err != nil {
+		return "", err
+	}
+	if apiResp.Error != nil {
+		return "", fmt.Errorf("API error: %s (Type: %s, Code: %v)", apiResp.Error.Message, apiResp.Error.Type, apiResp.Error.Code)
+	}
+	if len(apiResp.Choices) == 0 {
+		return "", fmt.Errorf("API returned no choices")
+	}
+	return strings.TrimSpace(apiResp.Choices[0].Message.Content), nil
+}
@@ -0,0 +1,46 @@
+package codegen
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCommitMessage(t *testing.T) {
+	diff := "diff --git a/main.go b/main.go\n+func hello() {}\n"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reqBody APIRequest
+		if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+			t.Fatalf("Failed to decode request body: %v", err)
+		}
+		if len(reqBody.Messages) != 2 || reqBody.Messages[0].Content != commitMessageSystemPrompt || reqBody.Messages[1].Content != diff {
+			t.Errorf("Unexpected messages: %+v", reqBody.Messages)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(APIResponse{
+			Choices: []APIResponseChoice{{Message: APIRequestMessage{Role: "assistant", Content: "feat: add hello\n"}}},
+		})
+	}))
+	defer server.Close()
+
+	originalChatEndpoint := chatCompletionsEndpoint
+	chatCompletionsEndpoint = server.URL + "/v1/chat/completions"
+	defer func() {
+		chatCompletionsEndpoint = originalChatEndpoint
+	}()
+
+	message, err := New("test-key").CommitMessage(diff)
+	if err != nil {
+		t.Fatalf("CommitMessage failed: %v", err)
+	}
+	if message != "feat: add hello" {
+		t.Errorf("Expected %q, got %q", "feat: add hello", message)
+	}
+}
+
+func TestCommitMessage_EmptyDiff(t *testing.T) {
+	if _, err := New("test-key").CommitMessage("  \n"); err == nil {
+		t.Error("Expected an error for an empty diff, got nil")
+	}
+}
@@ -0,0 +1,181 @@
+package codegen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// diffContextLines is the number of unchanged lines shown around each change in a unified diff.
+const diffContextLines = 3
+
+// Diff returns a unified diff between each file on disk and its generated contents, without writing anything.
+// Files that do not exist yet are shown as additions in full, unchanged files and nil entries are skipped.
+func (g *Generator) Diff(files []*File) (string, error) {
+	var out strings.Builder
+	for _, file := range files {
+		if file == nil {
+			continue // Not generated, see GenerateCode
+		}
+		oldName := "a/" + file.Path
+		oldContents, err := os.ReadFile(file.Path)
+		if errors.Is(err, fs.ErrNotExist) {
+			oldName = "/dev/null"
+		} else if err != nil {
+			return "", fmt.Errorf("error reading file %s: %w", file.Path, err)
+		}
+		out.WriteString(UnifiedDiff(oldName, "b/"+file.Path, oldContents, file.Contents))
+	}
+	return out.String(), nil
+}
+
+// diffOp is a line of a diff: kept (' '), removed ('-') or added ('+').
+// The line includes its terminating newline, unless it is the last line of a file without one.
+type diffOp struct {
+	kind byte
+	line string
+}
+
+// UnifiedDiff returns the changes turning oldContents into newContents in the unified diff format,
+// labeling the old and new version with oldName and newName.
+// It returns an empty string if the contents are equal.
+//
+// The diff is computed with a longest common subsequence of lines, which is quadratic in the number of changed lines;
+// this is fine for generated source files, but not meant for large inputs.
+func UnifiedDiff(oldName, newName string, oldContents, newContents []byte) string {
+	if bytes.Equal(oldContents, newContents) {
+		return ""
+	}
+	ops := diffLines(splitLines(string(oldContents)), splitLines(string(newContents)))
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", oldName, newName)
+	oldLine, newLine := 0, 0 // Lines of each version before ops[start]
+	for start := 0; start < len(ops); {
+		first := start
+		for first < len(ops) && ops[first].kind == ' ' {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+		// Changes separated by at most twice the context are shown in the same hunk.
+		end := first + 1
+		for k := first; k < len(ops) && k-end <= 2*diffContextLines; k++ {
+			if ops[k].kind != ' ' {
+				end = k + 1
+			}
+		}
+		hunkStart := max(first-diffContextLines, start)
+		hunkEnd := min(end+diffContextLines, len(ops))
+
+		// Only unchanged lines are skipped between hunks.
+		oldLine += hunkStart - start
+		newLine += hunkStart - start
+		oldCount, newCount := 0, 0
+		for _, op := range ops[hunkStart:hunkEnd] {
+			if op.kind != '+' {
+				oldCount++
+			}
+			if op.kind != '-' {
+				newCount++
+			}
+		}
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(oldLine, oldCount), hunkRange(newLine, newCount))
+		for _, op := range ops[hunkStart:hunkEnd] {
+			out.WriteByte(op.kind)
+			out.WriteString(op.line)
+			if !strings.HasSuffix(op.line, "\n") {
+				out.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		oldLine += oldCount
+		newLine += newCount
+		start = hunkEnd
+	}
+	return out.String()
+}
+
+// hunkRange formats the range of a hunk starting after line before and spanning count lines.
+func hunkRange(before, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", before)
+	}
+	return fmt.Sprintf("%d,%d", before+1, count)
+}
+
+// splitLines splits s into lines, keeping their terminating newlines.
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines returns the operations turning the lines a into the lines b.
+func diffLines(a, b []string) []diffOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	ops := make([]diffOp, 0, len(a)+len(b))
+	for _, line := range a[:prefix] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	ops = append(ops, lcsDiff(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	return ops
+}
+
+// lcsDiff returns the operations turning a into b, keeping a longest common subsequence of lines.
+// Removals are listed before additions where both are possible.
+func lcsDiff(a, b []string) []diffOp {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i, j = i+1, j+1
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+	return ops
+}
@@ -0,0 +1,87 @@
+package codegen
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	testCases := []struct {
+		name     string
+		old, new string
+		expected string
+	}{
+		{
+			name:     "equal",
+			old:      "a\nb\n",
+			new:      "a\nb\n",
+			expected: "",
+		},
+		{
+			name:     "changed line",
+			old:      "1\n2\n3\n4\n5\n6\n7\n8\n",
+			new:      "1\n2\n3\n4\nfive\n6\n7\n8\n",
+			expected: "--- old\n+++ new\n@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n",
+		},
+		{
+			name:     "distant changes in separate hunks",
+			old:      "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			new:      "one\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n",
+			expected: "--- old\n+++ new\n@@ -1,4 +1,4 @@\n-1\n+one\n 2\n 3\n 4\n@@ -10,3 +10,4 @@\n 10\n 11\n 12\n+13\n",
+		},
+		{
+			name:     "new file",
+			old:      "",
+			new:      "package main\n\nfunc main() {}\n",
+			expected: "--- old\n+++ new\n@@ -0,0 +1,3 @@\n+package main\n+\n+func main() {}\n",
+		},
+		{
+			name:     "missing newline at end of file",
+			old:      "a\nb",
+			new:      "a\nb\n",
+			expected: "--- old\n+++ new\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if diff := UnifiedDiff("old", "new", []byte(tc.old), []byte(tc.new)); diff != tc.expected {
+				t.Errorf("Expected diff:\n%s\ngot:\n%s", tc.expected, diff)
+			}
+		})
+	}
+}
+
+func TestGenerator_Diff(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := os.WriteFile("existing.go", []byte("package main\n"), 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	if err := os.WriteFile("unchanged.go", []byte("package same\n"), 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	files := []*File{
+		{Path: "existing.go", Contents: []byte("package changed\n")},
+		nil, // Failed to generate
+		{Path: "unchanged.go", Contents: []byte("package same\n")},
+		{Path: "new.go", Contents: []byte("package new\n")},
+	}
+	diff, err := New("").Diff(files)
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+
+	expected := "--- a/existing.go\n+++ b/existing.go\n@@ -1,1 +1,1 @@\n-package main\n+package changed\n" +
+		"--- /dev/null\n+++ b/new.go\n@@ -0,0 +1,1 @@\n+package new\n"
+	if diff != expected {
+		t.Errorf("Expected diff:\n%s\ngot:\n%s", expected, diff)
+	}
+	if _, err := os.Stat("new.go"); !os.IsNotExist(err) {
+		t.Errorf("Expected Diff not to create new.go, got %v", err)
+	}
+	if contents, _ := os.ReadFile("existing.go"); !strings.HasPrefix(string(contents), "package main") {
+		t.Errorf("Expected Diff not to modify existing.go, got %q", contents)
+	}
+}
@@ -0,0 +1,40 @@
+package codegen
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// BaseURLEnv is the environment variable selecting the base URL of the API for generators without a BaseURL.
+const BaseURLEnv = "SMOLCODE_CODEGEN_BASE_URL"
+
+// WithBaseURL sets the base URL of an OpenAI-compatible API to send requests to, e.g. "http://localhost:8080/v1".
+// Requests are sent to its "/chat/completions" endpoint.
+func (g *Generator) WithBaseURL(baseURL string) *Generator {
+	g.BaseURL = baseURL
+	return g
+}
+
+// endpoint returns the URL of the chat completions endpoint: below BaseURL if set,
+// otherwise below the base URL in BaseURLEnv, and the Inceptionlabs API by default.
+// It returns an error if the base URL is not an absolute http or https URL.
+func (g *Generator) endpoint() (string, error) {
+	baseURL, source := g.BaseURL, "base URL"
+	if baseURL == "" {
+		baseURL, source = os.Getenv(BaseURLEnv), BaseURLEnv
+	}
+	if baseURL == "" {
+		return chatCompletionsEndpoint, nil
+	}
+
+	parsed, err := url.Parse(baseURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s %q: %w", source, baseURL, err)
+	}
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return "", fmt.Errorf("invalid %s %q: expected an http or https URL such as %q", source, baseURL, apiURLBase)
+	}
+	return strings.TrimSuffix(baseURL, "/") + "/chat/completions", nil
+}
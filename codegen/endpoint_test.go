@@ -0,0 +1,55 @@
+package codegen
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSendChatCompletionsRequest_BaseURL(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(APIResponse{})
+	}))
+	defer server.Close()
+
+	originalChatEndpoint := chatCompletionsEndpoint
+	chatCompletionsEndpoint = "http://127.0.0.1:0/unused"
+	defer func() {
+		chatCompletionsEndpoint = originalChatEndpoint
+	}()
+
+	t.Setenv(BaseURLEnv, server.URL+"/from-env")
+	if _, err := makeChatCompletionsRequest(New("test-key"), "test instruction", nil, nil, DesiredFile{}); err != nil {
+		t.Fatalf("makeChatCompletionsRequest with %s failed: %v", BaseURLEnv, err)
+	}
+	if _, err := makeChatCompletionsRequest(New("test-key").WithBaseURL(server.URL+"/v1/"), "test instruction", nil, nil, DesiredFile{}); err != nil {
+		t.Fatalf("makeChatCompletionsRequest with BaseURL failed: %v", err)
+	}
+
+	expected := []string{"/from-env/chat/completions", "/v1/chat/completions"}
+	if strings.Join(paths, " ") != strings.Join(expected, " ") {
+		t.Errorf("Expected requests to %v, got %v", expected, paths)
+	}
+}
+
+func TestSendChatCompletionsRequest_InvalidBaseURL(t *testing.T) {
+	for _, baseURL := range []string{"localhost:8080/v1", "ftp://example.com/v1", "http://", "http://exa mple.com"} {
+		t.Run(baseURL, func(t *testing.T) {
+			_, err := makeChatCompletionsRequest(New("test-key").WithBaseURL(baseURL), "test instruction", nil, nil, DesiredFile{})
+			if err == nil || !strings.Contains(err.Error(), "invalid base URL") {
+				t.Errorf("Expected an invalid base URL error, got %v", err)
+			}
+		})
+	}
+
+	t.Setenv(BaseURLEnv, "not a url")
+	_, err := makeChatCompletionsRequest(New("test-key"), "test instruction", nil, nil, DesiredFile{})
+	if err == nil || !strings.Contains(err.Error(), "invalid "+BaseURLEnv) {
+		t.Errorf("Expected an invalid %s error, got %v", BaseURLEnv, err)
+	}
+}
@@ -0,0 +1,58 @@
+package codegen
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// factExtractionSystemPrompt instructs the model to only produce the extracted facts as JSON.
+const factExtractionSystemPrompt = `You extract durable knowledge from conversations between a developer and a coding assistant. You will be given the conversation as markdown. List the facts that stay useful in future sessions: decisions, conventions, project structure, commands, and preferences. Leave out anything only relevant to this conversation, like intermediate results or greetings. Each fact is 1-3 self-contained sentences. Respond ONLY with a JSON array of objects with the keys "id", a short kebab-case identifier such as "test-command", and "fact", without markdown formatting or any preamble. Respond with [] if there is nothing worth keeping.`
+
+// ExtractedFact is a fact found in a conversation by ExtractFacts.
+type ExtractedFact struct {
+	ID   string `json:"id"` // Suggested by the model; not guaranteed to be unique or well-formed.
+	Fact string `json:"fact"`
+}
+
+// ExtractFacts asks the model for the durable facts contained in transcript.
+func (g *Generator) ExtractFacts(transcript string) ([]ExtractedFact, error) {
+	if strings.TrimSpace(transcript) == "" {
+		return nil, fmt.Errorf("cannot extract facts from an empty conversation")
+	}
+
+	reqBody := g.newRequest(
+		APIRequestMessage{Role: "system", Content: factExtractionSystemPrompt},
+		APIRequestMessage{Role: "user", Content: transcript},
+	)
+
+	apiResp, err := g.sendChatCompletionsRequest(reqBody)
+	if err != nil {
+		return nil, err
+	}
+	if apiResp.Error != nil {
+		return nil, fmt.Errorf("API error: %s (Type: %s, Code: %v)", apiResp.Error.Message, apiResp.Error.Type, apiResp.Error.Code)
+	}
+	if len(apiResp.Choices) == 0 {
+		return nil, fmt.Errorf("API returned no choices")
+	}
+
+	content := strings.TrimSpace(apiResp.Choices[0].Message.Content)
+	// Models sometimes wrap JSON in a code fence despite being told not to.
+	content = strings.TrimPrefix(content, "```json")
+	content = strings.TrimPrefix(content, "```")
+	content = strings.TrimSuffix(content, "```")
+
+	var facts []ExtractedFact
+	if err := json.Unmarshal([]byte(strings.TrimSpace(content)), &facts); err != nil {
+		return nil, fmt.Errorf("failed to parse extracted facts: %w", err)
+	}
+	nonEmpty := []ExtractedFact{}
+	for _, fact := range facts {
+		fact.Fact = strings.TrimSpace(fact.Fact)
+		if fact.Fact != "" {
+			nonEmpty = append(nonEmpty, fact)
+		}
+	}
+	return nonEmpty, nil
+}
@@ -0,0 +1,48 @@
+package codegen
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestExtractFacts(t *testing.T) {
+	transcript := "## User\n\nWe always run the tests with -tags fts5.\n"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reqBody APIRequest
+		if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+			t.Fatalf("Failed to decode request body: %v", err)
+		}
+		if len(reqBody.Messages) != 2 || reqBody.Messages[0].Content != factExtractionSystemPrompt || reqBody.Messages[1].Content != transcript {
+			t.Errorf("Unexpected messages: %+v", reqBody.Messages)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(APIResponse{
+			Choices: []APIResponseChoice{{Message: APIRequestMessage{Role: "assistant", Content: "```json\n[{\"id\": \"test-tags\", \"fact\": \"Tests run with -tags fts5.\"}, {\"id\": \"empty\", \"fact\": \" \"}]\n```"}}},
+		})
+	}))
+	defer server.Close()
+
+	originalChatEndpoint := chatCompletionsEndpoint
+	chatCompletionsEndpoint = server.URL + "/v1/chat/completions"
+	defer func() {
+		chatCompletionsEndpoint = originalChatEndpoint
+	}()
+
+	facts, err := New("test-key").ExtractFacts(transcript)
+	if err != nil {
+		t.Fatalf("ExtractFacts failed: %v", err)
+	}
+	expected := []ExtractedFact{{ID: "test-tags", Fact: "Tests run with -tags fts5."}}
+	if !reflect.DeepEqual(facts, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, facts)
+	}
+}
+
+func TestExtractFacts_EmptyTranscript(t *testing.T) {
+	if _, err := New("test-key").ExtractFacts("  \n"); err == nil {
+		t.Error("Expected an error for an empty transcript, got nil")
+	}
+}
@@ -0,0 +1,102 @@
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// DefaultMaxRetries is the number of times a Generator retries a failed request by default.
+	DefaultMaxRetries = 3
+	// DefaultRetryBaseDelay is the delay before the first retry by default; it doubles with every retry.
+	DefaultRetryBaseDelay = 500 * time.Millisecond
+)
+
+// sleep waits between retries; replaced in tests.
+var sleep = time.Sleep
+
+// WithRetries sets how often a request failing with a network error or a 429, 500, 502 or 503 status is retried,
+// and the delay before the first retry, which doubles with every retry.
+// A Retry-After header in the response takes precedence over the delay.
+// A maxRetries of zero disables retries.
+func (g *Generator) WithRetries(maxRetries int, baseDelay time.Duration) *Generator {
+	g.MaxRetries = maxRetries
+	g.RetryBaseDelay = baseDelay
+	return g
+}
+
+// post sends jsonData to endpoint, retrying transient failures as configured by WithRetries.
+// It returns the status code and body of the last response.
+func (g *Generator) post(endpoint string, jsonData []byte) (int, []byte, error) {
+	resp, err := g.do(endpoint, jsonData)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	return resp.StatusCode, bodyBytes, nil
+}
+
+// do sends jsonData to endpoint, retrying transient failures as configured by WithRetries.
+// It returns the last response without reading its body, which the caller must close.
+func (g *Generator) do(endpoint string, jsonData []byte) (*http.Response, error) {
+	client := &http.Client{}
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequest("POST", endpoint, bytes.NewReader(jsonData))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+g.apiKey)
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			if attempt < g.MaxRetries {
+				sleep(g.retryDelay(attempt, nil))
+				continue
+			}
+			return nil, fmt.Errorf("failed to send HTTP request: %w", err)
+		}
+
+		if isRetryableStatus(resp.StatusCode) && attempt < g.MaxRetries {
+			io.Copy(io.Discard, resp.Body) // Drain the body so the connection can be reused
+			resp.Body.Close()
+			sleep(g.retryDelay(attempt, resp))
+			continue
+		}
+		return resp, nil
+	}
+}
+
+// isRetryableStatus reports whether a response with the given status code may succeed when the request is repeated.
+func isRetryableStatus(statusCode int) bool {
+	switch statusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable:
+		return true
+	}
+	return false
+}
+
+// retryDelay returns how long to wait before retrying after the given attempt, which failed with resp (nil for network errors).
+// It honors a Retry-After header in seconds or as an HTTP date, and backs off exponentially otherwise.
+func (g *Generator) retryDelay(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
+			if seconds, err := strconv.Atoi(retryAfter); err == nil && seconds >= 0 {
+				return time.Duration(seconds) * time.Second
+			}
+			if date, err := http.ParseTime(retryAfter); err == nil {
+				return max(time.Until(date), 0)
+			}
+		}
+	}
+	return g.RetryBaseDelay << attempt
+}
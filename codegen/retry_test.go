@@ -0,0 +1,119 @@
+package codegen
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recordSleeps replaces sleep with a function recording the delays for the duration of the test.
+func recordSleeps(t *testing.T) *[]time.Duration {
+	t.Helper()
+	var delays []time.Duration
+	originalSleep := sleep
+	sleep = func(d time.Duration) { delays = append(delays, d) }
+	t.Cleanup(func() { sleep = originalSleep })
+	return &delays
+}
+
+func TestSendChatCompletionsRequest_RetriesTransientErrors(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		switch requests {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprintln(w, `{"error": {"message": "Engine overloaded"}}`)
+		case 2:
+			w.Header().Set("Retry-After", "2")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprintln(w, `{"error": {"message": "Engine overloaded"}}`)
+		default:
+			json.NewEncoder(w).Encode(APIResponse{
+				Choices: []APIResponseChoice{{Message: APIRequestMessage{Role: "assistant", Content: "package main"}}},
+			})
+		}
+	}))
+	defer server.Close()
+
+	originalChatEndpoint := chatCompletionsEndpoint
+	chatCompletionsEndpoint = server.URL + "/v1/chat/completions"
+	defer func() {
+		chatCompletionsEndpoint = originalChatEndpoint
+	}()
+	delays := recordSleeps(t)
+
+	generator := New("test-key").WithRetries(3, 100*time.Millisecond)
+	resp, err := makeChatCompletionsRequest(generator, "test instruction", nil, nil, DesiredFile{})
+	if err != nil {
+		t.Fatalf("makeChatCompletionsRequest failed: %v", err)
+	}
+	if requests != 3 {
+		t.Errorf("Expected 3 requests, got %d", requests)
+	}
+	if len(resp.Choices) != 1 || resp.Choices[0].Message.Content != "package main" {
+		t.Errorf("Unexpected response: %+v", resp)
+	}
+	if expected := []time.Duration{100 * time.Millisecond, 2 * time.Second}; !reflect.DeepEqual(*delays, expected) {
+		t.Errorf("Expected delays %v, got %v", expected, *delays)
+	}
+}
+
+func TestSendChatCompletionsRequest_GivesUpAfterMaxRetries(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprintln(w, "slow down")
+	}))
+	defer server.Close()
+
+	originalChatEndpoint := chatCompletionsEndpoint
+	chatCompletionsEndpoint = server.URL + "/v1/chat/completions"
+	defer func() {
+		chatCompletionsEndpoint = originalChatEndpoint
+	}()
+	delays := recordSleeps(t)
+
+	generator := New("test-key").WithRetries(2, 10*time.Millisecond)
+	_, err := makeChatCompletionsRequest(generator, "test instruction", nil, nil, DesiredFile{})
+	if err == nil || !strings.Contains(err.Error(), "API request failed with status 429") {
+		t.Fatalf("Expected the last error to be returned, got %v", err)
+	}
+	if requests != 3 {
+		t.Errorf("Expected 3 requests, got %d", requests)
+	}
+	if expected := []time.Duration{10 * time.Millisecond, 20 * time.Millisecond}; !reflect.DeepEqual(*delays, expected) {
+		t.Errorf("Expected delays %v, got %v", expected, *delays)
+	}
+}
+
+func TestSendChatCompletionsRequest_DoesNotRetryClientErrors(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprintln(w, "unauthorized")
+	}))
+	defer server.Close()
+
+	originalChatEndpoint := chatCompletionsEndpoint
+	chatCompletionsEndpoint = server.URL + "/v1/chat/completions"
+	defer func() {
+		chatCompletionsEndpoint = originalChatEndpoint
+	}()
+	recordSleeps(t)
+
+	if _, err := makeChatCompletionsRequest(New("test-key"), "test instruction", nil, nil, DesiredFile{}); err == nil {
+		t.Fatal("Expected an error for a 401 response, got nil")
+	}
+	if requests != 1 {
+		t.Errorf("Expected 1 request, got %d", requests)
+	}
+}
@@ -0,0 +1,174 @@
+package codegen
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+	"sync"
+)
+
+// streamChunk is a chunk of a streamed chat completion, sent as the data of a server-sent event.
+type streamChunk struct {
+	Choices []struct {
+		Delta        APIRequestMessage `json:"delta"`
+		FinishReason *string           `json:"finish_reason"`
+	} `json:"choices"`
+	Error *APIErrorDetail `json:"error,omitempty"`
+}
+
+// GenerateCodeStream is like GenerateCode, but streams the responses of the API
+// and calls onChunk with each piece of content as it arrives, e.g. to show progress for large files.
+// Calls of onChunk are serialized, but chunks of different files are interleaved; path tells them apart.
+// A file's chunks concatenated are its generated contents.
+func (g *Generator) GenerateCodeStream(instruction string, existingFiles []File, desiredOutputFiles []DesiredFile, onChunk func(path string, chunk string)) ([]*File, error) {
+	if len(desiredOutputFiles) == 0 {
+		return []*File{}, nil
+	}
+
+	var mu sync.Mutex
+	return g.generateFiles(desiredOutputFiles, func(df DesiredFile) (*File, error) {
+		reqBody := g.fileRequest(instruction, existingFiles, desiredOutputFiles, df)
+		content, err := g.streamChatCompletionsRequest(reqBody, func(chunk string) {
+			mu.Lock()
+			defer mu.Unlock()
+			onChunk(df.Path, chunk)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("API request failed for %s: %w", df.Path, err)
+		}
+		if content == "" {
+			return nil, fmt.Errorf("API response for %s did not contain expected content", df.Path)
+		}
+		return &File{Path: df.Path, Contents: []byte(content)}, nil
+	})
+}
+
+// streamChatCompletionsRequest posts reqBody to the chat completions endpoint, asking for a stream of server-sent events.
+// It calls onChunk with the content of each chunk and returns the complete content.
+// If the server does not stream its response, the complete content is passed to onChunk at once.
+func (g *Generator) streamChatCompletionsRequest(reqBody APIRequest, onChunk func(chunk string)) (string, error) {
+	endpoint, err := g.endpoint()
+	if err != nil {
+		return "", err
+	}
+	reqBody.Stream = true
+	jsonData, err := encodeRequest(reqBody)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := g.do(endpoint, jsonData)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); resp.StatusCode >= 400 || mediaType != "text/event-stream" {
+		bodyBytes, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return "", fmt.Errorf("failed to read response body: %w", err)
+		}
+		if resp.StatusCode >= 400 {
+			return "", statusError(resp.StatusCode, bodyBytes)
+		}
+		apiResp, err := decodeResponse(bodyBytes)
+		if err != nil {
+			return "", err
+		}
+		if apiResp.Error != nil {
+			return "", fmt.Errorf("API error: %s (Type: %s, Code: %v)", apiResp.Error.Message, apiResp.Error.Type, apiResp.Error.Code)
+		}
+		if len(apiResp.Choices) == 0 {
+			return "", fmt.Errorf("API returned no choices")
+		}
+		content := apiResp.Choices[0].Message.Content
+		if content != "" {
+			onChunk(content)
+		}
+		return content, nil
+	}
+
+	var content strings.Builder
+	finished := false
+	err = readEventStream(resp.Body, func(data string) error {
+		var chunk streamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("failed to unmarshal stream chunk: %w. Chunk: %s", err, data)
+		}
+		if chunk.Error != nil {
+			return fmt.Errorf("API error: %s (Type: %s, Code: %v)", chunk.Error.Message, chunk.Error.Type, chunk.Error.Code)
+		}
+		for _, choice := range chunk.Choices {
+			if choice.Delta.Content != "" {
+				content.WriteString(choice.Delta.Content)
+				onChunk(choice.Delta.Content)
+			}
+			if choice.FinishReason != nil {
+				finished = true
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, errStreamDone) {
+		finished = true
+	} else if err != nil {
+		return "", err
+	}
+	if !finished {
+		return "", fmt.Errorf("the response stream ended unexpectedly after %d bytes", content.Len())
+	}
+	return content.String(), nil
+}
+
+// errStreamDone is returned by readEventStream when the stream is terminated by the [DONE] sentinel.
+var errStreamDone = errors.New("stream done")
+
+// readEventStream reads server-sent events from r and calls onData with the data of each event.
+// Data spanning multiple data lines is joined with newlines, and lines split across reads are reassembled.
+// It returns errStreamDone when an event's data is the [DONE] sentinel, and nil at the end of r.
+func readEventStream(r io.Reader, onData func(data string) error) error {
+	reader := bufio.NewReader(r)
+	var data []string
+	dispatch := func() error {
+		if len(data) == 0 {
+			return nil
+		}
+		payload := strings.Join(data, "\n")
+		data = nil
+		if payload == "[DONE]" {
+			return errStreamDone
+		}
+		return onData(payload)
+	}
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("failed to read response stream: %w", err)
+		}
+		atEOF := err == io.EOF
+
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case line == "":
+			// A blank line ends an event.
+			if err := dispatch(); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, ":"):
+			// Comments keep the connection alive.
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		default:
+			// Other fields, such as event and id, are not used by the API.
+		}
+
+		if atEOF {
+			return dispatch()
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package codegen
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// streamServer returns a server answering chat completion requests with the given event stream,
+// flushing it in the given pieces so that lines are split across reads.
+func streamServer(t *testing.T, pieces ...string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reqBody APIRequest
+		if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+			t.Errorf("Failed to decode request body: %v", err)
+		}
+		if !reqBody.Stream {
+			t.Errorf("Expected a streaming request")
+		}
+		w.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+		for _, piece := range pieces {
+			fmt.Fprint(w, piece)
+			w.(http.Flusher).Flush()
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	originalChatEndpoint := chatCompletionsEndpoint
+	chatCompletionsEndpoint = server.URL + "/v1/chat/completions"
+	t.Cleanup(func() { chatCompletionsEndpoint = originalChatEndpoint })
+	return server
+}
+
+func TestGenerateCodeStream(t *testing.T) {
+	streamServer(t,
+		": keep-alive\n\n",
+		`data: {"choices":[{"delta":{"role":"assistant","content":"package "}}]}`+"\n\n",
+		`data: {"choices":[{"delta":{"cont`,
+		`ent":"main\n"}}]}`+"\r\n\r\n",
+		`data: {"choices":[{"delta":{},"finish_reason":"stop"}]}`+"\n\n",
+		"data: [DONE]\n\n",
+	)
+
+	var chunks []string
+	files, err := New("test-key").GenerateCodeStream("instruction", nil, []DesiredFile{{Path: "main.go"}}, func(path, chunk string) {
+		if path != "main.go" {
+			t.Errorf("Expected chunks for main.go, got %s", path)
+		}
+		chunks = append(chunks, chunk)
+	})
+	if err != nil {
+		t.Fatalf("GenerateCodeStream failed: %v", err)
+	}
+	if expected := []string{"package ", "main\n"}; !reflect.DeepEqual(chunks, expected) {
+		t.Errorf("Expected chunks %q, got %q", expected, chunks)
+	}
+	if len(files) != 1 || files[0] == nil || string(files[0].Contents) != "package main\n" {
+		t.Errorf("Unexpected files: %+v", files)
+	}
+}
+
+func TestGenerateCodeStream_UnexpectedEnd(t *testing.T) {
+	streamServer(t, `data: {"choices":[{"delta":{"content":"package "}}]}`+"\n\n")
+
+	_, err := New("test-key").GenerateCodeStream("instruction", nil, []DesiredFile{{Path: "main.go"}}, func(path, chunk string) {})
+	if err == nil || !strings.Contains(err.Error(), "ended unexpectedly") {
+		t.Errorf("Expected an error for a truncated stream, got %v", err)
+	}
+}
+
+func TestGenerateCodeStream_ErrorChunk(t *testing.T) {
+	streamServer(t, `data: {"error":{"message":"overloaded","type":"server_error"}}`+"\n\n")
+
+	_, err := New("test-key").GenerateCodeStream("instruction", nil, []DesiredFile{{Path: "main.go"}}, func(path, chunk string) {})
+	if err == nil || !strings.Contains(err.Error(), "API error: overloaded") {
+		t.Errorf("Expected the API error, got %v", err)
+	}
+}
+
+func TestGenerateCodeStream_UnstreamedResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(APIResponse{
+			Choices: []APIResponseChoice{{Message: APIRequestMessage{Role: "assistant", Content: "package main\n"}}},
+		})
+	}))
+	defer server.Close()
+
+	var chunks []string
+	files, err := New("test-key").WithBaseURL(server.URL).GenerateCodeStream("instruction", nil, []DesiredFile{{Path: "main.go"}}, func(path, chunk string) {
+		chunks = append(chunks, chunk)
+	})
+	if err != nil {
+		t.Fatalf("GenerateCodeStream failed: %v", err)
+	}
+	if !reflect.DeepEqual(chunks, []string{"package main\n"}) || string(files[0].Contents) != "package main\n" {
+		t.Errorf("Expected the complete content as a single chunk, got %q and %+v", chunks, files)
+	}
+}
+
+func TestReadEventStream(t *testing.T) {
+	stream := "event: message\ndata: first\ndata: second\n\nid: 2\ndata: third"
+	var events []string
+	err := readEventStream(strings.NewReader(stream), func(data string) error {
+		events = append(events, data)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("readEventStream failed: %v", err)
+	}
+	if expected := []string{"first\nsecond", "third"}; !reflect.DeepEqual(events, expected) {
+		t.Errorf("Expected events %q, got %q", expected, events)
+	}
+
+	err = readEventStream(strings.NewReader("data: [DONE]\n\ndata: ignored\n\n"), func(data string) error {
+		t.Errorf("Unexpected event after [DONE]: %q", data)
+		return nil
+	})
+	if err != errStreamDone {
+		t.Errorf("Expected errStreamDone, got %v", err)
+	}
+}
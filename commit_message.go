@@ -0,0 +1,48 @@
+package smolcode
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/dhamidi/smolcode/codegen"
+)
+
+// ErrNothingToCommit is returned by SuggestCommitMessage when there are no changes.
+var ErrNothingToCommit = errors.New("no changes to describe: both the staged and the working tree diff are empty")
+
+// SuggestCommitMessage generates a commit message for the staged changes,
+// or for the working tree changes if nothing is staged.
+// It reports whether the message describes the staged changes.
+func SuggestCommitMessage() (message string, staged bool, err error) {
+	diff, err := gitDiff("--staged")
+	if err != nil {
+		return "", false, err
+	}
+	staged = strings.TrimSpace(diff) != ""
+	if !staged {
+		diff, err = gitDiff()
+		if err != nil {
+			return "", false, err
+		}
+	}
+	if strings.TrimSpace(diff) == "" {
+		return "", false, ErrNothingToCommit
+	}
+
+	message, err = codegen.New(os.Getenv("INCEPTION_API_KEY")).CommitMessage(diff)
+	if err != nil {
+		return "", staged, fmt.Errorf("failed to generate commit message: %w", err)
+	}
+	return message, staged, nil
+}
+
+func gitDiff(args ...string) (string, error) {
+	out, err := exec.Command("git", append([]string{"diff"}, args...)...).Output()
+	if err != nil {
+		return "", fmt.Errorf("git diff failed: %w", err)
+	}
+	return string(out), nil
+}
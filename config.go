@@ -0,0 +1,324 @@
+package smolcode
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"google.golang.org/genai"
+)
+
+// DefaultConfigPath is the location of the per-project configuration file.
+const DefaultConfigPath = ".smolcode/config.json"
+
+// PreferencesPath is the location of personal preferences.
+// They use the same keys as DefaultConfigPath and take precedence over it.
+const PreferencesPath = ".smolcode/preferences.json"
+
+// DefaultModel is the model used when no model is configured.
+const DefaultModel = "gemini-2.5-pro-preview-03-25"
+
+// envPrefix is prepended to the environment variables that override configuration keys.
+const envPrefix = "SMOLCODE_"
+
+// Config holds settings for the agent that are read from DefaultConfigPath and PreferencesPath.
+//
+// Example:
+//
+//	{
+//	  "safetySettings": [
+//	    {"category": "HARM_CATEGORY_DANGEROUS_CONTENT", "threshold": "BLOCK_ONLY_HIGH"}
+//	  ]
+//	}
+//
+// Valid categories are HARM_CATEGORY_HARASSMENT, HARM_CATEGORY_HATE_SPEECH,
+// HARM_CATEGORY_SEXUALLY_EXPLICIT, HARM_CATEGORY_DANGEROUS_CONTENT and
+// HARM_CATEGORY_CIVIC_INTEGRITY.
+// Valid thresholds are BLOCK_LOW_AND_ABOVE, BLOCK_MEDIUM_AND_ABOVE,
+// BLOCK_ONLY_HIGH, BLOCK_NONE and OFF.
+type Config struct {
+	// Model is the model used for new conversations. Resumed conversations keep their model.
+	Model string `json:"model,omitempty"`
+
+	// SafetySettings are sent with every request. When empty, the API defaults apply.
+	SafetySettings []*genai.SafetySetting `json:"safetySettings,omitempty"`
+
+	// ThinkingBudget limits the tokens spent on reasoning. Zero leaves the API default.
+	ThinkingBudget int `json:"thinkingBudget,omitempty"`
+
+	// ContextWindow limits the number of recent messages sent to the model. Zero sends everything.
+	ContextWindow int `json:"contextWindow,omitempty"`
+
+	// NoChangeSummary suppresses the list of modified files printed at the end of a session.
+	NoChangeSummary bool `json:"noChangeSummary,omitempty"`
+
+	// StrictConversation makes failing to load an explicitly requested conversation an error
+	// instead of starting a new conversation.
+	StrictConversation bool `json:"strictConversation,omitempty"`
+
+	// ToolRateLimit limits calls per second to each tool. Zero disables the limit.
+	ToolRateLimit float64 `json:"toolRateLimit,omitempty"`
+
+	// GlobalToolRateLimit limits calls per second to all tools together. Zero disables the limit.
+	GlobalToolRateLimit float64 `json:"globalToolRateLimit,omitempty"`
+
+	// Deterministic asks all models for reproducible output: temperature zero and a fixed seed.
+	// This is best-effort and depends on the model.
+	Deterministic bool `json:"deterministic,omitempty"`
+
+	// Stream displays the model's text as it is generated.
+	Stream bool `json:"stream,omitempty"`
+
+	// Quiet suppresses the startup banner, so that only errors and model output are displayed.
+	Quiet bool `json:"quiet,omitempty"`
+
+	// Clarify instructs the model to ask a clarifying question instead of guessing when a request is ambiguous.
+	Clarify bool `json:"clarify,omitempty"`
+
+	// AutoRecall adds memories matching each message of the user to the conversation, see Agent.EnableAutoRecall.
+	AutoRecall bool `json:"autoRecall,omitempty"`
+
+	// AutoRecallLimit is the number of memories auto-recall adds per message. Zero uses DefaultAutoRecallLimit.
+	AutoRecallLimit int `json:"autoRecallLimit,omitempty"`
+
+	// AutoRecallMinScore is the relevance score a memory needs to be added by auto-recall.
+	AutoRecallMinScore float64 `json:"autoRecallMinScore,omitempty"`
+
+	// PromptTemplate is the prompt shown before reading user input, see DefaultPromptTemplate.
+	PromptTemplate string `json:"promptTemplate,omitempty"`
+
+	// DisabledTools lists built-in tools that are not offered to the model.
+	DisabledTools []string `json:"disabledTools,omitempty"`
+
+	// AllowedRoots are the directories the file tools may read and write below.
+	// Relative paths are resolved against the working directory, which is the default.
+	AllowedRoots []string `json:"allowedRoots,omitempty"`
+
+	// AllowSymlinkWrites lets the file tools write through symbolic links, as long as their targets
+	// lie within AllowedRoots. By default such writes are refused.
+	AllowSymlinkWrites bool `json:"allowSymlinkWrites,omitempty"`
+
+	// MaxRetrySeconds limits the time spent retrying a failed request to the model.
+	// Zero uses the limit of DefaultRetryConfig.
+	MaxRetrySeconds int `json:"maxRetrySeconds,omitempty"`
+
+	// MaxReadFileSize is the size in bytes of the largest file read_file returns.
+	// Zero uses DefaultMaxReadFileSize.
+	MaxReadFileSize int `json:"maxReadFileSize,omitempty"`
+}
+
+// LoadConfig reads the configuration file at path.
+// A missing file is not an error and results in an empty configuration.
+func LoadConfig(path string) (*Config, error) {
+	config := &Config{}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return config, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// DefaultConfig returns the built-in defaults.
+func DefaultConfig() *Config {
+	return &Config{Model: DefaultModel}
+}
+
+// Sources of settings reported by ResolveConfigSources, in addition to the paths of configuration files.
+const (
+	SourceDefault     = "default"
+	SourceEnvironment = "environment"
+	SourceOverride    = "flag"
+)
+
+// ResolveConfig returns the effective configuration. Settings are taken from,
+// in increasing order of precedence: the built-in defaults, DefaultConfigPath,
+// PreferencesPath, SMOLCODE_* environment variables, and overrides, which usually come from flags.
+// Only settings with non-zero values take precedence over earlier sources.
+func ResolveConfig(overrides *Config) (*Config, error) {
+	config, _, err := ResolveConfigSources(overrides)
+	return config, err
+}
+
+// ResolveConfigSources works like ResolveConfig, and additionally reports where each setting came from.
+func ResolveConfigSources(overrides *Config) (*Config, map[string]string, error) {
+	config := DefaultConfig()
+	sources := map[string]string{}
+	for _, key := range ConfigKeys() {
+		sources[key] = SourceDefault
+	}
+	for _, path := range []string{DefaultConfigPath, PreferencesPath} {
+		fileConfig, err := LoadConfig(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		mergeConfig(config, fileConfig, path, sources)
+	}
+	envConfig, err := configFromEnv()
+	if err != nil {
+		return nil, nil, err
+	}
+	mergeConfig(config, envConfig, SourceEnvironment, sources)
+	if overrides != nil {
+		mergeConfig(config, overrides, SourceOverride, sources)
+	}
+	return config, sources, nil
+}
+
+// mergeConfig copies every setting of src that is not zero to dst and records source as its origin in sources.
+func mergeConfig(dst, src *Config, source string, sources map[string]string) {
+	dstValue, srcValue := reflect.ValueOf(dst).Elem(), reflect.ValueOf(src).Elem()
+	for i := 0; i < srcValue.NumField(); i++ {
+		if !srcValue.Field(i).IsZero() {
+			dstValue.Field(i).Set(srcValue.Field(i))
+			sources[configKey(srcValue.Type().Field(i))] = source
+		}
+	}
+}
+
+// ConfigKeys returns the keys of all settings, in the order they are declared in Config.
+func ConfigKeys() []string {
+	configType := reflect.TypeOf(Config{})
+	keys := make([]string, 0, configType.NumField())
+	for i := 0; i < configType.NumField(); i++ {
+		keys = append(keys, configKey(configType.Field(i)))
+	}
+	return keys
+}
+
+// configKey returns the key of a setting as it appears in configuration files.
+func configKey(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	return name
+}
+
+// ConfigEnvVar returns the name of the environment variable that overrides key, e.g. SMOLCODE_THINKING_BUDGET.
+func ConfigEnvVar(key string) string {
+	var name strings.Builder
+	name.WriteString(envPrefix)
+	for i, r := range key {
+		if unicode.IsUpper(r) && i > 0 {
+			name.WriteRune('_')
+		}
+		name.WriteRune(unicode.ToUpper(r))
+	}
+	return name.String()
+}
+
+// configFromEnv reads the settings that are overridden by environment variables.
+func configFromEnv() (*Config, error) {
+	config := &Config{}
+	for _, key := range ConfigKeys() {
+		value, found := os.LookupEnv(ConfigEnvVar(key))
+		if !found || value == "" {
+			continue
+		}
+		if err := config.Set(key, value); err != nil {
+			return nil, fmt.Errorf("invalid value in %s: %w", ConfigEnvVar(key), err)
+		}
+	}
+	return config, nil
+}
+
+// configField returns the field of config holding the setting key.
+func (config *Config) configField(key string) (reflect.Value, error) {
+	configValue := reflect.ValueOf(config).Elem()
+	for i := 0; i < configValue.NumField(); i++ {
+		if configKey(configValue.Type().Field(i)) == key {
+			return configValue.Field(i), nil
+		}
+	}
+	return reflect.Value{}, fmt.Errorf("unknown configuration key %q, valid keys are: %s", key, strings.Join(ConfigKeys(), ", "))
+}
+
+// Get returns the value of the setting key.
+func (config *Config) Get(key string) (any, error) {
+	field, err := config.configField(key)
+	if err != nil {
+		return nil, err
+	}
+	return field.Interface(), nil
+}
+
+// Set parses value according to the type of the setting key and stores it.
+// Strings, booleans and numbers are given as is, all other settings as JSON.
+func (config *Config) Set(key string, value string) error {
+	field, err := config.configField(key)
+	if err != nil {
+		return err
+	}
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%s must be true or false: %w", key, err)
+		}
+		field.SetBool(parsed)
+	case reflect.Int:
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%s must be an integer: %w", key, err)
+		}
+		field.SetInt(int64(parsed))
+	case reflect.Float64:
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("%s must be a number: %w", key, err)
+		}
+		field.SetFloat(parsed)
+	default:
+		parsed := reflect.New(field.Type())
+		if err := json.Unmarshal([]byte(value), parsed.Interface()); err != nil {
+			return fmt.Errorf("%s must be valid JSON: %w", key, err)
+		}
+		field.Set(parsed.Elem())
+	}
+	return nil
+}
+
+// SaveConfig writes config to path, creating the containing directory if necessary.
+func SaveConfig(config *Config, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode configuration: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// UnsafeSafetySettings returns safety settings that disable blocking for all harm categories.
+func UnsafeSafetySettings() []*genai.SafetySetting {
+	categories := []genai.HarmCategory{
+		genai.HarmCategoryHarassment,
+		genai.HarmCategoryHateSpeech,
+		genai.HarmCategorySexuallyExplicit,
+		genai.HarmCategoryDangerousContent,
+		genai.HarmCategoryCivicIntegrity,
+	}
+	settings := make([]*genai.SafetySetting, 0, len(categories))
+	for _, category := range categories {
+		settings = append(settings, &genai.SafetySetting{
+			Category:  category,
+			Threshold: genai.HarmBlockThresholdBlockNone,
+		})
+	}
+	return settings
+}
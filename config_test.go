@@ -0,0 +1,111 @@
+package smolcode
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeConfigFile(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("failed to create directory for %s: %v", path, err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
+
+func TestResolveConfigPrecedence(t *testing.T) {
+	t.Chdir(t.TempDir())
+	writeConfigFile(t, DefaultConfigPath, `{"model": "project-model", "thinkingBudget": 100, "contextWindow": 10, "toolRateLimit": 1}`)
+	writeConfigFile(t, PreferencesPath, `{"thinkingBudget": 200, "contextWindow": 20}`)
+	t.Setenv("SMOLCODE_CONTEXT_WINDOW", "30")
+	t.Setenv("SMOLCODE_NO_CHANGE_SUMMARY", "true")
+
+	config, err := ResolveConfig(&Config{ToolRateLimit: 5})
+	if err != nil {
+		t.Fatalf("ResolveConfig failed: %v", err)
+	}
+
+	if config.Model != "project-model" {
+		t.Errorf("expected the project config to override the default model, got %q", config.Model)
+	}
+	if config.ThinkingBudget != 200 {
+		t.Errorf("expected preferences to override the project config, got thinkingBudget %d", config.ThinkingBudget)
+	}
+	if config.ContextWindow != 30 || !config.NoChangeSummary {
+		t.Errorf("expected the environment to override preferences, got contextWindow %d, noChangeSummary %t", config.ContextWindow, config.NoChangeSummary)
+	}
+	if config.ToolRateLimit != 5 {
+		t.Errorf("expected overrides to take precedence, got toolRateLimit %v", config.ToolRateLimit)
+	}
+}
+
+func TestResolveConfigDefaults(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	config, err := ResolveConfig(nil)
+	if err != nil {
+		t.Fatalf("ResolveConfig failed: %v", err)
+	}
+
+	if config.Model != DefaultModel {
+		t.Errorf("expected the default model %q, got %q", DefaultModel, config.Model)
+	}
+}
+
+func TestConfigSet(t *testing.T) {
+	config := &Config{}
+
+	if err := config.Set("thinkingBudget", "1024"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := config.Set("safetySettings", `[{"category": "HARM_CATEGORY_HARASSMENT", "threshold": "BLOCK_NONE"}]`); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	if config.ThinkingBudget != 1024 {
+		t.Errorf("expected thinkingBudget 1024, got %d", config.ThinkingBudget)
+	}
+	if len(config.SafetySettings) != 1 || config.SafetySettings[0].Threshold != "BLOCK_NONE" {
+		t.Errorf("expected one safety setting, got %+v", config.SafetySettings)
+	}
+	if err := config.Set("thinkingBudget", "lots"); err == nil {
+		t.Error("expected an error for a non-numeric thinkingBudget")
+	}
+	if err := config.Set("doesNotExist", "1"); err == nil {
+		t.Error("expected an error for an unknown key")
+	}
+}
+
+func TestConfigEnvVar(t *testing.T) {
+	if got := ConfigEnvVar("globalToolRateLimit"); got != "SMOLCODE_GLOBAL_TOOL_RATE_LIMIT" {
+		t.Errorf("expected SMOLCODE_GLOBAL_TOOL_RATE_LIMIT, got %s", got)
+	}
+}
+
+func TestResolveConfigSources(t *testing.T) {
+	t.Chdir(t.TempDir())
+	writeConfigFile(t, DefaultConfigPath, `{"contextWindow": 10}`)
+	writeConfigFile(t, PreferencesPath, `{"thinkingBudget": 200}`)
+	t.Setenv("SMOLCODE_MODEL", "env-model")
+
+	_, sources, err := ResolveConfigSources(&Config{NoChangeSummary: true})
+	if err != nil {
+		t.Fatalf("ResolveConfigSources failed: %v", err)
+	}
+
+	want := map[string]string{
+		"model":           SourceEnvironment,
+		"thinkingBudget":  PreferencesPath,
+		"contextWindow":   DefaultConfigPath,
+		"noChangeSummary": SourceOverride,
+		"toolRateLimit":   SourceDefault,
+	}
+	for key, source := range want {
+		if sources[key] != source {
+			t.Errorf("expected %s to come from %s, got %s", key, source, sources[key])
+		}
+	}
+}
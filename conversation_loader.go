@@ -6,6 +6,7 @@ import (
 	"log"
 	"os"
 
+	"github.com/dhamidi/smolcode/history"
 	"google.golang.org/genai"
 )
 
@@ -33,3 +34,73 @@ func LoadConversationFromFile(filepath string) []*genai.Content {
 	fmt.Printf("Loaded %d initial conversation entries from %s\n", len(initialConversation), filepath)
 	return initialConversation
 }
+
+// contentsFromMessages converts the messages of a stored conversation into the agent's history.
+// Messages that cannot be decoded are replaced with a placeholder describing the problem,
+// so that no turn is dropped silently and the history keeps its length.
+func contentsFromMessages(messages []*history.Message) []*genai.Content {
+	contents := make([]*genai.Content, 0, len(messages))
+	for i, msg := range messages {
+		content, rawLength, err := decodeMessagePayload(msg.Payload)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: message %d/%d of the conversation is corrupt and was replaced with a placeholder: %v\n", i+1, len(messages), err)
+			content = corruptMessagePlaceholder(i, rawLength, err)
+		}
+		contents = append(contents, content)
+	}
+	return contents
+}
+
+// PayloadError describes a stored message that cannot be restored as a genai.Content.
+type PayloadError struct {
+	Sequence int // Position of the message in the conversation, starting at 0.
+	Err      error
+}
+
+func (e PayloadError) Error() string {
+	return fmt.Sprintf("message %d: %v", e.Sequence, e.Err)
+}
+
+// VerifyConversation decodes every message of conv the same way a session does when
+// resuming it, and returns the messages that would be replaced with a placeholder.
+func VerifyConversation(conv *history.Conversation) []PayloadError {
+	var problems []PayloadError
+	for i, msg := range conv.Messages {
+		if _, _, err := decodeMessagePayload(msg.Payload); err != nil {
+			problems = append(problems, PayloadError{Sequence: i, Err: err})
+		}
+	}
+	return problems
+}
+
+// decodeMessagePayload turns a stored message payload into a genai.Content.
+// Payloads are raw JSON bytes when history could not parse them, and generic
+// maps otherwise; maps are re-marshalled to JSON and decoded into the typed struct.
+// It also returns the length of the raw payload in bytes.
+func decodeMessagePayload(payload any) (*genai.Content, int, error) {
+	payloadBytes, isRaw := payload.([]byte)
+	if !isRaw {
+		var err error
+		payloadBytes, err = json.Marshal(payload)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to marshal payload of type %T: %w", payload, err)
+		}
+	}
+
+	var content genai.Content
+	if err := json.Unmarshal(payloadBytes, &content); err != nil {
+		return nil, len(payloadBytes), fmt.Errorf("failed to unmarshal payload: %w", err)
+	}
+	if isContentEmpty(&content) && content.Role == "" {
+		return nil, len(payloadBytes), fmt.Errorf("payload does not contain a message")
+	}
+	return &content, len(payloadBytes), nil
+}
+
+// corruptMessagePlaceholder stands in for the message at index that could not be restored.
+func corruptMessagePlaceholder(index int, rawLength int, err error) *genai.Content {
+	return genai.NewContentFromText(
+		fmt.Sprintf("[smolcode: message %d could not be restored from history (%d bytes of corrupt payload): %v]", index+1, rawLength, err),
+		genai.RoleUser,
+	)
+}
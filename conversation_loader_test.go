@@ -0,0 +1,76 @@
+package smolcode
+
+import (
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dhamidi/smolcode/history"
+	_ "github.com/mattn/go-sqlite3"
+	"google.golang.org/genai"
+)
+
+func TestContentsFromMessagesReplacesCorruptPayload(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "history.db")
+	conv, err := history.New()
+	if err != nil {
+		t.Fatalf("history.New failed: %v", err)
+	}
+	conv.Append(genai.NewContentFromText("first", genai.RoleUser))
+	conv.Append(genai.NewContentFromText("second", genai.RoleModel))
+	conv.Append(genai.NewContentFromText("third", genai.RoleUser))
+	if err := history.SaveTo(conv, dbPath); err != nil {
+		t.Fatalf("history.SaveTo failed: %v", err)
+	}
+
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	const corruptPayload = `{"role": "model", "parts": [{"te`
+	if _, err := db.Exec("UPDATE messages SET payload = ? WHERE conversation_id = ? AND sequence_number = 1", corruptPayload, conv.ID); err != nil {
+		t.Fatalf("failed to corrupt message: %v", err)
+	}
+	db.Close()
+
+	loaded, err := history.LoadFrom(conv.ID, dbPath)
+	if err != nil {
+		t.Fatalf("history.LoadFrom failed: %v", err)
+	}
+
+	contents := contentsFromMessages(loaded.Messages)
+
+	if len(contents) != 3 {
+		t.Fatalf("expected 3 messages, got %d", len(contents))
+	}
+	if contents[0].Parts[0].Text != "first" || contents[2].Parts[0].Text != "third" {
+		t.Errorf("expected intact messages to be restored, got %q and %q", contents[0].Parts[0].Text, contents[2].Parts[0].Text)
+	}
+	placeholder := contents[1].Parts[0].Text
+	if !strings.Contains(placeholder, "could not be restored") {
+		t.Errorf("expected a placeholder for the corrupt message, got %q", placeholder)
+	}
+	if !strings.Contains(placeholder, "32 bytes") {
+		t.Errorf("expected the placeholder to mention the payload size, got %q", placeholder)
+	}
+}
+
+func TestVerifyConversationReportsInvalidPayloads(t *testing.T) {
+	conv, err := history.New()
+	if err != nil {
+		t.Fatalf("history.New failed: %v", err)
+	}
+	conv.Append(map[string]any{"role": "user", "parts": []any{map[string]any{"text": "valid"}}})
+	conv.Append([]byte(`{"role": "model", "parts": [{"te`))
+	conv.Append("not a message")
+
+	problems := VerifyConversation(conv)
+
+	if len(problems) != 2 {
+		t.Fatalf("expected 2 invalid messages, got %v", problems)
+	}
+	if problems[0].Sequence != 1 || problems[1].Sequence != 2 {
+		t.Errorf("expected messages 1 and 2 to be reported, got %d and %d", problems[0].Sequence, problems[1].Sequence)
+	}
+}
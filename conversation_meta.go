@@ -0,0 +1,19 @@
+package smolcode
+
+import (
+	"os"
+	"strings"
+)
+
+// newConversationMeta returns the metadata attached to new conversations:
+// the working directory as "cwd", and the root of the git repository as "repo" if there is one.
+func newConversationMeta() map[string]string {
+	meta := map[string]string{}
+	if cwd, err := os.Getwd(); err == nil {
+		meta["cwd"] = cwd
+	}
+	if root, err := runGit(nil, "rev-parse", "--show-toplevel"); err == nil && strings.TrimSpace(root) != "" {
+		meta["repo"] = strings.TrimSpace(root)
+	}
+	return meta
+}
@@ -0,0 +1,29 @@
+package smolcode
+
+import (
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewConversationMeta(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	meta := newConversationMeta()
+	if _, ok := meta["repo"]; ok {
+		t.Errorf("expected no repo outside of a git repository, got %v", meta)
+	}
+
+	if err := exec.Command("git", "init", "--quiet").Run(); err != nil {
+		t.Skipf("git is not available: %v", err)
+	}
+	meta = newConversationMeta()
+	resolved, _ := filepath.EvalSymlinks(dir)
+	if cwd, _ := filepath.EvalSymlinks(meta["cwd"]); cwd != resolved {
+		t.Errorf("expected cwd %s, got %s", resolved, meta["cwd"])
+	}
+	if repo, _ := filepath.EvalSymlinks(meta["repo"]); repo != resolved {
+		t.Errorf("expected repo %s, got %s", resolved, meta["repo"])
+	}
+}
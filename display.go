@@ -50,12 +50,30 @@ func (r *RawTextDisplay) DisplayMessage(role string, colorCode string, historyCo
 	}
 }
 
+// DisplaySkip prints a message about something the agent skipped, like any other message.
+func (r *RawTextDisplay) DisplaySkip(reason SkipReason, historyCount int, format string, args ...interface{}) {
+	r.DisplayMessage("Skip  ", "96", historyCount, format, args...)
+}
+
+// SkipReason tells why the agent skipped a message, so that consumers can
+// distinguish normal flow-control skips from real problems.
+type SkipReason string
+
+const (
+	SkipEmptyUserInput     SkipReason = "empty_user_input"
+	SkipEmptyModelResponse SkipReason = "empty_model_response"
+	SkipEmptyToolResult    SkipReason = "empty_tool_result"
+	SkipEditCancelled      SkipReason = "edit_cancelled"
+)
+
 // TextDisplayer defines an interface for displaying text content.
 type TextDisplayer interface {
 	Display(content string) error
 	DisplayPrompt(format string, args ...interface{}) // For inline prompts
 	DisplayError(format string, args ...interface{})
 	DisplayMessage(role string, colorCode string, historyCount int, format string, args ...interface{})
+	// DisplaySkip reports that the agent skipped something, with a machine-readable reason alongside the text.
+	DisplaySkip(reason SkipReason, historyCount int, format string, args ...interface{})
 }
 
 // GlamourousTextDisplay attempts to render text using glamour, falling back to RawTextDisplay.
@@ -112,3 +130,19 @@ func (g *GlamourousTextDisplay) DisplayMessage(role string, colorCode string, hi
 	// Print the glamour-rendered message (which usually includes its own newline handling)
 	fmt.Print(prettyOutput)
 }
+
+// DisplaySkip prints a message about something the agent skipped, like any other message.
+func (g *GlamourousTextDisplay) DisplaySkip(reason SkipReason, historyCount int, format string, args ...interface{}) {
+	g.DisplayMessage("Skip  ", "96", historyCount, format, args...)
+}
+
+// displayWriter is an io.Writer that shows everything written to it through a displayer as soon as it is written,
+// like the streamed text of the model, e.g. the output of a running command.
+type displayWriter struct {
+	displayer TextDisplayer
+}
+
+func (w *displayWriter) Write(p []byte) (int, error) {
+	w.displayer.DisplayPrompt("%s", p)
+	return len(p), nil
+}
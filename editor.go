@@ -0,0 +1,53 @@
+package smolcode
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// ErrEditorNotSet is returned when composing a message in an editor without $EDITOR being set.
+var ErrEditorNotSet = errors.New("$EDITOR is not set; set it to the command of your editor, e.g. EDITOR=vim")
+
+// editMessage lets the user compose a message in $EDITOR, starting out with initial.
+// $EDITOR may include arguments, e.g. "code --wait".
+// It reports false if the editor exited without changing the text, which cancels the message.
+func editMessage(initial string) (string, bool, error) {
+	editor := strings.Fields(os.Getenv("EDITOR"))
+	if len(editor) == 0 {
+		return "", false, ErrEditorNotSet
+	}
+
+	file, err := os.CreateTemp("", "smolcode-message-*.md")
+	if err != nil {
+		return "", false, fmt.Errorf("failed to create message file: %w", err)
+	}
+	defer os.Remove(file.Name())
+	_, err = file.WriteString(initial)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to write message file: %w", err)
+	}
+
+	cmd := exec.Command(editor[0], append(editor[1:], file.Name())...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", false, fmt.Errorf("editor %q failed: %w", editor[0], err)
+	}
+
+	content, err := os.ReadFile(file.Name())
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read message file: %w", err)
+	}
+	message := strings.TrimRight(string(content), "\n")
+	if message == strings.TrimRight(initial, "\n") || strings.TrimSpace(message) == "" {
+		return "", false, nil
+	}
+	return message, true, nil
+}
@@ -0,0 +1,51 @@
+package smolcode
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fakeEditor sets $EDITOR to a shell script running script with the file to edit as $1.
+func fakeEditor(t *testing.T, script string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "editor")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0755); err != nil {
+		t.Fatalf("failed to write editor script: %v", err)
+	}
+	t.Setenv("EDITOR", path)
+}
+
+func TestEditMessageUsesSavedContents(t *testing.T) {
+	fakeEditor(t, `printf 'line one\nline two\n' > "$1"`)
+
+	message, edited, err := editMessage("")
+
+	if err != nil || !edited {
+		t.Fatalf("expected an edited message, got %t, %v", edited, err)
+	}
+	if message != "line one\nline two" {
+		t.Errorf("expected the contents of the file, got %q", message)
+	}
+}
+
+func TestEditMessageWithoutChangesCancels(t *testing.T) {
+	fakeEditor(t, "true")
+
+	_, edited, err := editMessage("draft")
+
+	if err != nil || edited {
+		t.Errorf("expected the message to be cancelled, got %t, %v", edited, err)
+	}
+}
+
+func TestEditMessageWithoutEditor(t *testing.T) {
+	t.Setenv("EDITOR", "")
+
+	_, _, err := editMessage("")
+
+	if !errors.Is(err, ErrEditorNotSet) {
+		t.Errorf("expected ErrEditorNotSet, got %v", err)
+	}
+}
@@ -0,0 +1,266 @@
+package history
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ArchiveVersion is the format version written by ExportArchive.
+const ArchiveVersion = 1
+
+// Archive is the self-contained representation of a conversation produced by ExportArchive.
+// Message payloads are kept verbatim as they are stored in the database,
+// so that importing an archive reproduces the conversation exactly.
+type Archive struct {
+	Version      int               `json:"version"`
+	Conversation ArchivedRecord    `json:"conversation"`
+	Messages     []ArchivedMessage `json:"messages"`
+	Meta         map[string]string `json:"meta,omitempty"` // See SetMeta.
+}
+
+// ArchivedRecord holds the conversation row of an archive.
+type ArchivedRecord struct {
+	ID          string    `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	Title       string    `json:"title,omitempty"`
+	Transcript  string    `json:"transcript,omitempty"`
+	TotalTokens int       `json:"total_tokens,omitempty"`
+	Model       string    `json:"model,omitempty"`
+}
+
+// ArchivedMessage holds a single message row of an archive.
+type ArchivedMessage struct {
+	SequenceNumber int             `json:"sequence_number"`
+	Payload        json.RawMessage `json:"payload"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+// ExportArchive writes the conversation identified by conversationID in the current store, see SetStore,
+// including all of its messages and metadata, as a single JSON document to w.
+func ExportArchive(conversationID string, w io.Writer) error {
+	return exportArchive(currentStore(), conversationID, w)
+}
+
+// exportArchive implements ExportArchive for store.
+func exportArchive(store Store, conversationID string, w io.Writer) error {
+	archive, err := store.Archive(conversationID)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(archive); err != nil {
+		return fmt.Errorf("failed to write archive for conversation '%s': %w", conversationID, err)
+	}
+	return nil
+}
+
+// ImportArchive reads an archive written by ExportArchive from r and stores it in the current store, see SetStore.
+// If a conversation with the archived ID already exists, the conversation is imported under a new ID.
+// Message contents from archives written by older versions are wrapped in the current payload envelope.
+// It returns the ID under which the conversation was stored.
+func ImportArchive(r io.Reader) (string, error) {
+	return importArchive(currentStore(), r)
+}
+
+// importArchive implements ImportArchive for store.
+func importArchive(store Store, r io.Reader) (string, error) {
+	var archive Archive
+	if err := json.NewDecoder(r).Decode(&archive); err != nil {
+		return "", fmt.Errorf("failed to decode archive: %w", err)
+	}
+	if archive.Version != ArchiveVersion {
+		return "", fmt.Errorf("unsupported archive version %d", archive.Version)
+	}
+	if archive.Conversation.ID == "" {
+		return "", fmt.Errorf("archive does not contain a conversation ID")
+	}
+	for i, msg := range archive.Messages {
+		if wrapped, ok := wrapContent(msg.Payload); ok {
+			archive.Messages[i].Payload = wrapped
+		}
+	}
+	return store.ImportArchive(&archive)
+}
+
+func (store *SQLiteStore) Archive(conversationID string) (*Archive, error) {
+	db, err := initDB(store.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open/initialize database at %s: %w", store.Path, err)
+	}
+	defer db.Close()
+
+	archive := Archive{Version: ArchiveVersion, Messages: []ArchivedMessage{}}
+	var title, transcript, model sql.NullString
+	err = db.QueryRow("SELECT id, created_at, title, transcript, total_tokens, model FROM conversations WHERE id = ?", conversationID).
+		Scan(&archive.Conversation.ID, &archive.Conversation.CreatedAt, &title, &transcript, &archive.Conversation.TotalTokens, &model)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("conversation with ID '%s' not found: %w", conversationID, ErrConversationNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query conversation '%s': %w", conversationID, err)
+	}
+	archive.Conversation.Title = title.String
+	archive.Conversation.Transcript = transcript.String
+	archive.Conversation.Model = model.String
+	if archive.Meta, err = loadMeta(db, conversationID); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query("SELECT sequence_number, payload, created_at FROM messages WHERE conversation_id = ? ORDER BY sequence_number ASC", conversationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query messages for conversation '%s': %w", conversationID, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var msg ArchivedMessage
+		var payload []byte
+		if err := rows.Scan(&msg.SequenceNumber, &payload, &msg.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan message for conversation '%s': %w", conversationID, err)
+		}
+		msg.Payload = json.RawMessage(payload)
+		archive.Messages = append(archive.Messages, msg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during message rows iteration for conversation '%s': %w", conversationID, err)
+	}
+	return &archive, nil
+}
+
+func (store *SQLiteStore) ImportArchive(archive *Archive) (string, error) {
+	db, err := initDB(store.Path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open/initialize database at %s: %w", store.Path, err)
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	id := archive.Conversation.ID
+	var exists int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM conversations WHERE id = ?", id).Scan(&exists); err != nil {
+		return "", fmt.Errorf("failed to check for existing conversation '%s': %w", id, err)
+	}
+	if exists > 0 {
+		if id, err = newConversationID(); err != nil {
+			return "", err
+		}
+	}
+
+	_, err = tx.Exec("INSERT INTO conversations (id, created_at, title, transcript, total_tokens, model) VALUES (?, ?, ?, ?, ?, ?)",
+		id, archive.Conversation.CreatedAt, nullString(archive.Conversation.Title), nullString(archive.Conversation.Transcript), archive.Conversation.TotalTokens, nullString(archive.Conversation.Model))
+	if err != nil {
+		return "", fmt.Errorf("failed to insert conversation '%s': %w", id, err)
+	}
+
+	for key, value := range archive.Meta {
+		if err := setMeta(tx, id, key, value); err != nil {
+			return "", err
+		}
+	}
+
+	stmt, err := tx.Prepare("INSERT INTO messages (conversation_id, sequence_number, payload, created_at) VALUES (?, ?, ?, ?)")
+	if err != nil {
+		return "", err
+	}
+	defer stmt.Close()
+
+	for _, msg := range archive.Messages {
+		if _, err := stmt.Exec(id, msg.SequenceNumber, string(msg.Payload), msg.CreatedAt); err != nil {
+			return "", fmt.Errorf("failed to insert message %d of conversation '%s': %w", msg.SequenceNumber, id, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+func (store *MemoryStore) Archive(conversationID string) (*Archive, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	stored, err := store.find(conversationID)
+	if err != nil {
+		return nil, err
+	}
+	archive := &Archive{
+		Version: ArchiveVersion,
+		Conversation: ArchivedRecord{
+			ID:          stored.conversation.ID,
+			CreatedAt:   stored.conversation.CreatedAt,
+			Title:       stored.title,
+			Transcript:  stored.transcript,
+			TotalTokens: stored.conversation.TotalTokens,
+			Model:       stored.conversation.Model,
+		},
+		Messages: []ArchivedMessage{},
+		Meta:     maps.Clone(stored.conversation.Meta),
+	}
+	for i, payload := range stored.payloads {
+		archive.Messages = append(archive.Messages, ArchivedMessage{
+			SequenceNumber: i,
+			Payload:        json.RawMessage(slices.Clone(payload)),
+			CreatedAt:      stored.messages[i].CreatedAt,
+		})
+	}
+	return archive, nil
+}
+
+func (store *MemoryStore) ImportArchive(archive *Archive) (string, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	id := archive.Conversation.ID
+	if _, found := store.conversations[id]; found {
+		var err error
+		if id, err = newConversationID(); err != nil {
+			return "", err
+		}
+	}
+
+	stored := &storedConversation{
+		conversation: Conversation{
+			ID:          id,
+			CreatedAt:   archive.Conversation.CreatedAt,
+			TotalTokens: archive.Conversation.TotalTokens,
+			Model:       archive.Conversation.Model,
+			Meta:        maps.Clone(archive.Meta),
+		},
+		title:      archive.Conversation.Title,
+		transcript: archive.Conversation.Transcript,
+	}
+	messages := slices.Clone(archive.Messages)
+	slices.SortStableFunc(messages, func(a, b ArchivedMessage) int { return a.SequenceNumber - b.SequenceNumber })
+	for _, msg := range messages {
+		stored.payloads = append(stored.payloads, slices.Clone([]byte(msg.Payload)))
+		stored.messages = append(stored.messages, Message{CreatedAt: msg.CreatedAt})
+	}
+	store.conversations[id] = stored
+	return id, nil
+}
+
+// newConversationID returns a random ID for a conversation, like New.
+func newConversationID() (string, error) {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return "", err
+	}
+	return id.String(), nil
+}
+
+// nullString maps empty strings to NULL so that optional columns round-trip unchanged.
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
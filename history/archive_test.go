@@ -0,0 +1,88 @@
+package history
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestArchiveRoundTrip(t *testing.T) {
+	for name, newStore := range storeFactories {
+		t.Run(name, func(t *testing.T) {
+			testArchiveRoundTrip(t, newStore)
+		})
+	}
+}
+
+func testArchiveRoundTrip(t *testing.T, newStore func(t *testing.T) Store) {
+	createdAt := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	conv := &Conversation{
+		ID:          "archived-conv",
+		CreatedAt:   createdAt,
+		TotalTokens: 1234,
+		Model:       "gemini-2.5-flash",
+		Messages: []*Message{
+			{Payload: map[string]interface{}{"role": "user", "parts": []interface{}{map[string]interface{}{"text": "Hello"}}}, CreatedAt: createdAt.Add(time.Minute)},
+			{Payload: "World", CreatedAt: createdAt.Add(2 * time.Minute)},
+		},
+	}
+	source := newStore(t)
+	if err := source.Save(conv); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	var archive bytes.Buffer
+	if err := exportArchive(source, conv.ID, &archive); err != nil {
+		t.Fatalf("exportArchive failed: %v", err)
+	}
+
+	t.Run("import into empty store keeps the ID", func(t *testing.T) {
+		target := newStore(t)
+		id, err := importArchive(target, bytes.NewReader(archive.Bytes()))
+		if err != nil {
+			t.Fatalf("importArchive failed: %v", err)
+		}
+		if id != conv.ID {
+			t.Errorf("expected ID %q, got %q", conv.ID, id)
+		}
+		loaded, err := target.Load(id)
+		if err != nil {
+			t.Fatalf("Load failed: %v", err)
+		}
+		if diff := cmp.Diff(conv, loaded); diff != "" {
+			t.Errorf("imported conversation mismatch (-want +got):\n%s", diff)
+		}
+		if got := titleOf(t, target, id); got != "Hello" {
+			t.Errorf("expected the title to be imported, got %q", got)
+		}
+	})
+
+	t.Run("import on collision remaps the ID", func(t *testing.T) {
+		id, err := importArchive(source, bytes.NewReader(archive.Bytes()))
+		if err != nil {
+			t.Fatalf("importArchive failed: %v", err)
+		}
+		if id == conv.ID {
+			t.Fatalf("expected a new ID on collision, got the original %q", id)
+		}
+		loaded, err := source.Load(id)
+		if err != nil {
+			t.Fatalf("Load failed: %v", err)
+		}
+		want := *conv
+		want.ID = id
+		if diff := cmp.Diff(&want, loaded); diff != "" {
+			t.Errorf("imported conversation mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("export of unknown conversation fails", func(t *testing.T) {
+		err := exportArchive(source, "does-not-exist", &bytes.Buffer{})
+		if !errors.Is(err, ErrConversationNotFound) {
+			t.Errorf("expected ErrConversationNotFound, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,170 @@
+package history
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+)
+
+// ErrMetaNotFound is returned by GetMeta when a conversation has no value for a key.
+var ErrMetaNotFound = errors.New("history: metadata key not found")
+
+// execer is implemented by both *sql.DB and *sql.Tx.
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// setMeta stores value under key for the conversation. An empty value removes the key.
+func setMeta(db execer, conversationID string, key string, value string) error {
+	if value == "" {
+		if _, err := db.Exec(`DELETE FROM conversation_metadata WHERE conversation_id = ? AND key = ?;`, conversationID, key); err != nil {
+			return fmt.Errorf("failed to remove metadata %q of conversation '%s': %w", key, conversationID, err)
+		}
+		return nil
+	}
+	_, err := db.Exec(`INSERT INTO conversation_metadata (conversation_id, key, value) VALUES (?, ?, ?)
+		ON CONFLICT(conversation_id, key) DO UPDATE SET value = excluded.value;`, conversationID, key, value)
+	if err != nil {
+		return fmt.Errorf("failed to set metadata %q of conversation '%s': %w", key, conversationID, err)
+	}
+	return nil
+}
+
+// loadMeta returns all metadata of the conversation, or nil if it has none.
+func loadMeta(db *sql.DB, conversationID string) (map[string]string, error) {
+	rows, err := db.Query(`SELECT key, value FROM conversation_metadata WHERE conversation_id = ?;`, conversationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query metadata of conversation '%s': %w", conversationID, err)
+	}
+	defer rows.Close()
+	var meta map[string]string
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, fmt.Errorf("failed to scan metadata of conversation '%s': %w", conversationID, err)
+		}
+		if meta == nil {
+			meta = map[string]string{}
+		}
+		meta[key] = value
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during metadata rows iteration for conversation '%s': %w", conversationID, err)
+	}
+	return meta, nil
+}
+
+// SetMeta stores value under key in the metadata of the conversation identified by conversationID
+// in the current store, see SetStore, replacing any previous value. An empty value removes the key.
+// It returns ErrConversationNotFound if there is no such conversation.
+func SetMeta(conversationID string, key string, value string) error {
+	return currentStore().SetMeta(conversationID, key, value)
+}
+
+// metaKey returns key without surrounding whitespace, or an error if nothing is left.
+func metaKey(key string) (string, error) {
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return "", fmt.Errorf("metadata key must not be empty")
+	}
+	return key, nil
+}
+
+func (store *SQLiteStore) SetMeta(conversationID string, key string, value string) error {
+	key, err := metaKey(key)
+	if err != nil {
+		return err
+	}
+	db, err := initDB(store.Path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := conversationExists(db, conversationID); err != nil {
+		return err
+	}
+	return setMeta(db, conversationID, key, value)
+}
+
+// GetMeta returns the value stored under key in the metadata of the conversation identified by conversationID.
+// It returns ErrConversationNotFound if there is no such conversation, and ErrMetaNotFound if the key is not set.
+func GetMeta(conversationID string, key string) (string, error) {
+	meta, err := Meta(conversationID)
+	if err != nil {
+		return "", err
+	}
+	value, ok := meta[key]
+	if !ok {
+		return "", fmt.Errorf("conversation '%s' has no %q: %w", conversationID, key, ErrMetaNotFound)
+	}
+	return value, nil
+}
+
+// Meta returns all metadata of the conversation identified by conversationID in the current store, see SetStore.
+// It returns ErrConversationNotFound if there is no such conversation.
+func Meta(conversationID string) (map[string]string, error) {
+	return currentStore().Meta(conversationID)
+}
+
+func (store *SQLiteStore) Meta(conversationID string) (map[string]string, error) {
+	db, err := initDB(store.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	if err := conversationExists(db, conversationID); err != nil {
+		return nil, err
+	}
+	return loadMeta(db, conversationID)
+}
+
+func (store *MemoryStore) SetMeta(conversationID string, key string, value string) error {
+	key, err := metaKey(key)
+	if err != nil {
+		return err
+	}
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	stored, err := store.find(conversationID)
+	if err != nil {
+		return err
+	}
+	if value == "" {
+		delete(stored.conversation.Meta, key)
+		if len(stored.conversation.Meta) == 0 {
+			stored.conversation.Meta = nil
+		}
+		return nil
+	}
+	if stored.conversation.Meta == nil {
+		stored.conversation.Meta = map[string]string{}
+	}
+	stored.conversation.Meta[key] = value
+	return nil
+}
+
+func (store *MemoryStore) Meta(conversationID string) (map[string]string, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	stored, err := store.find(conversationID)
+	if err != nil {
+		return nil, err
+	}
+	return maps.Clone(stored.conversation.Meta), nil
+}
+
+// conversationExists returns ErrConversationNotFound if db has no conversation with the ID.
+func conversationExists(db *sql.DB, conversationID string) error {
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM conversations WHERE id = ?;`, conversationID).Scan(&count); err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf("conversation with ID '%s' not found: %w", conversationID, ErrConversationNotFound)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package history
+
+import (
+	"bytes"
+	"errors"
+	"maps"
+	"testing"
+	"time"
+)
+
+func TestConversationMeta(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		conv := &Conversation{ID: "with-meta", CreatedAt: time.Now(), Meta: map[string]string{"repo": "/src/smolcode"}}
+		if err := store.Save(conv); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+
+		if err := store.SetMeta("with-meta", "ticket", "SMOL-42"); err != nil {
+			t.Fatalf("SetMeta failed: %v", err)
+		}
+		if meta, err := store.Meta("with-meta"); err != nil || meta["ticket"] != "SMOL-42" {
+			t.Errorf("Meta = %v, %v; want ticket SMOL-42", meta, err)
+		}
+		if err := store.SetMeta("with-meta", " ", "x"); err == nil {
+			t.Errorf("expected an error for an empty key")
+		}
+
+		// Saving the conversation again keeps metadata set by SetMeta.
+		conv.Meta["repo"] = "/src/other"
+		if err := store.Save(conv); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+		loaded, err := store.Load("with-meta")
+		if err != nil {
+			t.Fatalf("Load failed: %v", err)
+		}
+		want := map[string]string{"repo": "/src/other", "ticket": "SMOL-42"}
+		if !maps.Equal(loaded.Meta, want) {
+			t.Errorf("expected loaded metadata %v, got %v", want, loaded.Meta)
+		}
+
+		if err := store.SetMeta("with-meta", "ticket", ""); err != nil {
+			t.Fatalf("SetMeta with empty value failed: %v", err)
+		}
+		if meta, err := store.Meta("with-meta"); err != nil || !maps.Equal(meta, map[string]string{"repo": "/src/other"}) {
+			t.Errorf("expected the ticket to be removed, got %v (%v)", meta, err)
+		}
+
+		if err := store.SetMeta("missing", "repo", "x"); !errors.Is(err, ErrConversationNotFound) {
+			t.Errorf("expected ErrConversationNotFound for SetMeta on a missing conversation, got %v", err)
+		}
+		if _, err := store.Meta("missing"); !errors.Is(err, ErrConversationNotFound) {
+			t.Errorf("expected ErrConversationNotFound for Meta on a missing conversation, got %v", err)
+		}
+	})
+}
+
+func TestGetMetaUsesCurrentStore(t *testing.T) {
+	store := NewMemoryStore()
+	SetStore(store)
+	t.Cleanup(func() { SetStore(nil) })
+	if err := store.Save(&Conversation{ID: "with-meta", CreatedAt: time.Now(), Meta: map[string]string{"ticket": "SMOL-42"}}); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	if value, err := GetMeta("with-meta", "ticket"); err != nil || value != "SMOL-42" {
+		t.Errorf("GetMeta = %q, %v; want SMOL-42", value, err)
+	}
+	if _, err := GetMeta("with-meta", "missing"); !errors.Is(err, ErrMetaNotFound) {
+		t.Errorf("expected ErrMetaNotFound for a missing key, got %v", err)
+	}
+}
+
+func TestConversationMetaTravelsWithArchivesAndIsDeleted(t *testing.T) {
+	forEachStore(t, func(t *testing.T, source Store) {
+		target := NewMemoryStore()
+		conv := &Conversation{ID: "archived", CreatedAt: time.Now(), Meta: map[string]string{"cwd": "/work"}}
+		if err := source.Save(conv); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+
+		var archive bytes.Buffer
+		if err := exportArchive(source, "archived", &archive); err != nil {
+			t.Fatalf("exportArchive failed: %v", err)
+		}
+		id, err := importArchive(target, &archive)
+		if err != nil {
+			t.Fatalf("importArchive failed: %v", err)
+		}
+		if meta, err := target.Meta(id); err != nil || meta["cwd"] != "/work" {
+			t.Errorf("expected imported metadata cwd=/work, got %v (%v)", meta, err)
+		}
+
+		if err := source.Delete("archived"); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		if err := source.Save(&Conversation{ID: "archived", CreatedAt: time.Now()}); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+		if meta, err := source.Meta("archived"); err != nil || len(meta) != 0 {
+			t.Errorf("expected the metadata to be deleted with the conversation, got %v (%v)", meta, err)
+		}
+	})
+}
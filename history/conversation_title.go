@@ -0,0 +1,77 @@
+package history
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// MaxTitleLength is the number of characters of the first user message kept in a generated title.
+const MaxTitleLength = 60
+
+// SetTitle sets the title of the conversation identified by conversationID in the current store, see SetStore.
+// An empty title removes it, so that the next save generates one again.
+// It returns ErrConversationNotFound if there is no such conversation.
+func SetTitle(conversationID string, title string) error {
+	return currentStore().SetTitle(conversationID, title)
+}
+
+func (store *SQLiteStore) SetTitle(conversationID string, title string) error {
+	db, err := initDB(store.Path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	result, err := db.Exec(`UPDATE conversations SET title = ? WHERE id = ?;`, nullString(strings.TrimSpace(title)), conversationID)
+	if err != nil {
+		return err
+	}
+	if updated, err := result.RowsAffected(); err == nil && updated == 0 {
+		return ErrConversationNotFound
+	}
+	return nil
+}
+
+func (store *MemoryStore) SetTitle(conversationID string, title string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	stored, err := store.find(conversationID)
+	if err != nil {
+		return err
+	}
+	stored.title = strings.TrimSpace(title)
+	return nil
+}
+
+// generateTitle derives a title from the text of the first user message among the encoded payloads,
+// with whitespace collapsed and truncated to MaxTitleLength characters.
+// It returns an empty string if no user message contains text.
+func generateTitle(payloads [][]byte) string {
+	for _, data := range payloads {
+		var content struct {
+			Role  string `json:"role"`
+			Parts []struct {
+				Text    string `json:"text"`
+				Thought bool   `json:"thought"`
+			} `json:"parts"`
+		}
+		if err := json.Unmarshal(data, &content); err != nil || content.Role != "user" {
+			continue
+		}
+		var text []string
+		for _, part := range content.Parts {
+			if !part.Thought {
+				text = append(text, part.Text)
+			}
+		}
+		title := strings.Join(strings.Fields(strings.Join(text, " ")), " ")
+		if title == "" {
+			continue
+		}
+		if runes := []rune(title); len(runes) > MaxTitleLength {
+			title = strings.TrimSpace(string(runes[:MaxTitleLength]))
+		}
+		return title
+	}
+	return ""
+}
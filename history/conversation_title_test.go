@@ -0,0 +1,53 @@
+package history
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func titleOf(t *testing.T, store Store, id string) string {
+	t.Helper()
+	conversations, err := store.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	for _, conv := range conversations {
+		if conv.ID == id {
+			return conv.Title
+		}
+	}
+	t.Fatalf("conversation %s not listed", id)
+	return ""
+}
+
+func TestConversationTitles(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		conv := &Conversation{ID: "titled", CreatedAt: time.Now()}
+		conv.Append(map[string]interface{}{"role": "user", "parts": []interface{}{
+			map[string]interface{}{"text": "Please refactor the\n  history package " + strings.Repeat("and more ", 10)},
+		}})
+		if err := store.Save(conv); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+
+		if got, want := titleOf(t, store, "titled"), "Please refactor the history package and more and more and mo"; got != want {
+			t.Errorf("expected generated title %q, got %q", want, got)
+		}
+
+		if err := store.SetTitle("titled", "History refactoring"); err != nil {
+			t.Fatalf("SetTitle failed: %v", err)
+		}
+		if err := store.Save(conv); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+		if got := titleOf(t, store, "titled"); got != "History refactoring" {
+			t.Errorf("expected the title set with SetTitle to be kept, got %q", got)
+		}
+
+		if err := store.SetTitle("missing", "title"); !errors.Is(err, ErrConversationNotFound) {
+			t.Errorf("expected ErrConversationNotFound, got %v", err)
+		}
+	})
+}
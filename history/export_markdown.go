@@ -0,0 +1,135 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ExportMarkdown writes the conversation identified by id from the current store, see SetStore,
+// to w as a Markdown transcript, with a section for every message.
+// Sections are headed by who wrote the message: You, Gemini or Tool, for messages carrying
+// only function responses. Function calls and responses are rendered as fenced JSON blocks.
+func ExportMarkdown(id string, w io.Writer) error {
+	return exportMarkdown(currentStore(), id, w)
+}
+
+// exportMarkdown implements ExportMarkdown for store.
+func exportMarkdown(store Store, id string, w io.Writer) error {
+	conv, err := store.Load(id)
+	if err != nil {
+		return err
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "# Conversation %s\n\n", conv.ID)
+	fmt.Fprintf(&out, "- Created: %s\n", conv.CreatedAt.Format(time.RFC3339))
+	if conv.Model != "" {
+		fmt.Fprintf(&out, "- Model: %s\n", conv.Model)
+	}
+	fmt.Fprintf(&out, "- Messages: %d\n", len(conv.Messages))
+	for _, msg := range conv.Messages {
+		out.WriteString("\n")
+		writeMarkdownMessage(&out, msg)
+	}
+
+	if _, err := io.WriteString(w, out.String()); err != nil {
+		return fmt.Errorf("failed to write transcript of conversation '%s': %w", id, err)
+	}
+	return nil
+}
+
+// writeMarkdownMessage renders a single message as a Markdown section.
+// Payloads that are not message contents, like those added with the history append command, are rendered as they are.
+func writeMarkdownMessage(out *strings.Builder, msg *Message) {
+	timestamp := msg.CreatedAt.Format(time.RFC3339)
+	switch payload := msg.Payload.(type) {
+	case string:
+		fmt.Fprintf(out, "## Message (%s)\n\n%s\n", timestamp, markdownText(payload))
+		return
+	case []byte:
+		fmt.Fprintf(out, "## Unreadable message (%s)\n\n```\n%s\n```\n", timestamp, payload)
+		return
+	case map[string]interface{}:
+		role, _ := payload["role"].(string)
+		parts, _ := payload["parts"].([]interface{})
+		if role != "" || parts != nil {
+			writeMarkdownContent(out, role, parts, timestamp)
+			return
+		}
+	}
+	var body strings.Builder
+	writeJSONBlock(&body, msg.Payload)
+	fmt.Fprintf(out, "## Message (%s)\n\n%s", timestamp, strings.TrimSuffix(body.String(), "\n"))
+}
+
+// writeMarkdownContent renders a message content consisting of role and parts.
+func writeMarkdownContent(out *strings.Builder, role string, parts []interface{}, timestamp string) {
+	var body strings.Builder
+	hasText, hasFunctionResponse := false, false
+	for _, rawPart := range parts {
+		part, _ := rawPart.(map[string]interface{})
+		switch {
+		case part["functionCall"] != nil:
+			call, _ := part["functionCall"].(map[string]interface{})
+			fmt.Fprintf(&body, "**Function call:** `%v`\n\n", call["name"])
+			writeJSONBlock(&body, call["args"])
+		case part["functionResponse"] != nil:
+			hasFunctionResponse = true
+			response, _ := part["functionResponse"].(map[string]interface{})
+			fmt.Fprintf(&body, "**Function response:** `%v`\n\n", response["name"])
+			writeJSONBlock(&body, response["response"])
+		case part["text"] != nil:
+			text := fmt.Sprintf("%v", part["text"])
+			if strings.TrimSpace(text) == "" {
+				continue
+			}
+			if thought, _ := part["thought"].(bool); thought {
+				fmt.Fprintf(&body, "> **Thinking:** %s\n\n", strings.ReplaceAll(strings.TrimSpace(text), "\n", "\n> "))
+				continue
+			}
+			hasText = true
+			fmt.Fprintf(&body, "%s\n\n", markdownText(text))
+		default:
+			fmt.Fprintf(&body, "**Other part:**\n\n")
+			writeJSONBlock(&body, part)
+		}
+	}
+
+	speaker := role
+	switch {
+	case role == "model":
+		speaker = "Gemini"
+	case role == "user" && hasFunctionResponse && !hasText:
+		speaker = "Tool"
+	case role == "user":
+		speaker = "You"
+	case role == "":
+		speaker = "Message"
+	}
+	fmt.Fprintf(out, "## %s (%s)\n\n", speaker, timestamp)
+	if body.Len() == 0 {
+		out.WriteString("_(empty message)_\n")
+		return
+	}
+	if speaker == "Gemini" && !hasText {
+		out.WriteString("_(tool calls only)_\n\n")
+	}
+	out.WriteString(strings.TrimRight(body.String(), "\n") + "\n")
+}
+
+// writeJSONBlock renders value as indented JSON in a fenced code block.
+func writeJSONBlock(out *strings.Builder, value interface{}) {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		data = []byte(fmt.Sprintf("%v", value))
+	}
+	fmt.Fprintf(out, "```json\n%s\n```\n\n", data)
+}
+
+// markdownText trims trailing whitespace from text, so that sections are separated by exactly one blank line.
+func markdownText(text string) string {
+	return strings.TrimRight(text, " \t\n")
+}
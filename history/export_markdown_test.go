@@ -0,0 +1,59 @@
+package history
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportMarkdown(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+		conv := &Conversation{ID: "transcript", CreatedAt: createdAt, Model: "gemini-test"}
+		for _, payload := range []interface{}{
+			map[string]interface{}{"role": "user", "parts": []interface{}{map[string]interface{}{"text": "List the files"}}},
+			map[string]interface{}{"role": "model", "parts": []interface{}{
+				map[string]interface{}{"functionCall": map[string]interface{}{"name": "list_files", "args": map[string]interface{}{"filepath": "."}}},
+			}},
+			map[string]interface{}{"role": "user", "parts": []interface{}{
+				map[string]interface{}{"functionResponse": map[string]interface{}{"name": "list_files", "response": map[string]interface{}{"output": []interface{}{"main.go"}}}},
+			}},
+			map[string]interface{}{"role": "model", "parts": []interface{}{}},
+			"plain note",
+		} {
+			conv.Messages = append(conv.Messages, &Message{Payload: payload, CreatedAt: createdAt})
+		}
+		if err := store.Save(conv); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+
+		var out strings.Builder
+		if err := exportMarkdown(store, "transcript", &out); err != nil {
+			t.Fatalf("ExportMarkdown failed: %v", err)
+		}
+
+		transcript := out.String()
+		for _, want := range []string{
+			"# Conversation transcript\n",
+			"- Model: gemini-test\n",
+			"## You (2024-05-01T12:00:00Z)\n\nList the files\n",
+			"## Gemini (2024-05-01T12:00:00Z)\n\n_(tool calls only)_\n\n**Function call:** `list_files`\n\n```json\n{\n  \"filepath\": \".\"\n}\n```\n",
+			"## Tool (2024-05-01T12:00:00Z)\n\n**Function response:** `list_files`\n\n```json\n",
+			"## Gemini (2024-05-01T12:00:00Z)\n\n_(empty message)_\n",
+			"## Message (2024-05-01T12:00:00Z)\n\nplain note\n",
+		} {
+			if !strings.Contains(transcript, want) {
+				t.Errorf("expected transcript to contain %q, got:\n%s", want, transcript)
+			}
+		}
+	})
+}
+
+func TestExportMarkdownUnknownConversation(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		var out strings.Builder
+		if err := exportMarkdown(store, "missing", &out); err == nil {
+			t.Error("expected an error for an unknown conversation")
+		}
+	})
+}
@@ -3,7 +3,7 @@ package history
 import (
 	"database/sql"
 	_ "embed"
-	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 	"time"
@@ -26,9 +26,14 @@ type Message struct {
 
 // Conversation stores a conversation's ID and its messages.
 type Conversation struct {
-	ID        string
-	Messages  []*Message
-	CreatedAt time.Time
+	ID          string
+	Messages    []*Message
+	CreatedAt   time.Time
+	TotalTokens int    // Tokens consumed by all requests made in this conversation.
+	Model       string // Name of the model last used in this conversation; empty if unknown.
+	// Meta holds arbitrary key/value metadata, e.g. the repository the conversation is about, see SetMeta.
+	// Saving a conversation stores all of its entries, but never removes keys.
+	Meta map[string]string
 }
 
 // New creates a new Conversation with a unique ID and an empty list of messages.
@@ -41,6 +46,7 @@ func New() (*Conversation, error) {
 		ID:        id.String(),
 		Messages:  make([]*Message, 0),
 		CreatedAt: time.Now(),
+		Meta:      map[string]string{},
 	}, nil
 }
 
@@ -54,10 +60,126 @@ func (c *Conversation) Append(payload interface{}) {
 	c.Messages = append(c.Messages, msg)
 }
 
-// initializeSchema creates the database schema if it doesn't exist.
+// initializeSchema creates the database schema if it doesn't exist
+// and upgrades databases created by older versions.
 func initializeSchema(db *sql.DB) error {
-	_, err := db.Exec(schemaSQL)
-	return err
+	if _, err := db.Exec(schemaSQL); err != nil {
+		return err
+	}
+	err := addMissingColumns(db, "conversations", []columnDefinition{
+		{"title", "TEXT"},
+		{"transcript", "TEXT"},
+		{"total_tokens", "INTEGER NOT NULL DEFAULT 0"},
+		{"model", "TEXT"},
+	})
+	if err != nil {
+		return err
+	}
+	return runMigrations(db)
+}
+
+// migrations transform the data of databases created by older versions.
+// The number of migrations applied to a database is recorded in its user_version,
+// so new migrations must only ever be appended.
+var migrations = []func(tx *sql.Tx) error{
+	wrapBarePayloads,
+}
+
+// runMigrations applies all migrations that have not yet been applied to db.
+func runMigrations(db *sql.DB) error {
+	var applied int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&applied); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	for version := applied; version < len(migrations); version++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		if err := migrations[version](tx); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to apply migration %d: %w", version+1, err)
+		}
+		if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", version+1)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to record migration %d: %w", version+1, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// wrapBarePayloads wraps message contents stored by older versions in a versioned envelope.
+func wrapBarePayloads(tx *sql.Tx) error {
+	rows, err := tx.Query("SELECT id, payload FROM messages")
+	if err != nil {
+		return err
+	}
+	wrapped := map[int64][]byte{}
+	for rows.Next() {
+		var id int64
+		var payload []byte
+		if err := rows.Scan(&id, &payload); err != nil {
+			rows.Close()
+			return err
+		}
+		if envelope, ok := wrapContent(payload); ok {
+			wrapped[id] = envelope
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for id, envelope := range wrapped {
+		if _, err := tx.Exec("UPDATE messages SET payload = ? WHERE id = ?", string(envelope), id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// columnDefinition describes a column by its name and SQL type declaration.
+type columnDefinition struct {
+	name        string
+	declaration string
+}
+
+// addMissingColumns adds every column in columns that does not yet exist in table.
+func addMissingColumns(db *sql.DB, table string, columns []columnDefinition) error {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return fmt.Errorf("failed to inspect table %s: %w", table, err)
+	}
+	existing := map[string]bool{}
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, columnType string
+		var defaultValue sql.NullString
+		if err := rows.Scan(&cid, &name, &columnType, &notNull, &defaultValue, &pk); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to inspect table %s: %w", table, err)
+		}
+		existing[name] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to inspect table %s: %w", table, err)
+	}
+
+	for _, column := range columns {
+		if existing[column.name] {
+			continue
+		}
+		if _, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column.name, column.declaration)); err != nil {
+			return fmt.Errorf("failed to add column %s to table %s: %w", column.name, table, err)
+		}
+	}
+	return nil
 }
 
 // initDB ensures the database and tables exist, returning a connection.
@@ -83,10 +205,30 @@ func initDB(dataSourceName string) (*sql.DB, error) {
 	return db, nil
 }
 
+// Migrate creates the database at dbPath if necessary and upgrades it to the current schema,
+// e.g. after restoring a database written by an older version.
+func Migrate(dbPath string) error {
+	db, err := initDB(dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to migrate database %s: %w", dbPath, err)
+	}
+	return db.Close()
+}
+
 // SaveTo persists the conversation to the database at the specified dbPath.
 // It saves the conversation ID and all its messages.
 // If messages for this conversation ID already exist, they are cleared and replaced with the current messages.
+// Conversations without a title are titled after their first user message, see SetTitle.
 func SaveTo(conversation *Conversation, dbPath string) error {
+	payloads := make([][]byte, 0, len(conversation.Messages))
+	for _, msg := range conversation.Messages {
+		jsonBytes, err := encodePayload(msg.Payload) // Marshal only the payload
+		if err != nil {
+			return err
+		}
+		payloads = append(payloads, jsonBytes)
+	}
+
 	db, err := initDB(dbPath)
 	if err != nil {
 		return err
@@ -98,12 +240,29 @@ func SaveTo(conversation *Conversation, dbPath string) error {
 		return err
 	}
 
-	_, err = tx.Exec(`INSERT OR IGNORE INTO conversations (id, created_at) VALUES (?, ?);`, conversation.ID, conversation.CreatedAt)
+	_, err = tx.Exec(`INSERT INTO conversations (id, created_at, total_tokens, model) VALUES (?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET total_tokens = excluded.total_tokens, model = excluded.model;`,
+		conversation.ID, conversation.CreatedAt, conversation.TotalTokens, nullString(conversation.Model))
 	if err != nil {
 		tx.Rollback()
 		return err
 	}
 
+	if title := generateTitle(payloads); title != "" {
+		_, err = tx.Exec(`UPDATE conversations SET title = ? WHERE id = ? AND (title IS NULL OR title = '');`, title, conversation.ID)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	for key, value := range conversation.Meta {
+		if err := setMeta(tx, conversation.ID, key, value); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
 	_, err = tx.Exec(`DELETE FROM messages WHERE conversation_id = ?;`, conversation.ID)
 	if err != nil {
 		tx.Rollback()
@@ -118,12 +277,7 @@ func SaveTo(conversation *Conversation, dbPath string) error {
 	defer stmt.Close()
 
 	for i, msg := range conversation.Messages {
-		jsonBytes, jsonErr := json.Marshal(msg.Payload) // Marshal only the payload
-		if jsonErr != nil {
-			tx.Rollback()
-			return jsonErr
-		}
-		payloadString := string(jsonBytes)                                   // Convert byte slice to string
+		payloadString := string(payloads[i])                                 // Convert byte slice to string
 		_, err = stmt.Exec(conversation.ID, i, payloadString, msg.CreatedAt) // Store as string
 		if err != nil {
 			tx.Rollback()
@@ -134,9 +288,39 @@ func SaveTo(conversation *Conversation, dbPath string) error {
 	return tx.Commit()
 }
 
-// Save persists the conversation to the database using the DefaultDatabasePath.
-// It saves the conversation ID and all its messages.
-// If messages for this conversation ID already exist, they are cleared and replaced with the current messages.
-func Save(conversation *Conversation) error {
-	return SaveTo(conversation, DefaultDatabasePath)
+// DeleteConversation removes a conversation and its messages from the database at dbPath.
+// It returns ErrConversationNotFound if there is no such conversation.
+func DeleteConversation(conversationID string, dbPath string) error {
+	db, err := initDB(dbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM messages WHERE conversation_id = ?;`, conversationID); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM session_summaries WHERE conversation_id = ?;`, conversationID); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM conversation_metadata WHERE conversation_id = ?;`, conversationID); err != nil {
+		tx.Rollback()
+		return err
+	}
+	result, err := tx.Exec(`DELETE FROM conversations WHERE id = ?;`, conversationID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if deleted, err := result.RowsAffected(); err == nil && deleted == 0 {
+		tx.Rollback()
+		return ErrConversationNotFound
+	}
+	return tx.Commit()
 }
@@ -1,32 +1,39 @@
 package history
 
-import (
-	"database/sql"
-	"fmt"
+import "fmt"
 
-	_ "github.com/mattn/go-sqlite3" // SQLite driver
-)
-
-// GetLatestConversationID retrieves the ID of the most recent conversation
-// from the database at the given dbPath.
+// GetLatestConversationID retrieves the ID of the conversation with the most recent activity
+// from the current store, see SetStore, using the same ordering as List.
 // It returns the conversation ID and nil on success.
 // If no conversations are found, it returns an empty string and ErrConversationNotFound.
-// Other errors from database interaction are returned as well, potentially wrapped.
-func GetLatestConversationID(dbPath string) (string, error) {
-	db, err := initDB(dbPath) // initDB is defined in history.go
-	if err != nil {
-		return "", fmt.Errorf("failed to open/initialize database at %s: %w", dbPath, err)
-	}
-	defer db.Close()
+// Other errors from the store are returned as well, potentially wrapped.
+func GetLatestConversationID() (string, error) {
+	return GetNthLatestConversationID(1)
+}
 
-	var id string
-	query := "SELECT id FROM conversations ORDER BY created_at DESC LIMIT 1"
-	err = db.QueryRow(query).Scan(&id)
+// GetNthLatestConversationID retrieves the ID of the conversation with the n-th most recent activity
+// from the current store, see SetStore, counting from 1 for the latest conversation.
+// If there are no conversations, it returns ErrConversationNotFound.
+// If there are fewer than n conversations, the returned error wraps ErrConversationNotFound
+// and tells how many conversations there are.
+func GetNthLatestConversationID(n int) (string, error) {
+	return nthLatestConversationID(currentStore(), n)
+}
+
+// nthLatestConversationID implements GetNthLatestConversationID for store.
+func nthLatestConversationID(store Store, n int) (string, error) {
+	if n < 1 {
+		return "", fmt.Errorf("invalid conversation index %d, the latest conversation is 1", n)
+	}
+	conversations, err := store.List()
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return "", ErrConversationNotFound // Return custom error
-		}
 		return "", fmt.Errorf("failed to query for latest conversation ID: %w", err)
 	}
-	return id, nil
+	if len(conversations) == 0 {
+		return "", ErrConversationNotFound
+	}
+	if n > len(conversations) {
+		return "", fmt.Errorf("%w: asked for conversation %d, but there are only %d", ErrConversationNotFound, n, len(conversations))
+	}
+	return conversations[n-1].ID, nil
 }
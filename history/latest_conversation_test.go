@@ -1,20 +1,19 @@
 package history
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
-// Helper to create a test DB and save conversations
-func setupTestDB(t *testing.T, conversationsToCreate []Conversation) string {
+// saveConversations saves copies of the conversations to store.
+func saveConversations(t *testing.T, store Store, conversationsToCreate []Conversation) {
 	t.Helper()
-	tempDir := t.TempDir()
-	dbPath := filepath.Join(tempDir, "test_history.db")
-
 	for _, conv := range conversationsToCreate {
 		// Ensure CreatedAt is set if not provided for ordering
 		if conv.CreatedAt.IsZero() {
@@ -22,12 +21,11 @@ func setupTestDB(t *testing.T, conversationsToCreate []Conversation) string {
 			// Add a small delay to ensure distinct created_at for ordering tests
 			time.Sleep(10 * time.Millisecond)
 		}
-		err := SaveTo(&conv, dbPath) // SaveTo uses initDB internally
+		err := store.Save(&conv)
 		if err != nil {
 			t.Fatalf("Failed to save conversation %s for test setup: %v", conv.ID, err)
 		}
 	}
-	return dbPath
 }
 
 func TestGetLatestConversationID(t *testing.T) {
@@ -74,28 +72,24 @@ func TestGetLatestConversationID(t *testing.T) {
 	}
 
 	for _, tc := range tests {
-		t.Run(tc.name, func(t *testing.T) {
-			dbPath := setupTestDB(t, tc.conversationsToCreate)
+		forEachStore(t, func(t *testing.T, store Store) {
+			t.Run(tc.name, func(t *testing.T) {
+				saveConversations(t, store, tc.conversationsToCreate)
 
-			actualID, actualErr := GetLatestConversationID(dbPath)
+				actualID, actualErr := nthLatestConversationID(store, 1)
 
-			if actualErr != tc.expectedErr {
-				// Special handling for sql.ErrNoRows as it might be wrapped by fmt.Errorf in some implementations
-				// However, our GetLatestConversationID returns it directly.
-				t.Errorf("GetLatestConversationID() error = %v, wantErr %v", actualErr, tc.expectedErr)
-			}
-			if actualID != tc.expectedID {
-				t.Errorf("GetLatestConversationID() id = %s, want %s", actualID, tc.expectedID)
-			}
-
-			// Clean up the test database file if not using t.TempDir(), but t.TempDir() handles it.
-			// os.Remove(dbPath)
+				if actualErr != tc.expectedErr {
+					t.Errorf("GetLatestConversationID() error = %v, wantErr %v", actualErr, tc.expectedErr)
+				}
+				if actualID != tc.expectedID {
+					t.Errorf("GetLatestConversationID() id = %s, want %s", actualID, tc.expectedID)
+				}
+			})
 		})
 	}
 }
 
 // TestGetLatestConversationID_DBNotExists tests behavior when the DB file doesn't exist.
-// initDB (called by GetLatestConversationID) should create it.
 func TestGetLatestConversationID_DBNotExists(t *testing.T) {
 	tempDir := t.TempDir()
 	nonExistentDBPath := filepath.Join(tempDir, "non_existent.db")
@@ -106,7 +100,7 @@ func TestGetLatestConversationID_DBNotExists(t *testing.T) {
 		t.Fatalf("DB file %s unexpectedly exists or other error: %v", nonExistentDBPath, err)
 	}
 
-	id, err := GetLatestConversationID(nonExistentDBPath)
+	id, err := nthLatestConversationID(NewSQLiteStore(nonExistentDBPath), 1)
 	if err != ErrConversationNotFound {
 		t.Errorf("Expected sql.ErrNoRows when DB is new and empty, got %v", err)
 	}
@@ -114,3 +108,27 @@ func TestGetLatestConversationID_DBNotExists(t *testing.T) {
 		t.Errorf("Expected empty ID when DB is new and empty, got %s", id)
 	}
 }
+
+func TestGetNthLatestConversationID(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		saveConversations(t, store, []Conversation{
+			{ID: "oldest", CreatedAt: time.Now().Add(-3 * time.Hour)},
+			{ID: "latest", CreatedAt: time.Now().Add(-1 * time.Hour)},
+			{ID: "middle", CreatedAt: time.Now().Add(-2 * time.Hour)},
+		})
+
+		for n, want := range map[int]string{1: "latest", 2: "middle", 3: "oldest"} {
+			if id, err := nthLatestConversationID(store, n); err != nil || id != want {
+				t.Errorf("GetNthLatestConversationID(%d) = %q, %v, want %q", n, id, err, want)
+			}
+		}
+
+		_, err := nthLatestConversationID(store, 4)
+		if !errors.Is(err, ErrConversationNotFound) || !strings.Contains(err.Error(), "only 3") {
+			t.Errorf("expected an out of range error naming the number of conversations, got %v", err)
+		}
+		if _, err := nthLatestConversationID(store, 0); err == nil {
+			t.Error("expected an error for index 0")
+		}
+	})
+}
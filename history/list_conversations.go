@@ -9,13 +9,14 @@ import (
 	_ "github.com/mattn/go-sqlite3" // SQLite driver
 )
 
-// ListConversations retrieves metadata for all stored conversations from the specified SQLite database.
+// ListConversations retrieves metadata for all stored conversations from the specified SQLite database,
+// sorted by last activity, most recent first.
 func ListConversations(dbPath string) ([]ConversationMetadata, error) {
 	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
 		return nil, fmt.Errorf("database file does not exist: %s", dbPath)
 	}
 
-	db, err := sql.Open("sqlite3", dbPath)
+	db, err := initDB(dbPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -24,6 +25,8 @@ func ListConversations(dbPath string) ([]ConversationMetadata, error) {
 	query := `
 		SELECT
 			c.id,
+			COALESCE(c.title, ''),
+			c.total_tokens,
 			c.created_at,
 			COUNT(m.id) as message_count,
 			COALESCE(MAX(m.created_at), c.created_at) as latest_message_at 
@@ -55,7 +58,7 @@ func ListConversations(dbPath string) ([]ConversationMetadata, error) {
 		var createdAtStr string
 		var latestTimestampStr string
 
-		if err := rows.Scan(&meta.ID, &createdAtStr, &meta.MessageCount, &latestTimestampStr); err != nil {
+		if err := rows.Scan(&meta.ID, &meta.Title, &meta.TotalTokens, &createdAtStr, &meta.MessageCount, &latestTimestampStr); err != nil {
 			return nil, fmt.Errorf("failed to scan conversation metadata: %w", err)
 		}
 
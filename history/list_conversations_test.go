@@ -2,6 +2,7 @@ package history
 
 import (
 	"testing"
+	"time"
 	// "path/filepath" // Will need this later for test DBs
 	// "os"            // Will need this later for test DBs
 )
@@ -171,3 +172,47 @@ func TestListConversations(t *testing.T) {
 // For now, these tests mostly assert that `ListConversations` can be called
 // and makes some very basic checks that will likely fail, fulfilling the
 // "tests fail as expected before implementation" criterion in a broad sense.
+
+func TestListConversationsSortedByLastActivity(t *testing.T) {
+	now := time.Now().UTC()
+	older := &Conversation{ID: "created-first-active-last", CreatedAt: now.Add(-3 * time.Hour), TotalTokens: 42, Messages: []*Message{
+		{Payload: "recent message", CreatedAt: now.Add(-time.Minute)},
+	}}
+	newer := &Conversation{ID: "created-last-no-messages", CreatedAt: now.Add(-time.Hour), Messages: []*Message{}}
+	dbPath := createTestDB(t, older, newer)
+
+	db, err := initDB(dbPath)
+	if err != nil {
+		t.Fatalf("initDB failed: %v", err)
+	}
+	if _, err := db.Exec("UPDATE conversations SET title = ? WHERE id = ?", "Active one", older.ID); err != nil {
+		t.Fatalf("failed to set title: %v", err)
+	}
+	db.Close()
+
+	conversations, err := ListConversations(dbPath)
+	if err != nil {
+		t.Fatalf("ListConversations failed: %v", err)
+	}
+	if len(conversations) != 2 {
+		t.Fatalf("expected 2 conversations, got %d", len(conversations))
+	}
+	first := conversations[0]
+	if first.ID != older.ID {
+		t.Errorf("expected the conversation with the latest message first, got %s", first.ID)
+	}
+	if first.Title != "Active one" || first.TotalTokens != 42 || first.MessageCount != 1 {
+		t.Errorf("unexpected metadata: %+v", first)
+	}
+	if conversations[1].Title != "" || conversations[1].MessageCount != 0 {
+		t.Errorf("unexpected metadata for conversation without messages: %+v", conversations[1])
+	}
+
+	latestID, err := nthLatestConversationID(NewSQLiteStore(dbPath), 1)
+	if err != nil {
+		t.Fatalf("GetLatestConversationID failed: %v", err)
+	}
+	if latestID != first.ID {
+		t.Errorf("GetLatestConversationID() = %s, want %s", latestID, first.ID)
+	}
+}
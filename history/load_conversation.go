@@ -2,7 +2,6 @@ package history
 
 import (
 	"database/sql"
-	"encoding/json"
 	"fmt"
 	"sort"
 	"time"
@@ -22,8 +21,8 @@ func LoadFrom(conversationID string, dbPath string) (*Conversation, error) {
 
 	conv := &Conversation{ID: conversationID, Messages: make([]*Message, 0)}
 
-	// Load conversation metadata (specifically CreatedAt)
-	err = db.QueryRow("SELECT created_at FROM conversations WHERE id = ?", conversationID).Scan(&conv.CreatedAt)
+	// Load conversation metadata
+	err = db.QueryRow("SELECT created_at, total_tokens, COALESCE(model, '') FROM conversations WHERE id = ?", conversationID).Scan(&conv.CreatedAt, &conv.TotalTokens, &conv.Model)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("conversation with ID '%s' not found: %w", conversationID, err) // Consider a custom error type e.g. ErrConversationNotFound
@@ -31,6 +30,11 @@ func LoadFrom(conversationID string, dbPath string) (*Conversation, error) {
 		return nil, fmt.Errorf("failed to query conversation metadata for ID '%s': %w", conversationID, err)
 	}
 
+	conv.Meta, err = loadMeta(db, conversationID)
+	if err != nil {
+		return nil, err
+	}
+
 	// Load messages for the conversation
 	rows, err := db.Query("SELECT sequence_number, payload, created_at FROM messages WHERE conversation_id = ? ORDER BY sequence_number ASC", conversationID)
 	if err != nil {
@@ -54,9 +58,13 @@ func LoadFrom(conversationID string, dbPath string) (*Conversation, error) {
 			return nil, fmt.Errorf("failed to scan message for conversation ID '%s': %w", conversationID, err)
 		}
 
-		if err := json.Unmarshal(payloadJSON, &msg.Payload); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal message payload for conversation ID '%s': %w", conversationID, err)
+		payload, err := decodePayload(payloadJSON)
+		if err != nil {
+			// Keep the raw bytes of corrupt payloads instead of failing the whole conversation,
+			// so that callers can decide how to recover.
+			payload = payloadJSON
 		}
+		msg.Payload = payload
 		msg.CreatedAt = createdAt
 		tempMessages = append(tempMessages, indexedMessage{seq: seq, message: msg})
 	}
@@ -76,8 +84,3 @@ func LoadFrom(conversationID string, dbPath string) (*Conversation, error) {
 
 	return conv, nil
 }
-
-// Load retrieves a specific conversation and its messages from the DefaultDatabasePath.
-func Load(conversationID string) (*Conversation, error) {
-	return LoadFrom(conversationID, DefaultDatabasePath)
-}
@@ -0,0 +1,94 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// PayloadVersion is the version of the envelope in which message contents are stored.
+//
+// Message contents are objects consisting of a role and parts. Instead of storing
+// them exactly as they were marshalled, they are wrapped in an envelope like
+//
+//	{"v": 1, "role": "user", "parts": [...]}
+//
+// so that loading can keep reading old rows even if the shape of the contents changes.
+// Payloads of any other shape are stored as plain JSON.
+const PayloadVersion = 1
+
+// payloadEnvelope is the stored form of a message content.
+type payloadEnvelope struct {
+	V     int             `json:"v"`
+	Role  json.RawMessage `json:"role,omitempty"`
+	Parts json.RawMessage `json:"parts,omitempty"`
+}
+
+// envelopeKeys are the keys that may appear in a stored envelope, mapped to whether they are required.
+var envelopeKeys = map[string]bool{"v": true, "role": false, "parts": false}
+
+// encodePayload marshals payload for storage, wrapping message contents in a versioned envelope.
+func encodePayload(payload interface{}) ([]byte, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	if wrapped, ok := wrapContent(data); ok {
+		return wrapped, nil
+	}
+	return data, nil
+}
+
+// wrapContent wraps data in a versioned envelope if it is a bare message content.
+// It reports false if data is anything else, including a payload that is already wrapped.
+func wrapContent(data []byte) ([]byte, bool) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil || len(fields) == 0 {
+		return nil, false
+	}
+	for key := range fields {
+		if key != "role" && key != "parts" {
+			return nil, false
+		}
+	}
+	wrapped, err := json.Marshal(payloadEnvelope{V: PayloadVersion, Role: fields["role"], Parts: fields["parts"]})
+	if err != nil {
+		return nil, false
+	}
+	return wrapped, true
+}
+
+// decodePayload unmarshals a stored payload, unwrapping it if it is stored in an envelope.
+// Payloads without an envelope are returned as they were stored.
+func decodePayload(data []byte) (interface{}, error) {
+	var payload interface{}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, err
+	}
+	fields, ok := payload.(map[string]interface{})
+	if !ok || !isEnvelope(fields) {
+		return payload, nil
+	}
+
+	switch version := fields["v"]; version {
+	case float64(1):
+		delete(fields, "v")
+		return fields, nil
+	default:
+		return nil, fmt.Errorf("unsupported payload version %v", version)
+	}
+}
+
+// isEnvelope reports whether fields make up a versioned envelope.
+func isEnvelope(fields map[string]interface{}) bool {
+	for key, required := range envelopeKeys {
+		if _, found := fields[key]; required && !found {
+			return false
+		}
+	}
+	for key := range fields {
+		if _, known := envelopeKeys[key]; !known {
+			return false
+		}
+	}
+	return true
+}
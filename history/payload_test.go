@@ -0,0 +1,117 @@
+package history
+
+import (
+	"database/sql"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLoadLegacyAndVersionedPayloads(t *testing.T) {
+	db, dbPath, cleanup := newTestDB(t)
+	defer cleanup()
+
+	const conversationID = "mixed-payloads"
+	createdAt := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	if _, err := db.Exec("INSERT INTO conversations (id, created_at) VALUES (?, ?)", conversationID, createdAt); err != nil {
+		t.Fatalf("failed to insert conversation: %v", err)
+	}
+	storedPayloads := []string{
+		`{"role":"user","parts":[{"text":"legacy"}]}`,
+		`{"v":1,"role":"model","parts":[{"text":"versioned"}]}`,
+		`"plain string"`,
+	}
+	for i, payload := range storedPayloads {
+		if _, err := db.Exec("INSERT INTO messages (conversation_id, sequence_number, payload, created_at) VALUES (?, ?, ?, ?)",
+			conversationID, i, payload, createdAt); err != nil {
+			t.Fatalf("failed to insert message %d: %v", i, err)
+		}
+	}
+
+	loaded, err := LoadFrom(conversationID, dbPath)
+	if err != nil {
+		t.Fatalf("LoadFrom failed: %v", err)
+	}
+
+	want := []interface{}{
+		map[string]interface{}{"role": "user", "parts": []interface{}{map[string]interface{}{"text": "legacy"}}},
+		map[string]interface{}{"role": "model", "parts": []interface{}{map[string]interface{}{"text": "versioned"}}},
+		"plain string",
+	}
+	var got []interface{}
+	for _, msg := range loaded.Messages {
+		got = append(got, msg.Payload)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("loaded payloads mismatch (-want +got):\n%s", diff)
+	}
+
+	t.Run("migration wraps legacy payloads", func(t *testing.T) {
+		var payload string
+		if err := db.QueryRow("SELECT payload FROM messages WHERE conversation_id = ? AND sequence_number = 0", conversationID).Scan(&payload); err != nil {
+			t.Fatalf("failed to query migrated payload: %v", err)
+		}
+		var fields map[string]interface{}
+		if err := json.Unmarshal([]byte(payload), &fields); err != nil {
+			t.Fatalf("failed to unmarshal migrated payload %s: %v", payload, err)
+		}
+		if fields["v"] != float64(PayloadVersion) {
+			t.Errorf("expected migrated payload to have version %d, got %s", PayloadVersion, payload)
+		}
+
+		var plain string
+		if err := db.QueryRow("SELECT payload FROM messages WHERE conversation_id = ? AND sequence_number = 2", conversationID).Scan(&plain); err != nil {
+			t.Fatalf("failed to query plain payload: %v", err)
+		}
+		if plain != storedPayloads[2] {
+			t.Errorf("expected plain payload to be left alone, got %s", plain)
+		}
+	})
+
+	t.Run("save wraps contents", func(t *testing.T) {
+		if err := SaveTo(loaded, dbPath); err != nil {
+			t.Fatalf("SaveTo failed: %v", err)
+		}
+		var payload string
+		if err := db.QueryRow("SELECT payload FROM messages WHERE conversation_id = ? AND sequence_number = 1", conversationID).Scan(&payload); err != nil {
+			t.Fatalf("failed to query saved payload: %v", err)
+		}
+		if payload != storedPayloads[1] {
+			t.Errorf("expected saved payload %s, got %s", storedPayloads[1], payload)
+		}
+	})
+
+	t.Run("unsupported version is kept raw", func(t *testing.T) {
+		const future = `{"v":99,"role":"user","parts":[]}`
+		if _, err := db.Exec("UPDATE messages SET payload = ? WHERE conversation_id = ? AND sequence_number = 0", future, conversationID); err != nil {
+			t.Fatalf("failed to update payload: %v", err)
+		}
+		loaded, err := LoadFrom(conversationID, dbPath)
+		if err != nil {
+			t.Fatalf("LoadFrom failed: %v", err)
+		}
+		raw, ok := loaded.Messages[0].Payload.([]byte)
+		if !ok || string(raw) != future {
+			t.Errorf("expected raw payload %s, got %#v", future, loaded.Messages[0].Payload)
+		}
+	})
+}
+
+func TestMigrationsRunOnce(t *testing.T) {
+	dbPath := createTestDB(t)
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		t.Fatalf("failed to read user_version: %v", err)
+	}
+	if version != len(migrations) {
+		t.Errorf("expected user_version %d, got %d", len(migrations), version)
+	}
+}
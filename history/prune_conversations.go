@@ -0,0 +1,80 @@
+package history
+
+import (
+	"errors"
+	"os"
+	"time"
+)
+
+// PruneOlderThan deletes all conversations in the current store, see SetStore, whose last activity,
+// the time of their latest message or their creation if they have none, is before cutoff.
+// Messages are deleted along with their conversation. It returns the number of deleted conversations.
+func PruneOlderThan(cutoff time.Time) (int, error) {
+	return currentStore().PruneOlderThan(cutoff)
+}
+
+func (store *SQLiteStore) PruneOlderThan(cutoff time.Time) (int, error) {
+	if _, err := os.Stat(store.Path); errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	conversations, err := ListConversations(store.Path)
+	if err != nil {
+		return 0, err
+	}
+
+	db, err := initDB(store.Path)
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	pruned := 0
+	for _, conv := range conversations {
+		if !conv.LatestMessageTime.Before(cutoff) {
+			continue
+		}
+		if _, err := tx.Exec(`DELETE FROM messages WHERE conversation_id = ?;`, conv.ID); err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+		if _, err := tx.Exec(`DELETE FROM session_summaries WHERE conversation_id = ?;`, conv.ID); err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+		if _, err := tx.Exec(`DELETE FROM conversation_metadata WHERE conversation_id = ?;`, conv.ID); err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+		if _, err := tx.Exec(`DELETE FROM conversations WHERE id = ?;`, conv.ID); err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+		pruned++
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return pruned, nil
+}
+
+func (store *MemoryStore) PruneOlderThan(cutoff time.Time) (int, error) {
+	conversations, err := store.List()
+	if err != nil {
+		return 0, err
+	}
+	pruned := 0
+	for _, conv := range conversations {
+		if !conv.LatestMessageTime.Before(cutoff) {
+			continue
+		}
+		if err := store.Delete(conv.ID); err != nil && !errors.Is(err, ErrConversationNotFound) {
+			return pruned, err
+		}
+		pruned++
+	}
+	return pruned, nil
+}
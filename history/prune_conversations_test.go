@@ -0,0 +1,47 @@
+package history
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPruneOlderThan(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		now := time.Now().UTC()
+		stale := &Conversation{ID: "stale", CreatedAt: now.Add(-72 * time.Hour), Messages: []*Message{
+			{Payload: "old message", CreatedAt: now.Add(-48 * time.Hour)},
+		}}
+		revived := &Conversation{ID: "revived", CreatedAt: now.Add(-72 * time.Hour), Messages: []*Message{
+			{Payload: "recent message", CreatedAt: now.Add(-time.Hour)},
+		}}
+		empty := &Conversation{ID: "empty", CreatedAt: now.Add(-72 * time.Hour)}
+		for _, conv := range []*Conversation{stale, revived, empty} {
+			if err := store.Save(conv); err != nil {
+				t.Fatalf("Save failed: %v", err)
+			}
+		}
+
+		pruned, err := store.PruneOlderThan(now.Add(-24 * time.Hour))
+		if err != nil {
+			t.Fatalf("PruneOlderThan failed: %v", err)
+		}
+
+		if pruned != 2 {
+			t.Errorf("expected 2 conversations to be pruned, got %d", pruned)
+		}
+		if _, err := store.Load("revived"); err != nil {
+			t.Errorf("expected the recently active conversation to be kept: %v", err)
+		}
+		if err := store.Delete("stale"); !errors.Is(err, ErrConversationNotFound) {
+			t.Errorf("expected the stale conversation to be gone, got %v", err)
+		}
+	})
+}
+
+func TestPruneOlderThanWithoutDatabase(t *testing.T) {
+	store := storeFactories["sqlite"](t)
+	if pruned, err := store.PruneOlderThan(time.Now()); err != nil || pruned != 0 {
+		t.Errorf("expected nothing to prune without a database, got %d, %v", pruned, err)
+	}
+}
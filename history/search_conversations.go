@@ -0,0 +1,94 @@
+package history
+
+import (
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3" // SQLite driver
+)
+
+// SearchConversations returns metadata for all conversations in the current store, see SetStore, matching query,
+// ordered like List, most recent activity first.
+// Matching is a case-insensitive substring search on the conversation title
+// and, unless titlesOnly is set, on the message payloads.
+func SearchConversations(query string, titlesOnly bool) ([]ConversationMetadata, error) {
+	return currentStore().Search(query, titlesOnly)
+}
+
+func (store *SQLiteStore) Search(query string, titlesOnly bool) ([]ConversationMetadata, error) {
+	all, err := store.List()
+	if err != nil || len(all) == 0 {
+		return all, err
+	}
+
+	db, err := sql.Open("sqlite3", store.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	pattern := "%" + escapeLikePattern(query) + "%"
+	sqlQuery := `SELECT id FROM conversations WHERE title LIKE ? ESCAPE '\'`
+	args := []any{pattern}
+	if !titlesOnly {
+		sqlQuery += ` UNION SELECT DISTINCT conversation_id FROM messages WHERE payload LIKE ? ESCAPE '\'`
+		args = append(args, pattern)
+	}
+
+	rows, err := db.Query(sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search conversations: %w", err)
+	}
+	defer rows.Close()
+
+	matches := map[string]bool{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan conversation ID: %w", err)
+		}
+		matches[id] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	result := []ConversationMetadata{}
+	for _, meta := range all {
+		if matches[meta.ID] {
+			result = append(result, meta)
+		}
+	}
+	return result, nil
+}
+
+func (store *MemoryStore) Search(query string, titlesOnly bool) ([]ConversationMetadata, error) {
+	all, err := store.List()
+	if err != nil {
+		return nil, err
+	}
+	query = strings.ToLower(query)
+	matches := func(text string) bool { return strings.Contains(strings.ToLower(text), query) }
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	result := []ConversationMetadata{}
+	for _, meta := range all {
+		stored, found := store.conversations[meta.ID]
+		if !found {
+			continue // Deleted since listing
+		}
+		inPayloads := !titlesOnly && slices.ContainsFunc(stored.payloads, func(payload []byte) bool { return matches(string(payload)) })
+		if matches(stored.title) || inPayloads {
+			result = append(result, meta)
+		}
+	}
+	return result, nil
+}
+
+// escapeLikePattern escapes the wildcard characters of a LIKE pattern using '\' as escape character.
+func escapeLikePattern(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,63 @@
+package history
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSearchConversations(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		now := time.Now()
+		for _, conv := range []*Conversation{
+			{ID: "conv-cache", CreatedAt: now.Add(-2 * time.Hour), Messages: []*Message{
+				{Payload: "How does the Context Cache work?", CreatedAt: now.Add(-2 * time.Hour)},
+			}},
+			{ID: "conv-planner", CreatedAt: now.Add(-time.Hour), Messages: []*Message{
+				{Payload: "Let's refactor the planner", CreatedAt: now.Add(-time.Hour)},
+			}},
+		} {
+			if err := store.Save(conv); err != nil {
+				t.Fatalf("Save failed: %v", err)
+			}
+		}
+		if err := store.SetTitle("conv-planner", "Planner 100% rewrite"); err != nil {
+			t.Fatalf("SetTitle failed: %v", err)
+		}
+
+		tests := []struct {
+			name       string
+			query      string
+			titlesOnly bool
+			want       []string
+		}{
+			{name: "message body, case-insensitive", query: "context cache", want: []string{"conv-cache"}},
+			{name: "title and body match once", query: "planner", want: []string{"conv-planner"}},
+			{name: "titles only ignores bodies", query: "cache", titlesOnly: true, want: []string{}},
+			{name: "wildcards are literal", query: "100%", titlesOnly: true, want: []string{"conv-planner"}},
+			{name: "no match", query: "nothing like this", want: []string{}},
+			{name: "empty query matches everything", query: "", want: []string{"conv-planner", "conv-cache"}},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				got, err := store.Search(tt.query, tt.titlesOnly)
+				if err != nil {
+					t.Fatalf("Search failed: %v", err)
+				}
+				gotIDs := []string{}
+				for _, meta := range got {
+					gotIDs = append(gotIDs, meta.ID)
+				}
+				if len(gotIDs) != len(tt.want) {
+					t.Fatalf("expected %v, got %v", tt.want, gotIDs)
+				}
+				for i := range tt.want {
+					if gotIDs[i] != tt.want[i] {
+						t.Errorf("expected %v, got %v", tt.want, gotIDs)
+						break
+					}
+				}
+			})
+		}
+	})
+}
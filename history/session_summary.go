@@ -0,0 +1,135 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// SessionSummary describes a single run of the agent on a conversation.
+type SessionSummary struct {
+	ConversationID  string         `json:"conversationId"`
+	StartedAt       time.Time      `json:"startedAt"`
+	EndedAt         time.Time      `json:"endedAt"`
+	DurationSeconds float64        `json:"durationSeconds"`
+	Turns           int            `json:"turns"`     // Messages sent by the user.
+	ToolCalls       map[string]int `json:"toolCalls"` // Number of calls, keyed by tool name.
+	FilesModified   []string       `json:"filesModified"`
+	Tokens          SessionTokens  `json:"tokens"`
+	Plan            *SessionPlan   `json:"plan,omitempty"` // The plan last used during the session, if any.
+}
+
+// SessionTokens are the tokens used during a session.
+type SessionTokens struct {
+	Prompt      int64   `json:"prompt"`
+	Candidates  int64   `json:"candidates"`
+	Cached      int64   `json:"cached"`
+	Thoughts    int64   `json:"thoughts"`
+	Cost        float64 `json:"cost"`
+	CostUnknown bool    `json:"costUnknown,omitempty"` // Set if a model without known prices was used.
+}
+
+// SessionPlan is the status of a plan at the end of a session.
+type SessionPlan struct {
+	Name           string `json:"name"`
+	Status         string `json:"status"` // "DONE" or "TODO", empty if the plan could not be read.
+	CompletedSteps int    `json:"completedSteps"`
+	TotalSteps     int    `json:"totalSteps"`
+}
+
+// SaveSessionSummary adds summary to the summaries of its conversation in the current store, see SetStore.
+func SaveSessionSummary(summary SessionSummary) error {
+	return currentStore().SaveSessionSummary(summary)
+}
+
+func (store *SQLiteStore) SaveSessionSummary(summary SessionSummary) error {
+	return SaveSessionSummaryTo(summary, store.Path)
+}
+
+// SaveSessionSummaryTo adds summary to the summaries of its conversation in the database at dbPath.
+func SaveSessionSummaryTo(summary SessionSummary, dbPath string) error {
+	data, err := json.Marshal(summary)
+	if err != nil {
+		return fmt.Errorf("failed to encode session summary: %w", err)
+	}
+	db, err := initDB(dbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`INSERT INTO session_summaries (conversation_id, summary) VALUES (?, ?);`, summary.ConversationID, string(data))
+	return err
+}
+
+// SessionSummaries returns the summaries of all sessions on the conversation identified by conversationID
+// in the current store, see SetStore, oldest first.
+// It returns ErrConversationNotFound if there is no such conversation.
+func SessionSummaries(conversationID string) ([]SessionSummary, error) {
+	return currentStore().SessionSummaries(conversationID)
+}
+
+func (store *SQLiteStore) SessionSummaries(conversationID string) ([]SessionSummary, error) {
+	db, err := initDB(store.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	var exists int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM conversations WHERE id = ?;`, conversationID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if exists == 0 {
+		return nil, ErrConversationNotFound
+	}
+
+	rows, err := db.Query(`SELECT summary FROM session_summaries WHERE conversation_id = ? ORDER BY id ASC;`, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	summaries := []SessionSummary{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var summary SessionSummary
+		if err := json.Unmarshal([]byte(data), &summary); err != nil {
+			return nil, fmt.Errorf("failed to decode session summary: %w", err)
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries, rows.Err()
+}
+
+// Summaries are kept encoded, so that changes to a saved or returned summary don't affect the store.
+func (store *MemoryStore) SaveSessionSummary(summary SessionSummary) error {
+	data, err := json.Marshal(summary)
+	if err != nil {
+		return fmt.Errorf("failed to encode session summary: %w", err)
+	}
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.summaries[summary.ConversationID] = append(store.summaries[summary.ConversationID], data)
+	return nil
+}
+
+func (store *MemoryStore) SessionSummaries(conversationID string) ([]SessionSummary, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	if _, err := store.find(conversationID); err != nil {
+		return nil, err
+	}
+	summaries := []SessionSummary{}
+	for _, data := range store.summaries[conversationID] {
+		var summary SessionSummary
+		if err := json.Unmarshal(data, &summary); err != nil {
+			return nil, fmt.Errorf("failed to decode session summary: %w", err)
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries, nil
+}
@@ -0,0 +1,77 @@
+package history
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSessionSummaries(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		conv := &Conversation{ID: "summarized", CreatedAt: time.Now()}
+		if err := store.Save(conv); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+
+		first := SessionSummary{ConversationID: "summarized", Turns: 2, ToolCalls: map[string]int{"read_file": 3}}
+		second := SessionSummary{
+			ConversationID: "summarized",
+			Turns:          1,
+			FilesModified:  []string{"agent.go"},
+			Tokens:         SessionTokens{Prompt: 100, Candidates: 20},
+			Plan:           &SessionPlan{Name: "release", Status: "TODO", CompletedSteps: 1, TotalSteps: 3},
+		}
+		for _, summary := range []SessionSummary{first, second} {
+			if err := store.SaveSessionSummary(summary); err != nil {
+				t.Fatalf("SaveSessionSummary failed: %v", err)
+			}
+		}
+
+		summaries, err := store.SessionSummaries("summarized")
+		if err != nil {
+			t.Fatalf("SessionSummaries failed: %v", err)
+		}
+		if len(summaries) != 2 {
+			t.Fatalf("expected 2 summaries, got %d", len(summaries))
+		}
+		if summaries[0].ToolCalls["read_file"] != 3 || summaries[1].Turns != 1 {
+			t.Errorf("expected the summaries in the order they were saved, got %+v", summaries)
+		}
+		if plan := summaries[1].Plan; plan == nil || plan.Name != "release" || plan.CompletedSteps != 1 {
+			t.Errorf("expected the plan status to be kept, got %+v", plan)
+		}
+
+		if _, err := store.SessionSummaries("missing"); !errors.Is(err, ErrConversationNotFound) {
+			t.Errorf("expected ErrConversationNotFound, got %v", err)
+		}
+
+		if err := store.Delete("summarized"); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		if err := store.Save(conv); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+		if summaries, _ := store.SessionSummaries("summarized"); len(summaries) != 0 {
+			t.Errorf("expected deleting the conversation to delete its summaries, got %d", len(summaries))
+		}
+	})
+}
+
+func TestSaveSessionSummaryUsesCurrentStore(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "history.db")
+	SetStore(NewSQLiteStore(dbPath))
+	t.Cleanup(func() { SetStore(nil) })
+	if err := Save(&Conversation{ID: "current", CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	if err := SaveSessionSummary(SessionSummary{ConversationID: "current", Turns: 4}); err != nil {
+		t.Fatalf("SaveSessionSummary failed: %v", err)
+	}
+
+	summaries, err := NewSQLiteStore(dbPath).SessionSummaries("current")
+	if err != nil || len(summaries) != 1 || summaries[0].Turns != 4 {
+		t.Errorf("expected the summary in the store's database, got %+v (%v)", summaries, err)
+	}
+}
@@ -0,0 +1,256 @@
+package history
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Store persists conversations along with their titles, metadata and session summaries.
+//
+// The package level functions, like Save, Load, SetMeta or ExportArchive, use the store set with SetStore,
+// which by default keeps conversations in the SQLite database at DefaultDatabasePath.
+type Store interface {
+	// Save stores conversation, replacing all messages stored for it before.
+	Save(conversation *Conversation) error
+	// Load retrieves the conversation with the given ID and its messages.
+	Load(conversationID string) (*Conversation, error)
+	// List returns metadata for all stored conversations, sorted by last activity, most recent first.
+	List() ([]ConversationMetadata, error)
+	// Delete removes the conversation with the given ID and its messages.
+	// It returns ErrConversationNotFound if there is no such conversation.
+	Delete(conversationID string) error
+	// SetTitle sets the title of the conversation. An empty title removes it, so that the next save generates one again.
+	// It returns ErrConversationNotFound if there is no such conversation.
+	SetTitle(conversationID string, title string) error
+	// SetMeta stores value under key in the metadata of the conversation, replacing any previous value.
+	// An empty value removes the key. It returns ErrConversationNotFound if there is no such conversation.
+	SetMeta(conversationID string, key string, value string) error
+	// Meta returns all metadata of the conversation.
+	// It returns ErrConversationNotFound if there is no such conversation.
+	Meta(conversationID string) (map[string]string, error)
+	// SaveSessionSummary adds summary to the summaries of its conversation.
+	SaveSessionSummary(summary SessionSummary) error
+	// SessionSummaries returns the summaries of all sessions on the conversation, oldest first.
+	// It returns ErrConversationNotFound if there is no such conversation.
+	SessionSummaries(conversationID string) ([]SessionSummary, error)
+	// Search returns metadata for the conversations matching query, ordered like List, see SearchConversations.
+	Search(query string, titlesOnly bool) ([]ConversationMetadata, error)
+	// PruneOlderThan deletes the conversations whose last activity is before cutoff, see ConversationMetadata,
+	// and returns the number of deleted conversations.
+	PruneOlderThan(cutoff time.Time) (int, error)
+	// Archive returns the conversation with its messages and metadata as they are stored, see ExportArchive.
+	// It returns ErrConversationNotFound if there is no such conversation.
+	Archive(conversationID string) (*Archive, error)
+	// ImportArchive stores the archived conversation and returns its ID,
+	// which is a new one if a conversation with the archived ID exists already.
+	ImportArchive(archive *Archive) (string, error)
+}
+
+var (
+	storeMutex   sync.Mutex
+	defaultStore Store
+)
+
+// SetStore replaces the store used by the package level functions.
+// Setting it to nil restores the SQLite database at DefaultDatabasePath.
+func SetStore(store Store) {
+	storeMutex.Lock()
+	defer storeMutex.Unlock()
+	defaultStore = store
+}
+
+// currentStore returns the store set with SetStore, or the SQLite database at DefaultDatabasePath.
+func currentStore() Store {
+	storeMutex.Lock()
+	defer storeMutex.Unlock()
+	if defaultStore != nil {
+		return defaultStore
+	}
+	return NewSQLiteStore(DefaultDatabasePath)
+}
+
+// Save persists the conversation to the current store, see SetStore.
+// If messages for this conversation ID already exist, they are cleared and replaced with the current messages.
+func Save(conversation *Conversation) error {
+	return currentStore().Save(conversation)
+}
+
+// Load retrieves a specific conversation and its messages from the current store, see SetStore.
+func Load(conversationID string) (*Conversation, error) {
+	return currentStore().Load(conversationID)
+}
+
+// List returns metadata for all conversations in the current store, see SetStore.
+func List() ([]ConversationMetadata, error) {
+	return currentStore().List()
+}
+
+// Delete removes a conversation and its messages from the current store, see SetStore.
+func Delete(conversationID string) error {
+	return currentStore().Delete(conversationID)
+}
+
+// SQLiteStore keeps conversations in the SQLite database at Path.
+type SQLiteStore struct {
+	Path string
+}
+
+// NewSQLiteStore returns a store using the SQLite database at path, which is created when first written to.
+func NewSQLiteStore(path string) *SQLiteStore {
+	return &SQLiteStore{Path: path}
+}
+
+func (store *SQLiteStore) Save(conversation *Conversation) error {
+	return SaveTo(conversation, store.Path)
+}
+
+func (store *SQLiteStore) Load(conversationID string) (*Conversation, error) {
+	return LoadFrom(conversationID, store.Path)
+}
+
+// List returns no conversations if the database does not exist yet, unlike ListConversations.
+func (store *SQLiteStore) List() ([]ConversationMetadata, error) {
+	if _, err := os.Stat(store.Path); errors.Is(err, os.ErrNotExist) {
+		return []ConversationMetadata{}, nil
+	}
+	return ListConversations(store.Path)
+}
+
+func (store *SQLiteStore) Delete(conversationID string) error {
+	return DeleteConversation(conversationID, store.Path)
+}
+
+// MemoryStore keeps conversations in memory, which is useful for tests.
+// Messages are stored in the same encoding as in the database,
+// so loaded payloads have the same types as when using SQLite.
+type MemoryStore struct {
+	mutex         sync.Mutex
+	conversations map[string]*storedConversation
+	summaries     map[string][][]byte // Encoded session summaries, keyed by conversation ID.
+}
+
+// storedConversation is a conversation as kept by a MemoryStore.
+type storedConversation struct {
+	conversation Conversation // Without messages.
+	title        string
+	transcript   string // Only set by importing an archive, like in the database.
+	payloads     [][]byte
+	messages     []Message // Message timestamps; payloads are kept in payloads.
+}
+
+// NewMemoryStore returns an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{conversations: map[string]*storedConversation{}, summaries: map[string][][]byte{}}
+}
+
+// find returns the stored conversation with the given ID. The store's mutex must be held.
+func (store *MemoryStore) find(conversationID string) (*storedConversation, error) {
+	stored, found := store.conversations[conversationID]
+	if !found {
+		return nil, fmt.Errorf("conversation with ID '%s' not found: %w", conversationID, ErrConversationNotFound)
+	}
+	return stored, nil
+}
+
+func (store *MemoryStore) Save(conversation *Conversation) error {
+	stored := &storedConversation{conversation: *conversation}
+	stored.conversation.Messages = nil
+	stored.conversation.Meta = nil
+	for _, msg := range conversation.Messages {
+		payload, err := encodePayload(msg.Payload)
+		if err != nil {
+			return err
+		}
+		stored.payloads = append(stored.payloads, payload)
+		stored.messages = append(stored.messages, Message{CreatedAt: msg.CreatedAt})
+	}
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	meta := map[string]string{}
+	if existing, found := store.conversations[conversation.ID]; found {
+		// Like the database, keep the creation time of the first save, the title and metadata set since.
+		stored.conversation.CreatedAt = existing.conversation.CreatedAt
+		stored.title = existing.title
+		stored.transcript = existing.transcript
+		maps.Copy(meta, existing.conversation.Meta)
+	}
+	if stored.title == "" {
+		stored.title = generateTitle(stored.payloads)
+	}
+	for key, value := range conversation.Meta {
+		if value == "" {
+			delete(meta, key)
+		} else {
+			meta[key] = value
+		}
+	}
+	if len(meta) > 0 {
+		stored.conversation.Meta = meta
+	}
+	store.conversations[conversation.ID] = stored
+	return nil
+}
+
+func (store *MemoryStore) Load(conversationID string) (*Conversation, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	stored, err := store.find(conversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	conv := stored.conversation
+	conv.Meta = maps.Clone(stored.conversation.Meta)
+	conv.Messages = make([]*Message, 0, len(stored.payloads))
+	for i, data := range stored.payloads {
+		payload, err := decodePayload(data)
+		if err != nil {
+			payload = data
+		}
+		conv.Messages = append(conv.Messages, &Message{Payload: payload, CreatedAt: stored.messages[i].CreatedAt})
+	}
+	return &conv, nil
+}
+
+func (store *MemoryStore) List() ([]ConversationMetadata, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	metadataList := []ConversationMetadata{}
+	for _, stored := range store.conversations {
+		meta := ConversationMetadata{
+			ID:                stored.conversation.ID,
+			CreatedAt:         stored.conversation.CreatedAt,
+			TotalTokens:       stored.conversation.TotalTokens,
+			Title:             stored.title,
+			MessageCount:      len(stored.messages),
+			LatestMessageTime: stored.conversation.CreatedAt,
+		}
+		for i, msg := range stored.messages {
+			if i == 0 || msg.CreatedAt.After(meta.LatestMessageTime) {
+				meta.LatestMessageTime = msg.CreatedAt
+			}
+		}
+		metadataList = append(metadataList, meta)
+	}
+	sort.Slice(metadataList, func(i, j int) bool {
+		return metadataList[i].LatestMessageTime.After(metadataList[j].LatestMessageTime)
+	})
+	return metadataList, nil
+}
+
+func (store *MemoryStore) Delete(conversationID string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	if _, found := store.conversations[conversationID]; !found {
+		return ErrConversationNotFound
+	}
+	delete(store.conversations, conversationID)
+	delete(store.summaries, conversationID)
+	return nil
+}
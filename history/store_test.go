@@ -0,0 +1,166 @@
+package history
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// storeFactories create the stores every Store test runs against.
+var storeFactories = map[string]func(t *testing.T) Store{
+	"sqlite": func(t *testing.T) Store {
+		return NewSQLiteStore(filepath.Join(t.TempDir(), "history.db"))
+	},
+	"memory": func(t *testing.T) Store {
+		return NewMemoryStore()
+	},
+}
+
+func forEachStore(t *testing.T, test func(t *testing.T, store Store)) {
+	for name, newStore := range storeFactories {
+		t.Run(name, func(t *testing.T) {
+			test(t, newStore(t))
+		})
+	}
+}
+
+func TestStoreRoundTrip(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		conv, err := New()
+		if err != nil {
+			t.Fatalf("New() failed: %v", err)
+		}
+		conv.TotalTokens = 7
+		conv.Model = "test-model"
+		conv.Append(map[string]interface{}{"role": "user", "parts": []interface{}{map[string]interface{}{"text": "hello"}}})
+		conv.Append(struct {
+			Detail string `json:"detail"`
+		}{"item x"})
+		conv.Append("raw string")
+		conv.Append(456.789)
+
+		if err := store.Save(conv); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+		loaded, err := store.Load(conv.ID)
+		if err != nil {
+			t.Fatalf("Load failed: %v", err)
+		}
+
+		want := []interface{}{
+			map[string]interface{}{"role": "user", "parts": []interface{}{map[string]interface{}{"text": "hello"}}},
+			map[string]interface{}{"detail": "item x"},
+			"raw string",
+			456.789,
+		}
+		var got []interface{}
+		for _, msg := range loaded.Messages {
+			got = append(got, msg.Payload)
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("loaded payloads mismatch (-want +got):\n%s", diff)
+		}
+		if loaded.TotalTokens != 7 || loaded.Model != "test-model" {
+			t.Errorf("expected metadata to be restored, got tokens %d and model %q", loaded.TotalTokens, loaded.Model)
+		}
+		if loaded.CreatedAt.Unix() != conv.CreatedAt.Unix() {
+			t.Errorf("CreatedAt mismatch: got %v, want %v", loaded.CreatedAt, conv.CreatedAt)
+		}
+	})
+}
+
+func TestStoreSaveReplacesMessages(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		conv, _ := New()
+		conv.Append("first")
+		conv.Append("second")
+		if err := store.Save(conv); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+		conv.Messages = conv.Messages[:1]
+		if err := store.Save(conv); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+
+		loaded, err := store.Load(conv.ID)
+		if err != nil {
+			t.Fatalf("Load failed: %v", err)
+		}
+		if len(loaded.Messages) != 1 || loaded.Messages[0].Payload != "first" {
+			t.Errorf("expected only the first message, got %d messages", len(loaded.Messages))
+		}
+	})
+}
+
+func TestStoreListSortedByLastActivity(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		now := time.Now().UTC()
+		older := &Conversation{ID: "created-first-active-last", CreatedAt: now.Add(-3 * time.Hour), TotalTokens: 42, Messages: []*Message{
+			{Payload: "recent message", CreatedAt: now.Add(-time.Minute)},
+		}}
+		newer := &Conversation{ID: "created-last-no-messages", CreatedAt: now.Add(-time.Hour), Messages: []*Message{}}
+		for _, conv := range []*Conversation{older, newer} {
+			if err := store.Save(conv); err != nil {
+				t.Fatalf("Save failed: %v", err)
+			}
+		}
+
+		conversations, err := store.List()
+		if err != nil {
+			t.Fatalf("List failed: %v", err)
+		}
+		if len(conversations) != 2 {
+			t.Fatalf("expected 2 conversations, got %d", len(conversations))
+		}
+		if first := conversations[0]; first.ID != older.ID || first.TotalTokens != 42 || first.MessageCount != 1 {
+			t.Errorf("expected the conversation with the latest message first, got %+v", first)
+		}
+		if conversations[1].MessageCount != 0 {
+			t.Errorf("unexpected metadata for conversation without messages: %+v", conversations[1])
+		}
+	})
+}
+
+func TestStoreDelete(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		conv, _ := New()
+		conv.Append("message")
+		if err := store.Save(conv); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+
+		if err := store.Delete(conv.ID); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+
+		if _, err := store.Load(conv.ID); err == nil {
+			t.Errorf("expected loading a deleted conversation to fail")
+		}
+		if err := store.Delete(conv.ID); !errors.Is(err, ErrConversationNotFound) {
+			t.Errorf("expected ErrConversationNotFound when deleting again, got %v", err)
+		}
+	})
+}
+
+func TestSetStore(t *testing.T) {
+	store := NewMemoryStore()
+	SetStore(store)
+	t.Cleanup(func() { SetStore(nil) })
+
+	conv, _ := New()
+	conv.Append("message")
+	if err := Save(conv); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	if _, err := store.Load(conv.ID); err != nil {
+		t.Errorf("expected Save to use the store set with SetStore: %v", err)
+	}
+	conversations, err := List()
+	if err != nil || len(conversations) != 1 {
+		t.Errorf("expected List to return the saved conversation, got %v, %v", conversations, err)
+	}
+}
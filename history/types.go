@@ -11,7 +11,9 @@ var ErrConversationNotFound = errors.New("history: conversation not found")
 // ConversationMetadata holds summary information about a conversation.
 type ConversationMetadata struct {
 	ID                string
-	LatestMessageTime time.Time
+	Title             string
+	LatestMessageTime time.Time // Last activity: the time of the latest message, or the creation time if there are none.
 	MessageCount      int
 	CreatedAt         time.Time
+	TotalTokens       int
 }
@@ -0,0 +1,226 @@
+package smolcode
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// InputHistoryPath is the file in which lines entered at the interactive prompt are remembered.
+const InputHistoryPath = ".smolcode/input_history"
+
+// maxInputHistory bounds the number of lines kept in the input history.
+const maxInputHistory = 1000
+
+// multilineDelimiters map lines that start a message spanning several lines
+// to the line that ends it. /paste is meant for terminals without bracketed paste support.
+var multilineDelimiters = map[string]string{
+	`"""`:    `"""`,
+	"/paste": "/endpaste",
+}
+
+// continuationPrompt is shown while reading the lines of a multiline message.
+const continuationPrompt = "... "
+
+// NewUserMessageReader returns a function that reads user messages from in.
+//
+// If in is a terminal, lines can be edited, previous lines are recalled with the
+// arrow keys, and entered lines are remembered in historyPath across sessions.
+// Otherwise lines are read as they are.
+//
+// Text pasted into a terminal that supports bracketed paste becomes a single
+// message, which is sent when Enter is pressed after the paste. In all cases a
+// message can span several lines by enclosing them in lines consisting of """,
+// or in /paste and /endpaste.
+func NewUserMessageReader(in *os.File, out io.Writer, historyPath string) func() (string, bool) {
+	var readLine func(continuation bool) (inputLine, bool)
+	if term.IsTerminal(int(in.Fd())) {
+		readLine = newTerminalLineReader(in, out, historyPath).readLine
+	} else {
+		scanner := bufio.NewScanner(in)
+		readLine = func(bool) (inputLine, bool) {
+			if !scanner.Scan() {
+				return inputLine{}, false
+			}
+			return inputLine{text: scanner.Text()}, true
+		}
+	}
+	return func() (string, bool) {
+		return readMessage(readLine)
+	}
+}
+
+// inputLine is a line of user input.
+type inputLine struct {
+	text   string
+	pasted bool // The line was pasted rather than typed.
+}
+
+// readMessage reads a single user message using readLine, joining the lines of
+// multiline and pasted messages.
+func readMessage(readLine func(continuation bool) (inputLine, bool)) (string, bool) {
+	line, ok := readLine(false)
+	if !ok {
+		return "", false
+	}
+	if end, found := multilineDelimiters[strings.TrimSpace(line.text)]; found && !line.pasted {
+		lines := []string{}
+		for {
+			line, ok := readLine(true)
+			if !ok || strings.TrimSpace(line.text) == end {
+				return strings.Join(lines, "\n"), true
+			}
+			lines = append(lines, line.text)
+		}
+	}
+	if !line.pasted {
+		return line.text, true
+	}
+
+	// Keep reading until a line that was typed, which completes the message.
+	lines := []string{line.text}
+	for {
+		line, ok := readLine(true)
+		if !ok {
+			break
+		}
+		lines = append(lines, line.text)
+		if !line.pasted {
+			break
+		}
+	}
+	return strings.TrimRight(strings.Join(lines, "\n"), "\n"), true
+}
+
+// terminalLineReader reads lines from a terminal with line editing and history.
+type terminalLineReader struct {
+	fd       int
+	terminal *term.Terminal
+	rawState *term.State // Set while the terminal is in raw mode.
+}
+
+func newTerminalLineReader(in *os.File, out io.Writer, historyPath string) *terminalLineReader {
+	terminal := term.NewTerminal(struct {
+		io.Reader
+		io.Writer
+	}{in, out}, "")
+	terminal.History = loadFileHistory(historyPath)
+	return &terminalLineReader{fd: int(in.Fd()), terminal: terminal}
+}
+
+// readLine reads a line, switching the terminal to raw mode only while reading
+// so that output produced by the agent in the meantime is not affected.
+// During a paste the terminal stays in raw mode, so that the rest of the pasted text is read unchanged.
+// Ctrl-D on an empty line and Ctrl-C end the input.
+func (reader *terminalLineReader) readLine(continuation bool) (inputLine, bool) {
+	if reader.rawState == nil {
+		state, err := term.MakeRaw(reader.fd)
+		if err != nil {
+			return inputLine{}, false
+		}
+		reader.rawState = state
+		// Terminals without bracketed paste support ignore this.
+		reader.terminal.SetBracketedPasteMode(true)
+	}
+
+	if width, height, err := term.GetSize(reader.fd); err == nil {
+		reader.terminal.SetSize(width, height)
+	}
+	if continuation {
+		reader.terminal.SetPrompt(continuationPrompt)
+	} else {
+		// The agent prints its own prompt before asking for a message.
+		reader.terminal.SetPrompt("")
+	}
+	line, err := reader.terminal.ReadLine()
+	pasted := errors.Is(err, term.ErrPasteIndicator)
+	if !pasted {
+		reader.terminal.SetBracketedPasteMode(false)
+		term.Restore(reader.fd, reader.rawState)
+		reader.rawState = nil
+	}
+	if err != nil && !pasted {
+		return inputLine{}, false
+	}
+	return inputLine{text: line, pasted: pasted}, true
+}
+
+// fileHistory is a term.History that keeps its entries in a file.
+// Each entry is stored as a JSON string on a line of its own.
+type fileHistory struct {
+	path    string
+	entries []string // Oldest first.
+}
+
+// loadFileHistory reads the history stored at path. A missing or unreadable file results in an empty history.
+func loadFileHistory(path string) *fileHistory {
+	history := &fileHistory{path: path}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return history
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		var entry string
+		if err := json.Unmarshal([]byte(line), &entry); err == nil && entry != "" {
+			history.entries = append(history.entries, entry)
+		}
+	}
+	if len(history.entries) > maxInputHistory {
+		history.entries = history.entries[len(history.entries)-maxInputHistory:]
+		history.rewrite()
+	}
+	return history
+}
+
+// Add remembers entry, unless it is blank or repeats the most recent entry.
+// Failing to write the file is not fatal; the entry is still available in this session.
+func (history *fileHistory) Add(entry string) {
+	if strings.TrimSpace(entry) == "" {
+		return
+	}
+	if len(history.entries) > 0 && history.entries[len(history.entries)-1] == entry {
+		return
+	}
+	history.entries = append(history.entries, entry)
+	if len(history.entries) > maxInputHistory {
+		history.entries = history.entries[1:]
+	}
+
+	if err := os.MkdirAll(filepath.Dir(history.path), 0755); err != nil {
+		return
+	}
+	file, err := os.OpenFile(history.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	encoded, _ := json.Marshal(entry)
+	file.Write(append(encoded, '\n'))
+}
+
+// Len returns the number of entries.
+func (history *fileHistory) Len() int {
+	return len(history.entries)
+}
+
+// At returns the entry at idx, where 0 is the most recent entry.
+func (history *fileHistory) At(idx int) string {
+	return history.entries[len(history.entries)-1-idx]
+}
+
+// rewrite replaces the file with the entries currently in memory.
+func (history *fileHistory) rewrite() {
+	var content strings.Builder
+	for _, entry := range history.entries {
+		encoded, _ := json.Marshal(entry)
+		content.Write(encoded)
+		content.WriteByte('\n')
+	}
+	os.WriteFile(history.path, []byte(content.String()), 0600)
+}
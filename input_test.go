@@ -0,0 +1,121 @@
+package smolcode
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func lineReader(lines ...string) func(bool) (inputLine, bool) {
+	input := make([]inputLine, len(lines))
+	for i, line := range lines {
+		input[i] = inputLine{text: line}
+	}
+	return inputReader(input...)
+}
+
+func inputReader(lines ...inputLine) func(bool) (inputLine, bool) {
+	return func(bool) (inputLine, bool) {
+		if len(lines) == 0 {
+			return inputLine{}, false
+		}
+		line := lines[0]
+		lines = lines[1:]
+		return line, true
+	}
+}
+
+func TestReadMessageJoinsMultilineMessages(t *testing.T) {
+	readLine := lineReader("first", `"""`, "func main() {", "}", `"""`, "last")
+
+	var messages []string
+	for {
+		message, ok := readMessage(readLine)
+		if !ok {
+			break
+		}
+		messages = append(messages, message)
+	}
+
+	want := []string{"first", "func main() {\n}", "last"}
+	if len(messages) != len(want) {
+		t.Fatalf("expected messages %q, got %q", want, messages)
+	}
+	for i := range want {
+		if messages[i] != want[i] {
+			t.Errorf("message %d: expected %q, got %q", i, want[i], messages[i])
+		}
+	}
+}
+
+func TestReadMessageReturnsUnterminatedMultilineMessage(t *testing.T) {
+	message, ok := readMessage(lineReader(`"""`, "one", "two"))
+
+	if !ok || message != "one\ntwo" {
+		t.Errorf("expected the lines read before the end of input, got %q, %t", message, ok)
+	}
+}
+
+func TestReadMessageJoinsPasteBlocks(t *testing.T) {
+	message, ok := readMessage(lineReader("/paste", "one", `"""`, "/endpaste"))
+
+	if !ok || message != "one\n\"\"\"" {
+		t.Errorf("expected the lines between /paste and /endpaste, got %q, %t", message, ok)
+	}
+}
+
+func TestReadMessageBuffersPastedLines(t *testing.T) {
+	readLine := inputReader(
+		inputLine{text: "func main() {", pasted: true},
+		inputLine{text: `"""`, pasted: true},
+		inputLine{text: "}", pasted: true},
+		inputLine{text: ""},
+		inputLine{text: "next"},
+	)
+
+	first, _ := readMessage(readLine)
+	second, _ := readMessage(readLine)
+
+	if first != "func main() {\n\"\"\"\n}" {
+		t.Errorf("expected the pasted lines to form one message, got %q", first)
+	}
+	if second != "next" {
+		t.Errorf("expected the following line to be a message of its own, got %q", second)
+	}
+}
+
+func TestFileHistoryPersistsEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "input_history")
+	history := loadFileHistory(path)
+	history.Add("first")
+	history.Add("first")
+	history.Add("   ")
+	history.Add("second\nline")
+
+	reloaded := loadFileHistory(path)
+
+	if reloaded.Len() != 2 {
+		t.Fatalf("expected 2 entries, got %d", reloaded.Len())
+	}
+	if reloaded.At(0) != "second\nline" || reloaded.At(1) != "first" {
+		t.Errorf("expected most recent entry first, got %q and %q", reloaded.At(0), reloaded.At(1))
+	}
+}
+
+func TestFileHistoryIsBounded(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "input_history")
+	history := loadFileHistory(path)
+	for i := 0; i < maxInputHistory+10; i++ {
+		history.Add(fmt.Sprintf("entry %d", i))
+	}
+
+	reloaded := loadFileHistory(path)
+
+	if reloaded.Len() != maxInputHistory {
+		t.Errorf("expected %d entries, got %d", maxInputHistory, reloaded.Len())
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("expected the history file to exist: %v", err)
+	}
+}
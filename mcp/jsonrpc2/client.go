@@ -1,11 +1,14 @@
 package jsonrpc2
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"sync"
+	"time"
 )
 
 // Request represents a JSON-RPC 2.0 request object.
@@ -34,12 +37,74 @@ type IncomingMessage struct {
 	Error   *ErrorObject     `json:"error,omitempty"`  // Present in error responses
 }
 
+// UnmarshalJSON decodes an incoming message, keeping a null result, see keepNullResult.
+func (m *IncomingMessage) UnmarshalJSON(data []byte) error {
+	type plain IncomingMessage // Without this method, to avoid recursion.
+	if err := json.Unmarshal(data, (*plain)(m)); err != nil {
+		return err
+	}
+	m.Result = keepNullResult(data, m.Result)
+	return nil
+}
+
 // Response represents a JSON-RPC 2.0 response object.
 type Response struct {
 	JSONRPC string           `json:"jsonrpc"`
 	Result  *json.RawMessage `json:"result,omitempty"`
 	Error   *ErrorObject     `json:"error,omitempty"`
 	ID      interface{}      `json:"id"`
+
+	invalid error // Why a received response is not well-formed, see ValidateResponse.
+}
+
+// UnmarshalJSON decodes a response, keeping a null result, see keepNullResult.
+func (r *Response) UnmarshalJSON(data []byte) error {
+	type plain Response // Without this method, to avoid recursion.
+	if err := json.Unmarshal(data, (*plain)(r)); err != nil {
+		return err
+	}
+	r.Result = keepNullResult(data, r.Result)
+	return nil
+}
+
+// keepNullResult returns result, or a JSON null if result is nil although the JSON object message has a "result" member.
+// Decoding null into a *json.RawMessage leaves it nil, which would make a successful response with a null result
+// indistinguishable from one without a result.
+func keepNullResult(message []byte, result *json.RawMessage) *json.RawMessage {
+	if result != nil {
+		return result
+	}
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(message, &members); err != nil {
+		return nil
+	}
+	if _, ok := members["result"]; !ok {
+		return nil
+	}
+	null := json.RawMessage("null")
+	return &null
+}
+
+// Errors returned by ValidateResponse.
+var (
+	ErrInvalidVersion   = errors.New("jsonrpc: response has an invalid JSON-RPC version")
+	ErrResultAndError   = errors.New("jsonrpc: response contains both result and error fields")
+	ErrNoResultNorError = errors.New("jsonrpc: response contains neither result nor error field")
+)
+
+// ValidateResponse checks that resp is a well-formed JSON-RPC 2.0 response:
+// its version must be "2.0" and it must contain exactly one of result and error.
+func ValidateResponse(resp *Response) error {
+	if resp.JSONRPC != "2.0" {
+		return fmt.Errorf("%w: %q", ErrInvalidVersion, resp.JSONRPC)
+	}
+	if resp.Error != nil && resp.Result != nil {
+		return ErrResultAndError
+	}
+	if resp.Error == nil && resp.Result == nil {
+		return ErrNoResultNorError
+	}
+	return nil
 }
 
 // Transport defines the interface for sending and receiving JSON-RPC messages.
@@ -81,10 +146,16 @@ type Client struct {
 	notificationHandlers   map[string]func(params *json.RawMessage) error
 	notificationHandlersMu sync.Mutex
 
+	// For answering server-to-client requests
+	requestHandlers   map[string]RequestHandler
+	requestHandlersMu sync.Mutex
+
 	// For correlating responses to client-initiated calls
 	pendingCalls   map[interface{}]chan *Response // key is request ID
 	pendingCallsMu sync.Mutex
 
+	defaultCallTimeout time.Duration // See ClientOptions.
+
 	// Lifecycle management for the listener goroutine
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -105,6 +176,20 @@ type ClientNotifyArgs struct {
 	Params interface{}
 }
 
+// ClientOptions configures a client created with NewClientWithOptions.
+type ClientOptions struct {
+	// DefaultCallTimeout limits calls whose context has no deadline.
+	// Zero leaves such calls waiting until the response arrives or the client is closed.
+	DefaultCallTimeout time.Duration
+}
+
+// NewClientWithOptions creates a new JSON-RPC client with the given transport and options.
+func NewClientWithOptions(transport Transport, options ClientOptions) *Client {
+	c := NewClient(transport)
+	c.defaultCallTimeout = options.DefaultCallTimeout
+	return c
+}
+
 // NewClient creates a new JSON-RPC client with the given transport.
 // The client will not start listening for messages until its Listen method is called.
 func NewClient(transport Transport) *Client {
@@ -117,6 +202,8 @@ func NewClient(transport Transport) *Client {
 		notificationHandlers: make(map[string]func(params *json.RawMessage) error),
 		// notificationHandlersMu is zero-value sync.Mutex
 
+		requestHandlers: make(map[string]RequestHandler),
+
 		pendingCalls: make(map[interface{}]chan *Response),
 		// pendingCallsMu is zero-value sync.Mutex
 
@@ -136,10 +223,32 @@ func (c *Client) OnNotification(method string, handler func(params *json.RawMess
 	c.notificationHandlers[method] = handler
 }
 
+// RequestHandler answers a request sent by the server.
+// It returns the result to send back, or an error object if the request failed.
+type RequestHandler func(params *json.RawMessage) (interface{}, *ErrorObject)
+
+// OnRequest registers a handler for a request method sent by the server.
+// Unlike notifications, requests carry an ID and the server expects a response,
+// which the client sends with the result or error returned by handler.
+// Requests for methods without a handler are answered with a MethodNotFound error.
+// If a handler already exists for the method, it will be overwritten.
+func (c *Client) OnRequest(method string, handler RequestHandler) {
+	c.requestHandlersMu.Lock()
+	defer c.requestHandlersMu.Unlock()
+	c.requestHandlers[method] = handler
+}
+
 // Call sends a JSON-RPC request to the server and waits for a response.
 // args contains the method and parameters for the call.
 // resultDest is a pointer where the successful response's result field will be unmarshalled.
+// If ctx has no deadline, the client's default call timeout applies, see ClientOptions.
 func (c *Client) Call(ctx context.Context, args ClientCallArgs, resultDest interface{}) error {
+	if _, hasDeadline := ctx.Deadline(); !hasDeadline && c.defaultCallTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.defaultCallTimeout)
+		defer cancel()
+	}
+
 	c.idMu.Lock()
 	currentID := c.nextID
 	c.nextID++
@@ -184,29 +293,114 @@ func (c *Client) Call(ctx context.Context, args ClientCallArgs, resultDest inter
 	case <-c.ctx.Done(): // Client's main context, indicates listener might be shutting down
 		return fmt.Errorf("jsonrpc: client is closing: %w", c.ctx.Err())
 	case resp := <-respChan:
-		if resp == nil {
-			// This might happen if the listen loop closes the channel during shutdown without sending a response
-			return fmt.Errorf("jsonrpc: call for ID %v aborted due to client shutdown or an issue in listener", currentID)
+		return decodeResponse(resp, currentID, resultDest)
+	}
+}
+
+// decodeResponse unmarshals the result of resp, the response to the call with the given ID, into resultDest.
+// It returns the error object of resp if the call failed.
+func decodeResponse(resp *Response, id uint64, resultDest interface{}) error {
+	if resp == nil {
+		// This might happen if the listen loop closes the channel during shutdown without sending a response
+		return fmt.Errorf("jsonrpc: call for ID %v aborted due to client shutdown or an issue in listener", id)
+	}
+	if resp.invalid != nil {
+		return resp.invalid
+	}
+	// We have a response (which could be an error response from the server)
+	if resp.Error != nil {
+		return resp.Error
+	}
+
+	// Successful response
+	if resp.Result == nil && resultDest != nil {
+		// Server sent back a success response but with a null/omitted result field.
+		// If resultDest is non-nil, the caller expects a value.
+		// We don't error here; resultDest will remain in its zero state or unchanged.
+		return nil
+	}
+	if resultDest != nil && resp.Result != nil {
+		if err := json.Unmarshal(*resp.Result, resultDest); err != nil {
+			return fmt.Errorf("jsonrpc: failed to unmarshal result: %w", err)
 		}
-		// We have a response (which could be an error response from the server)
-		if resp.Error != nil {
-			return fmt.Errorf("jsonrpc: server error (code: %d): %s", resp.Error.Code, resp.Error.Message)
+	}
+	return nil
+}
+
+// CallBatch sends the calls to the server as a single batch request and waits for all responses.
+// The result of calls[i] is unmarshalled into resultDests[i], which may be nil to discard it.
+// The server may answer in any order; responses are matched to the calls by their IDs.
+// The returned error joins the errors of all failed calls, each naming the index and method of its call.
+// Like Call, the client's default call timeout applies if ctx has no deadline.
+func (c *Client) CallBatch(ctx context.Context, calls []ClientCallArgs, resultDests []interface{}) error {
+	if len(calls) != len(resultDests) {
+		return fmt.Errorf("jsonrpc: batch has %d calls but %d result destinations", len(calls), len(resultDests))
+	}
+	if len(calls) == 0 {
+		return nil
+	}
+	if _, hasDeadline := ctx.Deadline(); !hasDeadline && c.defaultCallTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.defaultCallTimeout)
+		defer cancel()
+	}
+
+	c.idMu.Lock()
+	firstID := c.nextID
+	c.nextID += uint64(len(calls))
+	c.idMu.Unlock()
+
+	requests := make([]Request, len(calls))
+	respChans := make([]chan *Response, len(calls))
+	for i, call := range calls {
+		requests[i] = Request{JSONRPC: "2.0", Method: call.Method, Params: call.Params, ID: firstID + uint64(i)}
+		respChans[i] = make(chan *Response, 1)
+	}
+	reqBytes, err := json.Marshal(requests)
+	if err != nil {
+		return fmt.Errorf("jsonrpc: failed to format batch request: %w", err)
+	}
+
+	c.pendingCallsMu.Lock()
+	select {
+	case <-c.ctx.Done():
+		c.pendingCallsMu.Unlock()
+		return fmt.Errorf("jsonrpc: client is closed: %w", c.ctx.Err())
+	default:
+	}
+	for i := range calls {
+		c.pendingCalls[firstID+uint64(i)] = respChans[i]
+	}
+	c.pendingCallsMu.Unlock()
+
+	defer func() {
+		c.pendingCallsMu.Lock()
+		for i := range calls {
+			delete(c.pendingCalls, firstID+uint64(i))
 		}
+		c.pendingCallsMu.Unlock()
+	}()
+
+	if err := c.transport.Send(ctx, reqBytes); err != nil {
+		return fmt.Errorf("jsonrpc: transport failed to send batch request: %w", err)
+	}
 
-		// Successful response
-		if resp.Result == nil && resultDest != nil {
-			// Server sent back a success response but with a null/omitted result field.
-			// If resultDest is non-nil, the caller expects a value.
-			// We don't error here; resultDest will remain in its zero state or unchanged.
-			return nil
+	var errs []error
+	for i, call := range calls {
+		var callErr error
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("jsonrpc: batch call timed out or was cancelled: %w", ctx.Err())
+		case <-c.ctx.Done():
+			return fmt.Errorf("jsonrpc: client is closing: %w", c.ctx.Err())
+		case resp := <-respChans[i]:
+			callErr = decodeResponse(resp, firstID+uint64(i), resultDests[i])
 		}
-		if resultDest != nil && resp.Result != nil {
-			if err := json.Unmarshal(*resp.Result, resultDest); err != nil {
-				return fmt.Errorf("jsonrpc: failed to unmarshal result: %w", err)
-			}
+		if callErr != nil {
+			errs = append(errs, fmt.Errorf("jsonrpc: batch call %d (%s) failed: %w", i, call.Method, callErr))
 		}
-		return nil
 	}
+	return errors.Join(errs...)
 }
 
 // Notify sends a JSON-RPC notification (a request without an ID).
@@ -265,68 +459,112 @@ func (c *Client) Listen() error {
 			continue
 		}
 
+		// A batch response is an array of messages.
+		if trimmed := bytes.TrimSpace(payload); len(trimmed) > 0 && trimmed[0] == '[' {
+			var batch []IncomingMessage
+			if err := json.Unmarshal(trimmed, &batch); err != nil {
+				fmt.Printf("jsonrpc: error unmarshalling incoming batch: %v: %s\n", err, string(payload))
+				continue
+			}
+			for _, incomingMsg := range batch {
+				c.dispatch(incomingMsg, payload)
+			}
+			continue
+		}
+
 		var incomingMsg IncomingMessage
 		if err := json.Unmarshal(payload, &incomingMsg); err != nil {
 			fmt.Printf("jsonrpc: error unmarshalling incoming message: %v: %s\n", err, string(payload))
 			continue
 		}
+		c.dispatch(incomingMsg, payload)
+	}
+}
 
-		// Dispatch the message
-		if incomingMsg.Method != "" { // It's a request or notification from server
-			c.notificationHandlersMu.Lock()
-			handler, ok := c.notificationHandlers[incomingMsg.Method]
-			c.notificationHandlersMu.Unlock()
-
-			if ok {
-				go func(p *json.RawMessage) {
-					if hErr := handler(p); hErr != nil {
-						fmt.Printf("jsonrpc: notification handler for method '%s' failed: %v\n", incomingMsg.Method, hErr)
-					}
-				}(incomingMsg.Params)
-			} else {
-				fmt.Printf("jsonrpc: no handler for notification method '%s'\n", incomingMsg.Method)
-			}
-		} else if incomingMsg.ID != nil { // It's a response to a client call
-			if incomingMsg.Error != nil && incomingMsg.Result != nil {
-				fmt.Printf("jsonrpc: received response with ID %v that has both result and error fields\n", incomingMsg.ID)
-				continue // Invalid response, skip
-			}
-			if incomingMsg.Error == nil && incomingMsg.Result == nil && incomingMsg.JSONRPC == "2.0" { // ID is present, JSONRPC is present, but no result/error
-				fmt.Printf("jsonrpc: received response with ID %v that has neither result nor error field\n", incomingMsg.ID)
-				continue // Invalid response, skip
-			}
+// dispatch passes a notification to its handler and a response to the call waiting for it.
+// payload is the message as received, for logging.
+func (c *Client) dispatch(incomingMsg IncomingMessage, payload []byte) {
+	if incomingMsg.Method != "" && incomingMsg.ID != nil { // It's a request from the server
+		c.requestHandlersMu.Lock()
+		handler, ok := c.requestHandlers[incomingMsg.Method]
+		c.requestHandlersMu.Unlock()
+
+		go c.answerRequest(incomingMsg, handler, ok)
+	} else if incomingMsg.Method != "" { // It's a notification from server
+		c.notificationHandlersMu.Lock()
+		handler, ok := c.notificationHandlers[incomingMsg.Method]
+		c.notificationHandlersMu.Unlock()
+
+		if ok {
+			go func(p *json.RawMessage) {
+				if hErr := handler(p); hErr != nil {
+					fmt.Printf("jsonrpc: notification handler for method '%s' failed: %v\n", incomingMsg.Method, hErr)
+				}
+			}(incomingMsg.Params)
+		} else {
+			fmt.Printf("jsonrpc: no handler for notification method '%s'\n", incomingMsg.Method)
+		}
+	} else if incomingMsg.ID != nil { // It's a response to a client call
+		responseForCall := &Response{
+			JSONRPC: incomingMsg.JSONRPC,
+			Result:  incomingMsg.Result,
+			Error:   incomingMsg.Error,
+			ID:      incomingMsg.ID,
+		}
+		if err := ValidateResponse(responseForCall); err != nil {
+			// The caller still learns about the response instead of waiting until it times out.
+			responseForCall = &Response{ID: incomingMsg.ID, invalid: fmt.Errorf("jsonrpc: received invalid response with ID %v: %w", incomingMsg.ID, err)}
+		}
 
-			var mapKey interface{}
-			switch idVal := incomingMsg.ID.(type) {
-			case float64: // JSON numbers are float64
-				mapKey = uint64(idVal)
-			case string:
-				mapKey = idVal // If IDs were strings
-			default:
-				mapKey = incomingMsg.ID // Use as is, assuming consistent types or Call side handles it
-			}
+		var mapKey interface{}
+		switch idVal := incomingMsg.ID.(type) {
+		case float64: // JSON numbers are float64
+			mapKey = uint64(idVal)
+		case string:
+			mapKey = idVal // If IDs were strings
+		default:
+			mapKey = incomingMsg.ID // Use as is, assuming consistent types or Call side handles it
+		}
 
-			c.pendingCallsMu.Lock()
-			ch, ok := c.pendingCalls[mapKey]
-			c.pendingCallsMu.Unlock()
+		c.pendingCallsMu.Lock()
+		ch, ok := c.pendingCalls[mapKey]
+		c.pendingCallsMu.Unlock()
 
-			if ok && ch != nil {
-				responseForCall := &Response{
-					JSONRPC: incomingMsg.JSONRPC,
-					Result:  incomingMsg.Result,
-					Error:   incomingMsg.Error,
-					ID:      incomingMsg.ID,
-				}
-				select {
-				case ch <- responseForCall:
-				case <-c.ctx.Done():
-				}
-			} else {
-				fmt.Printf("jsonrpc: received response for unknown or already handled ID: %v\n", mapKey)
+		if ok && ch != nil {
+			select {
+			case ch <- responseForCall:
+			case <-c.ctx.Done():
 			}
 		} else {
-			fmt.Printf("jsonrpc: received ill-formed message (no method and no/null ID for dispatch): %s\n", string(payload))
+			fmt.Printf("jsonrpc: received response for unknown or already handled ID: %v\n", mapKey)
 		}
+	} else {
+		fmt.Printf("jsonrpc: received ill-formed message (no method and no/null ID for dispatch): %s\n", string(payload))
+	}
+}
+
+// answerRequest runs the handler for a request from the server and sends its result back.
+// found reports whether a handler is registered; if not, the request is answered with a MethodNotFound error.
+func (c *Client) answerRequest(request IncomingMessage, handler RequestHandler, found bool) {
+	response := Response{JSONRPC: "2.0", ID: request.ID}
+	if !found {
+		response.Error = NewMethodNotFound(fmt.Sprintf("Method not found: %s", request.Method))
+	} else if result, errObj := handler(request.Params); errObj != nil {
+		response.Error = errObj
+	} else if data, err := json.Marshal(result); err != nil {
+		response.Error = NewInternalError(fmt.Sprintf("failed to encode result: %v", err))
+	} else {
+		raw := json.RawMessage(data) // A nil result is sent as null.
+		response.Result = &raw
+	}
+
+	responseBytes, err := json.Marshal(response)
+	if err != nil {
+		fmt.Printf("jsonrpc: failed to encode response to request '%s': %v\n", request.Method, err)
+		return
+	}
+	if err := c.transport.Send(c.ctx, responseBytes); err != nil {
+		fmt.Printf("jsonrpc: failed to send response to request '%s': %v\n", request.Method, err)
 	}
 }
 
@@ -391,17 +629,8 @@ func ParseResponse(jsonResponse []byte) (id interface{}, result *json.RawMessage
 	if parseErr != nil {
 		return nil, nil, nil, parseErr
 	}
-	if resp.JSONRPC != "" && resp.JSONRPC != "2.0" {
-		return resp.ID, nil, &ErrorObject{Code: -32600, Message: "Invalid JSON-RPC version"}, nil
-	}
-
-	// Validate mutual exclusivity of result and error
-	if resp.Error != nil && resp.Result != nil {
-		return resp.ID, nil, nil, fmt.Errorf("jsonrpc: response contains both result and error fields")
-	}
-	if resp.Error == nil && resp.Result == nil {
-		// A valid success response must have a "result" field (even if null), and an error response must have an "error" field.
-		return resp.ID, nil, nil, fmt.Errorf("jsonrpc: response contains neither result nor error field")
+	if err := ValidateResponse(&resp); err != nil {
+		return resp.ID, nil, nil, err
 	}
 
 	return resp.ID, resp.Result, resp.Error, nil
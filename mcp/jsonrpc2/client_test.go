@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json" // For TestClientHandlesNotification
 	"errors"        // For TestClientHandlesNotification
+	"fmt"
 	"io"
 	"testing"
 	"time"
@@ -200,3 +201,280 @@ func TestClientHandlesNotification(t *testing.T) {
 	}
 	<-serverDone
 }
+
+func TestValidateResponse(t *testing.T) {
+	result := json.RawMessage(`{"ok":true}`)
+	null := json.RawMessage(`null`)
+	failure := NewMethodNotFound("")
+
+	testCases := []struct {
+		name     string
+		response Response
+		wantErr  error
+	}{
+		{"result", Response{JSONRPC: "2.0", ID: 1, Result: &result}, nil},
+		{"error", Response{JSONRPC: "2.0", ID: 1, Error: failure}, nil},
+		{"both present", Response{JSONRPC: "2.0", ID: 1, Result: &result, Error: failure}, ErrResultAndError},
+		{"null result", Response{JSONRPC: "2.0", ID: 1, Result: &null}, nil},
+		{"neither present", Response{JSONRPC: "2.0", ID: 1}, ErrNoResultNorError},
+		{"bad version", Response{JSONRPC: "1.0", ID: 1, Result: &result}, ErrInvalidVersion},
+		{"missing version", Response{ID: 1, Result: &result}, ErrInvalidVersion},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateResponse(&tc.response)
+			if tc.wantErr == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseResponseValidates(t *testing.T) {
+	_, _, _, err := ParseResponse([]byte(`{"jsonrpc":"2.0","id":1}`))
+	assert.ErrorIs(t, err, ErrNoResultNorError)
+
+	_, _, _, err = ParseResponse([]byte(`{"jsonrpc":"1.0","id":1,"result":{}}`))
+	assert.ErrorIs(t, err, ErrInvalidVersion)
+
+	id, result, errResp, err := ParseResponse([]byte(`{"jsonrpc":"2.0","id":1,"result":{"ok":true}}`))
+	assert.NoError(t, err)
+	assert.Equal(t, float64(1), id)
+	assert.Nil(t, errResp)
+	assert.JSONEq(t, `{"ok":true}`, string(*result))
+
+	_, result, _, err = ParseResponse([]byte(`{"jsonrpc":"2.0","id":1,"result":null}`))
+	assert.NoError(t, err, "A null result is a valid result")
+	if assert.NotNil(t, result) {
+		assert.Equal(t, "null", string(*result))
+	}
+}
+
+// replyTransport answers every request with reply, using the ID of the request.
+type replyTransport struct {
+	reply     string
+	responses chan []byte
+}
+
+func (rt *replyTransport) Send(ctx context.Context, payload []byte) error {
+	var request Request
+	if err := json.Unmarshal(payload, &request); err != nil {
+		return err
+	}
+	rt.responses <- []byte(fmt.Sprintf(rt.reply, request.ID))
+	return nil
+}
+
+func (rt *replyTransport) Receive(ctx context.Context) ([]byte, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case response := <-rt.responses:
+		return response, nil
+	}
+}
+
+func TestCallAcceptsNullResult(t *testing.T) {
+	c := NewClient(&replyTransport{reply: `{"jsonrpc":"2.0","id":%v,"result":null}`, responses: make(chan []byte, 1)})
+	go c.Listen()
+	defer c.Close()
+
+	var result map[string]string
+	err := c.Call(context.Background(), ClientCallArgs{Method: "nothing"}, &result)
+
+	assert.NoError(t, err)
+	assert.Nil(t, result)
+}
+
+func TestCallReturnsInvalidResponseError(t *testing.T) {
+	c := NewClientWithOptions(&replyTransport{reply: `{"jsonrpc":"2.0","id":%v}`, responses: make(chan []byte, 1)}, ClientOptions{DefaultCallTimeout: time.Minute})
+	go c.Listen()
+	defer c.Close()
+
+	start := time.Now()
+	err := c.Call(context.Background(), ClientCallArgs{Method: "broken"}, nil)
+
+	assert.ErrorIs(t, err, ErrNoResultNorError)
+	assert.Less(t, time.Since(start), 5*time.Second, "Call should return once the invalid response arrives")
+}
+
+func TestCallUsesDefaultTimeout(t *testing.T) {
+	transport := &mockTransport{
+		writeBuf: new(bytes.Buffer),
+		readBuf:  new(bytes.Buffer), // The server never answers.
+		closed:   make(chan struct{}),
+	}
+	c := NewClientWithOptions(transport, ClientOptions{DefaultCallTimeout: 20 * time.Millisecond})
+	go c.Listen()
+	defer c.Close()
+
+	start := time.Now()
+	err := c.Call(context.Background(), ClientCallArgs{Method: "slowMethod"}, nil)
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 5*time.Second, "Call should return once the default timeout expires")
+	c.pendingCallsMu.Lock()
+	assert.Empty(t, c.pendingCalls, "The timed out call should no longer be pending")
+	c.pendingCallsMu.Unlock()
+}
+
+func TestCallKeepsCallerDeadline(t *testing.T) {
+	transport := &mockTransport{
+		writeBuf: new(bytes.Buffer),
+		readBuf:  new(bytes.Buffer),
+		closed:   make(chan struct{}),
+	}
+	c := NewClientWithOptions(transport, ClientOptions{DefaultCallTimeout: time.Hour})
+	go c.Listen()
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := c.Call(ctx, ClientCallArgs{Method: "slowMethod"}, nil)
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+// batchTransport answers batch requests with an array of responses in reverse order,
+// failing every call to the method "fail".
+type batchTransport struct {
+	responses chan []byte
+	sent      [][]byte
+}
+
+func (bt *batchTransport) Send(ctx context.Context, payload []byte) error {
+	bt.sent = append(bt.sent, payload)
+	var requests []Request
+	if err := json.Unmarshal(payload, &requests); err != nil {
+		return err
+	}
+	responses := []map[string]interface{}{}
+	for i := len(requests) - 1; i >= 0; i-- {
+		response := map[string]interface{}{"jsonrpc": "2.0", "id": requests[i].ID}
+		if requests[i].Method == "fail" {
+			response["error"] = NewInvalidParams("bad input")
+		} else {
+			response["result"] = map[string]string{"method": requests[i].Method}
+		}
+		responses = append(responses, response)
+	}
+	data, err := json.Marshal(responses)
+	if err != nil {
+		return err
+	}
+	bt.responses <- data
+	return nil
+}
+
+func (bt *batchTransport) Receive(ctx context.Context) ([]byte, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case response := <-bt.responses:
+		return response, nil
+	}
+}
+
+func TestCallBatch(t *testing.T) {
+	transport := &batchTransport{responses: make(chan []byte, 1)}
+	c := NewClient(transport)
+	go c.Listen()
+	defer c.Close()
+
+	var first, third map[string]string
+	calls := []ClientCallArgs{{Method: "first"}, {Method: "fail"}, {Method: "third"}}
+	err := c.CallBatch(context.Background(), calls, []interface{}{&first, nil, &third})
+
+	assert.Len(t, transport.sent, 1, "The batch should be sent in a single payload")
+	assert.Equal(t, "first", first["method"])
+	assert.Equal(t, "third", third["method"])
+	var errObj *ErrorObject
+	if assert.ErrorAs(t, err, &errObj) {
+		assert.Equal(t, InvalidParams, errObj.Code)
+	}
+	assert.Contains(t, err.Error(), "batch call 1 (fail)")
+	c.pendingCallsMu.Lock()
+	assert.Empty(t, c.pendingCalls, "No call of the batch should still be pending")
+	c.pendingCallsMu.Unlock()
+}
+
+func TestCallBatchRequiresADestinationPerCall(t *testing.T) {
+	c := NewClient(&batchTransport{responses: make(chan []byte, 1)})
+
+	err := c.CallBatch(context.Background(), []ClientCallArgs{{Method: "first"}}, nil)
+
+	assert.Error(t, err)
+}
+
+// chanTransport delivers the messages written to incoming and publishes sent payloads on sent.
+type chanTransport struct {
+	incoming chan []byte
+	sent     chan []byte
+}
+
+func (ct *chanTransport) Send(ctx context.Context, payload []byte) error {
+	ct.sent <- payload
+	return nil
+}
+
+func (ct *chanTransport) Receive(ctx context.Context) ([]byte, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case message := <-ct.incoming:
+		return message, nil
+	}
+}
+
+func TestClientAnswersServerRequests(t *testing.T) {
+	transport := &chanTransport{incoming: make(chan []byte, 3), sent: make(chan []byte, 3)}
+	c := NewClient(transport)
+	c.OnRequest("sampling/createMessage", func(params *json.RawMessage) (interface{}, *ErrorObject) {
+		var request struct {
+			Prompt string `json:"prompt"`
+		}
+		if err := json.Unmarshal(*params, &request); err != nil {
+			return nil, NewInvalidParams(err.Error())
+		}
+		return map[string]string{"text": "echo: " + request.Prompt}, nil
+	})
+	notified := make(chan bool, 1)
+	c.OnNotification("sampling/createMessage", func(params *json.RawMessage) error {
+		notified <- true
+		return nil
+	})
+	go c.Listen()
+	defer c.Close()
+
+	transport.incoming <- []byte(`{"jsonrpc":"2.0","id":"req-1","method":"sampling/createMessage","params":{"prompt":"hi"}}`)
+	transport.incoming <- []byte(`{"jsonrpc":"2.0","id":7,"method":"roots/list"}`)
+
+	responses := map[string]Response{}
+	for range 2 {
+		select {
+		case payload := <-transport.sent:
+			var response Response
+			if err := json.Unmarshal(payload, &response); err != nil {
+				t.Fatalf("invalid response %s: %v", payload, err)
+			}
+			assert.NoError(t, ValidateResponse(&response))
+			responses[fmt.Sprint(response.ID)] = response
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for the responses")
+		}
+	}
+
+	if answered, ok := responses["req-1"]; assert.True(t, ok, "expected a response with the request's ID") && assert.NotNil(t, answered.Result) {
+		assert.JSONEq(t, `{"text":"echo: hi"}`, string(*answered.Result))
+	}
+	if unknown, ok := responses["7"]; assert.True(t, ok, "expected a response to the unknown method") && assert.NotNil(t, unknown.Error) {
+		assert.Equal(t, MethodNotFound, unknown.Error.Code)
+	}
+	select {
+	case <-notified:
+		t.Error("A request must not be handled as a notification")
+	default:
+	}
+}
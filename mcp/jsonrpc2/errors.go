@@ -0,0 +1,52 @@
+package jsonrpc2
+
+import "fmt"
+
+// Error codes defined by the JSON-RPC 2.0 specification.
+// Codes from -32000 to -32099 are reserved for implementation-defined server errors.
+const (
+	ParseError     = -32700 // Invalid JSON was received.
+	InvalidRequest = -32600 // The JSON sent is not a valid request object.
+	MethodNotFound = -32601 // The method does not exist or is not available.
+	InvalidParams  = -32602 // Invalid method parameters.
+	InternalError  = -32603 // Internal JSON-RPC error.
+)
+
+// standardMessages are the messages the specification gives for the standard error codes.
+var standardMessages = map[int]string{
+	ParseError:     "Parse error",
+	InvalidRequest: "Invalid Request",
+	MethodNotFound: "Method not found",
+	InvalidParams:  "Invalid params",
+	InternalError:  "Internal error",
+}
+
+// Error implements the error interface, so that error objects can be returned as errors.
+// Client.Call returns the error objects it receives, so callers can inspect them with errors.As.
+func (e *ErrorObject) Error() string {
+	return fmt.Sprintf("jsonrpc: server error (code: %d): %s", e.Code, e.Message)
+}
+
+// newStandardError returns an error object with code and message,
+// or the message given by the specification if message is empty.
+func newStandardError(code int, message string) *ErrorObject {
+	if message == "" {
+		message = standardMessages[code]
+	}
+	return &ErrorObject{Code: code, Message: message}
+}
+
+// NewParseError returns an error object with the code ParseError.
+func NewParseError(message string) *ErrorObject { return newStandardError(ParseError, message) }
+
+// NewInvalidRequest returns an error object with the code InvalidRequest.
+func NewInvalidRequest(message string) *ErrorObject { return newStandardError(InvalidRequest, message) }
+
+// NewMethodNotFound returns an error object with the code MethodNotFound.
+func NewMethodNotFound(message string) *ErrorObject { return newStandardError(MethodNotFound, message) }
+
+// NewInvalidParams returns an error object with the code InvalidParams.
+func NewInvalidParams(message string) *ErrorObject { return newStandardError(InvalidParams, message) }
+
+// NewInternalError returns an error object with the code InternalError.
+func NewInternalError(message string) *ErrorObject { return newStandardError(InternalError, message) }
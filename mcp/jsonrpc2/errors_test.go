@@ -0,0 +1,23 @@
+package jsonrpc2
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStandardErrorCodesMatchSpec(t *testing.T) {
+	assert.Equal(t, -32700, ParseError)
+	assert.Equal(t, -32600, InvalidRequest)
+	assert.Equal(t, -32601, MethodNotFound)
+	assert.Equal(t, -32602, InvalidParams)
+	assert.Equal(t, -32603, InternalError)
+}
+
+func TestStandardErrorConstructors(t *testing.T) {
+	assert.Equal(t, &ErrorObject{Code: MethodNotFound, Message: "no such method: tools/run"}, NewMethodNotFound("no such method: tools/run"))
+	assert.Equal(t, &ErrorObject{Code: InvalidParams, Message: "Invalid params"}, NewInvalidParams(""))
+
+	var err error = NewInternalError("boom")
+	assert.EqualError(t, err, "jsonrpc: server error (code: -32603): boom")
+}
@@ -2,12 +2,15 @@ package mcp
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"strings" // Added for NewServer
+	"sync"
 
 	"github.com/dhamidi/smolcode/mcp/jsonrpc2" // Assuming this is the correct path
 )
@@ -52,9 +55,25 @@ func (s *stdioReadWriteCloser) Close() error {
 // Server represents an MCP server process and the client to communicate with it.
 type Server struct {
 	id      string
+	command string // The command as given to NewServer
 	cmdPath string
 	cmdArgs []string // Changed from cmd string to cmdPath and cmdArgs
 
+	started      bool
+	initResult   InitializeResult
+	maxToolPages int // See SetMaxToolPages; zero means DefaultMaxToolPages.
+
+	// MaxRestarts is the number of times Call restarts the server after its process died.
+	// NewServer sets it to DefaultMaxRestarts; zero disables restarts.
+	MaxRestarts int
+	// OnRestart, if set, is called after every attempt to restart the server.
+	OnRestart func(event RestartEvent)
+
+	restarts     int
+	restartMutex sync.Mutex
+	processCtx   context.Context // The context the process was started with, reused for restarts.
+	listenerDone chan struct{}   // Closed when the listener of the current process stops, e.g. because the process exited.
+
 	proc      *exec.Cmd
 	rpcClient *jsonrpc2.Client
 	closer    io.Closer // To close the subprocess's pipes
@@ -77,7 +96,15 @@ type InitializeParams struct {
 // Based on typical JSON-RPC, but mcp/docs.md doesn't specify its structure.
 // Assuming it might be an empty object or contain server capabilities.
 type InitializeResult struct {
-	Capabilities map[string]interface{} `json:"capabilities,omitempty"`
+	ProtocolVersion string                 `json:"protocolVersion,omitempty"`
+	Capabilities    map[string]interface{} `json:"capabilities,omitempty"`
+	ServerInfo      ServerInfo             `json:"serverInfo,omitempty"`
+}
+
+// ServerInfo describes the server implementation, as reported in the "initialize" response.
+type ServerInfo struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
 }
 
 // ToolsListParams defines the parameters for the "tools/list" request.
@@ -128,8 +155,11 @@ func NewServer(id string, cmd string) *Server {
 	}
 	return &Server{
 		id:      id,
+		command: cmd,
 		cmdPath: cmdPath,
 		cmdArgs: cmdArgs,
+
+		MaxRestarts: DefaultMaxRestarts,
 		// rpcClient, proc, and closer will be set in Start()
 		// requestIDCounter: 0, // Removed as jsonrpc2.Client handles IDs
 	}
@@ -140,9 +170,38 @@ func (s *Server) ID() string {
 	return s.id
 }
 
+// Command returns the command the server is started with.
+func (s *Server) Command() string {
+	return s.command
+}
+
+// Started reports whether the server has been started successfully.
+func (s *Server) Started() bool {
+	return s.started
+}
+
+// VersionHash returns a hash of the server implementation and protocol version
+// reported during initialization. It is empty before the server is started.
+func (s *Server) VersionHash() string {
+	if !s.started {
+		return ""
+	}
+	data, _ := json.Marshal([]string{s.initResult.ProtocolVersion, s.initResult.ServerInfo.Name, s.initResult.ServerInfo.Version})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
 // Start starts the server subprocess and performs the initialization handshake.
+// The subprocess is stopped when ctx is done.
 func (s *Server) Start(ctx context.Context) error {
-	s.proc = exec.CommandContext(ctx, s.cmdPath, s.cmdArgs...)
+	return s.start(ctx, ctx)
+}
+
+// start starts the server subprocess, which is stopped when processCtx is done,
+// and performs the initialization handshake within ctx.
+func (s *Server) start(processCtx context.Context, ctx context.Context) error {
+	s.processCtx = processCtx
+	s.proc = exec.CommandContext(processCtx, s.cmdPath, s.cmdArgs...)
 
 	stdin, err := s.proc.StdinPipe()
 	if err != nil {
@@ -168,8 +227,11 @@ func (s *Server) Start(ctx context.Context) error {
 		return fmt.Errorf("failed to start server process: %w", err)
 	}
 
+	client, listenerDone := s.rpcClient, make(chan struct{})
+	s.listenerDone = listenerDone
 	go func() {
-		err := s.rpcClient.Listen()
+		defer close(listenerDone)
+		err := client.Listen()
 		if err != nil && err != io.EOF && err != context.Canceled && !strings.Contains(err.Error(), "file already closed") {
 			fmt.Fprintf(os.Stderr, "MCP client listener error: %v\n", err)
 		}
@@ -205,26 +267,51 @@ func (s *Server) Start(ctx context.Context) error {
 		return fmt.Errorf("jsonrpc notify to 'notifications/initialized' failed: %w", err)
 	}
 
+	s.initResult = initResult
+	s.started = true
 	return nil
 }
 
-// ListTools sends a "tools/list" request to the server.
-func (s *Server) ListTools(ctx context.Context) (Tools, error) {
-	listParams := ToolsListParams{ /* Cursor: "" */ } // Empty cursor for the first request
-	var listResult ToolsListResult
+// DefaultMaxToolPages is the number of pages ListTools and ListResources request at most, see SetMaxToolPages.
+const DefaultMaxToolPages = 100
 
-	callArgs := jsonrpc2.ClientCallArgs{
-		Method: "tools/list",
-		Params: listParams,
-	}
+// SetMaxToolPages limits the number of "tools/list" pages ListTools requests,
+// and the number of "resources/list" pages ListResources requests,
+// which protects against servers that keep returning a cursor.
+// Zero or less restores DefaultMaxToolPages.
+func (s *Server) SetMaxToolPages(pages int) {
+	s.maxToolPages = pages
+}
 
-	if err := s.rpcClient.Call(ctx, callArgs, &listResult); err != nil {
-		return nil, fmt.Errorf("jsonrpc call to 'tools/list' failed: %w", err)
+// ListTools sends "tools/list" requests to the server and returns the tools of all pages.
+// It follows the cursor returned with each page until a page comes without one.
+func (s *Server) ListTools(ctx context.Context) (Tools, error) {
+	maxPages := s.maxToolPages
+	if maxPages <= 0 {
+		maxPages = DefaultMaxToolPages
 	}
 
-	// TODO: Handle pagination using listResult.NextCursor if necessary.
-	// For now, we return only the first page of tools.
-	return listResult.Tools, nil
+	tools := Tools{}
+	listParams := ToolsListParams{} // Empty cursor for the first request
+	for page := 1; ; page++ {
+		var listResult ToolsListResult
+		callArgs := jsonrpc2.ClientCallArgs{
+			Method: "tools/list",
+			Params: listParams,
+		}
+		if err := s.rpcClient.Call(ctx, callArgs, &listResult); err != nil {
+			return nil, fmt.Errorf("jsonrpc call to 'tools/list' failed: %w", err)
+		}
+		tools = append(tools, listResult.Tools...)
+
+		if listResult.NextCursor == "" {
+			return tools, nil
+		}
+		if page >= maxPages {
+			return nil, fmt.Errorf("'tools/list' still returned a cursor (%q) after %d pages; the server may be repeating its cursor", listResult.NextCursor, maxPages)
+		}
+		listParams.Cursor = listResult.NextCursor
+	}
 }
 
 // ByName finds a tool by its name from a list of tools.
@@ -238,7 +325,24 @@ func (t Tools) ByName(name string) (Tool, bool) {
 }
 
 // Call sends a "tools/call" request to the server for the specified tool.
+// If the server process has died, it is restarted first, see MaxRestarts.
+// If the process dies during the call, the server is restarted and the call is retried once.
 func (s *Server) Call(ctx context.Context, toolName string, params map[string]any) ([]ToolResultContent, error) {
+	if err := s.ensureAlive(ctx); err != nil {
+		return nil, err
+	}
+	content, err := s.call(ctx, toolName, params)
+	if err != nil && s.died(listenerExitGrace) {
+		if restartErr := s.ensureAlive(ctx); restartErr != nil {
+			return nil, fmt.Errorf("%w (%v)", err, restartErr)
+		}
+		return s.call(ctx, toolName, params)
+	}
+	return content, err
+}
+
+// call sends a single "tools/call" request.
+func (s *Server) call(ctx context.Context, toolName string, params map[string]any) ([]ToolResultContent, error) {
 	callPayload := ToolsCallParams{
 		Name:      toolName,
 		Arguments: params,
@@ -385,7 +489,7 @@ func (t *stdioTransport) Close() error {
 		// Check for os.ErrClosed specifically. Since os.ErrClosed is a specific error value,
 		// direct comparison is fine. Using err.Error() == os.ErrClosed.Error() is also okay
 		// but direct comparison is more idiomatic for sentinel errors.
-		if strings.Contains(err.Error(), "file already closed") || err == os.ErrClosed {
+		if err != nil && (strings.Contains(err.Error(), "file already closed") || err == os.ErrClosed) {
 			// log.Println("stdioTransport.Close: Closer was already closed, ignoring.")
 			return nil
 		}
@@ -0,0 +1,99 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/dhamidi/smolcode/mcp/jsonrpc2"
+)
+
+// fakeTransport answers list requests with the page registered for the requested cursor,
+// and "resources/read" requests with the result registered for the requested URI.
+type fakeTransport struct {
+	pages     map[string]any // Keyed by cursor or URI.
+	responses chan []byte
+	cursors   []string
+}
+
+func (f *fakeTransport) Send(ctx context.Context, payload []byte) error {
+	var request struct {
+		ID     uint64 `json:"id"`
+		Params struct {
+			Cursor string `json:"cursor"`
+			URI    string `json:"uri"`
+		} `json:"params"`
+	}
+	if err := json.Unmarshal(payload, &request); err != nil {
+		return err
+	}
+	f.cursors = append(f.cursors, request.Params.Cursor)
+	result, err := json.Marshal(f.pages[request.Params.Cursor+request.Params.URI])
+	if err != nil {
+		return err
+	}
+	f.responses <- []byte(fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"result":%s}`, request.ID, result))
+	return nil
+}
+
+func (f *fakeTransport) Receive(ctx context.Context) ([]byte, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case response := <-f.responses:
+		return response, nil
+	}
+}
+
+func (f *fakeTransport) Close() error { return nil }
+
+// newFakeServer returns a server whose requests are answered with pages.
+func newFakeServer(t *testing.T, pages map[string]any) (*Server, *fakeTransport) {
+	t.Helper()
+	transport := &fakeTransport{pages: pages, responses: make(chan []byte, 1)}
+	server := &Server{id: "fake", rpcClient: jsonrpc2.NewClient(transport)}
+	go server.rpcClient.Listen()
+	t.Cleanup(func() { server.rpcClient.Close() })
+	return server, transport
+}
+
+func TestListToolsFollowsCursors(t *testing.T) {
+	server, transport := newFakeServer(t, map[string]any{
+		"":       ToolsListResult{Tools: []Tool{{Name: "read"}, {Name: "write"}}, NextCursor: "page-2"},
+		"page-2": ToolsListResult{Tools: []Tool{{Name: "search"}}},
+	})
+
+	tools, err := server.ListTools(context.Background())
+	if err != nil {
+		t.Fatalf("ListTools failed: %v", err)
+	}
+
+	var names []string
+	for _, tool := range tools {
+		names = append(names, tool.Name)
+	}
+	if got := strings.Join(names, ","); got != "read,write,search" {
+		t.Errorf("expected the tools of both pages, got %s", got)
+	}
+	if got := strings.Join(transport.cursors, ","); got != ",page-2" {
+		t.Errorf("expected requests for the first page and page-2, got cursors %q", transport.cursors)
+	}
+}
+
+func TestListToolsStopsAtMaxPages(t *testing.T) {
+	server, transport := newFakeServer(t, map[string]any{
+		"":      ToolsListResult{Tools: []Tool{{Name: "read"}}, NextCursor: "again"},
+		"again": ToolsListResult{Tools: []Tool{{Name: "read"}}, NextCursor: "again"},
+	})
+	server.SetMaxToolPages(3)
+
+	_, err := server.ListTools(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "after 3 pages") {
+		t.Fatalf("expected an error after 3 pages, got %v", err)
+	}
+	if len(transport.cursors) != 3 {
+		t.Errorf("expected 3 requests, got %d", len(transport.cursors))
+	}
+}
@@ -0,0 +1,90 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dhamidi/smolcode/mcp/jsonrpc2"
+)
+
+// Resource describes a piece of context, like a file, that a server exposes.
+type Resource struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+}
+
+// ResourceContent is the content of a resource, as returned by "resources/read".
+// Exactly one of Text and Blob is set.
+type ResourceContent struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType,omitempty"`
+	Text     string `json:"text,omitempty"` // non-empty for text resources
+	Blob     string `json:"blob,omitempty"` // base64 encoded data of binary resources
+}
+
+// ResourcesListParams defines the parameters for the "resources/list" request.
+type ResourcesListParams struct {
+	Cursor string `json:"cursor,omitempty"`
+}
+
+// ResourcesListResult defines the result for the "resources/list" response.
+type ResourcesListResult struct {
+	Resources  []Resource `json:"resources"`
+	NextCursor string     `json:"nextCursor,omitempty"`
+}
+
+// ResourcesReadParams defines the parameters for the "resources/read" request.
+type ResourcesReadParams struct {
+	URI string `json:"uri"`
+}
+
+// ResourcesReadResult defines the result for the "resources/read" response.
+type ResourcesReadResult struct {
+	Contents []ResourceContent `json:"contents"`
+}
+
+// ListResources sends "resources/list" requests to the server and returns the resources of all pages.
+// Like ListTools, it follows the cursor returned with each page, up to the limit set with SetMaxToolPages.
+func (s *Server) ListResources(ctx context.Context) ([]Resource, error) {
+	maxPages := s.maxToolPages
+	if maxPages <= 0 {
+		maxPages = DefaultMaxToolPages
+	}
+
+	resources := []Resource{}
+	listParams := ResourcesListParams{} // Empty cursor for the first request
+	for page := 1; ; page++ {
+		var listResult ResourcesListResult
+		callArgs := jsonrpc2.ClientCallArgs{
+			Method: "resources/list",
+			Params: listParams,
+		}
+		if err := s.rpcClient.Call(ctx, callArgs, &listResult); err != nil {
+			return nil, fmt.Errorf("jsonrpc call to 'resources/list' failed: %w", err)
+		}
+		resources = append(resources, listResult.Resources...)
+
+		if listResult.NextCursor == "" {
+			return resources, nil
+		}
+		if page >= maxPages {
+			return nil, fmt.Errorf("'resources/list' still returned a cursor (%q) after %d pages; the server may be repeating its cursor", listResult.NextCursor, maxPages)
+		}
+		listParams.Cursor = listResult.NextCursor
+	}
+}
+
+// ReadResource sends a "resources/read" request for uri and returns the contents of the resource.
+func (s *Server) ReadResource(ctx context.Context, uri string) ([]ResourceContent, error) {
+	var readResult ResourcesReadResult
+	callArgs := jsonrpc2.ClientCallArgs{
+		Method: "resources/read",
+		Params: ResourcesReadParams{URI: uri},
+	}
+	if err := s.rpcClient.Call(ctx, callArgs, &readResult); err != nil {
+		return nil, fmt.Errorf("jsonrpc call to 'resources/read' (uri: %s) failed: %w", uri, err)
+	}
+	return readResult.Contents, nil
+}
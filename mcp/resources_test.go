@@ -0,0 +1,58 @@
+package mcp
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestListResourcesFollowsCursors(t *testing.T) {
+	server, transport := newFakeServer(t, map[string]any{
+		"":       ResourcesListResult{Resources: []Resource{{URI: "file:///README.md", Name: "README.md"}}, NextCursor: "page-2"},
+		"page-2": ResourcesListResult{Resources: []Resource{{URI: "file:///docs/api.md", Name: "api.md"}}},
+	})
+
+	resources, err := server.ListResources(context.Background())
+	if err != nil {
+		t.Fatalf("ListResources failed: %v", err)
+	}
+
+	var uris []string
+	for _, resource := range resources {
+		uris = append(uris, resource.URI)
+	}
+	if got := strings.Join(uris, ","); got != "file:///README.md,file:///docs/api.md" {
+		t.Errorf("expected the resources of both pages, got %s", got)
+	}
+	if got := strings.Join(transport.cursors, ","); got != ",page-2" {
+		t.Errorf("expected requests for the first page and page-2, got cursors %q", transport.cursors)
+	}
+}
+
+func TestListResourcesStopsAtMaxPages(t *testing.T) {
+	server, _ := newFakeServer(t, map[string]any{
+		"":      ResourcesListResult{NextCursor: "again"},
+		"again": ResourcesListResult{NextCursor: "again"},
+	})
+	server.SetMaxToolPages(2)
+
+	_, err := server.ListResources(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "after 2 pages") {
+		t.Fatalf("expected an error after 2 pages, got %v", err)
+	}
+}
+
+func TestReadResource(t *testing.T) {
+	server, _ := newFakeServer(t, map[string]any{
+		"file:///README.md": ResourcesReadResult{Contents: []ResourceContent{{URI: "file:///README.md", MimeType: "text/markdown", Text: "# smolcode"}}},
+	})
+
+	contents, err := server.ReadResource(context.Background(), "file:///README.md")
+	if err != nil {
+		t.Fatalf("ReadResource failed: %v", err)
+	}
+
+	if len(contents) != 1 || contents[0].Text != "# smolcode" || contents[0].MimeType != "text/markdown" {
+		t.Errorf("expected the README contents, got %+v", contents)
+	}
+}
@@ -0,0 +1,69 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// DefaultMaxRestarts is the number of times a server created by NewServer is restarted after its process died.
+const DefaultMaxRestarts = 3
+
+// listenerExitGrace is how long Call waits for the listener to notice that the process died after a call failed.
+const listenerExitGrace = 100 * time.Millisecond
+
+// RestartEvent describes an attempt to restart a server whose process died.
+type RestartEvent struct {
+	ServerID string
+	Attempt  int   // Counts restarts over the lifetime of the server, starting at 1.
+	Err      error // Why the restart failed; nil if the server is running again.
+}
+
+// died reports whether the process of a started server has stopped responding,
+// waiting up to grace for its listener to notice.
+func (s *Server) died(grace time.Duration) bool {
+	if !s.started || s.listenerDone == nil {
+		return false
+	}
+	timer := time.NewTimer(grace)
+	defer timer.Stop()
+	select {
+	case <-s.listenerDone:
+		return true
+	default:
+	}
+	select {
+	case <-s.listenerDone:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// ensureAlive restarts the server if its process died and performs the initialization handshake again within ctx.
+// The old process, its pipes and the listener goroutine are cleaned up before the new process is started.
+// It returns an error once the server has been restarted MaxRestarts times.
+func (s *Server) ensureAlive(ctx context.Context) error {
+	s.restartMutex.Lock()
+	defer s.restartMutex.Unlock()
+	if !s.died(0) {
+		return nil
+	}
+	if s.restarts >= s.MaxRestarts {
+		return fmt.Errorf("MCP server %s has stopped and was already restarted %d times", s.id, s.restarts)
+	}
+
+	// The process is gone, so errors from stopping it are expected.
+	_ = s.Close()
+	s.restarts++
+	var err error
+	if s.processCtx.Err() != nil {
+		err = fmt.Errorf("MCP server %s was stopped: %w", s.id, s.processCtx.Err())
+	} else if err = s.start(s.processCtx, ctx); err != nil {
+		err = fmt.Errorf("failed to restart MCP server %s: %w", s.id, err)
+	}
+	if s.OnRestart != nil {
+		s.OnRestart(RestartEvent{ServerID: s.id, Attempt: s.restarts, Err: err})
+	}
+	return err
+}
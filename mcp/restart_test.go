@@ -0,0 +1,62 @@
+package mcp
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// crashingServerScript is an MCP server that exits after answering a tool call.
+// The first time it is called, it exits without answering.
+const crashingServerScript = `#!/bin/sh
+while read -r line; do
+  id=$(printf '%s' "$line" | sed -n 's/.*"id":\([0-9]*\).*/\1/p')
+  case "$line" in
+    *'"initialize"'*)
+      echo '{"jsonrpc":"2.0","id":'$id',"result":{"protocolVersion":"2024-11-05","serverInfo":{"name":"crashing","version":"1"}}}' ;;
+    *'"tools/call"'*)
+      if [ ! -e answered ]; then touch answered; exit 1; fi
+      echo '{"jsonrpc":"2.0","id":'$id',"result":{"content":[{"type":"text","text":"pid '$$'"}]}}'
+      exit 0 ;;
+  esac
+done
+`
+
+func TestCallRestartsDeadServer(t *testing.T) {
+	t.Chdir(t.TempDir())
+	script := filepath.Join(t.TempDir(), "server.sh")
+	if err := os.WriteFile(script, []byte(crashingServerScript), 0755); err != nil {
+		t.Fatal(err)
+	}
+	server := NewServer("crashing", script)
+	server.MaxRestarts = 2
+	var events []RestartEvent
+	server.OnRestart = func(event RestartEvent) { events = append(events, event) }
+	if err := server.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	t.Cleanup(func() { server.Close() })
+
+	// The process dies during the first call, which is retried after a restart.
+	first, err := server.Call(context.Background(), "echo", nil)
+	if err != nil {
+		t.Fatalf("expected the call to be retried after a restart, got %v", err)
+	}
+	// The process exited after answering, so it is restarted before the second call.
+	second, err := server.Call(context.Background(), "echo", nil)
+	if err != nil {
+		t.Fatalf("expected the server to be restarted before the call, got %v", err)
+	}
+	if first[0].Text == second[0].Text {
+		t.Errorf("expected answers from different processes, got %q twice", first[0].Text)
+	}
+	if len(events) != 2 || events[0].Attempt != 1 || events[1].Attempt != 2 || events[0].Err != nil || events[1].Err != nil {
+		t.Errorf("expected two successful restart events, got %+v", events)
+	}
+
+	if _, err := server.Call(context.Background(), "echo", nil); err == nil || !strings.Contains(err.Error(), "restarted 2 times") {
+		t.Errorf("expected an error once MaxRestarts is reached, got %v", err)
+	}
+}
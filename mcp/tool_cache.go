@@ -0,0 +1,90 @@
+package mcp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// DefaultToolCacheDir is the directory tool catalogs are cached in by default.
+const DefaultToolCacheDir = ".smolcode/mcp-tools"
+
+// ErrNoCachedCatalog is returned by ToolCache.Load if no catalog is cached for a command.
+var ErrNoCachedCatalog = errors.New("no cached tool catalog")
+
+// ToolCatalog is the list of tools a server exposes, as kept in a ToolCache.
+type ToolCatalog struct {
+	Command     string `json:"command"`     // The command the server was started with.
+	VersionHash string `json:"versionHash"` // See Server.VersionHash.
+	Tools       Tools  `json:"tools"`
+}
+
+// ToolCache keeps the tool catalogs of servers on disk, so that their tools
+// can be registered without starting them.
+// Catalogs are keyed by the server command: changing the command of a server
+// means its catalog is no longer found.
+type ToolCache struct {
+	Dir string
+}
+
+// NewToolCache returns a cache keeping catalogs in dir, which is created when first written to.
+func NewToolCache(dir string) *ToolCache {
+	return &ToolCache{Dir: dir}
+}
+
+// path returns the file the catalog for command is stored in.
+func (cache *ToolCache) path(command string) string {
+	sum := sha256.Sum256([]byte(command))
+	return filepath.Join(cache.Dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// Load returns the catalog cached for command, or ErrNoCachedCatalog if there is none.
+func (cache *ToolCache) Load(command string) (*ToolCatalog, error) {
+	data, err := os.ReadFile(cache.path(command))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNoCachedCatalog
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tool catalog: %w", err)
+	}
+	var catalog ToolCatalog
+	if err := json.Unmarshal(data, &catalog); err != nil {
+		return nil, fmt.Errorf("failed to decode tool catalog: %w", err)
+	}
+	if catalog.Command != command {
+		return nil, ErrNoCachedCatalog
+	}
+	return &catalog, nil
+}
+
+// Store caches catalog, replacing the catalog previously stored for its command.
+func (cache *ToolCache) Store(catalog *ToolCatalog) error {
+	data, err := json.Marshal(catalog)
+	if err != nil {
+		return fmt.Errorf("failed to encode tool catalog: %w", err)
+	}
+	if err := os.MkdirAll(cache.Dir, 0755); err != nil {
+		return fmt.Errorf("failed to create tool cache directory: %w", err)
+	}
+	path := cache.path(catalog.Command)
+	tmp, err := os.CreateTemp(cache.Dir, "catalog-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write tool catalog: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write tool catalog: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write tool catalog: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write tool catalog: %w", err)
+	}
+	return nil
+}
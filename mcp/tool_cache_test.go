@@ -0,0 +1,43 @@
+package mcp
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestToolCacheRoundTrip(t *testing.T) {
+	cache := NewToolCache(t.TempDir())
+	catalog := &ToolCatalog{
+		Command:     "docs-server --stdio",
+		VersionHash: "abc",
+		Tools:       Tools{{Name: "search", Description: "Search the docs", RawInputSchema: json.RawMessage(`{"type":"object"}`)}},
+	}
+
+	if err := cache.Store(catalog); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	loaded, err := cache.Load("docs-server --stdio")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if diff := cmp.Diff(catalog, loaded); diff != "" {
+		t.Errorf("loaded catalog mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestToolCacheMissesForChangedCommand(t *testing.T) {
+	cache := NewToolCache(t.TempDir())
+	if err := cache.Store(&ToolCatalog{Command: "docs-server --stdio"}); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	_, err := cache.Load("docs-server --stdio --verbose")
+
+	if !errors.Is(err, ErrNoCachedCatalog) {
+		t.Errorf("expected ErrNoCachedCatalog, got %v", err)
+	}
+}
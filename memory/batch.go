@@ -0,0 +1,142 @@
+package memory
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// BatchResult reports what AddMemories did with each entry.
+type BatchResult struct {
+	Added   int
+	Updated int
+	Skipped int // Entries whose content was already stored unchanged.
+}
+
+// AddMemories adds or updates all memories within a single transaction.
+// Either all memories are stored or, on error, none are.
+func (m *MemoryManager) AddMemories(memories []*Memory) (BatchResult, error) {
+	var result BatchResult
+
+	tx, err := m.db.Begin()
+	if err != nil {
+		return result, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, mem := range memories {
+		var existing string
+		err := tx.QueryRow(`SELECT content FROM memories WHERE id = ?;`, mem.ID).Scan(&existing)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			if _, err := tx.Exec(`INSERT INTO memories (id, content, created_at, updated_at) VALUES (?, ?, `+nowSQL+`, `+nowSQL+`);`, mem.ID, mem.Content); err != nil {
+				return BatchResult{}, fmt.Errorf("failed to insert memory with id %s: %w", mem.ID, err)
+			}
+			result.Added++
+		case err != nil:
+			return BatchResult{}, fmt.Errorf("failed to query memory with id %s: %w", mem.ID, err)
+		case existing == mem.Content:
+			result.Skipped++
+		default:
+			if _, err := tx.Exec(`UPDATE memories SET content = ?, updated_at = `+nowSQL+` WHERE id = ?;`, mem.Content, mem.ID); err != nil {
+				return BatchResult{}, fmt.Errorf("failed to update memory with id %s: %w", mem.ID, err)
+			}
+			result.Updated++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return BatchResult{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return result, nil
+}
+
+// ParseEntries reads memories from r in the given format:
+//
+//   - "tsv": one memory per line, written as id<TAB>content; blank lines are ignored
+//   - "csv": two columns, id and content; a leading "id,content" header is ignored
+//   - "json": an array of {"id": ..., "content": ...} objects
+//
+// It returns an error if an ID is empty or appears more than once in the input.
+func ParseEntries(r io.Reader, format string) ([]*Memory, error) {
+	var memories []*Memory
+	var err error
+	switch format {
+	case "tsv":
+		memories, err = parseTSVEntries(r)
+	case "csv":
+		memories, err = parseCSVEntries(r)
+	case "json":
+		err = json.NewDecoder(r).Decode(&memories)
+	default:
+		return nil, fmt.Errorf("unsupported format %q, expected tsv, csv or json", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]int{}
+	for i, mem := range memories {
+		if mem == nil || mem.ID == "" {
+			return nil, fmt.Errorf("entry %d has an empty id", i+1)
+		}
+		seen[mem.ID]++
+	}
+	var conflicts []string
+	for id, count := range seen {
+		if count > 1 {
+			conflicts = append(conflicts, fmt.Sprintf("%s (%d times)", id, count))
+		}
+	}
+	if len(conflicts) > 0 {
+		sort.Strings(conflicts)
+		return nil, fmt.Errorf("duplicate ids in input: %s", strings.Join(conflicts, ", "))
+	}
+	return memories, nil
+}
+
+func parseTSVEntries(r io.Reader) ([]*Memory, error) {
+	var memories []*Memory
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		id, content, found := strings.Cut(line, "\t")
+		if !found {
+			return nil, fmt.Errorf("line %d: expected id<TAB>content", lineNumber)
+		}
+		memories = append(memories, &Memory{ID: strings.TrimSpace(id), Content: content})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read input: %w", err)
+	}
+	return memories, nil
+}
+
+func parseCSVEntries(r io.Reader) ([]*Memory, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 2
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read csv: %w", err)
+	}
+	if len(records) > 0 && strings.EqualFold(records[0][0], "id") && strings.EqualFold(records[0][1], "content") {
+		records = records[1:]
+	}
+	memories := make([]*Memory, 0, len(records))
+	for _, record := range records {
+		memories = append(memories, &Memory{ID: strings.TrimSpace(record[0]), Content: record[1]})
+	}
+	return memories, nil
+}
@@ -0,0 +1,87 @@
+package memory
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAddMemories(t *testing.T) {
+	mm, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	if err := mm.AddMemory("existing-same", "unchanged"); err != nil {
+		t.Fatalf("AddMemory failed: %v", err)
+	}
+	if err := mm.AddMemory("existing-changed", "old content"); err != nil {
+		t.Fatalf("AddMemory failed: %v", err)
+	}
+
+	result, err := mm.AddMemories([]*Memory{
+		{ID: "new", Content: "brand new"},
+		{ID: "existing-same", Content: "unchanged"},
+		{ID: "existing-changed", Content: "new content"},
+	})
+	if err != nil {
+		t.Fatalf("AddMemories failed: %v", err)
+	}
+	if result != (BatchResult{Added: 1, Updated: 1, Skipped: 1}) {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	mem, err := mm.GetMemoryByID("existing-changed")
+	if err != nil {
+		t.Fatalf("GetMemoryByID failed: %v", err)
+	}
+	if mem.Content != "new content" {
+		t.Errorf("expected updated content, got %q", mem.Content)
+	}
+
+	results, err := mm.SearchMemory("brand")
+	if err != nil {
+		t.Fatalf("SearchMemory failed: %v", err)
+	}
+	if len(results) != 1 || results[0].ID != "new" {
+		t.Errorf("expected batch-added memory to be searchable, got %v", results)
+	}
+}
+
+func TestParseEntries(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		input   string
+		wantIDs []string
+		wantErr string
+	}{
+		{name: "tsv", format: "tsv", input: "a\tfirst\n\nb\tsecond\twith tab\n", wantIDs: []string{"a", "b"}},
+		{name: "tsv without tab", format: "tsv", input: "a\tfirst\nbroken\n", wantErr: "line 2"},
+		{name: "csv with header", format: "csv", input: "id,content\na,first\nb,\"second, quoted\"\n", wantIDs: []string{"a", "b"}},
+		{name: "json", format: "json", input: `[{"id":"a","content":"first"},{"id":"b","content":"second"}]`, wantIDs: []string{"a", "b"}},
+		{name: "duplicate ids", format: "tsv", input: "a\tfirst\na\tagain\n", wantErr: "duplicate ids in input: a (2 times)"},
+		{name: "empty id", format: "json", input: `[{"id":"","content":"first"}]`, wantErr: "empty id"},
+		{name: "unknown format", format: "yaml", input: "", wantErr: "unsupported format"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			memories, err := ParseEntries(strings.NewReader(tt.input), tt.format)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseEntries failed: %v", err)
+			}
+			if len(memories) != len(tt.wantIDs) {
+				t.Fatalf("expected %d memories, got %d", len(tt.wantIDs), len(memories))
+			}
+			for i, id := range tt.wantIDs {
+				if memories[i].ID != id {
+					t.Errorf("memory %d: expected id %q, got %q", i, id, memories[i].ID)
+				}
+			}
+		})
+	}
+}
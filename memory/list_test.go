@@ -0,0 +1,114 @@
+package memory
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestListAllOrdersByUpdateTime(t *testing.T) {
+	mm, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	for _, id := range []string{"first", "second", "third"} {
+		if err := mm.AddMemory(id, "content of "+id); err != nil {
+			t.Fatalf("AddMemory failed: %v", err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	before, err := mm.GetMemoryByID("first")
+	if err != nil {
+		t.Fatalf("GetMemoryByID failed: %v", err)
+	}
+	if err := mm.AddMemory("first", "updated content"); err != nil {
+		t.Fatalf("AddMemory (update) failed: %v", err)
+	}
+
+	memories, err := mm.ListAll()
+	if err != nil {
+		t.Fatalf("ListAll failed: %v", err)
+	}
+	var ids []string
+	for _, mem := range memories {
+		ids = append(ids, mem.ID)
+	}
+	want := []string{"first", "third", "second"}
+	if len(ids) != len(want) {
+		t.Fatalf("ListAll returned %v, want %v", ids, want)
+	}
+	for i := range want {
+		if ids[i] != want[i] {
+			t.Fatalf("ListAll returned %v, want %v", ids, want)
+		}
+	}
+
+	updated := memories[0]
+	if updated.Content != "updated content" {
+		t.Errorf("expected updated content, got %q", updated.Content)
+	}
+	if !updated.CreatedAt.Equal(before.CreatedAt) {
+		t.Errorf("updating changed CreatedAt from %v to %v", before.CreatedAt, updated.CreatedAt)
+	}
+	if !updated.UpdatedAt.After(before.UpdatedAt) {
+		t.Errorf("expected UpdatedAt after %v, got %v", before.UpdatedAt, updated.UpdatedAt)
+	}
+	if time.Since(updated.CreatedAt) > time.Minute || time.Since(updated.CreatedAt) < 0 {
+		t.Errorf("CreatedAt %v is not close to now", updated.CreatedAt)
+	}
+}
+
+func TestNewAddsTimestampColumns(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "old.db")
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	// The schema before memories had timestamps.
+	if _, err := db.Exec(`
+	CREATE TABLE memories (docid INTEGER PRIMARY KEY AUTOINCREMENT, id TEXT UNIQUE NOT NULL, content TEXT NOT NULL);
+	CREATE VIRTUAL TABLE memories_fts USING fts5(content, content='memories', content_rowid='docid');
+	CREATE TRIGGER memories_ai AFTER INSERT ON memories BEGIN
+		INSERT INTO memories_fts (rowid, content) VALUES (new.docid, new.content);
+	END;
+	CREATE TRIGGER memories_au AFTER UPDATE ON memories BEGIN
+		INSERT INTO memories_fts (memories_fts, rowid, content) VALUES ('delete', old.docid, old.content);
+		INSERT INTO memories_fts (rowid, content) VALUES (new.docid, new.content);
+	END;
+	INSERT INTO memories (id, content) VALUES ('old', 'from before timestamps');
+	`); err != nil {
+		t.Fatalf("failed to create old schema: %v", err)
+	}
+	db.Close()
+
+	mm, err := New(dbPath)
+	if err != nil {
+		t.Fatalf("New() on old database failed: %v", err)
+	}
+	defer mm.Close()
+
+	mem, err := mm.GetMemoryByID("old")
+	if err != nil {
+		t.Fatalf("GetMemoryByID failed: %v", err)
+	}
+	if mem.CreatedAt.IsZero() || mem.UpdatedAt.IsZero() {
+		t.Errorf("expected existing memory to get timestamps, got %+v", mem)
+	}
+	if err := mm.AddMemory("new", "after the upgrade"); err != nil {
+		t.Fatalf("AddMemory failed: %v", err)
+	}
+	memories, err := mm.ListAll()
+	if err != nil {
+		t.Fatalf("ListAll failed: %v", err)
+	}
+	if len(memories) != 2 || memories[0].ID != "new" {
+		t.Errorf("expected the new memory first, got %d memories", len(memories))
+	}
+	results, err := mm.SearchMemory("timestamps")
+	if err != nil {
+		t.Fatalf("SearchMemory failed: %v", err)
+	}
+	if len(results) != 1 || results[0].ID != "old" {
+		t.Errorf("expected the old memory to remain searchable, got %v", results)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -20,8 +21,59 @@ type MemoryManager struct {
 }
 
 type Memory struct {
-	ID      string
-	Content string
+	ID        string
+	Content   string
+	CreatedAt time.Time
+	UpdatedAt time.Time // When the content was last set.
+	Tags      []string  // Sorted and normalized, see AddMemoryWithTags.
+}
+
+// nowSQL is the current time in the format of the created_at and updated_at columns,
+// with milliseconds so that memories changed within the same second are ordered correctly.
+const nowSQL = `strftime('%Y-%m-%d %H:%M:%f', 'now')`
+
+// memoryColumns are the columns scanned by scanMemory, in order.
+const memoryColumns = `id, content, created_at, updated_at`
+
+// scanMemory reads a memory from row, which must select memoryColumns.
+func scanMemory(row interface{ Scan(...any) error }) (*Memory, error) {
+	mem := &Memory{}
+	var createdAt, updatedAt sql.NullTime
+	if err := row.Scan(&mem.ID, &mem.Content, &createdAt, &updatedAt); err != nil {
+		return nil, err
+	}
+	mem.CreatedAt = createdAt.Time
+	mem.UpdatedAt = updatedAt.Time
+	return mem, nil
+}
+
+// SearchOptions tunes how SearchMemoryWithOptions interprets a query.
+type SearchOptions struct {
+	// Prefix matches simple terms as prefixes, so that "config" also finds "configuration".
+	// Terms containing special characters and FTS keywords are still matched literally.
+	Prefix bool
+
+	// Any matches memories containing any of the terms instead of all of them.
+	Any bool
+
+	// Boolean treats AND, OR and NOT in the query as FTS operators instead of literal words,
+	// e.g. "sqlite AND NOT postgres". Other terms are escaped as usual.
+	Boolean bool
+
+	// Limit caps the number of results, keeping the best matches by rank. Zero returns all matches.
+	Limit int
+
+	// Tag restricts the results to memories with this tag. Empty matches memories regardless of their tags.
+	Tag string
+}
+
+// isFTSOperator reports whether token is one of the FTS5 boolean operators.
+func isFTSOperator(token string) bool {
+	switch token {
+	case "AND", "OR", "NOT":
+		return true
+	}
+	return false
 }
 
 func New(dbPath string) (*MemoryManager, error) {
@@ -45,6 +97,42 @@ func initializeSchema(db *sql.DB) error {
 	if err != nil {
 		return fmt.Errorf("failed to execute schema initialization SQL: %w", err)
 	}
+	return addTimestampColumns(db)
+}
+
+// addTimestampColumns adds the created_at and updated_at columns to databases created without them.
+// Existing memories get the time of the upgrade, as their real creation time is unknown.
+func addTimestampColumns(db *sql.DB) error {
+	rows, err := db.Query(`SELECT name FROM pragma_table_info('memories');`)
+	if err != nil {
+		return fmt.Errorf("failed to inspect memories table: %w", err)
+	}
+	existing := map[string]bool{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to inspect memories table: %w", err)
+		}
+		existing[name] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to inspect memories table: %w", err)
+	}
+
+	for _, column := range []string{"created_at", "updated_at"} {
+		if existing[column] {
+			continue
+		}
+		// SQLite does not allow adding a column with a non-constant default, so existing rows are filled in afterwards.
+		if _, err := db.Exec(fmt.Sprintf(`ALTER TABLE memories ADD COLUMN %s DATETIME;`, column)); err != nil {
+			return fmt.Errorf("failed to add column %s: %w", column, err)
+		}
+		if _, err := db.Exec(fmt.Sprintf(`UPDATE memories SET %s = %s WHERE %s IS NULL;`, column, nowSQL, column)); err != nil {
+			return fmt.Errorf("failed to set column %s: %w", column, err)
+		}
+	}
 	return nil
 }
 
@@ -55,14 +143,18 @@ func (m *MemoryManager) Close() error {
 	return nil
 }
 
-func (m *MemoryManager) AddMemory(id string, content string) error {
-	insertSQL := `
-	INSERT INTO memories (id, content)
-	VALUES (?, ?)
+// upsertMemorySQL adds a memory, or replaces the content of the memory with the same id.
+const upsertMemorySQL = `
+	INSERT INTO memories (id, content, created_at, updated_at)
+	VALUES (?, ?, ` + nowSQL + `, ` + nowSQL + `)
 	ON CONFLICT(id) DO UPDATE SET
-		content = excluded.content;
+		content = excluded.content,
+		updated_at = excluded.updated_at;
 	`
-	_, err := m.db.Exec(insertSQL, id, content)
+
+// AddMemory adds a memory or updates its content. The tags of an existing memory are kept.
+func (m *MemoryManager) AddMemory(id string, content string) error {
+	_, err := m.db.Exec(upsertMemorySQL, id, content)
 	if err != nil {
 		return fmt.Errorf("failed to insert/replace memory with id %s: %w", id, err)
 	}
@@ -70,16 +162,17 @@ func (m *MemoryManager) AddMemory(id string, content string) error {
 }
 
 func (m *MemoryManager) GetMemoryByID(id string) (*Memory, error) {
-	querySQL := `SELECT id, content FROM memories WHERE id = ?;`
-	row := m.db.QueryRow(querySQL, id)
-	mem := &Memory{}
-	err := row.Scan(&mem.ID, &mem.Content)
+	querySQL := `SELECT ` + memoryColumns + ` FROM memories WHERE id = ?;`
+	mem, err := scanMemory(m.db.QueryRow(querySQL, id))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, fmt.Errorf("memory with id '%s': %w", id, ErrNotFound)
 		}
 		return nil, fmt.Errorf("failed to retrieve memory with id '%s': %w", id, err)
 	}
+	if err := m.attachTags([]*Memory{mem}); err != nil {
+		return nil, err
+	}
 	return mem, nil
 }
 
@@ -117,6 +210,10 @@ func escapeAndPrepareFTSToken(token string) string {
 }
 
 func prepareFTSQuery(query string) string {
+	return prepareFTSQueryWithOptions(query, SearchOptions{})
+}
+
+func prepareFTSQueryWithOptions(query string, opts SearchOptions) string {
 	trimmedQuery := strings.TrimSpace(query)
 	if trimmedQuery == "" {
 		return "\"\""
@@ -126,51 +223,116 @@ func prepareFTSQuery(query string) string {
 		return escapeAndPrepareFTSToken(trimmedQuery)
 	}
 	var preparedTerms []string
+	separator := " "
+	if opts.Any && !opts.Boolean {
+		separator = " OR "
+	}
 	for _, term := range terms {
-		preparedTerms = append(preparedTerms, escapeAndPrepareFTSToken(term))
+		if opts.Boolean && isFTSOperator(term) {
+			preparedTerms = append(preparedTerms, term)
+			continue
+		}
+		prepared := escapeAndPrepareFTSToken(term)
+		if opts.Prefix && prepared == term {
+			// Only unquoted barewords are safe to turn into FTS5 prefix queries.
+			prepared += "*"
+		}
+		preparedTerms = append(preparedTerms, prepared)
 	}
-	return strings.Join(preparedTerms, " ")
+	return strings.Join(preparedTerms, separator)
 }
 
 func (m *MemoryManager) SearchMemory(query string) ([]*Memory, error) {
+	return m.SearchMemoryWithOptions(query, SearchOptions{})
+}
+
+func (m *MemoryManager) SearchMemoryWithOptions(query string, opts SearchOptions) ([]*Memory, error) {
+	hits, err := m.SearchMemoryHits(query, opts)
+	if err != nil {
+		return nil, err
+	}
+	memories := make([]*Memory, len(hits))
+	for i, hit := range hits {
+		memories[i] = hit.Memory
+	}
+	return memories, nil
+}
+
+// MemoryHit is a memory found by a search, together with how well it matches.
+type MemoryHit struct {
+	*Memory
+	// Score is the negated FTS5 rank (bm25) of the match: higher scores are better matches.
+	// Scores are only comparable between hits of the same search.
+	Score float64
+}
+
+// SearchMemoryN returns at most limit memories matching query, best matches first.
+// A limit of zero or less returns all matches.
+func (m *MemoryManager) SearchMemoryN(query string, limit int) ([]*MemoryHit, error) {
+	return m.SearchMemoryHits(query, SearchOptions{Limit: limit})
+}
+
+// SearchMemoryHits returns the memories matching query together with their scores, best matches first.
+func (m *MemoryManager) SearchMemoryHits(query string, opts SearchOptions) ([]*MemoryHit, error) {
+	if strings.TrimSpace(query) == "" {
+		// An empty query matches nothing, there is no need to ask FTS about it.
+		return []*MemoryHit{}, nil
+	}
+	ftsFinalQuery := prepareFTSQueryWithOptions(query, opts)
+	args := []any{ftsFinalQuery}
+	tagFilter := ""
+	if opts.Tag != "" {
+		// The tag is a separate parameter, so the MATCH expression is the same with and without it.
+		tagFilter = `AND fts.rowid IN (SELECT m.docid FROM memories AS m JOIN memory_tags AS t ON t.memory_id = m.id WHERE t.tag = ?)`
+		args = append(args, normalizeTag(opts.Tag))
+	}
 	ftsQuerySQL := `
-	SELECT fts.rowid 
+	SELECT fts.rowid, fts.rank
 	FROM memories_fts AS fts
 	WHERE fts.memories_fts MATCH ?
-	ORDER BY rank;
+	` + tagFilter + `
+	ORDER BY rank
+	LIMIT ?;
 	`
-	ftsFinalQuery := prepareFTSQuery(query)
-	rows, err := m.db.Query(ftsQuerySQL, ftsFinalQuery)
+	limit := -1 // SQLite treats a negative limit as no limit.
+	if opts.Limit > 0 {
+		limit = opts.Limit
+	}
+	args = append(args, limit)
+	rows, err := m.db.Query(ftsQuerySQL, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute FTS query MATCH '%s': %w", ftsFinalQuery, err)
 	}
 	defer rows.Close()
 	var docIDs []int64
+	var ranks []float64
 	for rows.Next() {
 		var docID int64
-		if err := rows.Scan(&docID); err != nil {
+		var rank float64
+		if err := rows.Scan(&docID, &rank); err != nil {
 			rows.Close()
 			return nil, fmt.Errorf("failed to scan docID from FTS results: %w", err)
 		}
 		docIDs = append(docIDs, docID)
+		ranks = append(ranks, rank)
 	}
 	if err = rows.Err(); err != nil {
 		rows.Close()
 		return nil, fmt.Errorf("error iterating FTS docID results: %w", err)
 	}
 	if len(docIDs) == 0 {
-		return []*Memory{}, nil
+		return []*MemoryHit{}, nil
 	}
 	var memories []*Memory
-	stmtSQL := `SELECT id, content FROM memories WHERE docid = ?;`
+	var hits []*MemoryHit
+	stmtSQL := `SELECT ` + memoryColumns + ` FROM memories WHERE docid = ?;`
 	stmt, err := m.db.Prepare(stmtSQL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to prepare statement to get memory by docid: %w", err)
 	}
 	defer stmt.Close()
-	for _, docID := range docIDs {
-		mem := &Memory{}
-		err := stmt.QueryRow(docID).Scan(&mem.ID, &mem.Content)
+	for i, docID := range docIDs {
+		mem, err := scanMemory(stmt.QueryRow(docID))
 		if err != nil {
 			if errors.Is(err, sql.ErrNoRows) {
 				return nil, fmt.Errorf("FTS returned docID %d but no matching memory found (data inconsistency?): %w", docID, err)
@@ -178,6 +340,35 @@ func (m *MemoryManager) SearchMemory(query string) ([]*Memory, error) {
 			return nil, fmt.Errorf("failed to retrieve memory for docID %d: %w", docID, err)
 		}
 		memories = append(memories, mem)
+		hits = append(hits, &MemoryHit{Memory: mem, Score: -ranks[i]})
+	}
+	if err := m.attachTags(memories); err != nil {
+		return nil, err
+	}
+	return hits, nil
+}
+
+// ListAll returns all memories, most recently updated first.
+func (m *MemoryManager) ListAll() ([]*Memory, error) {
+	rows, err := m.db.Query(`SELECT ` + memoryColumns + ` FROM memories ORDER BY updated_at DESC, docid DESC;`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list memories: %w", err)
+	}
+	defer rows.Close()
+	memories := []*Memory{}
+	for rows.Next() {
+		mem, err := scanMemory(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan memory: %w", err)
+		}
+		memories = append(memories, mem)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating memories: %w", err)
+	}
+	rows.Close()
+	if err := m.attachTags(memories); err != nil {
+		return nil, err
 	}
 	return memories, nil
 }
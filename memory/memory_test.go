@@ -43,6 +43,212 @@ func TestPrepareFTSQuery(t *testing.T) {
 	}
 }
 
+func TestPrepareFTSQueryWithPrefix(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"simple string", "config", "config*"},
+		{"multiple terms", "config file", "config* file*"},
+		{"quoted term stays literal", "\"config\"", "\"\"\"config\"\"\""},
+		{"complex term stays literal", "path/to/file.txt", "\"path/to/file.txt\""},
+		{"keyword stays literal", "config AND file", "config* \"AND\" file*"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := prepareFTSQueryWithOptions(tt.input, SearchOptions{Prefix: true}); got != tt.want {
+				t.Errorf("prepareFTSQueryWithOptions(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchMemoryWithPrefix(t *testing.T) {
+	mm, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	if err := mm.AddMemory("cfg", "The configuration lives in config.json"); err != nil {
+		t.Fatalf("AddMemory failed: %v", err)
+	}
+	if err := mm.AddMemory("other", "Configure nothing here, just conf"); err != nil {
+		t.Fatalf("AddMemory failed: %v", err)
+	}
+
+	results, err := mm.SearchMemory("configur")
+	if err != nil {
+		t.Fatalf("SearchMemory failed: %v", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("expected no results without prefix mode, got %d", len(results))
+	}
+
+	results, err = mm.SearchMemoryWithOptions("configur", SearchOptions{Prefix: true})
+	if err != nil {
+		t.Fatalf("SearchMemoryWithOptions failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Errorf("expected 'configur*' to match both memories, got %d", len(results))
+	}
+
+	results, err = mm.SearchMemoryWithOptions("\"conf\"", SearchOptions{Prefix: true})
+	if err != nil {
+		t.Fatalf("SearchMemoryWithOptions failed: %v", err)
+	}
+	if len(results) != 1 || results[0].ID != "other" {
+		t.Errorf("expected quoted term to match exactly one memory, got %v", results)
+	}
+}
+
+func TestPrepareFTSQueryWithOperators(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		opts  SearchOptions
+		want  string
+	}{
+		{"any term", "sqlite postgres", SearchOptions{Any: true}, "sqlite OR postgres"},
+		{"any term keeps keywords literal", "sqlite AND postgres", SearchOptions{Any: true}, "sqlite OR \"AND\" OR postgres"},
+		{"any term with prefix", "sql post", SearchOptions{Any: true, Prefix: true}, "sql* OR post*"},
+		{"boolean operators pass through", "sqlite AND NOT postgres", SearchOptions{Boolean: true}, "sqlite AND NOT postgres"},
+		{"boolean escapes other terms", "main.go OR agent.go", SearchOptions{Boolean: true}, "\"main.go\" OR \"agent.go\""},
+		{"boolean operators are case-sensitive", "sqlite and postgres", SearchOptions{Boolean: true}, "sqlite \"and\" postgres"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := prepareFTSQueryWithOptions(tt.input, tt.opts); got != tt.want {
+				t.Errorf("prepareFTSQueryWithOptions(%q, %+v) = %q, want %q", tt.input, tt.opts, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchMemoryAnyVersusAllTerms(t *testing.T) {
+	mm, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	for id, content := range map[string]string{
+		"both":     "We use sqlite and postgres",
+		"sqlite":   "Memories are stored in sqlite",
+		"postgres": "Production runs on postgres",
+	} {
+		if err := mm.AddMemory(id, content); err != nil {
+			t.Fatalf("AddMemory failed: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		query   string
+		opts    SearchOptions
+		wantIDs []string
+	}{
+		{"all terms by default", "sqlite postgres", SearchOptions{}, []string{"both"}},
+		{"any term", "sqlite postgres", SearchOptions{Any: true}, []string{"both", "sqlite", "postgres"}},
+		{"boolean NOT", "sqlite NOT postgres", SearchOptions{Boolean: true}, []string{"sqlite"}},
+		{"boolean OR", "sqlite OR postgres", SearchOptions{Boolean: true}, []string{"both", "sqlite", "postgres"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := mm.SearchMemoryWithOptions(tt.query, tt.opts)
+			if err != nil {
+				t.Fatalf("SearchMemoryWithOptions failed: %v", err)
+			}
+			found := map[string]bool{}
+			for _, res := range results {
+				found[res.ID] = true
+			}
+			if len(results) != len(tt.wantIDs) {
+				t.Errorf("got %d results, want %d: %v", len(results), len(tt.wantIDs), results)
+			}
+			for _, id := range tt.wantIDs {
+				if !found[id] {
+					t.Errorf("expected result %q, got %v", id, results)
+				}
+			}
+		})
+	}
+}
+
+func TestSearchMemoryWithLimit(t *testing.T) {
+	mm, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	// The memory mentioning the term most often ranks best.
+	memories := map[string]string{
+		"best":   "sqlite sqlite sqlite",
+		"good":   "sqlite sqlite and more words here",
+		"weak":   "sqlite is mentioned once among many other unrelated words in this memory",
+		"absent": "nothing to see here",
+	}
+	for id, content := range memories {
+		if err := mm.AddMemory(id, content); err != nil {
+			t.Fatalf("AddMemory failed: %v", err)
+		}
+	}
+
+	all, err := mm.SearchMemoryWithOptions("sqlite", SearchOptions{})
+	if err != nil {
+		t.Fatalf("SearchMemoryWithOptions failed: %v", err)
+	}
+	if len(all) != 3 {
+		t.Fatalf("expected 3 results without a limit, got %d", len(all))
+	}
+
+	limited, err := mm.SearchMemoryWithOptions("sqlite", SearchOptions{Limit: 2})
+	if err != nil {
+		t.Fatalf("SearchMemoryWithOptions failed: %v", err)
+	}
+	if len(limited) != 2 {
+		t.Fatalf("expected 2 results with limit 2, got %d", len(limited))
+	}
+	if limited[0].ID != "best" || limited[1].ID != "good" {
+		t.Errorf("expected the best ranked results [best good], got [%s %s]", limited[0].ID, limited[1].ID)
+	}
+}
+
+func TestSearchMemoryNScoresHits(t *testing.T) {
+	mm, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	for id, content := range map[string]string{
+		"best": "sqlite sqlite sqlite",
+		"good": "sqlite sqlite and more words here",
+		"weak": "sqlite is mentioned once among many other unrelated words in this memory",
+	} {
+		if err := mm.AddMemory(id, content); err != nil {
+			t.Fatalf("AddMemory failed: %v", err)
+		}
+	}
+
+	hits, err := mm.SearchMemoryN("sqlite", 0)
+	if err != nil {
+		t.Fatalf("SearchMemoryN failed: %v", err)
+	}
+	if len(hits) != 3 {
+		t.Fatalf("expected 3 hits without a limit, got %d", len(hits))
+	}
+	for i := 1; i < len(hits); i++ {
+		if hits[i-1].Score <= hits[i].Score {
+			t.Errorf("expected hits ordered by decreasing score, got %s (%f) before %s (%f)", hits[i-1].ID, hits[i-1].Score, hits[i].ID, hits[i].Score)
+		}
+	}
+	if hits[0].ID != "best" || hits[0].Content != "sqlite sqlite sqlite" {
+		t.Errorf("expected the best hit first, got %+v", hits[0].Memory)
+	}
+
+	limited, err := mm.SearchMemoryN("sqlite", 1)
+	if err != nil {
+		t.Fatalf("SearchMemoryN failed: %v", err)
+	}
+	if len(limited) != 1 || limited[0].ID != "best" || limited[0].Score != hits[0].Score {
+		t.Errorf("expected only the best hit with limit 1, got %d hits", len(limited))
+	}
+}
+
 func setupTestDB(t *testing.T) (*MemoryManager, func()) {
 	tempDir, err := os.MkdirTemp("", "memory_test_")
 	if err != nil {
@@ -106,7 +312,6 @@ func TestSearchMemoryWithSpecialChars(t *testing.T) {
 		{"search path with spaces", "/mnt/my docs/report.docx", []string{"id8"}, 1},
 		{"search non-existent", "nonexistent", []string{}, 0},
 		{"search part of path", "path/to", []string{"id2"}, 1}, // FTS might tokenize this. Exact phrase needed.
-		{"search empty string", "", []string{}, 0},             // We escape to "" which likely matches nothing.
 	}
 
 	for _, st := range searchTests {
@@ -135,6 +340,23 @@ func TestSearchMemoryWithSpecialChars(t *testing.T) {
 		})
 	}
 
+	// Empty queries must return no results without touching the database.
+	// Closing the database makes any query fail, so a nil error proves the short-circuit.
+	t.Run("search empty string short-circuits", func(t *testing.T) {
+		emptyMM, emptyCleanup := setupTestDB(t)
+		defer emptyCleanup()
+		emptyMM.Close()
+		for _, query := range []string{"", "   "} {
+			results, err := emptyMM.SearchMemory(query)
+			if err != nil {
+				t.Errorf("SearchMemory(%q) queried the database: %v", query, err)
+			}
+			if results == nil || len(results) != 0 {
+				t.Errorf("SearchMemory(%q): expected an empty, non-nil result, got %v", query, results)
+			}
+		}
+	})
+
 	// Test specifically how FTS treats the escaped query "path/to/document.txt"
 	// It should find ID2
 	t.Run("search exact path direct FTS expectation", func(t *testing.T) {
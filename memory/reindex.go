@@ -0,0 +1,21 @@
+package memory
+
+import "fmt"
+
+// CheckIndex runs the FTS5 integrity check on the full-text index.
+// Passing a rank of 1 makes FTS5 also compare the index against the external content table.
+// It returns an error describing the problem if the index does not match the memories table.
+func (m *MemoryManager) CheckIndex() error {
+	if _, err := m.db.Exec(`INSERT INTO memories_fts(memories_fts, rank) VALUES('integrity-check', 1);`); err != nil {
+		return fmt.Errorf("full-text index integrity check failed: %w", err)
+	}
+	return nil
+}
+
+// RebuildIndex discards the full-text index and repopulates it from the memories table.
+func (m *MemoryManager) RebuildIndex() error {
+	if _, err := m.db.Exec(`INSERT INTO memories_fts(memories_fts) VALUES('rebuild');`); err != nil {
+		return fmt.Errorf("failed to rebuild full-text index: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package memory
+
+import "testing"
+
+func TestRebuildIndexFixesSearch(t *testing.T) {
+	mm, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	if err := mm.AddMemory("build", "go build -tags fts5"); err != nil {
+		t.Fatalf("AddMemory failed: %v", err)
+	}
+	if err := mm.CheckIndex(); err != nil {
+		t.Fatalf("CheckIndex on a healthy index failed: %v", err)
+	}
+
+	// Corrupt the index by emptying it behind the memories table's back.
+	if _, err := mm.db.Exec(`INSERT INTO memories_fts(memories_fts) VALUES('delete-all');`); err != nil {
+		t.Fatalf("failed to corrupt index: %v", err)
+	}
+	results, err := mm.SearchMemory("build")
+	if err != nil {
+		t.Fatalf("SearchMemory failed: %v", err)
+	}
+	if len(results) != 0 {
+		t.Fatalf("expected corrupted index to return no results, got %d", len(results))
+	}
+	if err := mm.CheckIndex(); err == nil {
+		t.Error("expected CheckIndex to report the corrupted index")
+	}
+
+	if err := mm.RebuildIndex(); err != nil {
+		t.Fatalf("RebuildIndex failed: %v", err)
+	}
+	if err := mm.CheckIndex(); err != nil {
+		t.Errorf("CheckIndex after rebuild failed: %v", err)
+	}
+	results, err = mm.SearchMemory("build")
+	if err != nil {
+		t.Fatalf("SearchMemory failed: %v", err)
+	}
+	if len(results) != 1 || results[0].ID != "build" {
+		t.Errorf("expected rebuilt index to find the memory, got %v", results)
+	}
+}
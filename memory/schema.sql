@@ -1,7 +1,9 @@
 CREATE TABLE IF NOT EXISTS memories (
     docid INTEGER PRIMARY KEY AUTOINCREMENT, -- Integer primary key for FTS
     id TEXT UNIQUE NOT NULL, -- User-facing string ID
-    content TEXT NOT NULL
+    content TEXT NOT NULL,
+    created_at DATETIME DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now')),
+    updated_at DATETIME DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now'))
 );
 
 CREATE VIRTUAL TABLE IF NOT EXISTS memories_fts USING fts5(
@@ -21,3 +23,14 @@ CREATE TRIGGER IF NOT EXISTS memories_au AFTER UPDATE ON memories BEGIN
     INSERT INTO memories_fts (memories_fts, rowid, content) VALUES ('delete', old.docid, old.content);
     INSERT INTO memories_fts (rowid, content) VALUES (new.docid, new.content);
 END;
+
+-- Tags group memories, e.g. by project area. They are normalized to lowercase, see normalizeTags.
+CREATE TABLE IF NOT EXISTS memory_tags (
+    memory_id TEXT NOT NULL, -- The id of the tagged memory
+    tag TEXT NOT NULL,
+    PRIMARY KEY (memory_id, tag)
+);
+CREATE INDEX IF NOT EXISTS memory_tags_tag ON memory_tags (tag);
+CREATE TRIGGER IF NOT EXISTS memories_tags_ad AFTER DELETE ON memories BEGIN
+    DELETE FROM memory_tags WHERE memory_id = old.id;
+END;
@@ -0,0 +1,80 @@
+package memory
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// MemoryStats summarizes the contents of the memory store.
+type MemoryStats struct {
+	TotalEntries      int
+	TotalContentBytes int64
+	AverageBytes      float64
+	SmallestBytes     int64
+	LargestBytes      int64
+	NewestID          string     // Most recently added memory, empty if the store is empty.
+	OldestID          string     // Least recently added memory, empty if the store is empty.
+	Tags              []TagCount // Every tag in use, most used first.
+}
+
+// TagCount is the number of memories with a tag.
+type TagCount struct {
+	Tag   string
+	Count int
+}
+
+// Stats computes summary statistics over all memories.
+// Sizes are computed with a single aggregate query; the newest and oldest
+// memories are found through the docid primary key without scanning the table.
+func (m *MemoryManager) Stats() (MemoryStats, error) {
+	var stats MemoryStats
+	var total, smallest, largest sql.NullInt64
+	var average sql.NullFloat64
+	err := m.db.QueryRow(`
+	SELECT COUNT(*), SUM(LENGTH(CAST(content AS BLOB))), AVG(LENGTH(CAST(content AS BLOB))),
+	       MIN(LENGTH(CAST(content AS BLOB))), MAX(LENGTH(CAST(content AS BLOB)))
+	FROM memories;`).Scan(&stats.TotalEntries, &total, &average, &smallest, &largest)
+	if err != nil {
+		return MemoryStats{}, fmt.Errorf("failed to compute memory statistics: %w", err)
+	}
+	stats.TotalContentBytes = total.Int64
+	stats.AverageBytes = average.Float64
+	stats.SmallestBytes = smallest.Int64
+	stats.LargestBytes = largest.Int64
+
+	if stats.TotalEntries == 0 {
+		return stats, nil
+	}
+	if err := m.db.QueryRow(`SELECT id FROM memories ORDER BY docid DESC LIMIT 1;`).Scan(&stats.NewestID); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return MemoryStats{}, fmt.Errorf("failed to find newest memory: %w", err)
+	}
+	if err := m.db.QueryRow(`SELECT id FROM memories ORDER BY docid ASC LIMIT 1;`).Scan(&stats.OldestID); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return MemoryStats{}, fmt.Errorf("failed to find oldest memory: %w", err)
+	}
+	if stats.Tags, err = m.tagCounts(); err != nil {
+		return MemoryStats{}, err
+	}
+	return stats, nil
+}
+
+// tagCounts returns the number of memories with each tag, most used first and alphabetically among equally used tags.
+func (m *MemoryManager) tagCounts() ([]TagCount, error) {
+	rows, err := m.db.Query(`SELECT tag, COUNT(*) FROM memory_tags GROUP BY tag ORDER BY COUNT(*) DESC, tag;`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count tags: %w", err)
+	}
+	defer rows.Close()
+	var counts []TagCount
+	for rows.Next() {
+		var count TagCount
+		if err := rows.Scan(&count.Tag, &count.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan tag count: %w", err)
+		}
+		counts = append(counts, count)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating tag counts: %w", err)
+	}
+	return counts, nil
+}
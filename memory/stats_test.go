@@ -0,0 +1,72 @@
+package memory
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	mm, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	stats, err := mm.Stats()
+	if err != nil {
+		t.Fatalf("Stats on empty store failed: %v", err)
+	}
+	if !reflect.DeepEqual(stats, MemoryStats{}) {
+		t.Errorf("expected zero stats for empty store, got %+v", stats)
+	}
+
+	for _, mem := range []*Memory{
+		{ID: "first", Content: "ab"},
+		{ID: "second", Content: "abcd"},
+		{ID: "third", Content: "äbcdef"}, // ä is two bytes
+	} {
+		if err := mm.AddMemory(mem.ID, mem.Content); err != nil {
+			t.Fatalf("AddMemory failed: %v", err)
+		}
+	}
+
+	stats, err = mm.Stats()
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	want := MemoryStats{
+		TotalEntries:      3,
+		TotalContentBytes: 13,
+		AverageBytes:      13.0 / 3.0,
+		SmallestBytes:     2,
+		LargestBytes:      7,
+		NewestID:          "third",
+		OldestID:          "first",
+	}
+	if !reflect.DeepEqual(stats, want) {
+		t.Errorf("Stats() = %+v, want %+v", stats, want)
+	}
+}
+
+func TestStatsCountsTags(t *testing.T) {
+	mm, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	for id, tags := range map[string][]string{
+		"schema":   {"database", "go"},
+		"queries":  {"database"},
+		"style":    {"go"},
+		"deploy":   {"ops"},
+		"untagged": nil,
+	} {
+		if err := mm.AddMemoryWithTags(id, "content of "+id, tags); err != nil {
+			t.Fatalf("AddMemoryWithTags failed: %v", err)
+		}
+	}
+
+	stats, err := mm.Stats()
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	want := []TagCount{{"database", 2}, {"go", 2}, {"ops", 1}}
+	if !reflect.DeepEqual(stats.Tags, want) {
+		t.Errorf("Stats().Tags = %+v, want %+v", stats.Tags, want)
+	}
+}
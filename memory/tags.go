@@ -0,0 +1,112 @@
+package memory
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// normalizeTag trims tag and converts it to lowercase, so that "Database" and "database " are the same tag.
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// normalizeTags normalizes every tag, dropping empty and duplicate ones, and sorts the result.
+func normalizeTags(tags []string) []string {
+	normalized := []string{}
+	for _, tag := range tags {
+		if tag = normalizeTag(tag); tag != "" {
+			normalized = append(normalized, tag)
+		}
+	}
+	slices.Sort(normalized)
+	return slices.Compact(normalized)
+}
+
+// AddMemoryWithTags adds a memory or updates its content, and replaces its tags with tags.
+// Tags are case-insensitive and stored in lowercase.
+func (m *MemoryManager) AddMemoryWithTags(id string, content string, tags []string) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(upsertMemorySQL, id, content); err != nil {
+		return fmt.Errorf("failed to insert/replace memory with id %s: %w", id, err)
+	}
+	if _, err := tx.Exec(`DELETE FROM memory_tags WHERE memory_id = ?;`, id); err != nil {
+		return fmt.Errorf("failed to clear tags of memory %s: %w", id, err)
+	}
+	for _, tag := range normalizeTags(tags) {
+		if _, err := tx.Exec(`INSERT INTO memory_tags (memory_id, tag) VALUES (?, ?);`, id, tag); err != nil {
+			return fmt.Errorf("failed to tag memory %s with %q: %w", id, tag, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit memory %s: %w", id, err)
+	}
+	return nil
+}
+
+// SearchMemoryByTag returns all memories with tag, most recently updated first.
+func (m *MemoryManager) SearchMemoryByTag(tag string) ([]*Memory, error) {
+	rows, err := m.db.Query(`
+	SELECT m.id, m.content, m.created_at, m.updated_at
+	FROM memories AS m JOIN memory_tags AS t ON t.memory_id = m.id
+	WHERE t.tag = ?
+	ORDER BY m.updated_at DESC, m.docid DESC;
+	`, normalizeTag(tag))
+	if err != nil {
+		return nil, fmt.Errorf("failed to search memories by tag %q: %w", tag, err)
+	}
+	defer rows.Close()
+	memories := []*Memory{}
+	for rows.Next() {
+		mem, err := scanMemory(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan memory: %w", err)
+		}
+		memories = append(memories, mem)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating memories: %w", err)
+	}
+	rows.Close()
+	if err := m.attachTags(memories); err != nil {
+		return nil, err
+	}
+	return memories, nil
+}
+
+// attachTags sets the Tags of every memory in memories.
+func (m *MemoryManager) attachTags(memories []*Memory) error {
+	if len(memories) == 0 {
+		return nil
+	}
+	byID := make(map[string]*Memory, len(memories))
+	placeholders := make([]string, 0, len(memories))
+	args := make([]any, 0, len(memories))
+	for _, mem := range memories {
+		mem.Tags = []string{}
+		byID[mem.ID] = mem
+		placeholders = append(placeholders, "?")
+		args = append(args, mem.ID)
+	}
+	rows, err := m.db.Query(`SELECT memory_id, tag FROM memory_tags WHERE memory_id IN (`+strings.Join(placeholders, ", ")+`) ORDER BY tag;`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to load tags: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, tag string
+		if err := rows.Scan(&id, &tag); err != nil {
+			return fmt.Errorf("failed to scan tag: %w", err)
+		}
+		byID[id].Tags = append(byID[id].Tags, tag)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating tags: %w", err)
+	}
+	return nil
+}
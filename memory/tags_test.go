@@ -0,0 +1,97 @@
+package memory
+
+import (
+	"slices"
+	"testing"
+)
+
+func memoryIDs(memories []*Memory) []string {
+	ids := []string{}
+	for _, mem := range memories {
+		ids = append(ids, mem.ID)
+	}
+	return ids
+}
+
+func TestNormalizeTags(t *testing.T) {
+	got := normalizeTags([]string{"Database", " sqlite ", "", "database", "CLI"})
+	want := []string{"cli", "database", "sqlite"}
+	if !slices.Equal(got, want) {
+		t.Errorf("normalizeTags() = %v, want %v", got, want)
+	}
+}
+
+func TestAddMemoryWithTags(t *testing.T) {
+	mm, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	if err := mm.AddMemoryWithTags("schema", "the history schema lives in history/schema.sql", []string{"Database", "history"}); err != nil {
+		t.Fatalf("AddMemoryWithTags failed: %v", err)
+	}
+	if err := mm.AddMemoryWithTags("flags", "flags are declared in cmd_default.go", []string{"cli"}); err != nil {
+		t.Fatalf("AddMemoryWithTags failed: %v", err)
+	}
+	if err := mm.AddMemory("untagged", "the schema of plans is in planner"); err != nil {
+		t.Fatalf("AddMemory failed: %v", err)
+	}
+
+	mem, err := mm.GetMemoryByID("schema")
+	if err != nil {
+		t.Fatalf("GetMemoryByID failed: %v", err)
+	}
+	if !slices.Equal(mem.Tags, []string{"database", "history"}) {
+		t.Errorf("expected normalized tags, got %v", mem.Tags)
+	}
+
+	byTag, err := mm.SearchMemoryByTag("DATABASE")
+	if err != nil {
+		t.Fatalf("SearchMemoryByTag failed: %v", err)
+	}
+	if ids := memoryIDs(byTag); !slices.Equal(ids, []string{"schema"}) {
+		t.Errorf("SearchMemoryByTag returned %v, want [schema]", ids)
+	}
+
+	all, err := mm.SearchMemory("schema")
+	if err != nil {
+		t.Fatalf("SearchMemory failed: %v", err)
+	}
+	if len(all) != 2 {
+		t.Errorf("expected 2 memories about the schema, got %v", memoryIDs(all))
+	}
+	tagged, err := mm.SearchMemoryWithOptions("schema", SearchOptions{Tag: "database"})
+	if err != nil {
+		t.Fatalf("SearchMemoryWithOptions with tag failed: %v", err)
+	}
+	if ids := memoryIDs(tagged); !slices.Equal(ids, []string{"schema"}) {
+		t.Errorf("search with tag returned %v, want [schema]", ids)
+	}
+	// Quoted and prefix terms must still work together with the tag filter.
+	tagged, err = mm.SearchMemoryWithOptions(`history/schema.sql decl`, SearchOptions{Tag: "cli", Prefix: true, Any: true})
+	if err != nil {
+		t.Fatalf("SearchMemoryWithOptions with tag and special characters failed: %v", err)
+	}
+	if ids := memoryIDs(tagged); !slices.Equal(ids, []string{"flags"}) {
+		t.Errorf("search with tag and prefix returned %v, want [flags]", ids)
+	}
+
+	// AddMemory keeps the tags, AddMemoryWithTags replaces them.
+	if err := mm.AddMemory("schema", "the history schema is embedded"); err != nil {
+		t.Fatalf("AddMemory failed: %v", err)
+	}
+	if mem, _ := mm.GetMemoryByID("schema"); !slices.Equal(mem.Tags, []string{"database", "history"}) {
+		t.Errorf("expected AddMemory to keep the tags, got %v", mem.Tags)
+	}
+	if err := mm.AddMemoryWithTags("schema", "the history schema is embedded", nil); err != nil {
+		t.Fatalf("AddMemoryWithTags failed: %v", err)
+	}
+	if byTag, _ := mm.SearchMemoryByTag("database"); len(byTag) != 0 {
+		t.Errorf("expected the tags to be removed, got %v", memoryIDs(byTag))
+	}
+
+	if err := mm.Forget("flags"); err != nil {
+		t.Fatalf("Forget failed: %v", err)
+	}
+	if byTag, _ := mm.SearchMemoryByTag("cli"); len(byTag) != 0 {
+		t.Errorf("expected the tags of a forgotten memory to be removed, got %v", memoryIDs(byTag))
+	}
+}
@@ -0,0 +1,122 @@
+package planner
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrDependencyCycle is returned when steps of a plan depend on each other in a cycle,
+// so that none of them can ever be worked on.
+var ErrDependencyCycle = errors.New("step dependencies form a cycle")
+
+// ErrStepsBlocked is returned when no remaining step of a plan can be worked on
+// because each of them waits for a step that is not done.
+var ErrStepsBlocked = errors.New("all remaining steps are blocked by their dependencies")
+
+// NextReadyStep returns the step NextStep would return, but reports why there is none:
+// ErrPlanCompleted if all steps are done,
+// an error wrapping ErrDependencyCycle naming the steps of the cycle if the remaining steps depend on each other,
+// and an error wrapping ErrStepsBlocked otherwise.
+func (pl *Plan) NextReadyStep() (*Step, error) {
+	if step := pl.NextStep(); step != nil {
+		return step, nil
+	}
+	if pl.IsCompleted() {
+		return nil, ErrPlanCompleted
+	}
+	if cycle := pl.findCycle(); cycle != nil {
+		return nil, fmt.Errorf("%w: %s", ErrDependencyCycle, strings.Join(cycle, " -> "))
+	}
+	return nil, fmt.Errorf("%w in plan '%s'", ErrStepsBlocked, pl.ID)
+}
+
+// dependenciesDone reports whether every step that step depends on is marked as "DONE".
+// A dependency on a step that is not part of the plan is never done.
+func (pl *Plan) dependenciesDone(step *Step) bool {
+	for _, id := range step.dependsOn {
+		dependency := pl.step(id)
+		if dependency == nil || strings.ToUpper(dependency.status) != "DONE" {
+			return false
+		}
+	}
+	return true
+}
+
+// step returns the step with the given ID, or nil if the plan has no such step.
+func (pl *Plan) step(id string) *Step {
+	for _, step := range pl.Steps {
+		if step.id == id {
+			return step
+		}
+	}
+	return nil
+}
+
+// checkDependencies returns an error if a step depends on itself, on a step that is not part of the plan,
+// or if the dependencies form a cycle.
+func (pl *Plan) checkDependencies() error {
+	for _, step := range pl.Steps {
+		for _, id := range step.dependsOn {
+			if id == step.id {
+				return fmt.Errorf("step '%s' in plan '%s' cannot depend on itself", step.id, pl.ID)
+			}
+			if pl.step(id) == nil {
+				return fmt.Errorf("step '%s' in plan '%s' depends on unknown step '%s'", step.id, pl.ID, id)
+			}
+		}
+	}
+	if cycle := pl.findCycle(); cycle != nil {
+		return fmt.Errorf("%w in plan '%s': %s", ErrDependencyCycle, pl.ID, strings.Join(cycle, " -> "))
+	}
+	return nil
+}
+
+// findCycle returns the IDs of the steps forming a dependency cycle, starting and ending with the same step,
+// or nil if the dependencies are acyclic.
+// Dependencies on steps that are not part of the plan are ignored.
+func (pl *Plan) findCycle() []string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(pl.Steps))
+	var path []string
+
+	var visit func(step *Step) []string
+	visit = func(step *Step) []string {
+		state[step.id] = visiting
+		path = append(path, step.id)
+		for _, id := range step.dependsOn {
+			dependency := pl.step(id)
+			if dependency == nil {
+				continue
+			}
+			switch state[id] {
+			case visiting:
+				for i, pathID := range path {
+					if pathID == id {
+						return append(append([]string{}, path[i:]...), id)
+					}
+				}
+			case unvisited:
+				if cycle := visit(dependency); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[step.id] = visited
+		return nil
+	}
+
+	for _, step := range pl.Steps {
+		if state[step.id] == unvisited {
+			if cycle := visit(step); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
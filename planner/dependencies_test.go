@@ -0,0 +1,142 @@
+package planner
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPlan_NextStepSkipsBlockedSteps(t *testing.T) {
+	plan := &Plan{ID: "deps-plan", Steps: []*Step{}}
+	plan.AddStep("deploy", "Deploy", nil, "build", "test")
+	plan.AddStep("build", "Build", nil)
+	plan.AddStep("test", "Test", nil, "build")
+
+	var completed []string
+	for next := plan.NextStep(); next != nil; next = plan.NextStep() {
+		completed = append(completed, next.ID())
+		plan.MarkAsCompleted(next.ID())
+	}
+	if expected := []string{"build", "test", "deploy"}; !reflect.DeepEqual(completed, expected) {
+		t.Errorf("steps were completed in order %v, expected %v", completed, expected)
+	}
+	if !plan.IsCompleted() {
+		t.Error("expected the plan to be complete")
+	}
+}
+
+func TestPlan_NextReadyStepReportsCycles(t *testing.T) {
+	plan := &Plan{ID: "cycle-plan", Steps: []*Step{}}
+	plan.AddStep("setup", "Setup", nil)
+	plan.AddStep("a", "A", nil, "b")
+	plan.AddStep("b", "B", nil, "a")
+	plan.MarkAsCompleted("setup")
+
+	if next := plan.NextStep(); next != nil {
+		t.Fatalf("expected no next step, got %s", next.ID())
+	}
+	if plan.IsCompleted() {
+		t.Fatal("a plan with blocked steps must not be completed")
+	}
+	_, err := plan.NextReadyStep()
+	if !errors.Is(err, ErrDependencyCycle) {
+		t.Fatalf("expected ErrDependencyCycle, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "a -> b -> a") {
+		t.Errorf("expected the error to name the cycle, got %q", err)
+	}
+	if _, err := plan.Advance(""); !errors.Is(err, ErrDependencyCycle) {
+		t.Errorf("expected Advance to report the cycle, got %v", err)
+	}
+
+	plan.MarkAsCompleted("a")
+	plan.MarkAsCompleted("b")
+	if _, err := plan.NextReadyStep(); !errors.Is(err, ErrPlanCompleted) {
+		t.Errorf("expected ErrPlanCompleted, got %v", err)
+	}
+}
+
+func TestPlan_NextReadyStepReportsUnknownDependencies(t *testing.T) {
+	plan := &Plan{ID: "blocked-plan", Steps: []*Step{}}
+	plan.AddStep("a", "A", nil, "missing")
+	if _, err := plan.NextReadyStep(); !errors.Is(err, ErrStepsBlocked) {
+		t.Errorf("expected ErrStepsBlocked, got %v", err)
+	}
+}
+
+func TestPlanner_SaveAndGetDependencies(t *testing.T) {
+	p, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	plan, _ := p.Create("deps-plan")
+	plan.AddStep("deploy", "Deploy", nil, "test", "build")
+	plan.AddStep("build", "Build", nil)
+	plan.AddStep("test", "Test", nil, "build")
+	if err := p.Save(plan); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, err := p.Get("deps-plan")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if got := loaded.Steps[0].DependsOn(); !reflect.DeepEqual(got, []string{"test", "build"}) {
+		t.Errorf("deploy depends on %v, expected [test build]", got)
+	}
+	if got := loaded.Steps[1].DependsOn(); len(got) != 0 {
+		t.Errorf("build depends on %v, expected nothing", got)
+	}
+	if next := loaded.NextStep(); next == nil || next.ID() != "build" {
+		t.Fatalf("expected next step build, got %v", next)
+	}
+
+	status, err := p.PlanStatus("deps-plan")
+	if err != nil {
+		t.Fatalf("PlanStatus failed: %v", err)
+	}
+	if status.NextStepID != "build" || status.Status != "TODO" {
+		t.Errorf("expected next step build of an incomplete plan, got %+v", status)
+	}
+
+	// Removing a step removes the dependencies on it.
+	loaded.RemoveSteps([]string{"test"})
+	if err := p.Save(loaded); err != nil {
+		t.Fatalf("Save after RemoveSteps failed: %v", err)
+	}
+	reloaded, err := p.Get("deps-plan")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if got := reloaded.Steps[0].DependsOn(); !reflect.DeepEqual(got, []string{"build"}) {
+		t.Errorf("deploy depends on %v after removing test, expected [build]", got)
+	}
+}
+
+func TestPlanner_SaveRejectsInvalidDependencies(t *testing.T) {
+	p, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	unknown, _ := p.Create("unknown-plan")
+	unknown.AddStep("a", "A", nil, "missing")
+	if err := p.Save(unknown); err == nil || !strings.Contains(err.Error(), "unknown step 'missing'") {
+		t.Errorf("expected an error about the unknown step, got %v", err)
+	}
+
+	self, _ := p.Create("self-plan")
+	self.AddStep("a", "A", nil, "a")
+	if err := p.Save(self); err == nil || !strings.Contains(err.Error(), "cannot depend on itself") {
+		t.Errorf("expected an error about the self-dependency, got %v", err)
+	}
+
+	cycle, _ := p.Create("cycle-plan")
+	cycle.AddStep("a", "A", nil, "c")
+	cycle.AddStep("b", "B", nil, "a")
+	cycle.AddStep("c", "C", nil, "b")
+	if err := p.Save(cycle); !errors.Is(err, ErrDependencyCycle) {
+		t.Errorf("expected ErrDependencyCycle, got %v", err)
+	}
+	if _, err := p.Get("cycle-plan"); err == nil {
+		t.Error("a plan with a dependency cycle must not be saved")
+	}
+}
@@ -0,0 +1,155 @@
+package planner
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrPlanExists is returned when importing a plan whose ID is already used by a plan in the database.
+var ErrPlanExists = errors.New("plan already exists")
+
+// stepJSON is the JSON encoding of a Step, as used by ExportJSON and ImportJSON.
+type stepJSON struct {
+	ID                 string   `json:"id"`
+	Description        string   `json:"description"`
+	Status             string   `json:"status"` // "DONE" or "TODO"; empty means "TODO" when importing
+	AcceptanceCriteria []string `json:"acceptance_criteria,omitempty"`
+	DependsOn          []string `json:"depends_on,omitempty"`
+	Priority           int      `json:"priority,omitempty"`
+	Notes              []string `json:"notes,omitempty"`
+}
+
+// MarshalJSON encodes the step as a stepJSON object.
+func (step *Step) MarshalJSON() ([]byte, error) {
+	return json.Marshal(stepJSON{
+		ID:                 step.id,
+		Description:        step.description,
+		Status:             step.Status(),
+		AcceptanceCriteria: step.acceptance,
+		DependsOn:          step.dependsOn,
+		Priority:           step.priority,
+		Notes:              step.notes,
+	})
+}
+
+// UnmarshalJSON decodes a stepJSON object into the step.
+func (step *Step) UnmarshalJSON(data []byte) error {
+	var decoded stepJSON
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*step = Step{
+		id:          decoded.ID,
+		description: decoded.Description,
+		status:      strings.ToUpper(decoded.Status),
+		acceptance:  decoded.AcceptanceCriteria,
+		dependsOn:   decoded.DependsOn,
+		priority:    decoded.Priority,
+		notes:       decoded.Notes,
+	}
+	if step.status == "" {
+		step.status = "TODO"
+	}
+	return nil
+}
+
+// ExportJSON returns the plan with the given name as indented JSON,
+// including its steps in order with their status, acceptance criteria, dependencies, priority and notes.
+func (p *Planner) ExportJSON(name string) ([]byte, error) {
+	plan, err := p.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode plan '%s': %w", name, err)
+	}
+	return data, nil
+}
+
+// ImportJSON stores a plan exported with ExportJSON, e.g. from another database.
+// It returns an error wrapping ErrPlanExists if a plan with the same ID already exists.
+func (p *Planner) ImportJSON(data []byte) (*Plan, error) {
+	return p.importJSON(data, false)
+}
+
+// ImportJSONOverwrite is like ImportJSON, but replaces an existing plan with the same ID, including its notes.
+func (p *Planner) ImportJSONOverwrite(data []byte) (*Plan, error) {
+	return p.importJSON(data, true)
+}
+
+func (p *Planner) importJSON(data []byte, overwrite bool) (*Plan, error) {
+	var plan Plan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, fmt.Errorf("failed to decode plan: %w", err)
+	}
+	if err := plan.checkImport(); err != nil {
+		return nil, err
+	}
+	plan.isNew = true
+
+	tx, err := p.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() // Rollback if not committed
+
+	var existingID string
+	err = tx.QueryRow("SELECT id FROM plans WHERE id = ?", plan.ID).Scan(&existingID)
+	switch {
+	case err == nil && !overwrite:
+		return nil, fmt.Errorf("cannot import plan '%s': %w", plan.ID, ErrPlanExists)
+	case err == nil:
+		// Delete explicitly instead of relying on cascading deletes,
+		// since foreign keys are only enforced on the connection that enabled them.
+		for _, table := range []string{"plan_changes", "step_notes", "step_dependencies", "step_acceptance_criteria", "steps"} {
+			if _, err := tx.Exec("DELETE FROM "+table+" WHERE plan_id = ?", plan.ID); err != nil {
+				return nil, fmt.Errorf("failed to delete existing plan '%s': %w", plan.ID, err)
+			}
+		}
+		if _, err := tx.Exec("DELETE FROM plans WHERE id = ?", plan.ID); err != nil {
+			return nil, fmt.Errorf("failed to delete existing plan '%s': %w", plan.ID, err)
+		}
+	case !errors.Is(err, sql.ErrNoRows):
+		return nil, fmt.Errorf("failed to query plan '%s': %w", plan.ID, err)
+	}
+
+	if err := saveInTx(tx, &plan); err != nil {
+		return nil, err
+	}
+	for _, step := range plan.Steps {
+		if err := insertNotes(tx, plan.ID, step); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction for plan '%s': %w", plan.ID, err)
+	}
+	plan.isNew = false
+	return &plan, nil
+}
+
+// checkImport returns an error if the decoded plan has no ID, or if a step has no or a duplicate ID or an unknown status.
+func (pl *Plan) checkImport() error {
+	if pl.ID == "" {
+		return fmt.Errorf("plan name cannot be empty")
+	}
+	seen := map[string]bool{}
+	for i, step := range pl.Steps {
+		if step == nil || step.id == "" {
+			return fmt.Errorf("step %d of plan '%s' has no ID", i+1, pl.ID)
+		}
+		if seen[step.id] {
+			return fmt.Errorf("plan '%s' contains step '%s' more than once", pl.ID, step.id)
+		}
+		seen[step.id] = true
+		if step.status != "DONE" && step.status != "TODO" {
+			return fmt.Errorf("step '%s' of plan '%s' has unknown status '%s', expected DONE or TODO", step.id, pl.ID, step.status)
+		}
+	}
+	return nil
+}
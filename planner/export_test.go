@@ -0,0 +1,98 @@
+package planner
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPlanner_ExportImportJSON(t *testing.T) {
+	source, cleanupSource := setupTestDB(t)
+	defer cleanupSource()
+
+	plan, _ := source.Create("export-plan")
+	plan.AddStep("step1", "Step 1 desc", []string{"criterion 1", "criterion 2"})
+	plan.AddStep("step2", "Step 2 desc", nil, "step1")
+	plan.AddStep("step3", "Step 3 desc", nil)
+	plan.MarkAsCompleted("step1")
+	plan.SetPriority("step3", 2)
+	if err := source.Save(plan); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if err := source.AddNote("export-plan", "step2", "started"); err != nil {
+		t.Fatalf("AddNote failed: %v", err)
+	}
+
+	data, err := source.ExportJSON("export-plan")
+	if err != nil {
+		t.Fatalf("ExportJSON failed: %v", err)
+	}
+	if !strings.Contains(string(data), `"acceptance_criteria": [`) {
+		t.Errorf("expected the export to contain acceptance criteria, got:\n%s", data)
+	}
+
+	destination, cleanupDestination := setupTestDB(t)
+	defer cleanupDestination()
+	imported, err := destination.ImportJSON(data)
+	if err != nil {
+		t.Fatalf("ImportJSON failed: %v", err)
+	}
+	if imported.ID != "export-plan" || len(imported.Steps) != 3 {
+		t.Fatalf("unexpected imported plan %+v", imported)
+	}
+
+	original, _ := source.Get("export-plan")
+	roundTripped, err := destination.Get("export-plan")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if original.Inspect() != roundTripped.Inspect() {
+		t.Errorf("imported plan differs from the original:\n%s\nexpected:\n%s", roundTripped.Inspect(), original.Inspect())
+	}
+	if got := roundTripped.Steps[1].DependsOn(); !reflect.DeepEqual(got, []string{"step1"}) {
+		t.Errorf("expected step2 to depend on step1, got %v", got)
+	}
+
+	// Importing again fails unless the plan is overwritten.
+	if _, err := destination.ImportJSON(data); !errors.Is(err, ErrPlanExists) {
+		t.Errorf("expected ErrPlanExists, got %v", err)
+	}
+	changed := strings.Replace(string(data), "Step 3 desc", "Changed", 1)
+	if _, err := destination.ImportJSONOverwrite([]byte(changed)); err != nil {
+		t.Fatalf("ImportJSONOverwrite failed: %v", err)
+	}
+	overwritten, _ := destination.Get("export-plan")
+	if overwritten.Steps[2].Description() != "Changed" || len(overwritten.Steps[1].Notes()) != 1 {
+		t.Errorf("unexpected overwritten plan:\n%s", overwritten.Inspect())
+	}
+}
+
+func TestPlanner_ImportJSONRejectsInvalidPlans(t *testing.T) {
+	p, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	for _, data := range []string{
+		`not json`,
+		`{"steps": []}`,
+		`{"id": "p", "steps": [{"description": "no id"}]}`,
+		`{"id": "p", "steps": [{"id": "a"}, {"id": "a"}]}`,
+		`{"id": "p", "steps": [{"id": "a", "status": "DOING"}]}`,
+		`{"id": "p", "steps": [{"id": "a", "depends_on": ["b"]}]}`,
+	} {
+		if _, err := p.ImportJSON([]byte(data)); err == nil {
+			t.Errorf("expected an error importing %s", data)
+		}
+	}
+	if _, err := p.Get("p"); err == nil {
+		t.Error("no plan should have been stored")
+	}
+
+	plan, err := p.ImportJSON([]byte(`{"id": "p", "steps": [{"id": "a", "description": "A"}]}`))
+	if err != nil {
+		t.Fatalf("ImportJSON failed: %v", err)
+	}
+	if plan.Steps[0].Status() != "TODO" {
+		t.Errorf("expected a step without status to be TODO, got %s", plan.Steps[0].Status())
+	}
+}
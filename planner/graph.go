@@ -0,0 +1,102 @@
+package planner
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RenderGraph returns a Graphviz DOT representation of the named plan.
+// Steps are nodes colored by status, see Plan.RenderDOT.
+func (p *Planner) RenderGraph(planName string) (string, error) {
+	plan, err := p.Get(planName)
+	if err != nil {
+		return "", err
+	}
+	return plan.RenderDOT(), nil
+}
+
+// RenderMermaid returns a Mermaid flowchart representation of the named plan.
+func (p *Planner) RenderMermaid(planName string) (string, error) {
+	plan, err := p.Get(planName)
+	if err != nil {
+		return "", err
+	}
+	return plan.RenderMermaid(), nil
+}
+
+// RenderDOT returns a Graphviz DOT representation of the plan.
+// Completed steps are green, open steps are orange.
+// Edges point from each step to the steps depending on it, see Plan.edges.
+func (pl *Plan) RenderDOT() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %s {\n", quoteDOT(pl.ID))
+	b.WriteString("  rankdir=TB;\n")
+	b.WriteString("  node [shape=box, style=filled];\n")
+	for _, step := range pl.Steps {
+		color := "orange"
+		if step.Status() == "DONE" {
+			color = "palegreen"
+		}
+		label := fmt.Sprintf("%s\\n%s", step.id, step.Status())
+		fmt.Fprintf(&b, "  %s [label=%s, fillcolor=%s];\n", quoteDOT(step.id), quoteDOT(label), color)
+	}
+	for _, edge := range pl.edges() {
+		fmt.Fprintf(&b, "  %s -> %s;\n", quoteDOT(pl.Steps[edge[0]].id), quoteDOT(pl.Steps[edge[1]].id))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// RenderMermaid returns a Mermaid flowchart representation of the plan.
+// Completed steps are green, open steps are orange.
+// Edges point from each step to the steps depending on it, see Plan.edges.
+func (pl *Plan) RenderMermaid() string {
+	var b strings.Builder
+	b.WriteString("flowchart TD\n")
+	b.WriteString("  classDef done fill:#98fb98;\n")
+	b.WriteString("  classDef todo fill:#ffa500;\n")
+	for i, step := range pl.Steps {
+		class := "todo"
+		if step.Status() == "DONE" {
+			class = "done"
+		}
+		label := strings.ReplaceAll(fmt.Sprintf("%s (%s)", step.id, step.Status()), `"`, "#quot;")
+		fmt.Fprintf(&b, "  s%d[\"%s\"]:::%s\n", i, label, class)
+	}
+	for _, edge := range pl.edges() {
+		fmt.Fprintf(&b, "  s%d --> s%d\n", edge[0], edge[1])
+	}
+	return b.String()
+}
+
+// edges returns the edges of the plan's graph as pairs of step indices, from a dependency to the step depending on it.
+// Dependencies on steps that are not part of the plan are ignored.
+// If no step has dependencies, the steps are connected in plan order instead.
+func (pl *Plan) edges() [][2]int {
+	index := make(map[string]int, len(pl.Steps))
+	for i, step := range pl.Steps {
+		index[step.id] = i
+	}
+	var edges [][2]int
+	hasDependencies := false
+	for i, step := range pl.Steps {
+		for _, id := range step.dependsOn {
+			hasDependencies = true
+			if from, ok := index[id]; ok {
+				edges = append(edges, [2]int{from, i})
+			}
+		}
+	}
+	if hasDependencies {
+		return edges
+	}
+	for i := 1; i < len(pl.Steps); i++ {
+		edges = append(edges, [2]int{i - 1, i})
+	}
+	return edges
+}
+
+// quoteDOT quotes s as a DOT identifier.
+func quoteDOT(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
@@ -0,0 +1,81 @@
+package planner
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPlan_RenderDOT(t *testing.T) {
+	plan := &Plan{ID: "graph-plan", Steps: []*Step{}}
+	plan.AddStep("design", "Design it", nil)
+	plan.AddStep("build", "Build it", nil)
+	plan.AddStep("ship", "Ship it", nil)
+	plan.MarkAsCompleted("design")
+
+	dot := plan.RenderDOT()
+
+	for _, want := range []string{
+		`digraph "graph-plan" {`,
+		`"design" [label="design\nDONE", fillcolor=palegreen];`,
+		`"build" [label="build\nTODO", fillcolor=orange];`,
+		`"design" -> "build";`,
+		`"build" -> "ship";`,
+	} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("DOT output missing %q. Got:\n%s", want, dot)
+		}
+	}
+}
+
+func TestPlan_RenderMermaid(t *testing.T) {
+	plan := &Plan{ID: "graph-plan", Steps: []*Step{}}
+	plan.AddStep("design", "Design it", nil)
+	plan.AddStep("build", "Build it", nil)
+	plan.MarkAsCompleted("design")
+
+	mermaid := plan.RenderMermaid()
+
+	for _, want := range []string{
+		"flowchart TD",
+		`s0["design (DONE)"]:::done`,
+		`s1["build (TODO)"]:::todo`,
+		"s0 --> s1",
+	} {
+		if !strings.Contains(mermaid, want) {
+			t.Errorf("Mermaid output missing %q. Got:\n%s", want, mermaid)
+		}
+	}
+}
+
+func TestPlan_RenderGraphWithDependencies(t *testing.T) {
+	plan := &Plan{ID: "graph-plan", Steps: []*Step{}}
+	plan.AddStep("design", "Design it", nil)
+	plan.AddStep("docs", "Document it", nil, "design")
+	plan.AddStep("build", "Build it", nil, "design")
+	plan.AddStep("ship", "Ship it", nil, "build", "docs")
+
+	dot := plan.RenderDOT()
+	for _, want := range []string{
+		`"design" -> "docs";`,
+		`"design" -> "build";`,
+		`"build" -> "ship";`,
+		`"docs" -> "ship";`,
+	} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("DOT output missing %q. Got:\n%s", want, dot)
+		}
+	}
+	if strings.Contains(dot, `"docs" -> "build";`) {
+		t.Errorf("DOT output connects independent steps in plan order. Got:\n%s", dot)
+	}
+
+	mermaid := plan.RenderMermaid()
+	for _, want := range []string{"s0 --> s1", "s0 --> s2", "s2 --> s3", "s1 --> s3"} {
+		if !strings.Contains(mermaid, want) {
+			t.Errorf("Mermaid output missing %q. Got:\n%s", want, mermaid)
+		}
+	}
+	if strings.Contains(mermaid, "s1 --> s2") {
+		t.Errorf("Mermaid output connects independent steps in plan order. Got:\n%s", mermaid)
+	}
+}
@@ -2,6 +2,7 @@ package planner
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -10,6 +11,9 @@ import (
 	_ "github.com/mattn/go-sqlite3" // SQLite driver
 )
 
+// ErrPlanCompleted is returned when advancing a plan that has no incomplete steps left.
+var ErrPlanCompleted = errors.New("plan is already complete")
+
 // Planner manages plans using a SQLite database.
 type Planner struct {
 	db *sql.DB
@@ -20,6 +24,8 @@ type Plan struct {
 	ID    string  `json:"id"` // Unique identifier for the plan, e.g., "active"
 	Steps []*Step `json:"steps"`
 	isNew bool    // Internal flag to indicate if the plan is new and not yet saved
+	// pendingChanges are the changes made since the plan was loaded or saved; Save records them for Undo.
+	pendingChanges []planChange
 }
 
 // PlanInfo holds summary information about a plan.
@@ -29,14 +35,20 @@ type PlanInfo struct {
 	Status         string `json:"status"` // "DONE" or "TODO"
 	TotalTasks     int    `json:"total_tasks"`
 	CompletedTasks int    `json:"completed_tasks"`
+	// PercentComplete is the percentage of completed tasks, between 0 and 100; it is 0 for a plan without tasks.
+	PercentComplete float64 `json:"percent_complete"`
 }
 
 // Step represents a single task in a plan.
+// Its JSON encoding is described by stepJSON.
 type Step struct {
-	id          string   `json:"id"` // Short identifier, e.g., "add-tests"
-	description string   `json:"description"`
-	status      string   `json:"status"` // "DONE" or "TODO"
-	acceptance  []string `json:"acceptance"`
+	id          string // Short identifier, e.g., "add-tests"
+	description string
+	status      string // "DONE" or "TODO"
+	acceptance  []string
+	dependsOn   []string // IDs of steps that must be DONE before this one
+	notes       []string // Progress notes, oldest first; added with Planner.AddNote
+	priority    int      // Higher values are picked first by NextStepByPriority; defaults to 0
 	stepOrder   int      // Internal field to keep track of order from DB
 }
 
@@ -87,6 +99,10 @@ func New(databasePath string) (*Planner, error) {
 		db.Close()
 		return nil, fmt.Errorf("failed to execute schema: %w", err)
 	}
+	if err := addPriorityColumn(db); err != nil {
+		db.Close()
+		return nil, err
+	}
 
 	return &Planner{
 		db: db,
@@ -137,7 +153,7 @@ func (p *Planner) Get(name string) (*Plan, error) {
 		isNew: false, // Explicitly set isNew to false for a plan loaded from DB
 	}
 
-	rows, err := p.db.Query("SELECT id, description, status, step_order FROM steps WHERE plan_id = ? ORDER BY step_order ASC", planID)
+	rows, err := p.db.Query("SELECT id, description, status, step_order, priority FROM steps WHERE plan_id = ? ORDER BY step_order ASC", planID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query steps for plan '%s': %w", name, err)
 	}
@@ -148,7 +164,7 @@ func (p *Planner) Get(name string) (*Plan, error) {
 
 	for rows.Next() {
 		step := &Step{}
-		err := rows.Scan(&step.id, &step.description, &step.status, &step.stepOrder)
+		err := rows.Scan(&step.id, &step.description, &step.status, &step.stepOrder, &step.priority)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan step for plan '%s': %w", name, err)
 		}
@@ -186,6 +202,43 @@ func (p *Planner) Get(name string) (*Plan, error) {
 		acRows.Close() // Close after successful iteration
 	}
 
+	// Finally, fetch the dependencies of all steps in one query
+	depRows, err := p.db.Query("SELECT step_id, depends_on FROM step_dependencies WHERE plan_id = ? ORDER BY step_id, dependency_order ASC", planID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query step dependencies for plan '%s': %w", name, err)
+	}
+	defer depRows.Close()
+	for depRows.Next() {
+		var stepID, dependsOn string
+		if err := depRows.Scan(&stepID, &dependsOn); err != nil {
+			return nil, fmt.Errorf("failed to scan step dependency for plan '%s': %w", name, err)
+		}
+		if step, ok := stepsByID[stepID]; ok {
+			step.dependsOn = append(step.dependsOn, dependsOn)
+		}
+	}
+	if err = depRows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating step dependencies for plan '%s': %w", name, err)
+	}
+
+	noteRows, err := p.db.Query("SELECT step_id, note FROM step_notes WHERE plan_id = ? ORDER BY created_at ASC, id ASC", planID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query step notes for plan '%s': %w", name, err)
+	}
+	defer noteRows.Close()
+	for noteRows.Next() {
+		var stepID, note string
+		if err := noteRows.Scan(&stepID, &note); err != nil {
+			return nil, fmt.Errorf("failed to scan step note for plan '%s': %w", name, err)
+		}
+		if step, ok := stepsByID[stepID]; ok {
+			step.notes = append(step.notes, note)
+		}
+	}
+	if err = noteRows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating step notes for plan '%s': %w", name, err)
+	}
+
 	return plan, nil
 }
 
@@ -195,10 +248,15 @@ func (pl *Plan) Inspect() string {
 	// Maybe add a title for the plan itself?
 	// builder.WriteString(fmt.Sprintf("# Plan: %s\n\n", pl.ID))
 
+	builder.WriteString("Progress: " + FormatProgress(pl.Progress()) + "\n\n")
+
 	for i, step := range pl.Steps {
 		// Headline: includes step number, status, and ID.
-		header := fmt.Sprintf("## %d. [%s] %s\n", i+1, strings.ToUpper(step.status), step.id) // Use fields
-		builder.WriteString(header)
+		header := fmt.Sprintf("## %d. [%s] %s", i+1, strings.ToUpper(step.status), step.id) // Use fields
+		if step.priority != 0 {
+			header += fmt.Sprintf(" (priority %d)", step.priority)
+		}
+		builder.WriteString(header + "\n")
 
 		// Description paragraph (if not empty)
 		if step.description != "" {
@@ -214,21 +272,35 @@ func (pl *Plan) Inspect() string {
 			}
 			builder.WriteString("\n") // Add a newline after the list
 		}
+
+		if len(step.dependsOn) > 0 {
+			builder.WriteString("Depends on: " + strings.Join(step.dependsOn, ", ") + "\n\n")
+		}
+
+		if len(step.notes) > 0 {
+			builder.WriteString("Notes:\n")
+			for _, note := range step.notes {
+				builder.WriteString("- " + note + "\n")
+			}
+			builder.WriteString("\n")
+		}
 	}
 
 	return builder.String()
 }
 
-// NextStep returns the first step in the plan that is not marked as "DONE".
-// It returns nil if all steps are completed.
+// NextStep returns the first step in the plan that is not marked as "DONE"
+// and whose dependencies are all marked as "DONE".
+// It returns nil if all steps are completed or if every remaining step is blocked;
+// use NextReadyStep to tell the two apart.
 func (pl *Plan) NextStep() *Step {
 	for _, step := range pl.Steps {
 		// Case-insensitive comparison just in case
-		if strings.ToUpper(step.status) != "DONE" { // Use field
+		if strings.ToUpper(step.status) != "DONE" && pl.dependenciesDone(step) { // Use field
 			return step
 		}
 	}
-	return nil // All steps are done
+	return nil // All steps are done or blocked
 }
 
 // ID returns the short identifier of the step.
@@ -253,11 +325,24 @@ func (step *Step) AcceptanceCriteria() []string {
 	return step.acceptance
 }
 
+// DependsOn returns the IDs of the steps that must be completed before this step.
+func (step *Step) DependsOn() []string {
+	return step.dependsOn
+}
+
+// Notes returns the progress notes of the step, oldest first.
+func (step *Step) Notes() []string {
+	return step.notes
+}
+
 // MarkAsCompleted sets the status of the step with the given stepID to "DONE" in-memory.
 // It returns an error if the step is not found.
 func (pl *Plan) MarkAsCompleted(stepID string) error {
 	for _, step := range pl.Steps {
 		if step.id == stepID {
+			if step.Status() != "DONE" {
+				pl.recordStatusChange(step, "DONE")
+			}
 			step.status = "DONE"
 			return nil
 		}
@@ -270,6 +355,9 @@ func (pl *Plan) MarkAsCompleted(stepID string) error {
 func (pl *Plan) MarkAsIncomplete(stepID string) error {
 	for _, step := range pl.Steps {
 		if step.id == stepID {
+			if step.Status() != "TODO" {
+				pl.recordStatusChange(step, "TODO")
+			}
 			step.status = "TODO"
 			return nil
 		}
@@ -277,19 +365,69 @@ func (pl *Plan) MarkAsIncomplete(stepID string) error {
 	return fmt.Errorf("step with ID '%s' not found in plan '%s'", stepID, pl.ID)
 }
 
+// Advance marks the step with the given stepID as "DONE" in-memory and returns the new next step.
+// If stepID is empty, the current next step is marked as done.
+// It returns ErrPlanCompleted if stepID is empty and all steps are already done,
+// and the error of NextReadyStep if stepID is empty and all remaining steps are blocked.
+// The returned step is nil if the plan is complete or blocked after advancing.
+func (pl *Plan) Advance(stepID string) (*Step, error) {
+	if stepID == "" {
+		current, err := pl.NextReadyStep()
+		if err != nil {
+			return nil, err
+		}
+		stepID = current.id
+	}
+	if err := pl.MarkAsCompleted(stepID); err != nil {
+		return nil, err
+	}
+	return pl.NextStep(), nil
+}
+
+// CompleteIfVerified marks the step with the given stepID as "DONE" in-memory,
+// but only if confirmed contains every one of its acceptance criteria, identified by their position starting at 1.
+// Otherwise the step is left unchanged and the criteria that were not confirmed are returned.
+func (pl *Plan) CompleteIfVerified(stepID string, confirmed []int) ([]string, error) {
+	for _, step := range pl.Steps {
+		if step.id != stepID {
+			continue
+		}
+		isConfirmed := map[int]bool{}
+		for _, index := range confirmed {
+			isConfirmed[index] = true
+		}
+		unmet := []string{}
+		for i, criterion := range step.acceptance {
+			if !isConfirmed[i+1] {
+				unmet = append(unmet, criterion)
+			}
+		}
+		if len(unmet) == 0 && step.Status() != "DONE" {
+			pl.recordStatusChange(step, "DONE")
+			step.status = "DONE"
+		}
+		return unmet, nil
+	}
+	return nil, fmt.Errorf("step with ID '%s' not found in plan '%s'", stepID, pl.ID)
+}
+
 // AddStep appends a new step to the plan.
 // The new step is initialized with status "TODO".
-func (pl *Plan) AddStep(id, description string, acceptanceCriteria []string) {
+// dependsOn lists the IDs of steps that must be completed before the new step;
+// they are validated when the plan is saved.
+func (pl *Plan) AddStep(id, description string, acceptanceCriteria []string, dependsOn ...string) {
 	newStep := &Step{
 		id:          id,
 		description: description,
 		status:      "TODO", // Default status for new steps
 		acceptance:  acceptanceCriteria,
+		dependsOn:   dependsOn,
 	}
 	pl.Steps = append(pl.Steps, newStep)
 }
 
 // RemoveSteps removes steps from the plan based on the provided slice of step IDs.
+// Remaining steps no longer depend on the removed steps.
 // It returns the number of steps actually removed.
 // It is not an error if a provided step ID is not found in the plan.
 func (pl *Plan) RemoveSteps(stepIDs []string) int {
@@ -307,14 +445,31 @@ func (pl *Plan) RemoveSteps(stepIDs []string) int {
 	}
 
 	var newSteps []*Step
-	removedCount := 0
-	for _, step := range pl.Steps {
+	var removed []*removedStep
+	removedByID := map[string]*removedStep{}
+	for i, step := range pl.Steps {
 		if _, found := idsToRemove[step.id]; found {
-			removedCount++
+			removedByID[step.id] = &removedStep{Position: i, Step: step}
+			removed = append(removed, removedByID[step.id])
 		} else {
 			newSteps = append(newSteps, step)
 		}
 	}
+	for _, step := range newSteps {
+		var dependsOn []string
+		for _, id := range step.dependsOn {
+			if r, found := removedByID[id]; found {
+				r.Dependents = append(r.Dependents, step.id)
+			} else {
+				dependsOn = append(dependsOn, id)
+			}
+		}
+		step.dependsOn = dependsOn
+	}
+	removedCount := len(removed)
+	if removedCount > 0 {
+		pl.recordChange(planChange{Action: fmt.Sprintf("remove %d step(s)", removedCount), RemovedSteps: removed})
+	}
 
 	pl.Steps = newSteps
 	return removedCount
@@ -364,12 +519,27 @@ func (pl *Plan) Reorder(newStepOrder []string) {
 		}
 	}
 
+	for i, step := range reorderedSteps {
+		if pl.Steps[i] != step {
+			previousOrder := make([]string, len(pl.Steps))
+			for j, previous := range pl.Steps {
+				previousOrder[j] = previous.id
+			}
+			pl.recordChange(planChange{Action: "reorder steps", PreviousOrder: previousOrder})
+			break
+		}
+	}
 	pl.Steps = reorderedSteps
 }
 
 // IsCompleted checks if all steps in the plan are marked as "DONE".
 func (pl *Plan) IsCompleted() bool {
-	return pl.NextStep() == nil // If NextStep is nil, all steps are DONE
+	for _, step := range pl.Steps {
+		if strings.ToUpper(step.status) != "DONE" {
+			return false
+		}
+	}
+	return true
 }
 
 // List retrieves summary information for all plans from the database.
@@ -400,6 +570,7 @@ func (p *Planner) List() ([]PlanInfo, error) {
 
 		info.TotalTasks = int(totalTasks.Int64)         // Assign, defaults to 0 if NULL
 		info.CompletedTasks = int(completedTasks.Int64) // Assign, defaults to 0 if NULL
+		info.PercentComplete = percentComplete(info.CompletedTasks, info.TotalTasks)
 
 		if info.TotalTasks > 0 && info.CompletedTasks == info.TotalTasks {
 			info.Status = "DONE"
@@ -416,6 +587,66 @@ func (p *Planner) List() ([]PlanInfo, error) {
 	return plansInfo, nil
 }
 
+// PlanStatus summarizes the progress of a plan.
+// It is returned by PlanStatus, which is cheaper than loading the whole plan with Get.
+type PlanStatus struct {
+	Name           string `json:"name"`
+	Status         string `json:"status"` // "DONE" or "TODO"
+	TotalTasks     int    `json:"total_tasks"`
+	CompletedTasks int    `json:"completed_tasks"`
+	// NextStepID and NextStepDescription describe the step NextStep would return;
+	// they are empty if the plan is complete or all remaining steps are blocked by their dependencies.
+	NextStepID          string `json:"next_step_id,omitempty"`
+	NextStepDescription string `json:"next_step_description,omitempty"`
+}
+
+// PlanStatus returns the progress of the plan with the given name and its next step in a single query.
+func (p *Planner) PlanStatus(name string) (PlanStatus, error) {
+	status := PlanStatus{Name: name}
+	var totalTasks, completedTasks sql.NullInt64
+	var nextStepID, nextStepDescription sql.NullString
+	err := p.db.QueryRow(`
+        SELECT
+            COUNT(s.id),
+            SUM(CASE WHEN UPPER(s.status) = 'DONE' THEN 1 ELSE 0 END),
+            next.id,
+            next.description
+        FROM plans p
+        LEFT JOIN steps s ON p.id = s.plan_id
+        LEFT JOIN (
+            SELECT id, description FROM steps candidate
+            WHERE plan_id = ? AND UPPER(status) != 'DONE'
+            AND NOT EXISTS (
+                SELECT 1 FROM step_dependencies d
+                LEFT JOIN steps dep ON dep.plan_id = d.plan_id AND dep.id = d.depends_on
+                WHERE d.plan_id = candidate.plan_id AND d.step_id = candidate.id
+                AND (dep.id IS NULL OR UPPER(dep.status) != 'DONE')
+            )
+            ORDER BY step_order ASC
+            LIMIT 1
+        ) next
+        WHERE p.id = ?
+        GROUP BY p.id
+    `, name, name).Scan(&totalTasks, &completedTasks, &nextStepID, &nextStepDescription)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return status, fmt.Errorf("plan with name '%s' not found", name)
+		}
+		return status, fmt.Errorf("failed to query status of plan '%s': %w", name, err)
+	}
+
+	status.TotalTasks = int(totalTasks.Int64)
+	status.CompletedTasks = int(completedTasks.Int64)
+	status.NextStepID = nextStepID.String
+	status.NextStepDescription = nextStepDescription.String
+	if status.TotalTasks > 0 && status.CompletedTasks == status.TotalTasks {
+		status.Status = "DONE"
+	} else {
+		status.Status = "TODO"
+	}
+	return status, nil
+}
+
 // Save persists changes to a plan and its steps in the database using a transaction.
 // If plan.isNew is true, it inserts the plan into the 'plans' table first.
 // After successful save of a new plan, plan.isNew is set to false.
@@ -426,6 +657,29 @@ func (p *Planner) Save(plan *Plan) error {
 	}
 	defer tx.Rollback() // Rollback if not committed
 
+	if err := saveInTx(tx, plan); err != nil {
+		return err
+	}
+	if err := recordChanges(tx, plan); err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit transaction for plan '%s': %w", plan.ID, err)
+	}
+
+	// If we successfully committed a new plan, update its in-memory status.
+	if plan.isNew {
+		plan.isNew = false
+	}
+	plan.pendingChanges = nil
+
+	return nil
+}
+
+// saveInTx writes plan and its steps within tx, see Save.
+func saveInTx(tx *sql.Tx, plan *Plan) error {
 	if plan.isNew {
 		_, err := tx.Exec("INSERT INTO plans (id) VALUES (?)", plan.ID)
 		if err != nil {
@@ -450,6 +704,10 @@ func (p *Planner) Save(plan *Plan) error {
 		}
 	}
 
+	if err := plan.checkDependencies(); err != nil {
+		return err
+	}
+
 	// --- Synchronize steps --- //
 
 	// Get existing step IDs from the DB for this plan
@@ -476,12 +734,22 @@ func (p *Planner) Save(plan *Plan) error {
 		planStepIDs[step.id] = true
 	}
 
+	// Dependencies are rewritten after all steps exist, since they may refer to steps added later in the plan.
+	_, err = tx.Exec("DELETE FROM step_dependencies WHERE plan_id = ?", plan.ID)
+	if err != nil {
+		return fmt.Errorf("failed to delete old step dependencies for plan '%s': %w", plan.ID, err)
+	}
+
 	for dbStepID := range dbStepIDs {
 		if !planStepIDs[dbStepID] {
 			_, err = tx.Exec("DELETE FROM step_acceptance_criteria WHERE plan_id = ? AND step_id = ?", plan.ID, dbStepID)
 			if err != nil {
 				return fmt.Errorf("failed to delete old acceptance criteria for step '%s' in plan '%s': %w", dbStepID, plan.ID, err)
 			}
+			_, err = tx.Exec("DELETE FROM step_notes WHERE plan_id = ? AND step_id = ?", plan.ID, dbStepID)
+			if err != nil {
+				return fmt.Errorf("failed to delete notes of step '%s' in plan '%s': %w", dbStepID, plan.ID, err)
+			}
 			_, err = tx.Exec("DELETE FROM steps WHERE plan_id = ? AND id = ?", plan.ID, dbStepID)
 			if err != nil {
 				return fmt.Errorf("failed to delete step '%s' from plan '%s': %w", dbStepID, plan.ID, err)
@@ -492,14 +760,14 @@ func (p *Planner) Save(plan *Plan) error {
 	for i, step := range plan.Steps {
 		step.stepOrder = i
 		if dbStepIDs[step.id] {
-			_, err = tx.Exec("UPDATE steps SET description = ?, status = ?, step_order = ? WHERE plan_id = ? AND id = ?",
-				step.description, step.status, step.stepOrder, plan.ID, step.id)
+			_, err = tx.Exec("UPDATE steps SET description = ?, status = ?, step_order = ?, priority = ? WHERE plan_id = ? AND id = ?",
+				step.description, step.status, step.stepOrder, step.priority, plan.ID, step.id)
 			if err != nil {
 				return fmt.Errorf("failed to update step '%s' in plan '%s': %w", step.id, plan.ID, err)
 			}
 		} else {
-			_, err = tx.Exec("INSERT INTO steps (id, plan_id, description, status, step_order) VALUES (?, ?, ?, ?, ?)",
-				step.id, plan.ID, step.description, step.status, step.stepOrder)
+			_, err = tx.Exec("INSERT INTO steps (id, plan_id, description, status, step_order, priority) VALUES (?, ?, ?, ?, ?, ?)",
+				step.id, plan.ID, step.description, step.status, step.stepOrder, step.priority)
 			if err != nil {
 				return fmt.Errorf("failed to insert step '%s' into plan '%s': %w", step.id, plan.ID, err)
 			}
@@ -519,16 +787,38 @@ func (p *Planner) Save(plan *Plan) error {
 		}
 	}
 
-	err = tx.Commit()
-	if err != nil {
-		return fmt.Errorf("failed to commit transaction for plan '%s': %w", plan.ID, err)
+	for _, step := range plan.Steps {
+		for j, dependsOn := range step.dependsOn {
+			_, err = tx.Exec("INSERT INTO step_dependencies (plan_id, step_id, depends_on, dependency_order) VALUES (?, ?, ?, ?)",
+				plan.ID, step.id, dependsOn, j)
+			if err != nil {
+				return fmt.Errorf("failed to insert dependency of step '%s' on '%s' in plan '%s': %w", step.id, dependsOn, plan.ID, err)
+			}
+		}
 	}
 
-	// If we successfully committed a new plan, update its in-memory status.
-	if plan.isNew {
-		plan.isNew = false
-	}
+	return nil
+}
 
+// AddNote records a progress note on the step stepID of the plan planID, without changing the step itself.
+// The note is stored right away; plans loaded afterwards with Get include it in Step.Notes.
+func (p *Planner) AddNote(planID, stepID, note string) error {
+	note = strings.TrimSpace(note)
+	if note == "" {
+		return fmt.Errorf("note for step '%s' in plan '%s' cannot be empty", stepID, planID)
+	}
+	var exists int
+	err := p.db.QueryRow("SELECT COUNT(*) FROM steps WHERE plan_id = ? AND id = ?", planID, stepID).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to look up step '%s' in plan '%s': %w", stepID, planID, err)
+	}
+	if exists == 0 {
+		return fmt.Errorf("step with ID '%s' not found in plan '%s'", stepID, planID)
+	}
+	_, err = p.db.Exec("INSERT INTO step_notes (plan_id, step_id, note) VALUES (?, ?, ?)", planID, stepID, note)
+	if err != nil {
+		return fmt.Errorf("failed to add note to step '%s' in plan '%s': %w", stepID, planID, err)
+	}
 	return nil
 }
 
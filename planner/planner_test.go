@@ -2,10 +2,12 @@ package planner
 
 import (
 	"database/sql" // Import database/sql
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"reflect" // Will be used later for deep comparisons
+	"strings"
 	"testing"
 )
 
@@ -330,6 +332,53 @@ func TestPlan_MarkStatus(t *testing.T) {
 	}
 }
 
+func TestPlan_Advance(t *testing.T) {
+	plan := &Plan{ID: "test-advance-plan", Steps: []*Step{}}
+	plan.AddStep("step1", "Step 1 desc", nil)
+	plan.AddStep("step2", "Step 2 desc", nil)
+	plan.AddStep("step3", "Step 3 desc", nil)
+
+	// Advance the current step
+	next, err := plan.Advance("")
+	if err != nil {
+		t.Fatalf("Advance failed: %v", err)
+	}
+	if plan.Steps[0].Status() != "DONE" {
+		t.Errorf("Status of step1 after Advance was %s, expected DONE", plan.Steps[0].Status())
+	}
+	if next == nil || next.ID() != "step2" {
+		t.Fatalf("Expected next step to be step2, got %v", next)
+	}
+
+	// Advance a specific step
+	next, err = plan.Advance("step3")
+	if err != nil {
+		t.Fatalf("Advance for step3 failed: %v", err)
+	}
+	if next == nil || next.ID() != "step2" {
+		t.Fatalf("Expected next step to still be step2, got %v", next)
+	}
+
+	// Advance the last remaining step
+	next, err = plan.Advance("")
+	if err != nil {
+		t.Fatalf("Advance for step2 failed: %v", err)
+	}
+	if next != nil {
+		t.Errorf("Expected no next step after completing the plan, got %s", next.ID())
+	}
+
+	// Advance a completed plan
+	if _, err := plan.Advance(""); !errors.Is(err, ErrPlanCompleted) {
+		t.Errorf("Expected ErrPlanCompleted, got %v", err)
+	}
+
+	// Advance a non-existent step
+	if _, err := plan.Advance("non-existent-step"); err == nil {
+		t.Error("Expected error when advancing non-existent step, got nil")
+	}
+}
+
 // TestPlanner_Save_NewAndExisting specifically tests the isNew logic with Save.
 func TestPlanner_Save_NewAndExisting(t *testing.T) {
 	planner, cleanup := setupTestDB(t)
@@ -429,3 +478,149 @@ func TestPlanner_Save_NewAndExisting(t *testing.T) {
 }
 
 // --- Add tests for List, Remove, Compact, MarkAsComplete/Incomplete etc. ---
+
+func TestPlanner_PlanStatus(t *testing.T) {
+	p, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	plan, _ := p.Create("status-plan")
+	plan.AddStep("step1", "Step 1 desc", nil)
+	plan.AddStep("step2", "Step 2 desc", []string{"criterion"})
+	plan.AddStep("step3", "Step 3 desc", nil)
+	plan.MarkAsCompleted("step1")
+	plan.MarkAsCompleted("step3")
+	if err := p.Save(plan); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	status, err := p.PlanStatus("status-plan")
+	if err != nil {
+		t.Fatalf("PlanStatus failed: %v", err)
+	}
+	expected := PlanStatus{Name: "status-plan", Status: "TODO", TotalTasks: 3, CompletedTasks: 2, NextStepID: "step2", NextStepDescription: "Step 2 desc"}
+	if !reflect.DeepEqual(status, expected) {
+		t.Errorf("PlanStatus() = %+v, expected %+v", status, expected)
+	}
+
+	plan.MarkAsCompleted("step2")
+	if err := p.Save(plan); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	status, err = p.PlanStatus("status-plan")
+	if err != nil {
+		t.Fatalf("PlanStatus failed: %v", err)
+	}
+	if status.Status != "DONE" || status.CompletedTasks != 3 || status.NextStepID != "" {
+		t.Errorf("expected a completed plan without next step, got %+v", status)
+	}
+
+	empty, _ := p.Create("empty-plan")
+	if err := p.Save(empty); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	status, err = p.PlanStatus("empty-plan")
+	if err != nil {
+		t.Fatalf("PlanStatus failed: %v", err)
+	}
+	if status.Status != "TODO" || status.TotalTasks != 0 {
+		t.Errorf("expected an empty plan to be TODO, as in List, got %+v", status)
+	}
+
+	if _, err := p.PlanStatus("non-existent-plan"); err == nil {
+		t.Error("expected an error for a non-existent plan")
+	}
+}
+
+func TestPlan_CompleteIfVerified(t *testing.T) {
+	plan := &Plan{ID: "test-verify-plan", Steps: []*Step{}}
+	plan.AddStep("step1", "Step 1 desc", []string{"tests pass", "docs updated"})
+
+	unmet, err := plan.CompleteIfVerified("step1", []int{1})
+	if err != nil {
+		t.Fatalf("CompleteIfVerified failed: %v", err)
+	}
+	if !reflect.DeepEqual(unmet, []string{"docs updated"}) {
+		t.Errorf("expected the unconfirmed criterion to be reported, got %v", unmet)
+	}
+	if plan.Steps[0].Status() != "TODO" {
+		t.Errorf("expected the step to stay TODO, got %s", plan.Steps[0].Status())
+	}
+
+	unmet, err = plan.CompleteIfVerified("step1", []int{1, 2})
+	if err != nil {
+		t.Fatalf("CompleteIfVerified failed: %v", err)
+	}
+	if len(unmet) != 0 || plan.Steps[0].Status() != "DONE" {
+		t.Errorf("expected the step to be DONE with all criteria confirmed, got %s and %v", plan.Steps[0].Status(), unmet)
+	}
+
+	if _, err := plan.CompleteIfVerified("non-existent-step", nil); err == nil {
+		t.Error("expected an error for a non-existent step")
+	}
+}
+
+func TestPlanner_AddNote(t *testing.T) {
+	p, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	plan, _ := p.Create("notes-plan")
+	plan.AddStep("step1", "Step 1 desc", nil)
+	plan.AddStep("step2", "Step 2 desc", nil)
+	plan.AddStep("step3", "Step 3 desc", nil)
+	if err := p.Save(plan); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	for _, note := range []string{"started on it", "half way there"} {
+		if err := p.AddNote("notes-plan", "step2", note); err != nil {
+			t.Fatalf("AddNote failed: %v", err)
+		}
+	}
+	if err := p.AddNote("notes-plan", "step3", "delete me"); err != nil {
+		t.Fatalf("AddNote failed: %v", err)
+	}
+	if err := p.AddNote("notes-plan", "missing", "note"); err == nil {
+		t.Error("expected an error for a non-existent step")
+	}
+	if err := p.AddNote("notes-plan", "step1", "  "); err == nil {
+		t.Error("expected an error for an empty note")
+	}
+
+	// Notes survive reordering and removing other steps.
+	loaded, err := p.Get("notes-plan")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	loaded.Reorder([]string{"step3", "step2"})
+	loaded.RemoveSteps([]string{"step1"})
+	if err := p.Save(loaded); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	loaded, err = p.Get("notes-plan")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if got, expected := loaded.Steps[1].Notes(), []string{"started on it", "half way there"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("Notes() = %v, expected %v", got, expected)
+	}
+	if !strings.Contains(loaded.Inspect(), "Notes:\n- started on it\n- half way there\n") {
+		t.Errorf("expected Inspect to list the notes, got:\n%s", loaded.Inspect())
+	}
+
+	// Removing a step removes its notes.
+	loaded.RemoveSteps([]string{"step3"})
+	if err := p.Save(loaded); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	loaded.AddStep("step3", "Step 3 again", nil)
+	if err := p.Save(loaded); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	loaded, err = p.Get("notes-plan")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if notes := loaded.Steps[1].Notes(); len(notes) != 0 {
+		t.Errorf("expected a re-added step to have no notes, got %v", notes)
+	}
+}
@@ -0,0 +1,77 @@
+package planner
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// addPriorityColumn adds the priority column to the steps table of databases created before steps had priorities.
+func addPriorityColumn(db *sql.DB) error {
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM pragma_table_info('steps') WHERE name = 'priority'").Scan(&count)
+	if err != nil {
+		return fmt.Errorf("failed to inspect steps table: %w", err)
+	}
+	if count > 0 {
+		return nil
+	}
+	if _, err := db.Exec("ALTER TABLE steps ADD COLUMN priority INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return fmt.Errorf("failed to add priority column to steps table: %w", err)
+	}
+	return nil
+}
+
+// Priority returns the priority of the step; higher priorities are picked first by NextStepByPriority.
+func (step *Step) Priority() int {
+	return step.priority
+}
+
+// SetPriority sets the priority of the step with the given stepID in-memory.
+// It returns an error if the step is not found.
+func (pl *Plan) SetPriority(stepID string, priority int) error {
+	step := pl.step(stepID)
+	if step == nil {
+		return fmt.Errorf("step with ID '%s' not found in plan '%s'", stepID, pl.ID)
+	}
+	step.priority = priority
+	return nil
+}
+
+// NextStepByPriority returns the incomplete step with the highest priority whose dependencies are all marked as "DONE".
+// Steps with the same priority are picked in plan order.
+// Like NextStep, it returns nil if all steps are completed or every remaining step is blocked.
+func (pl *Plan) NextStepByPriority() *Step {
+	var next *Step
+	for _, step := range pl.Steps {
+		if strings.ToUpper(step.status) == "DONE" || !pl.dependenciesDone(step) {
+			continue
+		}
+		if next == nil || step.priority > next.priority {
+			next = step
+		}
+	}
+	return next
+}
+
+// CompletionRatio returns the fraction of the plan's steps that are done, between 0 and 1.
+// A plan without steps has a ratio of 0.
+func (info PlanInfo) CompletionRatio() float64 {
+	if info.TotalTasks == 0 {
+		return 0
+	}
+	return float64(info.CompletedTasks) / float64(info.TotalTasks)
+}
+
+// SortByCompletion sorts plans by their completion ratio, most complete first.
+// Plans with the same ratio are sorted by name.
+func SortByCompletion(plans []PlanInfo) {
+	sort.SliceStable(plans, func(i, j int) bool {
+		ri, rj := plans[i].CompletionRatio(), plans[j].CompletionRatio()
+		if ri != rj {
+			return ri > rj
+		}
+		return plans[i].Name < plans[j].Name
+	})
+}
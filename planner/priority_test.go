@@ -0,0 +1,138 @@
+package planner
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestPlan_NextStepByPriority(t *testing.T) {
+	plan := &Plan{ID: "priority-plan", Steps: []*Step{}}
+	plan.AddStep("low", "Low", nil)
+	plan.AddStep("high", "High", nil)
+	plan.AddStep("also-high", "Also high", nil)
+	plan.AddStep("blocked", "Blocked", nil, "low")
+	for id, priority := range map[string]int{"high": 2, "also-high": 2, "blocked": 5} {
+		if err := plan.SetPriority(id, priority); err != nil {
+			t.Fatalf("SetPriority failed: %v", err)
+		}
+	}
+	if err := plan.SetPriority("missing", 1); err == nil {
+		t.Error("expected an error for a non-existent step")
+	}
+
+	var order []string
+	for next := plan.NextStepByPriority(); next != nil; next = plan.NextStepByPriority() {
+		order = append(order, next.ID())
+		plan.MarkAsCompleted(next.ID())
+	}
+	if expected := []string{"high", "also-high", "low", "blocked"}; !reflect.DeepEqual(order, expected) {
+		t.Errorf("steps were picked in order %v, expected %v", order, expected)
+	}
+
+	// NextStep keeps ignoring priorities.
+	plan.MarkAsIncomplete("low")
+	plan.MarkAsIncomplete("high")
+	if next := plan.NextStep(); next == nil || next.ID() != "low" {
+		t.Errorf("expected NextStep to return low, got %v", next)
+	}
+}
+
+func TestPlanner_SaveAndGetPriority(t *testing.T) {
+	p, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	plan, _ := p.Create("priority-plan")
+	plan.AddStep("step1", "Step 1 desc", nil)
+	plan.AddStep("step2", "Step 2 desc", nil)
+	plan.SetPriority("step2", 3)
+	if err := p.Save(plan); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, err := p.Get("priority-plan")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if loaded.Steps[0].Priority() != 0 || loaded.Steps[1].Priority() != 3 {
+		t.Errorf("expected priorities 0 and 3, got %d and %d", loaded.Steps[0].Priority(), loaded.Steps[1].Priority())
+	}
+	if next := loaded.NextStepByPriority(); next == nil || next.ID() != "step2" {
+		t.Errorf("expected step2 to be picked first, got %v", next)
+	}
+
+	loaded.SetPriority("step2", -1)
+	if err := p.Save(loaded); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	loaded, err = p.Get("priority-plan")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if loaded.Steps[1].Priority() != -1 {
+		t.Errorf("expected the updated priority -1, got %d", loaded.Steps[1].Priority())
+	}
+}
+
+func TestNew_AddsPriorityColumn(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "old_planner.db")
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("sql.Open failed: %v", err)
+	}
+	_, err = db.Exec(`
+        CREATE TABLE plans (id TEXT PRIMARY KEY NOT NULL, created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP);
+        CREATE TABLE steps (
+            id TEXT NOT NULL, plan_id TEXT NOT NULL, description TEXT,
+            status TEXT NOT NULL CHECK(status IN ('TODO', 'DONE')), step_order INTEGER NOT NULL,
+            created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+            PRIMARY KEY (plan_id, id), FOREIGN KEY (plan_id) REFERENCES plans(id) ON DELETE CASCADE
+        );
+        INSERT INTO plans (id) VALUES ('old-plan');
+        INSERT INTO steps (id, plan_id, description, status, step_order) VALUES ('step1', 'old-plan', 'Step 1 desc', 'TODO', 0);
+    `)
+	db.Close()
+	if err != nil {
+		t.Fatalf("failed to create old schema: %v", err)
+	}
+	schemaContent, err := os.ReadFile("schema.sql")
+	if err != nil {
+		t.Fatalf("Failed to read schema file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "schema.sql"), schemaContent, 0644); err != nil {
+		t.Fatalf("Failed to write temporary schema file: %v", err)
+	}
+
+	p, err := New(dbPath)
+	if err != nil {
+		t.Fatalf("New failed on an old database: %v", err)
+	}
+	defer p.Close()
+	plan, err := p.Get("old-plan")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if len(plan.Steps) != 1 || plan.Steps[0].Priority() != 0 {
+		t.Errorf("expected one step with priority 0, got %+v", plan.Steps)
+	}
+}
+
+func TestSortByCompletion(t *testing.T) {
+	plans := []PlanInfo{
+		{Name: "empty"},
+		{Name: "half", TotalTasks: 4, CompletedTasks: 2},
+		{Name: "done", TotalTasks: 1, CompletedTasks: 1},
+		{Name: "also-half", TotalTasks: 2, CompletedTasks: 1},
+	}
+	SortByCompletion(plans)
+	var names []string
+	for _, plan := range plans {
+		names = append(names, plan.Name)
+	}
+	if expected := []string{"done", "also-half", "half", "empty"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("plans sorted as %v, expected %v", names, expected)
+	}
+}
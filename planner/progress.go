@@ -0,0 +1,42 @@
+package planner
+
+import (
+	"fmt"
+	"strings"
+)
+
+// percentComplete returns the percentage of completed out of total steps, or 0 if there are no steps.
+func percentComplete(completed, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return 100 * float64(completed) / float64(total)
+}
+
+// Progress returns the number of completed steps and the total number of steps of the plan.
+func (pl *Plan) Progress() (completed, total int) {
+	for _, step := range pl.Steps {
+		if strings.ToUpper(step.status) == "DONE" {
+			completed++
+		}
+	}
+	return completed, len(pl.Steps)
+}
+
+// FormatProgress describes the progress of a plan, e.g. "3/7 (43%)", or "0/0 (empty)" for a plan without steps.
+func FormatProgress(completed, total int) string {
+	if total == 0 {
+		return "0/0 (empty)"
+	}
+	return fmt.Sprintf("%d/%d (%.0f%%)", completed, total, percentComplete(completed, total))
+}
+
+// ProgressBar renders the progress of a plan as a bar of width characters between brackets, e.g. "[####------]".
+// The bar of a plan without steps is empty.
+func ProgressBar(completed, total, width int) string {
+	filled := 0
+	if total > 0 {
+		filled = completed * width / total
+	}
+	return "[" + strings.Repeat("#", filled) + strings.Repeat("-", width-filled) + "]"
+}
@@ -0,0 +1,62 @@
+package planner
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormatProgressAndProgressBar(t *testing.T) {
+	tests := []struct {
+		completed, total int
+		progress, bar    string
+	}{
+		{3, 7, "3/7 (43%)", "[####------]"},
+		{0, 0, "0/0 (empty)", "[----------]"},
+		{2, 2, "2/2 (100%)", "[##########]"},
+	}
+	for _, test := range tests {
+		if got := FormatProgress(test.completed, test.total); got != test.progress {
+			t.Errorf("FormatProgress(%d, %d) = %q, expected %q", test.completed, test.total, got, test.progress)
+		}
+		if got := ProgressBar(test.completed, test.total, 10); got != test.bar {
+			t.Errorf("ProgressBar(%d, %d, 10) = %q, expected %q", test.completed, test.total, got, test.bar)
+		}
+	}
+}
+
+func TestPlanner_ListAndInspectProgress(t *testing.T) {
+	p, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	empty, _ := p.Create("empty")
+	if err := p.Save(empty); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if !strings.HasPrefix(empty.Inspect(), "Progress: 0/0 (empty)\n") {
+		t.Errorf("unexpected Inspect output for an empty plan:\n%s", empty.Inspect())
+	}
+
+	plan, _ := p.Create("partial")
+	for _, id := range []string{"a", "b", "c", "d"} {
+		plan.AddStep(id, "Step "+id, nil)
+	}
+	plan.MarkAsCompleted("a")
+	if err := p.Save(plan); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if !strings.HasPrefix(plan.Inspect(), "Progress: 1/4 (25%)\n\n## 1. [DONE] a") {
+		t.Errorf("unexpected Inspect output:\n%s", plan.Inspect())
+	}
+
+	infos, err := p.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	percentages := map[string]float64{}
+	for _, info := range infos {
+		percentages[info.Name] = info.PercentComplete
+	}
+	if percentages["empty"] != 0 || percentages["partial"] != 25 {
+		t.Errorf("unexpected percentages %v", percentages)
+	}
+}
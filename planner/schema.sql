@@ -25,6 +25,7 @@ CREATE TABLE IF NOT EXISTS steps (
     description TEXT,
     status TEXT NOT NULL CHECK(status IN ('TODO', 'DONE')),
     step_order INTEGER NOT NULL, -- Order of steps within a plan
+    priority INTEGER NOT NULL DEFAULT 0, -- Higher priorities are worked on first when selecting steps by priority
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     PRIMARY KEY (plan_id, id),
@@ -64,3 +65,43 @@ CREATE INDEX IF NOT EXISTS idx_step_acceptance_criteria_plan_step ON step_accept
 -- without more complex recursive triggers or application-level logic.
 -- The steps_updated_at trigger will handle plan update when a step changes.
 -- For criteria, we'll rely on application logic or direct step update if its criteria change.
+
+-- step_dependencies table: Stores the steps that must be DONE before a step can be worked on
+CREATE TABLE IF NOT EXISTS step_dependencies (
+    plan_id TEXT NOT NULL,
+    step_id TEXT NOT NULL,
+    depends_on TEXT NOT NULL, -- ID of a step in the same plan
+    dependency_order INTEGER NOT NULL, -- Order in which the dependencies were declared
+    PRIMARY KEY (plan_id, step_id, depends_on),
+    FOREIGN KEY (plan_id, step_id) REFERENCES steps(plan_id, id) ON DELETE CASCADE,
+    FOREIGN KEY (plan_id, depends_on) REFERENCES steps(plan_id, id) ON DELETE CASCADE
+);
+
+-- Index for faster dependency lookup
+CREATE INDEX IF NOT EXISTS idx_step_dependencies_plan_step ON step_dependencies(plan_id, step_id);
+
+-- step_notes table: Stores progress notes jotted down while working on a step
+CREATE TABLE IF NOT EXISTS step_notes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT, -- Keeps notes with the same timestamp in insertion order
+    plan_id TEXT NOT NULL,
+    step_id TEXT NOT NULL,
+    note TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (plan_id, step_id) REFERENCES steps(plan_id, id) ON DELETE CASCADE
+);
+
+-- Index for faster note lookup
+CREATE INDEX IF NOT EXISTS idx_step_notes_plan_step ON step_notes(plan_id, step_id);
+
+-- plan_changes table: Stores the recent changes of a plan with what is needed to reverse them, so that they can be undone
+CREATE TABLE IF NOT EXISTS plan_changes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT, -- Most recent change has the highest id
+    plan_id TEXT NOT NULL,
+    action TEXT NOT NULL, -- Description of the change, e.g. "mark 'add-tests' as DONE"
+    reversal TEXT NOT NULL, -- JSON object describing how to reverse the change, see planChange
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (plan_id) REFERENCES plans(id) ON DELETE CASCADE
+);
+
+-- Index for faster lookup of the latest changes of a plan
+CREATE INDEX IF NOT EXISTS idx_plan_changes_plan_id ON plan_changes(plan_id, id);
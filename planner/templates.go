@@ -0,0 +1,97 @@
+package planner
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// TemplateDir is the directory CreateFromTemplate and Templates read plan templates from.
+// Each template is a JSON file named after the template, e.g. "feature.json" for the template "feature".
+var TemplateDir = ".smolcode/plan-templates"
+
+// Template is a reusable list of steps to scaffold plans with.
+// In a template file, steps use the same format as ExportJSON; their status is ignored.
+type Template struct {
+	Name        string  `json:"-"` // Derived from the file name
+	Description string  `json:"description,omitempty"`
+	Steps       []*Step `json:"steps"`
+}
+
+// Templates returns the templates in TemplateDir, sorted by name.
+// It returns no templates if the directory does not exist.
+func (p *Planner) Templates() ([]*Template, error) {
+	paths, err := filepath.Glob(filepath.Join(TemplateDir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list plan templates: %w", err)
+	}
+	sort.Strings(paths)
+	templates := make([]*Template, 0, len(paths))
+	for _, path := range paths {
+		template, err := readTemplate(path)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, template)
+	}
+	return templates, nil
+}
+
+// CreateFromTemplate returns an in-memory plan named name with the steps of the template templateName, all marked "TODO".
+// Like Create, it does not persist the plan; call Save to store it.
+// If there is no such template, the error lists the available templates.
+func (p *Planner) CreateFromTemplate(name, templateName string) (*Plan, error) {
+	plan, err := p.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	template, err := readTemplate(filepath.Join(TemplateDir, templateName+".json"))
+	if errors.Is(err, os.ErrNotExist) || strings.ContainsAny(templateName, `/\`) {
+		return nil, p.templateNotFound(templateName)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, step := range template.Steps {
+		step.status = "TODO"
+		step.notes = nil
+	}
+	plan.Steps = template.Steps
+	if err := plan.checkImport(); err != nil {
+		return nil, fmt.Errorf("invalid plan template '%s': %w", templateName, err)
+	}
+	return plan, nil
+}
+
+// templateNotFound returns the error for a missing template, listing the available templates.
+func (p *Planner) templateNotFound(templateName string) error {
+	templates, err := p.Templates()
+	if err != nil {
+		return fmt.Errorf("plan template '%s' not found: %w", templateName, err)
+	}
+	if len(templates) == 0 {
+		return fmt.Errorf("plan template '%s' not found: there are no templates in %s", templateName, TemplateDir)
+	}
+	names := make([]string, len(templates))
+	for i, template := range templates {
+		names[i] = template.Name
+	}
+	return fmt.Errorf("plan template '%s' not found, available templates: %s", templateName, strings.Join(names, ", "))
+}
+
+// readTemplate reads the template file at path.
+func readTemplate(path string) (*Template, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan template: %w", err)
+	}
+	template := &Template{Name: strings.TrimSuffix(filepath.Base(path), ".json")}
+	if err := json.Unmarshal(data, template); err != nil {
+		return nil, fmt.Errorf("failed to decode plan template '%s': %w", template.Name, err)
+	}
+	return template, nil
+}
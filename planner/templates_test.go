@@ -0,0 +1,68 @@
+package planner
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTemplate(t *testing.T, name, content string) {
+	t.Helper()
+	if err := os.MkdirAll(TemplateDir, 0755); err != nil {
+		t.Fatalf("MkdirAll failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(TemplateDir, name+".json"), []byte(content), 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+}
+
+func TestPlanner_CreateFromTemplate(t *testing.T) {
+	p, cleanup := setupTestDB(t)
+	defer cleanup()
+	t.Chdir(t.TempDir())
+
+	if _, err := p.CreateFromTemplate("plan", "feature"); err == nil || !strings.Contains(err.Error(), "no templates") {
+		t.Errorf("expected an error about missing templates, got %v", err)
+	}
+
+	writeTemplate(t, "feature", `{
+		"description": "A new feature",
+		"steps": [
+			{"id": "tests", "description": "Write tests", "status": "DONE", "acceptance_criteria": ["go test passes"]},
+			{"id": "implement", "description": "Implement it", "depends_on": ["tests"]}
+		]
+	}`)
+	writeTemplate(t, "bugfix", `{"steps": [{"id": "reproduce", "description": "Reproduce the bug"}]}`)
+
+	templates, err := p.Templates()
+	if err != nil {
+		t.Fatalf("Templates failed: %v", err)
+	}
+	if len(templates) != 2 || templates[0].Name != "bugfix" || templates[1].Name != "feature" || templates[1].Description != "A new feature" {
+		t.Errorf("unexpected templates %+v", templates)
+	}
+
+	plan, err := p.CreateFromTemplate("my-feature", "feature")
+	if err != nil {
+		t.Fatalf("CreateFromTemplate failed: %v", err)
+	}
+	if err := p.Save(plan); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	loaded, err := p.Get("my-feature")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if len(loaded.Steps) != 2 || loaded.Steps[0].Status() != "TODO" || loaded.Steps[0].AcceptanceCriteria()[0] != "go test passes" {
+		t.Errorf("unexpected plan from template:\n%s", loaded.Inspect())
+	}
+	if next := loaded.NextStep(); next == nil || next.ID() != "tests" {
+		t.Errorf("expected next step tests, got %v", next)
+	}
+
+	_, err = p.CreateFromTemplate("plan", "missing")
+	if err == nil || !strings.Contains(err.Error(), "available templates: bugfix, feature") {
+		t.Errorf("expected the error to list the available templates, got %v", err)
+	}
+}
@@ -0,0 +1,198 @@
+package planner
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// MaxUndoChanges is the number of changes kept per plan for Undo; older changes are discarded when a plan is saved.
+const MaxUndoChanges = 20
+
+// ErrNothingToUndo is returned by Undo when no change of the plan is recorded.
+var ErrNothingToUndo = errors.New("no change to undo")
+
+// planChange is a change made to a plan in-memory, recorded with what is needed to reverse it.
+// Exactly one of StepID, RemovedSteps and PreviousOrder is set, depending on the kind of change.
+type planChange struct {
+	Action         string         `json:"-"`                         // Description of the change, e.g. "mark 'add-tests' as DONE"
+	StepID         string         `json:"step_id,omitempty"`         // The step whose status changed
+	PreviousStatus string         `json:"previous_status,omitempty"` // The status of StepID before the change
+	RemovedSteps   []*removedStep `json:"removed_steps,omitempty"`
+	PreviousOrder  []string       `json:"previous_order,omitempty"` // The IDs of the steps before they were reordered
+}
+
+// removedStep is a step removed from a plan, recorded to restore it.
+type removedStep struct {
+	Position   int      `json:"position"` // Index of the step in the plan before it was removed
+	Step       *Step    `json:"step"`
+	Dependents []string `json:"dependents,omitempty"` // Steps that depended on the step until it was removed
+}
+
+// recordStatusChange records that the status of step is about to change to status.
+func (pl *Plan) recordStatusChange(step *Step, status string) {
+	pl.recordChange(planChange{
+		Action:         fmt.Sprintf("mark '%s' as %s", step.id, status),
+		StepID:         step.id,
+		PreviousStatus: step.Status(),
+	})
+}
+
+// recordChange remembers change until the plan is saved.
+func (pl *Plan) recordChange(change planChange) {
+	pl.pendingChanges = append(pl.pendingChanges, change)
+}
+
+// recordChanges stores the pending changes of plan within tx and discards all but the latest MaxUndoChanges.
+func recordChanges(tx *sql.Tx, plan *Plan) error {
+	if len(plan.pendingChanges) == 0 {
+		return nil
+	}
+	for _, change := range plan.pendingChanges {
+		reversal, err := json.Marshal(change)
+		if err != nil {
+			return fmt.Errorf("failed to encode change '%s' of plan '%s': %w", change.Action, plan.ID, err)
+		}
+		_, err = tx.Exec("INSERT INTO plan_changes (plan_id, action, reversal) VALUES (?, ?, ?)",
+			plan.ID, change.Action, string(reversal))
+		if err != nil {
+			return fmt.Errorf("failed to record change '%s' of plan '%s': %w", change.Action, plan.ID, err)
+		}
+	}
+	_, err := tx.Exec(`
+        DELETE FROM plan_changes
+        WHERE plan_id = ? AND id NOT IN (
+            SELECT id FROM plan_changes WHERE plan_id = ? ORDER BY id DESC LIMIT ?
+        )`, plan.ID, plan.ID, MaxUndoChanges)
+	if err != nil {
+		return fmt.Errorf("failed to discard old changes of plan '%s': %w", plan.ID, err)
+	}
+	return nil
+}
+
+// Undo reverts the most recent recorded change of the plan planID:
+// marking a step as DONE or TODO, removing steps or reordering them.
+// Only that change is reversed; steps added or edited since then are kept.
+// Removed steps are restored at their old positions with their acceptance criteria, dependencies and notes,
+// and steps that depended on them depend on them again.
+// It returns an error wrapping ErrNothingToUndo if no change is recorded.
+// Only the latest MaxUndoChanges changes of each plan are kept.
+func (p *Planner) Undo(planID string) error {
+	plan, err := p.Get(planID)
+	if err != nil {
+		return err
+	}
+
+	tx, err := p.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() // Rollback if not committed
+
+	var changeID int64
+	var change planChange
+	var reversal string
+	err = tx.QueryRow("SELECT id, action, reversal FROM plan_changes WHERE plan_id = ? ORDER BY id DESC LIMIT 1", planID).
+		Scan(&changeID, &change.Action, &reversal)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("cannot undo in plan '%s': %w", planID, ErrNothingToUndo)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to query the last change of plan '%s': %w", planID, err)
+	}
+	if err := json.Unmarshal([]byte(reversal), &change); err != nil {
+		return fmt.Errorf("failed to decode change '%s' of plan '%s': %w", change.Action, planID, err)
+	}
+
+	if err := plan.reverse(change); err != nil {
+		return fmt.Errorf("cannot undo '%s' in plan '%s': %w", change.Action, planID, err)
+	}
+	if err := saveInTx(tx, plan); err != nil {
+		return fmt.Errorf("failed to undo '%s' in plan '%s': %w", change.Action, planID, err)
+	}
+	// The notes of removed steps were deleted with them.
+	for _, removed := range change.RemovedSteps {
+		if err := insertNotes(tx, planID, removed.Step); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec("DELETE FROM plan_changes WHERE id = ?", changeID); err != nil {
+		return fmt.Errorf("failed to delete change '%s' of plan '%s': %w", change.Action, planID, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction for plan '%s': %w", planID, err)
+	}
+	return nil
+}
+
+// reverse applies the reversal of change to the plan in-memory, without recording it as a change.
+func (pl *Plan) reverse(change planChange) error {
+	switch {
+	case change.StepID != "":
+		step := pl.step(change.StepID)
+		if step == nil {
+			return fmt.Errorf("step '%s' no longer exists", change.StepID)
+		}
+		step.status = change.PreviousStatus
+
+	case len(change.RemovedSteps) > 0:
+		// Restoring in the order of the old positions puts every step back where it was,
+		// as long as no steps were added or removed since.
+		for _, removed := range change.RemovedSteps {
+			if pl.step(removed.Step.id) != nil {
+				return fmt.Errorf("a step with ID '%s' was added since", removed.Step.id)
+			}
+			// Dependencies removed since then cannot be restored.
+			var dependsOn []string
+			for _, id := range removed.Step.dependsOn {
+				if pl.step(id) != nil {
+					dependsOn = append(dependsOn, id)
+				}
+			}
+			removed.Step.dependsOn = dependsOn
+			position := min(removed.Position, len(pl.Steps))
+			pl.Steps = append(pl.Steps[:position], append([]*Step{removed.Step}, pl.Steps[position:]...)...)
+		}
+		for _, removed := range change.RemovedSteps {
+			for _, id := range removed.Dependents {
+				if dependent := pl.step(id); dependent != nil && !slices.Contains(dependent.dependsOn, removed.Step.id) {
+					dependent.dependsOn = append(dependent.dependsOn, removed.Step.id)
+				}
+			}
+		}
+
+	case len(change.PreviousOrder) > 0:
+		// Steps added since the reordering keep their place after the reordered ones.
+		var steps []*Step
+		placed := map[string]bool{}
+		for _, id := range change.PreviousOrder {
+			if step := pl.step(id); step != nil && !placed[id] {
+				steps = append(steps, step)
+				placed[id] = true
+			}
+		}
+		for _, step := range pl.Steps {
+			if !placed[step.id] {
+				steps = append(steps, step)
+			}
+		}
+		pl.Steps = steps
+
+	default:
+		return fmt.Errorf("unknown change")
+	}
+	return nil
+}
+
+// insertNotes stores the notes of step within tx.
+func insertNotes(tx *sql.Tx, planID string, step *Step) error {
+	for _, note := range step.notes {
+		if _, err := tx.Exec("INSERT INTO step_notes (plan_id, step_id, note) VALUES (?, ?, ?)", planID, step.id, note); err != nil {
+			return fmt.Errorf("failed to insert note of step '%s' in plan '%s': %w", step.id, planID, err)
+		}
+	}
+	return nil
+}
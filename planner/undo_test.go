@@ -0,0 +1,209 @@
+package planner
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func saveUndoPlan(t *testing.T, p *Planner) *Plan {
+	t.Helper()
+	plan, _ := p.Create("undo-plan")
+	plan.AddStep("step1", "Step 1 desc", []string{"crit1"})
+	plan.AddStep("step2", "Step 2 desc", []string{"crit2a", "crit2b"}, "step1")
+	plan.AddStep("step3", "Step 3 desc", nil, "step2")
+	if err := p.Save(plan); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	return plan
+}
+
+func stepIDs(plan *Plan) []string {
+	ids := make([]string, len(plan.Steps))
+	for i, step := range plan.Steps {
+		ids[i] = step.ID()
+	}
+	return ids
+}
+
+func TestPlanner_UndoMarkAsCompleted(t *testing.T) {
+	p, cleanup := setupTestDB(t)
+	defer cleanup()
+	plan := saveUndoPlan(t, p)
+
+	if err := p.Undo("undo-plan"); !errors.Is(err, ErrNothingToUndo) {
+		t.Fatalf("expected ErrNothingToUndo before any change, got %v", err)
+	}
+
+	plan.MarkAsCompleted("step1")
+	plan.MarkAsCompleted("step1") // No change, so nothing is recorded
+	if err := p.Save(plan); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	plan.MarkAsCompleted("step2")
+	if err := p.Save(plan); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	if err := p.Undo("undo-plan"); err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	loaded, _ := p.Get("undo-plan")
+	if loaded.Steps[0].Status() != "DONE" || loaded.Steps[1].Status() != "TODO" {
+		t.Errorf("expected step1 DONE and step2 TODO, got %s and %s", loaded.Steps[0].Status(), loaded.Steps[1].Status())
+	}
+
+	if err := p.Undo("undo-plan"); err != nil {
+		t.Fatalf("second Undo failed: %v", err)
+	}
+	loaded, _ = p.Get("undo-plan")
+	if loaded.Steps[0].Status() != "TODO" {
+		t.Errorf("expected step1 TODO after undoing twice, got %s", loaded.Steps[0].Status())
+	}
+	if err := p.Undo("undo-plan"); !errors.Is(err, ErrNothingToUndo) {
+		t.Errorf("expected ErrNothingToUndo after undoing all changes, got %v", err)
+	}
+}
+
+func TestPlanner_UndoRemoveSteps(t *testing.T) {
+	p, cleanup := setupTestDB(t)
+	defer cleanup()
+	plan := saveUndoPlan(t, p)
+	if err := p.AddNote("undo-plan", "step2", "halfway there"); err != nil {
+		t.Fatalf("AddNote failed: %v", err)
+	}
+	if err := p.AddNote("undo-plan", "step1", "kept"); err != nil {
+		t.Fatalf("AddNote failed: %v", err)
+	}
+
+	plan, _ = p.Get("undo-plan")
+	if removed := plan.RemoveSteps([]string{"step2"}); removed != 1 {
+		t.Fatalf("expected 1 removed step, got %d", removed)
+	}
+	if err := p.Save(plan); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	if err := p.Undo("undo-plan"); err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	loaded, _ := p.Get("undo-plan")
+	if ids := stepIDs(loaded); !reflect.DeepEqual(ids, []string{"step1", "step2", "step3"}) {
+		t.Fatalf("expected all steps to be restored, got %v", ids)
+	}
+	step1, step2, step3 := loaded.Steps[0], loaded.Steps[1], loaded.Steps[2]
+	if !reflect.DeepEqual(step2.AcceptanceCriteria(), []string{"crit2a", "crit2b"}) {
+		t.Errorf("expected criteria of step2 to be restored, got %v", step2.AcceptanceCriteria())
+	}
+	if !reflect.DeepEqual(step2.DependsOn(), []string{"step1"}) || !reflect.DeepEqual(step3.DependsOn(), []string{"step2"}) {
+		t.Errorf("expected dependencies to be restored, got %v and %v", step2.DependsOn(), step3.DependsOn())
+	}
+	if !reflect.DeepEqual(step2.Notes(), []string{"halfway there"}) {
+		t.Errorf("expected notes of step2 to be restored, got %v", step2.Notes())
+	}
+	if !reflect.DeepEqual(step1.Notes(), []string{"kept"}) {
+		t.Errorf("expected notes of step1 to be kept once, got %v", step1.Notes())
+	}
+}
+
+func TestPlanner_UndoReorder(t *testing.T) {
+	p, cleanup := setupTestDB(t)
+	defer cleanup()
+	plan := saveUndoPlan(t, p)
+
+	plan.Reorder([]string{"step3", "step1"})
+	if err := p.Save(plan); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if err := p.Undo("undo-plan"); err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	loaded, _ := p.Get("undo-plan")
+	if ids := stepIDs(loaded); !reflect.DeepEqual(ids, []string{"step1", "step2", "step3"}) {
+		t.Errorf("expected the original order, got %v", ids)
+	}
+}
+
+func TestPlanner_UndoKeepsLaterChanges(t *testing.T) {
+	p, cleanup := setupTestDB(t)
+	defer cleanup()
+	plan := saveUndoPlan(t, p)
+
+	plan.MarkAsCompleted("step1")
+	if err := p.Save(plan); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	// Changes that are not recorded for Undo, made after the recorded one.
+	plan, _ = p.Get("undo-plan")
+	plan.AddStep("step4", "Step 4 desc", []string{"crit4"}, "step3")
+	plan.SetPriority("step2", 7)
+	if err := p.Save(plan); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if err := p.AddNote("undo-plan", "step4", "started"); err != nil {
+		t.Fatalf("AddNote failed: %v", err)
+	}
+
+	if err := p.Undo("undo-plan"); err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	loaded, _ := p.Get("undo-plan")
+	if ids := stepIDs(loaded); !reflect.DeepEqual(ids, []string{"step1", "step2", "step3", "step4"}) {
+		t.Fatalf("expected the added step to be kept, got %v", ids)
+	}
+	if loaded.Steps[0].Status() != "TODO" {
+		t.Errorf("expected step1 TODO, got %s", loaded.Steps[0].Status())
+	}
+	if loaded.Steps[1].Priority() != 7 {
+		t.Errorf("expected the priority of step2 to be kept, got %d", loaded.Steps[1].Priority())
+	}
+	step4 := loaded.Steps[3]
+	if !reflect.DeepEqual(step4.AcceptanceCriteria(), []string{"crit4"}) || !reflect.DeepEqual(step4.Notes(), []string{"started"}) {
+		t.Errorf("expected criteria and notes of step4 to be kept, got %v and %v", step4.AcceptanceCriteria(), step4.Notes())
+	}
+}
+
+func TestPlanner_UndoReorderKeepsAddedSteps(t *testing.T) {
+	p, cleanup := setupTestDB(t)
+	defer cleanup()
+	plan := saveUndoPlan(t, p)
+
+	plan.Reorder([]string{"step3"})
+	plan.AddStep("step4", "Step 4 desc", nil)
+	if err := p.Save(plan); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if err := p.Undo("undo-plan"); err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	loaded, _ := p.Get("undo-plan")
+	if ids := stepIDs(loaded); !reflect.DeepEqual(ids, []string{"step1", "step2", "step3", "step4"}) {
+		t.Errorf("expected the original order followed by the added step, got %v", ids)
+	}
+}
+
+func TestPlanner_UndoKeepsLatestChanges(t *testing.T) {
+	p, cleanup := setupTestDB(t)
+	defer cleanup()
+	plan := saveUndoPlan(t, p)
+
+	for i := 0; i < MaxUndoChanges+5; i++ {
+		plan.MarkAsCompleted("step1")
+		plan.MarkAsIncomplete("step1")
+	}
+	if err := p.Save(plan); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	undone := 0
+	for ; undone <= 2*MaxUndoChanges; undone++ {
+		if err := p.Undo("undo-plan"); errors.Is(err, ErrNothingToUndo) {
+			break
+		} else if err != nil {
+			t.Fatalf("Undo failed: %v", err)
+		}
+	}
+	if undone != MaxUndoChanges {
+		t.Errorf("expected %d changes to undo, got %d", MaxUndoChanges, undone)
+	}
+}
@@ -0,0 +1,89 @@
+package smolcode
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+// ModelPricing is the price of a model in US dollars per million tokens.
+type ModelPricing struct {
+	InputPerMillion       float64 // Prompt tokens not served from the cache.
+	CachedInputPerMillion float64 // Prompt tokens served from the cache.
+	OutputPerMillion      float64 // Candidate and thought tokens.
+}
+
+// modelPrices lists the prices of known models.
+// Models are matched by prefix, see pricingFor, so entries cover all versions of a model.
+var modelPrices = map[string]ModelPricing{
+	"gemini-2.5-pro":        {InputPerMillion: 1.25, CachedInputPerMillion: 0.31, OutputPerMillion: 10.00},
+	"gemini-2.5-flash-lite": {InputPerMillion: 0.10, CachedInputPerMillion: 0.025, OutputPerMillion: 0.40},
+	"gemini-2.5-flash":      {InputPerMillion: 0.30, CachedInputPerMillion: 0.075, OutputPerMillion: 2.50},
+	"gemini-2.0-flash-lite": {InputPerMillion: 0.075, CachedInputPerMillion: 0.075, OutputPerMillion: 0.30},
+	"gemini-2.0-flash":      {InputPerMillion: 0.10, CachedInputPerMillion: 0.025, OutputPerMillion: 0.40},
+}
+
+// pricingFor returns the prices of modelName, using the longest entry in modelPrices it starts with.
+func pricingFor(modelName string) (ModelPricing, bool) {
+	modelName = strings.TrimPrefix(modelName, "models/")
+	var pricing ModelPricing
+	matched := ""
+	for prefix, candidate := range modelPrices {
+		if strings.HasPrefix(modelName, prefix) && len(prefix) > len(matched) {
+			pricing, matched = candidate, prefix
+		}
+	}
+	return pricing, matched != ""
+}
+
+// Cost returns the price in US dollars of a single request with the given usage.
+func (pricing ModelPricing) Cost(metadata *genai.GenerateContentResponseUsageMetadata) float64 {
+	uncached := metadata.PromptTokenCount - metadata.CachedContentTokenCount
+	output := metadata.CandidatesTokenCount + metadata.ThoughtsTokenCount
+	return (float64(uncached)*pricing.InputPerMillion +
+		float64(metadata.CachedContentTokenCount)*pricing.CachedInputPerMillion +
+		float64(output)*pricing.OutputPerMillion) / 1_000_000
+}
+
+// tokenUsage accumulates the token usage of all requests in a conversation.
+type tokenUsage struct {
+	PromptTokens     int64
+	CandidatesTokens int64
+	CachedTokens     int64
+	ThoughtsTokens   int64
+	Cost             float64
+	UnknownCost      bool // Set once a request was made with a model without known prices.
+}
+
+// Add records the usage of a single request made with modelName.
+func (usage *tokenUsage) Add(modelName string, metadata *genai.GenerateContentResponseUsageMetadata) {
+	if metadata == nil {
+		return
+	}
+	usage.PromptTokens += int64(metadata.PromptTokenCount)
+	usage.CandidatesTokens += int64(metadata.CandidatesTokenCount)
+	usage.CachedTokens += int64(metadata.CachedContentTokenCount)
+	usage.ThoughtsTokens += int64(metadata.ThoughtsTokenCount)
+	if pricing, found := pricingFor(modelName); found {
+		usage.Cost += pricing.Cost(metadata)
+	} else {
+		usage.UnknownCost = true
+	}
+}
+
+// String summarizes the accumulated usage, including the estimated cost.
+func (usage *tokenUsage) String() string {
+	cost := "unknown"
+	if !usage.UnknownCost {
+		cost = fmt.Sprintf("$%.4f", usage.Cost)
+	}
+	return fmt.Sprintf(
+		"Conversation usage: Prompt=%d, Candidates=%d, Cached=%d, Thoughts=%d, Estimated cost: %s",
+		usage.PromptTokens,
+		usage.CandidatesTokens,
+		usage.CachedTokens,
+		usage.ThoughtsTokens,
+		cost,
+	)
+}
@@ -0,0 +1,46 @@
+package smolcode
+
+import (
+	"testing"
+
+	"google.golang.org/genai"
+)
+
+func TestPricingForMatchesLongestPrefix(t *testing.T) {
+	pricing, found := pricingFor("gemini-2.5-flash-lite-preview-06-17")
+
+	if !found || pricing != modelPrices["gemini-2.5-flash-lite"] {
+		t.Errorf("expected the flash-lite prices, got %+v, %t", pricing, found)
+	}
+}
+
+func TestTokenUsageAccumulatesCost(t *testing.T) {
+	modelPrices["test-model"] = ModelPricing{InputPerMillion: 1, CachedInputPerMillion: 0.5, OutputPerMillion: 4}
+	t.Cleanup(func() { delete(modelPrices, "test-model") })
+	metadata := &genai.GenerateContentResponseUsageMetadata{
+		PromptTokenCount:        3_000_000,
+		CachedContentTokenCount: 2_000_000,
+		CandidatesTokenCount:    500_000,
+		ThoughtsTokenCount:      500_000,
+	}
+
+	usage := &tokenUsage{}
+	usage.Add("test-model", metadata)
+	usage.Add("test-model", metadata)
+
+	if usage.PromptTokens != 6_000_000 || usage.CachedTokens != 4_000_000 {
+		t.Errorf("expected token counts to be summed, got %+v", usage)
+	}
+	if want := 2 * (1.0 + 1.0 + 4.0); usage.Cost != want {
+		t.Errorf("expected a cost of %v, got %v", want, usage.Cost)
+	}
+}
+
+func TestTokenUsageWithUnknownModel(t *testing.T) {
+	usage := &tokenUsage{}
+	usage.Add("some-other-model", &genai.GenerateContentResponseUsageMetadata{PromptTokenCount: 10})
+
+	if got, want := usage.String(), "Conversation usage: Prompt=10, Candidates=0, Cached=0, Thoughts=0, Estimated cost: unknown"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
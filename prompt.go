@@ -0,0 +1,37 @@
+package smolcode
+
+import (
+	"strconv"
+	"strings"
+)
+
+// DefaultPromptTemplate is the prompt shown before reading user input at a terminal.
+// Templates may contain these tokens:
+//
+//	{count}  the number of messages in the conversation
+//	{model}  the name of the model in use
+//	{plan}   the name of the plan last used with the manage_plan tool, if any
+const DefaultPromptTemplate = "\u001b[94mYou [{count}]\u001b[0m: "
+
+// PlainPromptTemplate is the prompt shown when output is not a terminal and no template is configured.
+const PlainPromptTemplate = "You [{count}]: "
+
+// WithPromptTemplate sets the template of the prompt shown before reading user input,
+// see DefaultPromptTemplate for the tokens it may contain.
+func (agent *Agent) WithPromptTemplate(template string) *Agent {
+	agent.promptTemplate = template
+	return agent
+}
+
+// prompt expands the agent's prompt template, falling back to DefaultPromptTemplate.
+func (agent *Agent) prompt() string {
+	template := agent.promptTemplate
+	if template == "" {
+		template = DefaultPromptTemplate
+	}
+	return strings.NewReplacer(
+		"{count}", strconv.Itoa(len(agent.history)),
+		"{model}", agent.modelName,
+		"{plan}", agent.activePlan,
+	).Replace(template)
+}
@@ -0,0 +1,24 @@
+package smolcode
+
+import (
+	"testing"
+
+	"google.golang.org/genai"
+)
+
+func TestPromptExpandsTokens(t *testing.T) {
+	agent := (&Agent{
+		history:    []*genai.Content{genai.NewContentFromText("hello", genai.RoleUser)},
+		activePlan: "release",
+	}).ChooseModel("gemini-2.5-flash").WithPromptTemplate("{model} {plan} [{count}]> ")
+
+	if got, want := agent.prompt(), "gemini-2.5-flash release [1]> "; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestPromptDefaultsToColoredPrompt(t *testing.T) {
+	if got, want := (&Agent{}).prompt(), "\u001b[94mYou [0]\u001b[0m: "; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,140 @@
+package smolcode
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+// RetryConfig controls how failed requests to the model are retried.
+// Fields left at their zero value fall back to DefaultRetryConfig.
+type RetryConfig struct {
+	// MaxRetries is the number of attempts made before giving up, including the first one.
+	MaxRetries int
+	// Delays are the waits before each retry. When there are more retries than delays, the last delay is repeated.
+	Delays []time.Duration
+	// RetryableErrorMatchers decide which errors are retried; an error is retried if any matcher returns true.
+	RetryableErrorMatchers []func(error) bool
+	// MaxRetryTime bounds the wall clock time spent on a request including its retries:
+	// no retry is made whose delay would end after MaxRetryTime, regardless of MaxRetries.
+	MaxRetryTime time.Duration
+}
+
+// DefaultRetryConfig returns the retry policy used unless configured otherwise:
+// up to five attempts within two minutes, retrying internal server errors with increasing delays.
+// The delays add up to a minute, which leaves another minute for the requests themselves.
+func DefaultRetryConfig() RetryConfig {
+	return RetryConfig{
+		MaxRetries:             5,
+		Delays:                 []time.Duration{5 * time.Second, 10 * time.Second, 15 * time.Second, 30 * time.Second},
+		RetryableErrorMatchers: []func(error) bool{IsServerError},
+		MaxRetryTime:           2 * time.Minute,
+	}
+}
+
+// WithRetryConfig sets the policy for retrying failed requests to the model.
+func (agent *Agent) WithRetryConfig(config RetryConfig) *Agent {
+	agent.retryConfig = config
+	return agent
+}
+
+// clock is the source of time for retrying requests, replaced in tests.
+type clock interface {
+	Now() time.Time
+	// Sleep waits for delay, returning early with the context's error if ctx is cancelled.
+	Sleep(ctx context.Context, delay time.Duration) error
+}
+
+// realClock is the clock used unless an agent has another one.
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) Sleep(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// IsServerError reports whether err is an internal error of the API.
+func IsServerError(err error) bool {
+	return strings.Contains(err.Error(), "An internal error has occurred") || strings.Contains(err.Error(), "server error")
+}
+
+// RetryOnStatus returns a matcher for RetryConfig.RetryableErrorMatchers that matches API errors
+// with one of the given HTTP status codes, e.g. RetryOnStatus(429) to retry when rate limited.
+func RetryOnStatus(codes ...int) func(error) bool {
+	return func(err error) bool {
+		apiErr, ok := asAPIError(err)
+		if !ok {
+			return false
+		}
+		for _, code := range codes {
+			if apiErr.Code == code {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// asAPIError returns the API error in err's chain.
+// The client returns APIError values, but pointers are accepted as well.
+func asAPIError(err error) (genai.APIError, bool) {
+	var apiErr genai.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr, true
+	}
+	var apiErrPtr *genai.APIError
+	if errors.As(err, &apiErrPtr) && apiErrPtr != nil {
+		return *apiErrPtr, true
+	}
+	return genai.APIError{}, false
+}
+
+// withDefaults returns config with unset fields taken from DefaultRetryConfig.
+func (config RetryConfig) withDefaults() RetryConfig {
+	defaults := DefaultRetryConfig()
+	if config.MaxRetries <= 0 {
+		config.MaxRetries = defaults.MaxRetries
+	}
+	if config.Delays == nil {
+		config.Delays = defaults.Delays
+	}
+	if config.RetryableErrorMatchers == nil {
+		config.RetryableErrorMatchers = defaults.RetryableErrorMatchers
+	}
+	if config.MaxRetryTime <= 0 {
+		config.MaxRetryTime = defaults.MaxRetryTime
+	}
+	return config
+}
+
+// isRetryable reports whether any of the matchers matches err.
+func (config RetryConfig) isRetryable(err error) bool {
+	for _, matches := range config.RetryableErrorMatchers {
+		if matches(err) {
+			return true
+		}
+	}
+	return false
+}
+
+// delay returns how long to wait after the failed attempt with the given index, starting at 0.
+func (config RetryConfig) delay(attempt int) time.Duration {
+	if len(config.Delays) == 0 {
+		return 0
+	}
+	if attempt < len(config.Delays) {
+		return config.Delays[attempt]
+	}
+	return config.Delays[len(config.Delays)-1]
+}
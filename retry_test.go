@@ -0,0 +1,167 @@
+package smolcode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+func TestRetryConfigFallsBackToDefaults(t *testing.T) {
+	config := RetryConfig{MaxRetries: 2}.withDefaults()
+
+	if config.MaxRetries != 2 {
+		t.Errorf("expected the configured MaxRetries to be kept, got %d", config.MaxRetries)
+	}
+	if len(config.Delays) != len(DefaultRetryConfig().Delays) {
+		t.Errorf("expected the default delays, got %v", config.Delays)
+	}
+	if !config.isRetryable(errors.New("server error")) {
+		t.Errorf("expected server errors to be retried by default")
+	}
+	if config.isRetryable(genai.APIError{Code: 429}) {
+		t.Errorf("expected rate limit errors not to be retried by default")
+	}
+}
+
+func TestRetryConfigDelayRepeatsLastDelay(t *testing.T) {
+	config := RetryConfig{Delays: []time.Duration{time.Second, 2 * time.Second}}
+
+	got := []time.Duration{config.delay(0), config.delay(1), config.delay(5)}
+
+	want := []time.Duration{time.Second, 2 * time.Second, 2 * time.Second}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("delay %d: expected %s, got %s", i, want[i], got[i])
+		}
+	}
+}
+
+func TestRetryOnStatusMatchesAPIErrors(t *testing.T) {
+	config := RetryConfig{RetryableErrorMatchers: []func(error) bool{RetryOnStatus(429)}}
+
+	if !config.isRetryable(fmt.Errorf("request failed: %w", genai.APIError{Code: 429})) {
+		t.Errorf("expected a wrapped 429 to be retried")
+	}
+	if !config.isRetryable(&genai.APIError{Code: 429}) {
+		t.Errorf("expected a 429 pointer to be retried")
+	}
+	if config.isRetryable(genai.APIError{Code: 400}) {
+		t.Errorf("expected a 400 not to be retried")
+	}
+}
+
+// fakeClock is a clock that only advances when told to or when sleeping, recording the delays slept.
+type fakeClock struct {
+	mu    sync.Mutex
+	now   time.Time
+	slept []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func (c *fakeClock) Sleep(ctx context.Context, delay time.Duration) error {
+	c.mu.Lock()
+	c.slept = append(c.slept, delay)
+	c.mu.Unlock()
+	c.Advance(delay)
+	return ctx.Err()
+}
+
+func TestRunInferenceDefaultPolicyMakesAllAttempts(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		clock.Advance(10 * time.Second) // Every request is slow to fail
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprint(w, `{"error":{"code":500,"message":"An internal error has occurred.","status":"INTERNAL"}}`)
+	}))
+	defer server.Close()
+	client, err := genai.NewClient(context.Background(), &genai.ClientConfig{
+		APIKey:      "test-key",
+		Backend:     genai.BackendGeminiAPI,
+		HTTPOptions: genai.HTTPOptions{BaseURL: server.URL},
+	})
+	if err != nil {
+		t.Fatalf("genai.NewClient failed: %v", err)
+	}
+	agent := (&Agent{client: client, displayer: &recordingDisplay{}, clock: clock}).ChooseModel("gemini-2.5-flash")
+
+	_, err = agent.runInference(context.Background(), []*genai.Content{genai.NewContentFromText("hello", genai.RoleUser)})
+
+	if err == nil {
+		t.Fatal("expected runInference to fail")
+	}
+	defaults := DefaultRetryConfig()
+	if n := int(requests.Load()); n != defaults.MaxRetries {
+		t.Errorf("expected %d attempts, got %d", defaults.MaxRetries, n)
+	}
+	if !reflect.DeepEqual(clock.slept, defaults.Delays) {
+		t.Errorf("expected to wait %v between attempts, got %v", defaults.Delays, clock.slept)
+	}
+}
+
+func TestRunInferenceRespectsMaxRetryTime(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprint(w, `{"error":{"code":503,"message":"unavailable","status":"UNAVAILABLE"}}`)
+	}))
+	defer server.Close()
+	client, err := genai.NewClient(context.Background(), &genai.ClientConfig{
+		APIKey:      "test-key",
+		Backend:     genai.BackendGeminiAPI,
+		HTTPOptions: genai.HTTPOptions{BaseURL: server.URL},
+	})
+	if err != nil {
+		t.Fatalf("genai.NewClient failed: %v", err)
+	}
+	agent := (&Agent{client: client, displayer: &recordingDisplay{}}).
+		ChooseModel("gemini-2.5-flash").
+		WithRetryConfig(RetryConfig{
+			MaxRetries:             100,
+			Delays:                 []time.Duration{50 * time.Millisecond},
+			RetryableErrorMatchers: []func(error) bool{RetryOnStatus(503)},
+			MaxRetryTime:           300 * time.Millisecond,
+		})
+
+	start := time.Now()
+	_, err = agent.runInference(context.Background(), []*genai.Content{genai.NewContentFromText("hello", genai.RoleUser)})
+	elapsed := time.Since(start)
+
+	if err == nil {
+		t.Fatal("expected runInference to fail")
+	}
+	if elapsed > 2*time.Second {
+		t.Errorf("expected to give up after about 300ms, took %s", elapsed)
+	}
+	if n := requests.Load(); n < 2 || n > 7 {
+		t.Errorf("expected a few attempts within the time limit, got %d", n)
+	}
+	if !strings.Contains(err.Error(), fmt.Sprintf("after %d attempts in ", requests.Load())) {
+		t.Errorf("expected the error to report the attempts and elapsed time, got %q", err)
+	}
+}
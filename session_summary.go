@@ -0,0 +1,78 @@
+package smolcode
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/dhamidi/smolcode/history"
+	"github.com/dhamidi/smolcode/planner"
+)
+
+// countToolCall records a call of the tool name for the session summary.
+func (agent *Agent) countToolCall(name string) {
+	if agent.toolCalls == nil {
+		agent.toolCalls = map[string]int{}
+	}
+	agent.toolCalls[name]++
+}
+
+// sessionSummary summarizes the session from its start until end.
+func (agent *Agent) sessionSummary(end time.Time) history.SessionSummary {
+	summary := history.SessionSummary{
+		StartedAt:       agent.sessionStart,
+		EndedAt:         end,
+		DurationSeconds: end.Sub(agent.sessionStart).Seconds(),
+		Turns:           agent.turns,
+		ToolCalls:       map[string]int{},
+		FilesModified:   []string{},
+		Tokens: history.SessionTokens{
+			Prompt:      agent.usage.PromptTokens,
+			Candidates:  agent.usage.CandidatesTokens,
+			Cached:      agent.usage.CachedTokens,
+			Thoughts:    agent.usage.ThoughtsTokens,
+			Cost:        agent.usage.Cost,
+			CostUnknown: agent.usage.UnknownCost,
+		},
+	}
+	if agent.persistentConversation != nil {
+		summary.ConversationID = agent.persistentConversation.ID
+	}
+	for name, count := range agent.toolCalls {
+		summary.ToolCalls[name] = count
+	}
+	for path := range agent.touchedFiles {
+		summary.FilesModified = append(summary.FilesModified, path)
+	}
+	sort.Strings(summary.FilesModified)
+	if agent.activePlan != "" {
+		summary.Plan = &history.SessionPlan{Name: agent.activePlan}
+		if status, err := activePlanStatus(agent.activePlan); err == nil {
+			summary.Plan.Status = status.Status
+			summary.Plan.CompletedSteps = status.CompletedTasks
+			summary.Plan.TotalSteps = status.TotalTasks
+		}
+	}
+	return summary
+}
+
+// activePlanStatus reads the status of the plan name from the planner tool's database.
+func activePlanStatus(name string) (planner.PlanStatus, error) {
+	plans, err := planner.New(planStoragePath)
+	if err != nil {
+		return planner.PlanStatus{}, err
+	}
+	defer plans.Close()
+	return plans.PlanStatus(name)
+}
+
+// saveSessionSummary stores the summary of the session with the conversation, see history.SaveSessionSummary.
+func (agent *Agent) saveSessionSummary() {
+	if agent.persistentConversation == nil {
+		return
+	}
+	if err := history.SaveSessionSummary(agent.sessionSummary(time.Now())); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to save session summary: %v\n", err)
+	}
+}
@@ -0,0 +1,61 @@
+package smolcode
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dhamidi/smolcode/history"
+)
+
+func TestSessionSummary(t *testing.T) {
+	t.Chdir(t.TempDir()) // The plan does not exist here.
+	start := time.Now()
+	agent := &Agent{sessionStart: start, turns: 2, activePlan: "release"}
+	agent.countToolCall("read_file")
+	agent.countToolCall("read_file")
+	agent.countToolCall("edit_file")
+	agent.touchFile("b.go")
+	agent.touchFile("a.go")
+	agent.usage.PromptTokens = 100
+
+	summary := agent.sessionSummary(start.Add(90 * time.Second))
+
+	if summary.Turns != 2 || summary.DurationSeconds != 90 || summary.Tokens.Prompt != 100 {
+		t.Errorf("expected turns, duration and tokens of the session, got %+v", summary)
+	}
+	if want := map[string]int{"read_file": 2, "edit_file": 1}; !reflect.DeepEqual(summary.ToolCalls, want) {
+		t.Errorf("expected tool calls %v, got %v", want, summary.ToolCalls)
+	}
+	if want := []string{"a.go", "b.go"}; !reflect.DeepEqual(summary.FilesModified, want) {
+		t.Errorf("expected files %q, got %q", want, summary.FilesModified)
+	}
+	if want := (&history.SessionPlan{Name: "release"}); !reflect.DeepEqual(summary.Plan, want) {
+		t.Errorf("expected plan %+v, got %+v", want, summary.Plan)
+	}
+}
+
+func TestRunSavesSessionSummary(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "history.db")
+	history.SetStore(history.NewSQLiteStore(dbPath))
+	t.Cleanup(func() { history.SetStore(nil) })
+	conv, err := history.New()
+	if err != nil {
+		t.Fatalf("history.New failed: %v", err)
+	}
+	agent := (&Agent{persistentConversation: conv, displayer: &recordingDisplay{}, getUserMessage: endOfInput}).Quiet()
+
+	if err := agent.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	summaries, err := history.SessionSummaries(conv.ID)
+	if err != nil {
+		t.Fatalf("SessionSummaries failed: %v", err)
+	}
+	if len(summaries) != 1 || summaries[0].ConversationID != conv.ID || summaries[0].StartedAt.IsZero() {
+		t.Errorf("expected a summary of the session, got %+v", summaries)
+	}
+}
@@ -0,0 +1,103 @@
+package smolcode
+
+import (
+	"context"
+
+	"google.golang.org/genai"
+)
+
+// EnableStreaming makes the agent display the model's text as it is generated,
+// instead of waiting for the complete response.
+// Function calls are still executed once the whole response has arrived.
+func (agent *Agent) EnableStreaming() *Agent {
+	agent.streaming = true
+	return agent
+}
+
+// generateContent sends a request to the model, streaming the response if streaming is enabled.
+// Streamed text is displayed as it arrives; the returned response combines all chunks.
+func (agent *Agent) generateContent(ctx context.Context, contents []*genai.Content, config *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error) {
+	if !agent.streaming {
+		return agent.client.Models.GenerateContent(ctx, agent.modelName, contents, config)
+	}
+
+	var chunks []*genai.GenerateContentResponse
+	displayedText := false
+	defer func() {
+		if displayedText {
+			agent.displayer.DisplayPrompt("\n")
+		}
+	}()
+	for chunk, err := range agent.client.Models.GenerateContentStream(ctx, agent.modelName, contents, config) {
+		if err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, chunk)
+		if len(chunk.Candidates) == 0 || chunk.Candidates[0].Content == nil {
+			continue
+		}
+		for _, part := range chunk.Candidates[0].Content.Parts {
+			if part == nil || part.Text == "" {
+				continue
+			}
+			if !displayedText {
+				agent.displayer.DisplayPrompt("\u001b[93mGemini [%d]\u001b[0m: ", len(agent.history))
+				displayedText = true
+			}
+			agent.displayer.DisplayPrompt("%s", part.Text)
+		}
+	}
+	return mergeStreamedResponses(chunks), nil
+}
+
+// mergeStreamedResponses combines the chunks of a streamed response into a single response.
+// Consecutive text parts are joined, and everything but the content, like the usage metadata
+// and the finish reason, is taken from the final chunk.
+func mergeStreamedResponses(chunks []*genai.GenerateContentResponse) *genai.GenerateContentResponse {
+	if len(chunks) == 0 {
+		return &genai.GenerateContentResponse{}
+	}
+	merged := *chunks[len(chunks)-1]
+
+	content := &genai.Content{Role: genai.RoleModel}
+	var lastCandidate *genai.Candidate
+	for _, chunk := range chunks {
+		if merged.PromptFeedback == nil && chunk.PromptFeedback != nil {
+			merged.PromptFeedback = chunk.PromptFeedback
+		}
+		if len(chunk.Candidates) == 0 || chunk.Candidates[0] == nil {
+			continue
+		}
+		lastCandidate = chunk.Candidates[0]
+		if lastCandidate.Content == nil {
+			continue
+		}
+		if lastCandidate.Content.Role != "" {
+			content.Role = lastCandidate.Content.Role
+		}
+		for _, part := range lastCandidate.Content.Parts {
+			if part == nil {
+				continue
+			}
+			if n := len(content.Parts); n > 0 && isTextPart(part) && isTextPart(content.Parts[n-1]) && content.Parts[n-1].Thought == part.Thought {
+				content.Parts[n-1].Text += part.Text
+				continue
+			}
+			partCopy := *part
+			content.Parts = append(content.Parts, &partCopy)
+		}
+	}
+	if lastCandidate == nil {
+		return &merged
+	}
+
+	candidate := *lastCandidate
+	candidate.Content = content
+	merged.Candidates = []*genai.Candidate{&candidate}
+	return &merged
+}
+
+// isTextPart reports whether part carries text and nothing else that needs to be kept separate.
+func isTextPart(part *genai.Part) bool {
+	return part.Text != "" && part.FunctionCall == nil && part.FunctionResponse == nil && part.InlineData == nil
+}
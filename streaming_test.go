@@ -0,0 +1,45 @@
+package smolcode
+
+import (
+	"testing"
+
+	"google.golang.org/genai"
+)
+
+func TestMergeStreamedResponses(t *testing.T) {
+	chunk := func(parts ...*genai.Part) *genai.GenerateContentResponse {
+		return &genai.GenerateContentResponse{Candidates: []*genai.Candidate{{Content: &genai.Content{Role: genai.RoleModel, Parts: parts}}}}
+	}
+	final := chunk(&genai.Part{FunctionCall: &genai.FunctionCall{Name: "read_file"}})
+	final.Candidates[0].FinishReason = genai.FinishReasonStop
+	final.UsageMetadata = &genai.GenerateContentResponseUsageMetadata{TotalTokenCount: 12}
+
+	merged := mergeStreamedResponses([]*genai.GenerateContentResponse{
+		chunk(genai.NewPartFromText("Hello, ")),
+		chunk(genai.NewPartFromText("world")),
+		final,
+	})
+
+	if len(merged.Candidates) != 1 {
+		t.Fatalf("expected a single candidate, got %d", len(merged.Candidates))
+	}
+	candidate := merged.Candidates[0]
+	parts := candidate.Content.Parts
+	if len(parts) != 2 || parts[0].Text != "Hello, world" || parts[1].FunctionCall == nil {
+		t.Errorf("expected joined text followed by the function call, got %+v", parts)
+	}
+	if candidate.FinishReason != genai.FinishReasonStop {
+		t.Errorf("expected the finish reason of the final chunk, got %q", candidate.FinishReason)
+	}
+	if merged.UsageMetadata == nil || merged.UsageMetadata.TotalTokenCount != 12 {
+		t.Errorf("expected the usage metadata of the final chunk, got %+v", merged.UsageMetadata)
+	}
+}
+
+func TestMergeStreamedResponsesWithoutCandidates(t *testing.T) {
+	merged := mergeStreamedResponses([]*genai.GenerateContentResponse{{}})
+
+	if len(merged.Candidates) != 0 {
+		t.Errorf("expected no candidates, got %d", len(merged.Candidates))
+	}
+}
@@ -0,0 +1,90 @@
+package smolcode
+
+import (
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// maxCachedToolResults bounds the number of results remembered per cached tool.
+const maxCachedToolResults = 64
+
+// readOnlyToolCacheTTL is how long results of the built-in read-only tools are reused.
+const readOnlyToolCacheTTL = 30 * time.Second
+
+// toolCacheGeneration is incremented whenever a tool that may have changed the
+// outside world runs. Cached results from an older generation are not reused.
+var toolCacheGeneration atomic.Int64
+
+// invalidateToolCaches discards the results remembered by all cached tools.
+func invalidateToolCaches() {
+	toolCacheGeneration.Add(1)
+}
+
+// CachedTool wraps def so that successful results are reused for calls with the same arguments
+// for up to ttl, or until a tool that is not cached runs.
+func CachedTool(def *ToolDefinition, ttl time.Duration) *ToolDefinition {
+	cache := &toolCache{ttl: ttl, entries: map[string]cachedToolResult{}}
+	return &ToolDefinition{
+		Tool: def.Tool,
+		RichFunction: func(args map[string]any) (*ToolResult, error) {
+			// Maps are marshalled with sorted keys, so equal arguments produce equal keys.
+			key, err := json.Marshal(args)
+			if err != nil {
+				return def.Call(args)
+			}
+			if result, found := cache.get(string(key)); found {
+				return result, nil
+			}
+			result, err := def.Call(args)
+			if err != nil {
+				return nil, err
+			}
+			cache.put(string(key), result)
+			return result, nil
+		},
+		cached: true,
+	}
+}
+
+// toolCache holds the results of a single cached tool.
+type toolCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]cachedToolResult
+	order   []string // Keys in the order they were added, oldest first.
+}
+
+type cachedToolResult struct {
+	result     *ToolResult
+	expires    time.Time
+	generation int64
+}
+
+func (cache *toolCache) get(key string) (*ToolResult, bool) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	entry, found := cache.entries[key]
+	if !found || time.Now().After(entry.expires) || entry.generation != toolCacheGeneration.Load() {
+		return nil, false
+	}
+	return entry.result, true
+}
+
+func (cache *toolCache) put(key string, result *ToolResult) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if _, found := cache.entries[key]; !found {
+		cache.order = append(cache.order, key)
+	}
+	cache.entries[key] = cachedToolResult{
+		result:     result,
+		expires:    time.Now().Add(cache.ttl),
+		generation: toolCacheGeneration.Load(),
+	}
+	for len(cache.order) > maxCachedToolResults {
+		delete(cache.entries, cache.order[0])
+		cache.order = cache.order[1:]
+	}
+}
@@ -0,0 +1,73 @@
+package smolcode
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func countingTool(calls *int, err error) *ToolDefinition {
+	tool := testTool("list_files", "Lists files.")
+	tool.Function = func(args map[string]any) (map[string]any, error) {
+		*calls++
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"files": []string{"a.go"}}, nil
+	}
+	return tool
+}
+
+func TestCachedToolCallsUnderlyingFunctionOnce(t *testing.T) {
+	calls := 0
+	tool := CachedTool(countingTool(&calls, nil), time.Minute)
+
+	for i := 0; i < 2; i++ {
+		if _, err := tool.Call(map[string]any{"path": ".", "recursive": true}); err != nil {
+			t.Fatalf("Call %d failed: %v", i+1, err)
+		}
+	}
+
+	if calls != 1 {
+		t.Errorf("expected the underlying function to be called once, got %d calls", calls)
+	}
+}
+
+func TestCachedToolDoesNotCacheErrors(t *testing.T) {
+	calls := 0
+	tool := CachedTool(countingTool(&calls, errors.New("permission denied")), time.Minute)
+
+	tool.Call(map[string]any{"path": "."})
+	tool.Call(map[string]any{"path": "."})
+
+	if calls != 2 {
+		t.Errorf("expected failed calls to be retried, got %d calls", calls)
+	}
+}
+
+func TestCachedToolIsInvalidated(t *testing.T) {
+	calls := 0
+	tool := CachedTool(countingTool(&calls, nil), time.Minute)
+
+	tool.Call(map[string]any{"path": "."})
+	invalidateToolCaches()
+	tool.Call(map[string]any{"path": "."})
+
+	if calls != 2 {
+		t.Errorf("expected the result to be recomputed after invalidation, got %d calls", calls)
+	}
+}
+
+func TestCachedToolBoundsCacheSize(t *testing.T) {
+	calls := 0
+	tool := CachedTool(countingTool(&calls, nil), time.Minute)
+
+	for i := 0; i <= maxCachedToolResults; i++ {
+		tool.Call(map[string]any{"path": i})
+	}
+	tool.Call(map[string]any{"path": 0})
+
+	if calls != maxCachedToolResults+2 {
+		t.Errorf("expected the oldest result to be evicted, got %d calls", calls)
+	}
+}
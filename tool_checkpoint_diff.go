@@ -0,0 +1,46 @@
+package smolcode
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+var CheckpointDiffTool = &ToolDefinition{
+	Tool: &genai.Tool{
+		FunctionDeclarations: []*genai.FunctionDeclaration{
+			{
+				Name: "checkpoint_diff",
+				Description: strings.TrimSpace(`
+Show a unified diff from a named checkpoint to the current files in the project.
+
+Use this to review what changed since a checkpoint made with named_checkpoint.
+To see the changes since the last git commit, use list_changes instead.`),
+				Parameters: &genai.Schema{
+					Type: genai.TypeObject,
+					Properties: map[string]*genai.Schema{
+						"name": {
+							Type:        genai.TypeString,
+							Description: "The name of the checkpoint to compare with.",
+						},
+					},
+					Required: []string{"name"},
+				},
+			},
+		},
+	},
+	Function: checkpointDiff,
+}
+
+func checkpointDiff(args map[string]any) (map[string]any, error) {
+	name, _ := args["name"].(string)
+	if name == "" {
+		return nil, fmt.Errorf("checkpoint_diff: no checkpoint name specified")
+	}
+	diff, err := CheckpointDiff(name)
+	if err != nil {
+		return nil, fmt.Errorf("checkpoint_diff: %w", err)
+	}
+	return map[string]any{"diff": diff}, nil
+}
@@ -102,23 +102,33 @@ var CodegenTool = &ToolDefinition{
 		}
 
 		generator := codegen.New(apiKey)
-		generatedFiles, err := generator.GenerateCode(instruction, existingCodegenFiles, desiredOutputFiles)
-		if err != nil {
-			return nil, fmt.Errorf("perform_code_generation: error from GenerateCode: %w", err)
+		generatedFiles, generateErr := generator.GenerateCode(instruction, existingCodegenFiles, desiredOutputFiles)
+		writtenPaths := []string{}
+		for _, f := range generatedFiles {
+			if f != nil {
+				writtenPaths = append(writtenPaths, f.Path)
+			}
+		}
+		if generateErr != nil && len(writtenPaths) == 0 {
+			return nil, fmt.Errorf("perform_code_generation: error from GenerateCode: %w", generateErr)
 		}
 
 		if len(generatedFiles) == 0 {
 			return map[string]any{"result": "Code generation completed, but no files were returned by the API."}, nil
 		}
 
-		err = generator.Write(generatedFiles)
+		err := generator.Write(generatedFiles)
 		if err != nil {
 			return nil, fmt.Errorf("perform_code_generation: error from Write: %w", err)
 		}
 
-		writtenPaths := make([]string, len(generatedFiles))
-		for i, f := range generatedFiles {
-			writtenPaths[i] = f.Path
+		if generateErr != nil {
+			// Report the partial result, so that only the failed files need to be generated again.
+			return map[string]any{
+				"result":        fmt.Sprintf("Generated and wrote %d of %d file(s); the others failed.", len(writtenPaths), len(generatedFiles)),
+				"files_written": writtenPaths,
+				"errors":        generateErr.Error(),
+			}, nil
 		}
 
 		return map[string]any{
@@ -56,6 +56,10 @@ func editFile(args map[string]any) (map[string]any, error) {
 	if filepath == "" {
 		return nil, fmt.Errorf("edit_file: filepath is missing")
 	}
+	target, err := allowedWritePath(filepath)
+	if err != nil {
+		return nil, fmt.Errorf("edit_file: %w", err)
+	}
 
 	oldStr := fmt.Sprintf("%s", args["old_str"])
 	newStr := fmt.Sprintf("%s", args["new_str"])
@@ -64,10 +68,10 @@ func editFile(args map[string]any) (map[string]any, error) {
 		return nil, fmt.Errorf("edit_file: old_str and new_str must be different")
 	}
 
-	content, err := os.ReadFile(filepath)
+	content, err := os.ReadFile(target)
 	if err != nil {
 		if os.IsNotExist(err) && oldStr == "" {
-			return createNewFile(filepath, newStr)
+			return createNewFile(filepath, target, newStr)
 		}
 		return nil, err
 	}
@@ -79,7 +83,7 @@ func editFile(args map[string]any) (map[string]any, error) {
 		return nil, fmt.Errorf("edit_file: old_str not found in file")
 	}
 
-	err = os.WriteFile(filepath, []byte(newContent), 0644)
+	err = os.WriteFile(target, []byte(newContent), 0644)
 	if err != nil {
 		return nil, err
 	}
@@ -87,16 +91,12 @@ func editFile(args map[string]any) (map[string]any, error) {
 	return map[string]any{"wrote": filepath}, nil
 }
 
-func createNewFile(filePath, content string) (map[string]any, error) {
-	dir := path.Dir(filePath)
-	if dir != "." {
-		err := os.MkdirAll(dir, 0755)
-		if err != nil {
-			return nil, fmt.Errorf("edit_file: failed to create directory: %w", err)
-		}
+func createNewFile(filePath, target, content string) (map[string]any, error) {
+	if err := os.MkdirAll(path.Dir(target), 0755); err != nil {
+		return nil, fmt.Errorf("edit_file: failed to create directory: %w", err)
 	}
 
-	err := os.WriteFile(filePath, []byte(content), 0644)
+	err := os.WriteFile(target, []byte(content), 0644)
 	if err != nil {
 		return nil, fmt.Errorf("edit_file: failed to create file: %w", err)
 	}
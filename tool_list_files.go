@@ -32,9 +32,12 @@ var ListFilesTool = &ToolDefinition{
 		if args["filepath"] != nil {
 			providedPath = fmt.Sprintf("%s", args["filepath"])
 		}
-		dir := filepath.Join(".", providedPath)
+		dir, err := allowedPath(filepath.Join(".", providedPath))
+		if err != nil {
+			return nil, fmt.Errorf("list_files: %w", err)
+		}
 		files := []string{}
-		err := filepath.Walk(dir, func(path string, info fs.FileInfo, err error) error {
+		err = filepath.Walk(dir, func(path string, info fs.FileInfo, err error) error {
 			if err != nil {
 				return err
 			}
@@ -0,0 +1,75 @@
+package smolcode
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+var NamedCheckpointTool = &ToolDefinition{
+	Tool: &genai.Tool{
+		FunctionDeclarations: []*genai.FunctionDeclaration{
+			{
+				Name: "named_checkpoint",
+				Description: strings.TrimSpace(`
+Save, list and restore named snapshots of all files in the project.
+
+Unlike create_checkpoint, a named checkpoint does not create a git commit on the current branch.
+Create one before risky changes, and restore it to undo them.
+Restoring deletes files created since the checkpoint and reports every file it changed.`),
+				Parameters: &genai.Schema{
+					Type: genai.TypeObject,
+					Properties: map[string]*genai.Schema{
+						"action": {
+							Type:        genai.TypeString,
+							Description: "What to do with checkpoints.",
+							Format:      "enum",
+							Enum:        []string{"create", "list", "restore"},
+						},
+						"name": {
+							Type:        genai.TypeString,
+							Description: "The name of the checkpoint, required for 'create' and 'restore'. Letters, digits, '.', '_' and '-' are allowed.",
+						},
+					},
+					Required: []string{"action"},
+				},
+			},
+		},
+	},
+	Function: namedCheckpoint,
+}
+
+func namedCheckpoint(args map[string]any) (map[string]any, error) {
+	action, _ := args["action"].(string)
+	name, _ := args["name"].(string)
+	if action != "list" && name == "" {
+		return nil, fmt.Errorf("named_checkpoint: '%s' requires 'name'", action)
+	}
+
+	switch action {
+	case "create":
+		checkpoint, err := CreateCheckpoint(name)
+		if err != nil {
+			return nil, fmt.Errorf("named_checkpoint: %w", err)
+		}
+		return map[string]any{"result": fmt.Sprintf("Checkpoint '%s' created.", checkpoint.Name)}, nil
+
+	case "list":
+		checkpoints, err := ListCheckpoints()
+		if err != nil {
+			return nil, fmt.Errorf("named_checkpoint: %w", err)
+		}
+		return map[string]any{"checkpoints": checkpoints}, nil
+
+	case "restore":
+		changes, err := RestoreCheckpoint(name)
+		if err != nil {
+			return nil, fmt.Errorf("named_checkpoint: %w", err)
+		}
+		return map[string]any{"result": fmt.Sprintf("Checkpoint '%s' restored.", name), "changed_files": changes}, nil
+
+	default:
+		return nil, fmt.Errorf("named_checkpoint: unknown action '%s'", action)
+	}
+}
@@ -0,0 +1,28 @@
+package smolcode
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// maxPanicStackLength is the number of bytes of the stack included in the result of a tool that panicked.
+const maxPanicStackLength = 2000
+
+// callTool runs tool with args. A panic in the tool is returned as an error with the
+// panic message and the beginning of the stack, so that one misbehaving tool cannot
+// end the session. The full stack is traced.
+func (agent *Agent) callTool(tool *ToolDefinition, args map[string]any) (result *ToolResult, err error) {
+	defer func() {
+		recovered := recover()
+		if recovered == nil {
+			return
+		}
+		stack := string(debug.Stack())
+		agent.trace("ToolPanic", map[string]string{"tool": tool.Name(), "panic": fmt.Sprint(recovered), "stack": stack})
+		if len(stack) > maxPanicStackLength {
+			stack = stack[:maxPanicStackLength] + "…"
+		}
+		result, err = nil, fmt.Errorf("tool panicked: %v\n%s", recovered, stack)
+	}()
+	return tool.Call(args)
+}
@@ -0,0 +1,42 @@
+package smolcode
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"google.golang.org/genai"
+)
+
+func TestExecuteToolRecoversFromPanic(t *testing.T) {
+	panicking := testTool("buggy", "Panics.")
+	panicking.Function = func(args map[string]any) (map[string]any, error) {
+		var counts map[string]int
+		counts["calls"]++
+		return nil, nil
+	}
+	working := testTool("working", "Works.")
+	working.Function = func(args map[string]any) (map[string]any, error) {
+		return map[string]any{"output": "ok"}, nil
+	}
+	agent := &Agent{displayer: &recordingDisplay{}, tools: NewToolBox().Add(panicking).Add(working)}
+
+	content := agent.executeTool(context.Background(), &genai.FunctionCall{Name: "buggy"})
+
+	response := content.Parts[0].FunctionResponse.Response
+	message, _ := response["error"].(string)
+	if !strings.Contains(message, "tool panicked: assignment to entry in nil map") {
+		t.Errorf("expected the panic message in the error, got %q", message)
+	}
+	if !strings.Contains(message, "goroutine") {
+		t.Errorf("expected the stack in the error, got %q", message)
+	}
+	if len(message) > maxPanicStackLength+200 {
+		t.Errorf("expected the stack to be truncated, got %d bytes", len(message))
+	}
+
+	content = agent.executeTool(context.Background(), &genai.FunctionCall{Name: "working"})
+	if output := content.Parts[0].FunctionResponse.Response["output"]; output != "ok" {
+		t.Errorf("expected tools to keep working after a panic, got %v", content.Parts[0].FunctionResponse.Response)
+	}
+}
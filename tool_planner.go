@@ -1,6 +1,7 @@
 package smolcode
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"strings"
@@ -30,9 +31,40 @@ var plannerStepSchema = &genai.Schema{
 			},
 			Description: "A list of criteria that must be met for the step to be considered DONE.",
 		},
+		"depends_on": {
+			Type: genai.TypeArray,
+			Items: &genai.Schema{
+				Type: genai.TypeString,
+			},
+			Description: "IDs of steps in the same plan that must be DONE before this step can be worked on. 'get_next_step' skips steps whose dependencies are not DONE.",
+		},
+		"priority": {
+			Type:        genai.TypeInteger,
+			Description: "The priority of the step, 0 by default. 'get_next_step' with 'by_priority' picks the step with the highest priority first.",
+		},
 		// Status is implicitly TODO when adding steps.
 	},
-	Required: []string{"id", "description"}, // Acceptance criteria are optional
+	Required: []string{"id", "description"}, // Acceptance criteria, dependencies and priority are optional
+}
+
+// plannerCriterionResultSchema describes the result of checking an acceptance criterion for 'verify_step'.
+var plannerCriterionResultSchema = &genai.Schema{
+	Type: genai.TypeObject,
+	Properties: map[string]*genai.Schema{
+		"index": {
+			Type:        genai.TypeInteger,
+			Description: "The position of the criterion in the step's list of acceptance criteria, starting at 1.",
+		},
+		"satisfied": {
+			Type:        genai.TypeBoolean,
+			Description: "Whether the criterion is met.",
+		},
+		"evidence": {
+			Type:        genai.TypeString,
+			Description: "How the criterion was checked, e.g. the test command that was run and its outcome.",
+		},
+	},
+	Required: []string{"index", "satisfied"},
 }
 
 var PlannerTool = &ToolDefinition{
@@ -57,8 +89,11 @@ Plans are stored in a SQLite database at '.smolcode/plans.db'. Always specify th
 								"inspect",       // Get the Markdown representation of the plan.
 								"get_next_step", // Get details of the next incomplete step.
 								"set_status",    // Mark a specific step as DONE or TODO.
+								"advance",       // Mark a step (default: the next step) as DONE and get the new next step.
 								"add_steps",     // Add one or more new steps to the end of the plan, creating it if necessary
+								"verify_step",   // Mark a step as DONE only if all of its acceptance criteria are confirmed.
 								"is_completed",  // Check if all steps in the plan are DONE.
+								"status",        // Get the number of completed and total steps and the next step.
 								"list_plans",    // List all available plan names.
 								"remove_steps",  // Remove specified steps from a plan.
 								"compact_plans", // Remove all completed plan files. The plan_name argument will be ignored for this action.
@@ -69,13 +104,22 @@ Plans are stored in a SQLite database at '.smolcode/plans.db'. Always specify th
 						// Parameters specific to certain actions
 						"step_id": {
 							Type:        genai.TypeString,
-							Description: "The ID of the step to target (required for 'set_status').",
+							Description: "The ID of the step to target (required for 'set_status' and 'verify_step', optional for 'advance').",
+						},
+						"by_priority": {
+							Type:        genai.TypeBoolean,
+							Description: "For 'get_next_step': return the ready step with the highest priority instead of the first ready step in plan order.",
 						},
 						"status": {
 							Type:        genai.TypeString,
 							Enum:        []string{"DONE", "TODO"},
 							Description: "The status to set for a step (required for 'set_status').",
 						},
+						"criteria_results": {
+							Type:        genai.TypeArray,
+							Items:       plannerCriterionResultSchema,
+							Description: "The result of checking each acceptance criterion of the step (required for 'verify_step'). Check every criterion, e.g. by running the tests, before reporting it as satisfied.",
+						},
 						"steps_to_add": {
 							Type:        genai.TypeArray,
 							Items:       plannerStepSchema,
@@ -136,9 +180,14 @@ func managePlan(args map[string]any) (map[string]any, error) {
 		if err != nil {
 			return nil, fmt.Errorf("manage_plan: failed to get plan '%s': %w", plannerName, err)
 		}
-		next := plan.NextStep()
-		if next == nil {
+		next, err := plan.NextReadyStep()
+		if byPriority, _ := args["by_priority"].(bool); byPriority && err == nil {
+			next = plan.NextStepByPriority()
+		}
+		if errors.Is(err, planner.ErrPlanCompleted) {
 			return map[string]any{"result": "Plan is complete."}, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("manage_plan: no step of plan '%s' can be worked on: %w", plannerName, err)
 		} else {
 			return map[string]any{
 				"next_step": map[string]any{
@@ -146,6 +195,7 @@ func managePlan(args map[string]any) (map[string]any, error) {
 					"status":              next.Status(),
 					"description":         next.Description(),
 					"acceptance_criteria": next.AcceptanceCriteria(),
+					"priority":            next.Priority(),
 				},
 			}, nil
 		}
@@ -182,6 +232,87 @@ func managePlan(args map[string]any) (map[string]any, error) {
 
 		return map[string]any{"result": fmt.Sprintf("Step '%s' in plan '%s' set to '%s'.", stepID, plannerName, status)}, nil
 
+	case "advance":
+		stepID, _ := args["step_id"].(string)
+
+		retrievedPlan, err := plans.Get(plannerName)
+		if err != nil {
+			return nil, fmt.Errorf("manage_plan: failed to get plan '%s' for advance: %w", plannerName, err)
+		}
+		if stepID == "" && retrievedPlan.NextStep() != nil {
+			stepID = retrievedPlan.NextStep().ID()
+		}
+
+		next, err := retrievedPlan.Advance(stepID)
+		if errors.Is(err, planner.ErrPlanCompleted) {
+			return map[string]any{"result": fmt.Sprintf("Plan '%s' is already complete, there is no step to advance.", plannerName)}, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("manage_plan: failed to advance step '%s' in plan '%s': %w", stepID, plannerName, err)
+		}
+
+		if err = plans.Save(retrievedPlan); err != nil {
+			return nil, fmt.Errorf("manage_plan: failed to save plan '%s' after advancing: %w", plannerName, err)
+		}
+
+		result := map[string]any{"result": fmt.Sprintf("Step '%s' in plan '%s' set to 'DONE'.", stepID, plannerName)}
+		if next == nil {
+			result["next_step"] = "Plan is complete."
+		} else {
+			result["next_step"] = map[string]any{
+				"id":                  next.ID(),
+				"status":              next.Status(),
+				"description":         next.Description(),
+				"acceptance_criteria": next.AcceptanceCriteria(),
+			}
+		}
+		return result, nil
+
+	case "verify_step":
+		stepID, ok := args["step_id"].(string)
+		if !ok || stepID == "" {
+			return nil, fmt.Errorf("manage_plan: 'verify_step' requires 'step_id'")
+		}
+		resultsArg, ok := args["criteria_results"].([]any)
+		if !ok {
+			return nil, fmt.Errorf("manage_plan: 'verify_step' requires 'criteria_results' array")
+		}
+
+		var confirmed []int
+		for i, resultArg := range resultsArg {
+			resultMap, ok := resultArg.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("manage_plan: invalid item type in 'criteria_results' at index %d, expected object", i)
+			}
+			index, ok := resultMap["index"].(float64)
+			if !ok {
+				return nil, fmt.Errorf("manage_plan: missing 'index' in criteria result at index %d", i)
+			}
+			if satisfied, _ := resultMap["satisfied"].(bool); satisfied {
+				confirmed = append(confirmed, int(index))
+			}
+		}
+
+		retrievedPlan, err := plans.Get(plannerName)
+		if err != nil {
+			return nil, fmt.Errorf("manage_plan: failed to get plan '%s' for verify_step: %w", plannerName, err)
+		}
+		unmet, err := retrievedPlan.CompleteIfVerified(stepID, confirmed)
+		if err != nil {
+			return nil, fmt.Errorf("manage_plan: failed to verify step '%s' in plan '%s': %w", stepID, plannerName, err)
+		}
+		if len(unmet) > 0 {
+			return map[string]any{
+				"result":         fmt.Sprintf("Step '%s' in plan '%s' was left as 'TODO' because %d acceptance criteria were not confirmed.", stepID, plannerName, len(unmet)),
+				"unmet_criteria": unmet,
+			}, nil
+		}
+
+		if err = plans.Save(retrievedPlan); err != nil {
+			return nil, fmt.Errorf("manage_plan: failed to save plan '%s' after verifying step: %w", plannerName, err)
+		}
+		return map[string]any{"result": fmt.Sprintf("All acceptance criteria of step '%s' in plan '%s' are confirmed, step set to 'DONE'.", stepID, plannerName)}, nil
+
 	case "add_steps":
 		stepsToAddArg, ok := args["steps_to_add"].([]any)
 		if !ok {
@@ -232,7 +363,21 @@ func managePlan(args map[string]any) (map[string]any, error) {
 				}
 			}
 
-			plan.AddStep(id, description, criteria)
+			var dependsOn []string
+			if dependsOnArg, present := stepMap["depends_on"].([]any); present {
+				for j, depArg := range dependsOnArg {
+					depStr, ok := depArg.(string)
+					if !ok {
+						return nil, fmt.Errorf("manage_plan: invalid dependency type in step '%s' at index %d, dependency %d", id, i, j)
+					}
+					dependsOn = append(dependsOn, depStr)
+				}
+			}
+
+			plan.AddStep(id, description, criteria, dependsOn...)
+			if priority, present := stepMap["priority"].(float64); present {
+				plan.SetPriority(id, int(priority))
+			}
 			addedCount++
 		}
 
@@ -249,6 +394,13 @@ func managePlan(args map[string]any) (map[string]any, error) {
 		isCompleted := plan.IsCompleted()
 		return map[string]any{"is_completed": isCompleted}, nil
 
+	case "status":
+		status, err := plans.PlanStatus(plannerName)
+		if err != nil {
+			return nil, fmt.Errorf("manage_plan: failed to get status of plan '%s': %w", plannerName, err)
+		}
+		return map[string]any{"status": status}, nil
+
 	case "list_plans":
 		plansInfo, err := plans.List() // planner.List() now returns []planner.PlanInfo
 		if err != nil {
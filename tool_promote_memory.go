@@ -0,0 +1,168 @@
+package smolcode
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/dhamidi/smolcode/codegen"
+	"github.com/dhamidi/smolcode/history"
+	"github.com/dhamidi/smolcode/memory"
+	"google.golang.org/genai"
+)
+
+var PromoteToMemoryTool = &ToolDefinition{
+	Tool: &genai.Tool{
+		FunctionDeclarations: []*genai.FunctionDeclaration{
+			{
+				Name: "promote_to_memory",
+				Description: strings.TrimSpace(
+					`
+Promotes an insight from the current conversation into a durable memory,
+so that it is available in future sessions through recall_memory.
+
+Use this when the conversation produced a fact worth keeping, e.g. a decision,
+a convention of the project or a command that turned out to work.
+The memory replaces any existing memory with the same ID.
+`,
+				),
+				Parameters: &genai.Schema{
+					Type: genai.TypeObject,
+					Properties: map[string]*genai.Schema{
+						"id": {
+							Type:        genai.TypeString,
+							Description: "A short identifier for the memory (e.g., 'test-command').",
+						},
+						"content": {
+							Type:        genai.TypeString,
+							Description: "The insight itself, self-contained and 1-3 sentences long.",
+						},
+					},
+					Required: []string{"id", "content"},
+				},
+			},
+		},
+	},
+	Function: promoteToMemoryTool,
+}
+
+func promoteToMemoryTool(args map[string]any) (map[string]any, error) {
+	id, _ := args["id"].(string)
+	content, _ := args["content"].(string)
+	if err := promoteToMemory(id, content); err != nil {
+		return nil, fmt.Errorf("promote_to_memory: %w", err)
+	}
+	return map[string]any{"result": fmt.Sprintf("Stored memory '%s'.", id)}, nil
+}
+
+// promoteToMemory stores content as the memory id, replacing the memory's content if it exists.
+func promoteToMemory(id, content string) error {
+	id, content = strings.TrimSpace(id), strings.TrimSpace(content)
+	if id == "" {
+		return fmt.Errorf("the memory ID must not be empty")
+	}
+	if strings.ContainsAny(id, `/\`) {
+		return fmt.Errorf("the memory ID '%s' must not contain slashes", id)
+	}
+	if content == "" {
+		return fmt.Errorf("the content of memory '%s' must not be empty", id)
+	}
+
+	mgr, err := memory.New(memoryDBPath)
+	if err != nil {
+		return fmt.Errorf("failed to initialize memory manager: %w", err)
+	}
+	defer mgr.Close()
+	return mgr.AddMemory(id, content)
+}
+
+// parseRememberCommand splits the input of the /remember slash command into the memory's ID and content.
+// It reports false if either of them is missing.
+func parseRememberCommand(input string) (id, content string, ok bool) {
+	rest := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(input), "/remember"))
+	end := strings.IndexFunc(rest, unicode.IsSpace)
+	if end < 0 {
+		return "", "", false
+	}
+	id, content = rest[:end], strings.TrimSpace(rest[end:])
+	return id, content, id != "" && content != ""
+}
+
+// ExtractConversationMemories asks the model for the durable facts of the conversation with the given ID
+// and returns them as memories, without storing them.
+// Memory IDs are derived from the IDs suggested by the model and are unique within the result.
+// They never clash with the existing memories, see memoriesFromFacts, so storing the result overwrites none of them.
+func ExtractConversationMemories(conversationID string, existing []*memory.Memory) ([]*memory.Memory, error) {
+	var transcript bytes.Buffer
+	if err := history.ExportMarkdown(conversationID, &transcript); err != nil {
+		return nil, fmt.Errorf("failed to load conversation '%s': %w", conversationID, err)
+	}
+	facts, err := codegen.New(os.Getenv("INCEPTION_API_KEY")).ExtractFacts(transcript.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract facts from conversation '%s': %w", conversationID, err)
+	}
+
+	return memoriesFromFacts(facts, existing), nil
+}
+
+// memoriesFromFacts turns facts into memories with IDs that are unique within the result and among existing.
+// A fact that is already stored with the same content keeps its ID, so that storing it again changes nothing;
+// other clashes get a numeric suffix, see uniqueMemoryID.
+func memoriesFromFacts(facts []codegen.ExtractedFact, existing []*memory.Memory) []*memory.Memory {
+	stored := map[string]string{}
+	taken := map[string]bool{}
+	for _, mem := range existing {
+		stored[mem.ID] = mem.Content
+		taken[mem.ID] = true
+	}
+	memories := make([]*memory.Memory, 0, len(facts))
+	used := map[string]bool{} // IDs of the result, which must not repeat even for unchanged facts
+	for _, fact := range facts {
+		id := memoryIDFromText(fact.ID)
+		if content, found := stored[id]; !found || content != fact.Fact || used[id] {
+			id = uniqueMemoryID(id, taken)
+		}
+		used[id] = true
+		memories = append(memories, &memory.Memory{ID: id, Content: fact.Fact})
+	}
+	return memories
+}
+
+// memoryIDFromText turns text into a kebab-case memory ID of at most 48 characters.
+// It returns "fact" if text contains no letters or digits.
+func memoryIDFromText(text string) string {
+	var id strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(text) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if dash && id.Len() > 0 {
+				id.WriteByte('-')
+			}
+			id.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+		if id.Len() >= 48 {
+			break
+		}
+	}
+	if id.Len() == 0 {
+		return "fact"
+	}
+	return strings.TrimSuffix(id.String(), "-")
+}
+
+// uniqueMemoryID returns id, or id with the lowest numeric suffix starting at 2 that is not taken yet,
+// and marks the returned ID as taken.
+func uniqueMemoryID(id string, taken map[string]bool) string {
+	unique := id
+	for n := 2; taken[unique]; n++ {
+		unique = id + "-" + strconv.Itoa(n)
+	}
+	taken[unique] = true
+	return unique
+}
@@ -0,0 +1,97 @@
+package smolcode
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dhamidi/smolcode/codegen"
+	"github.com/dhamidi/smolcode/memory"
+)
+
+func TestPromoteToMemoryTool(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	if _, err := promoteToMemoryTool(map[string]any{"id": "test-command", "content": "Run the tests with -tags fts5."}); err != nil {
+		t.Fatalf("promoteToMemoryTool failed: %v", err)
+	}
+	mgr, err := memory.New(memoryDBPath)
+	if err != nil {
+		t.Fatalf("memory.New failed: %v", err)
+	}
+	defer mgr.Close()
+	mem, err := mgr.GetMemoryByID("test-command")
+	if err != nil {
+		t.Fatalf("GetMemoryByID failed: %v", err)
+	}
+	if mem.Content != "Run the tests with -tags fts5." {
+		t.Errorf("unexpected content %q", mem.Content)
+	}
+
+	for _, args := range []map[string]any{
+		{"id": "", "content": "content"},
+		{"id": "a/b", "content": "content"},
+		{"id": "empty", "content": "  "},
+	} {
+		if _, err := promoteToMemoryTool(args); err == nil {
+			t.Errorf("expected an error for %v", args)
+		}
+	}
+}
+
+func TestParseRememberCommand(t *testing.T) {
+	tests := []struct {
+		input   string
+		id      string
+		content string
+		ok      bool
+	}{
+		{"/remember build-cmd  make smolcode ", "build-cmd", "make smolcode", true},
+		{"/remember\tid\tcontent with\twords", "id", "content with\twords", true},
+		{"/remember id", "", "", false},
+		{"/remember", "", "", false},
+	}
+	for _, test := range tests {
+		id, content, ok := parseRememberCommand(test.input)
+		if id != test.id || content != test.content || ok != test.ok {
+			t.Errorf("parseRememberCommand(%q) = %q, %q, %v, expected %q, %q, %v", test.input, id, content, ok, test.id, test.content, test.ok)
+		}
+	}
+}
+
+func TestMemoriesFromFactsKeepExistingMemories(t *testing.T) {
+	existing := []*memory.Memory{
+		{ID: "build-command", Content: "Build with make"},
+		{ID: "test-command", Content: "Run go test ./..."},
+		{ID: "test-command-2", Content: "Run go vet ./..."},
+	}
+	facts := []codegen.ExtractedFact{
+		{ID: "build command", Fact: "Build with make"},
+		{ID: "test command", Fact: "Run the tests with -tags fts5"},
+		{ID: "Test Command", Fact: "Run go test ./..."},
+	}
+
+	var got []string
+	for _, mem := range memoriesFromFacts(facts, existing) {
+		got = append(got, mem.ID)
+	}
+
+	want := []string{"build-command", "test-command-3", "test-command"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected IDs %v, got %v", want, got)
+	}
+}
+
+func TestMemoryIDFromText(t *testing.T) {
+	taken := map[string]bool{}
+	for _, test := range []struct{ text, id string }{
+		{"Test Command", "test-command"},
+		{"  --build_cmd!! ", "build-cmd"},
+		{"test-command", "test-command-2"},
+		{"???", "fact"},
+		{"", "fact-2"},
+	} {
+		if id := uniqueMemoryID(memoryIDFromText(test.text), taken); id != test.id {
+			t.Errorf("ID for %q = %q, expected %q", test.text, id, test.id)
+		}
+	}
+}
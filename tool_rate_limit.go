@@ -0,0 +1,84 @@
+package smolcode
+
+import (
+	"math"
+	"time"
+)
+
+// tokenBucket allows up to rate events per second on average, with bursts of up to burst events.
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(perSecond float64) *tokenBucket {
+	burst := math.Max(1, perSecond)
+	return &tokenBucket{rate: perSecond, burst: burst, tokens: burst}
+}
+
+// allow takes a token from the bucket if one is available at now.
+func (bucket *tokenBucket) allow(now time.Time) bool {
+	if !bucket.last.IsZero() {
+		bucket.tokens = math.Min(bucket.burst, bucket.tokens+now.Sub(bucket.last).Seconds()*bucket.rate)
+	}
+	bucket.last = now
+	if bucket.tokens < 1 {
+		return false
+	}
+	bucket.tokens--
+	return true
+}
+
+// refund returns a token taken by allow, for an event that did not happen after all.
+func (bucket *tokenBucket) refund() {
+	bucket.tokens = math.Min(bucket.burst, bucket.tokens+1)
+}
+
+// WithToolRateLimit limits how often each tool may be called, in calls per second.
+// Calls over the limit are not executed; the model is told to retry instead.
+// Zero, the default, disables the limit.
+func (agent *Agent) WithToolRateLimit(perSecond float64) *Agent {
+	agent.toolRateLimit = perSecond
+	agent.toolBuckets = map[string]*tokenBucket{}
+	return agent
+}
+
+// WithGlobalToolRateLimit limits how often tools may be called in total, in calls per second.
+// Zero, the default, disables the limit.
+func (agent *Agent) WithGlobalToolRateLimit(perSecond float64) *Agent {
+	agent.globalToolRateLimit = perSecond
+	agent.globalToolBucket = nil
+	if perSecond > 0 {
+		agent.globalToolBucket = newTokenBucket(perSecond)
+	}
+	return agent
+}
+
+// allowToolCall reports whether the tool called name may run now under the configured rate limits.
+// A call rejected by one limit does not count against the other.
+func (agent *Agent) allowToolCall(name string, now time.Time) bool {
+	var bucket *tokenBucket
+	if agent.toolRateLimit > 0 {
+		if agent.toolBuckets == nil {
+			agent.toolBuckets = map[string]*tokenBucket{}
+		}
+		var found bool
+		bucket, found = agent.toolBuckets[name]
+		if !found {
+			bucket = newTokenBucket(agent.toolRateLimit)
+			agent.toolBuckets[name] = bucket
+		}
+		if !bucket.allow(now) {
+			return false
+		}
+	}
+	if agent.globalToolBucket != nil && !agent.globalToolBucket.allow(now) {
+		if bucket != nil {
+			bucket.refund()
+		}
+		return false
+	}
+	return true
+}
@@ -0,0 +1,62 @@
+package smolcode
+
+import (
+	"testing"
+	"time"
+)
+
+func TestToolRateLimitPerTool(t *testing.T) {
+	agent := (&Agent{}).WithToolRateLimit(1)
+	now := time.Now()
+
+	if !agent.allowToolCall("read_file", now) {
+		t.Fatal("expected the first call to be allowed")
+	}
+	if agent.allowToolCall("read_file", now) {
+		t.Error("expected a second call within the same second to be rate limited")
+	}
+	if !agent.allowToolCall("list_files", now) {
+		t.Error("expected other tools to have their own limit")
+	}
+	if !agent.allowToolCall("read_file", now.Add(time.Second)) {
+		t.Error("expected the call to be allowed again after a second")
+	}
+}
+
+func TestGlobalToolRateLimit(t *testing.T) {
+	agent := (&Agent{}).WithGlobalToolRateLimit(2)
+	now := time.Now()
+
+	agent.allowToolCall("read_file", now)
+	agent.allowToolCall("list_files", now)
+
+	if agent.allowToolCall("search_code", now) {
+		t.Error("expected the third call within the same second to be rate limited")
+	}
+}
+
+func TestGlobalToolRateLimitDoesNotUsePerToolQuota(t *testing.T) {
+	agent := (&Agent{}).WithToolRateLimit(1).WithGlobalToolRateLimit(2)
+	now := time.Now()
+
+	agent.allowToolCall("read_file", now)
+	agent.allowToolCall("list_files", now)
+	if agent.allowToolCall("search_code", now) {
+		t.Fatal("expected the third call within the same second to be rate limited globally")
+	}
+
+	if !agent.allowToolCall("search_code", now.Add(500*time.Millisecond)) {
+		t.Error("expected the globally rejected call not to use up the quota of the tool")
+	}
+}
+
+func TestToolRateLimitDisabledByDefault(t *testing.T) {
+	agent := &Agent{}
+	now := time.Now()
+
+	for i := 0; i < 100; i++ {
+		if !agent.allowToolCall("read_file", now) {
+			t.Fatalf("expected call %d to be allowed without a rate limit", i+1)
+		}
+	}
+}
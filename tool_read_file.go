@@ -5,11 +5,30 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"sync/atomic"
 	"unicode/utf8"
 
 	"google.golang.org/genai"
 )
 
+// DefaultMaxReadFileSize is the size in bytes of the largest file read_file returns, see SetMaxReadFileSize.
+const DefaultMaxReadFileSize = 1 << 20
+
+var maxReadFileSize atomic.Int64
+
+// SetMaxReadFileSize limits the size in bytes of the files read_file returns.
+// Zero or less restores DefaultMaxReadFileSize.
+func SetMaxReadFileSize(size int64) {
+	if size <= 0 {
+		size = DefaultMaxReadFileSize
+	}
+	maxReadFileSize.Store(size)
+}
+
+func init() {
+	SetMaxReadFileSize(DefaultMaxReadFileSize)
+}
+
 var ReadFileTool = &ToolDefinition{
 	Tool: &genai.Tool{
 		FunctionDeclarations: []*genai.FunctionDeclaration{
@@ -35,7 +54,18 @@ var ReadFileTool = &ToolDefinition{
 		}
 		providedPath := fmt.Sprintf("%s", args["filepath"])
 		sanitizedFilename := path.Join(".", providedPath)
-		contents, err := os.ReadFile(sanitizedFilename)
+		target, err := allowedPath(sanitizedFilename)
+		if err != nil {
+			return nil, fmt.Errorf("read_file: %w", err)
+		}
+		info, err := os.Stat(target)
+		if err != nil {
+			return nil, fmt.Errorf("read_file: %w", err)
+		}
+		if limit := maxReadFileSize.Load(); info.Size() > limit {
+			return nil, fmt.Errorf("read_file: %s is %d bytes, more than the limit of %d bytes; read a range of lines instead, e.g. with run_command and sed -n '1,200p' %s", providedPath, info.Size(), limit, providedPath)
+		}
+		contents, err := os.ReadFile(target)
 		if err != nil {
 			return nil, fmt.Errorf("read_file: %w", err)
 		}
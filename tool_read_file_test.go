@@ -0,0 +1,26 @@
+package smolcode
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestReadFileRefusesFilesOverTheSizeLimit(t *testing.T) {
+	t.Chdir(t.TempDir())
+	SetMaxReadFileSize(16)
+	t.Cleanup(func() { SetMaxReadFileSize(0) })
+	os.WriteFile("small.txt", []byte("fits"), 0644)
+	os.WriteFile("large.txt", []byte(strings.Repeat("x", 17)), 0644)
+
+	if _, err := ReadFileTool.Function(map[string]any{"filepath": "small.txt"}); err != nil {
+		t.Errorf("expected a file within the limit to be read, got %v", err)
+	}
+	_, err := ReadFileTool.Function(map[string]any{"filepath": "large.txt"})
+	if err == nil {
+		t.Fatal("expected a file over the limit to be refused")
+	}
+	if !strings.Contains(err.Error(), "range of lines") {
+		t.Errorf("expected the error to suggest reading a range of lines, got %v", err)
+	}
+}
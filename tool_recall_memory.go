@@ -28,6 +28,8 @@ Either provide a specific 'factID' to retrieve a single fact,
 or provide an 'about' search term to find relevant facts using full-text search.
 
 When searching, prefer to search with single words and narrow down as needed.
+Searches return the best matches first, each with a relevance score (higher is better).
+Use 'limit' to ask for more or fewer facts, and 'min_score' to drop weak matches.
 `,
 				),
 				Parameters: &genai.Schema{
@@ -41,6 +43,14 @@ When searching, prefer to search with single words and narrow down as needed.
 							Type:        genai.TypeString,
 							Description: "The specific ID of the fact to recall.",
 						},
+						"limit": {
+							Type:        genai.TypeInteger,
+							Description: fmt.Sprintf("The maximum number of facts to return when searching, best matches first. Defaults to %d.", defaultRecallLimit),
+						},
+						"min_score": {
+							Type:        genai.TypeNumber,
+							Description: "Only return facts whose relevance score is at least this value when searching. Scores are only comparable within one search; by default, all matches up to 'limit' are returned.",
+						},
 					},
 					// Although technically optional, validation is done in the function
 					Required: []string{}, // Neither is strictly required by schema, logic handles it
@@ -51,8 +61,16 @@ When searching, prefer to search with single words and narrow down as needed.
 	Function: recallMemory,
 }
 
+// defaultRecallLimit bounds the number of facts returned by a search, so that broad queries do not flood the context.
+const defaultRecallLimit = 5
+
 func recallMemory(args map[string]any) (map[string]any, error) {
 	var about, factID string
+	limit := defaultRecallLimit
+	if limitRaw, ok := args["limit"].(float64); ok && limitRaw > 0 {
+		limit = int(limitRaw)
+	}
+	minScore, hasMinScore := args["min_score"].(float64)
 
 	if aboutRaw, ok := args["about"]; ok {
 		about, _ = aboutRaw.(string)
@@ -93,22 +111,29 @@ func recallMemory(args map[string]any) (map[string]any, error) {
 			return nil, fmt.Errorf("recall_memory: 'about' parameter cannot be empty or only whitespace")
 		}
 
-		mems, err := mgr.SearchMemory(about)
+		hits, err := mgr.SearchMemoryN(about, limit)
 		if err != nil {
 			return nil, fmt.Errorf("recall_memory: error searching for facts about '%s': %w", about, err)
 		}
 
-		if len(mems) == 0 {
+		if len(hits) == 0 {
 			return nil, fmt.Errorf("recall_memory: no facts found containing all words in '%s'", about)
 		}
 
-		// Convert []*memory.Memory to []map[string]string for the tool response
-		matches := make([]map[string]string, len(mems))
-		for i, mem := range mems {
-			matches[i] = map[string]string{
-				"id":   mem.ID,
-				"fact": mem.Content,
+		// Convert []*memory.MemoryHit to []map[string]any for the tool response
+		matches := []map[string]any{}
+		for _, hit := range hits {
+			if hasMinScore && hit.Score < minScore {
+				continue
 			}
+			matches = append(matches, map[string]any{
+				"id":    hit.ID,
+				"fact":  hit.Content,
+				"score": hit.Score,
+			})
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("recall_memory: no facts about '%s' have a score of at least %g; the best score was %g", about, minScore, hits[0].Score)
 		}
 
 		return map[string]any{
@@ -0,0 +1,57 @@
+package smolcode
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dhamidi/smolcode/memory"
+)
+
+func TestRecallMemoryLimitsAndFiltersByScore(t *testing.T) {
+	t.Chdir(t.TempDir())
+	mgr, err := memory.New(memoryDBPath)
+	if err != nil {
+		t.Fatalf("memory.New failed: %v", err)
+	}
+	for i, content := range []string{
+		"sqlite sqlite sqlite",
+		"sqlite sqlite and a few more words",
+		"sqlite is mentioned once among many other unrelated words in this fact",
+		"sqlite again, with some words",
+		"sqlite and more",
+		"sqlite appears here as well, with even more unrelated words around it",
+	} {
+		if err := mgr.AddMemory(string(rune('a'+i)), content); err != nil {
+			t.Fatalf("AddMemory failed: %v", err)
+		}
+	}
+	mgr.Close()
+
+	// Without the new arguments, the default limit applies.
+	result, err := recallMemory(map[string]any{"about": "sqlite"})
+	if err != nil {
+		t.Fatalf("recallMemory failed: %v", err)
+	}
+	matches := result["matches"].([]map[string]any)
+	if len(matches) != defaultRecallLimit {
+		t.Fatalf("expected %d matches by default, got %d", defaultRecallLimit, len(matches))
+	}
+	if matches[0]["id"] != "a" {
+		t.Errorf("expected the best match first, got %v", matches[0])
+	}
+	best := matches[0]["score"].(float64)
+	second := matches[1]["score"].(float64)
+
+	result, err = recallMemory(map[string]any{"about": "sqlite", "limit": float64(10), "min_score": second})
+	if err != nil {
+		t.Fatalf("recallMemory with min_score failed: %v", err)
+	}
+	if matches := result["matches"].([]map[string]any); len(matches) != 2 {
+		t.Errorf("expected the 2 matches with a score of at least %f, got %v", second, matches)
+	}
+
+	_, err = recallMemory(map[string]any{"about": "sqlite", "min_score": best + 1})
+	if err == nil || !strings.Contains(err.Error(), "score of at least") {
+		t.Errorf("expected an error when no match reaches min_score, got %v", err)
+	}
+}
@@ -47,19 +47,19 @@ func writeFile(args map[string]any) (map[string]any, error) {
 	if filepath == "" {
 		return nil, fmt.Errorf("write_file: filepath is missing")
 	}
+	target, err := allowedWritePath(filepath)
+	if err != nil {
+		return nil, fmt.Errorf("write_file: %w", err)
+	}
 
 	content := fmt.Sprintf("%s", args["content"])
 
 	// Create directory if it doesn't exist
-	dir := path.Dir(filepath)
-	if dir != "." {
-		err := os.MkdirAll(dir, 0755)
-		if err != nil {
-			return nil, fmt.Errorf("write_file: failed to create directory: %w", err)
-		}
+	if err := os.MkdirAll(path.Dir(target), 0755); err != nil {
+		return nil, fmt.Errorf("write_file: failed to create directory: %w", err)
 	}
 
-	err := os.WriteFile(filepath, []byte(content), 0644)
+	err = os.WriteFile(target, []byte(content), 0644)
 	if err != nil {
 		return nil, fmt.Errorf("write_file: failed to write file: %w", err)
 	}
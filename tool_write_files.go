@@ -0,0 +1,97 @@
+package smolcode
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+var WriteFilesTool = &ToolDefinition{
+	Tool: &genai.Tool{
+		FunctionDeclarations: []*genai.FunctionDeclaration{
+			{
+				Name: "write_files",
+				Description: strings.TrimSpace(
+					`
+Writes several files in one call, creating missing parent directories.
+
+Use this instead of repeated calls to write_file when scaffolding multiple files.
+Every file is attempted, even if writing an earlier one failed;
+the result reports for every file whether it was written and why not.
+`),
+				Parameters: &genai.Schema{
+					Type: genai.TypeObject,
+					Properties: map[string]*genai.Schema{
+						"files": {
+							Type:        genai.TypeArray,
+							Description: "The files to write. Each item is an object with 'path', the relative path of a file in the working directory, and 'contents', its new content.",
+							Items: &genai.Schema{
+								Type: genai.TypeObject,
+								Properties: map[string]*genai.Schema{
+									"path":     {Type: genai.TypeString},
+									"contents": {Type: genai.TypeString},
+								},
+								Required: []string{"path", "contents"},
+							},
+						},
+						"create_only": {
+							Type:        genai.TypeBoolean,
+							Description: "Optional. If true, files that already exist are left untouched and reported as failed.",
+						},
+					},
+					Required: []string{"files"},
+				},
+			},
+		},
+	},
+	Function: writeFiles,
+}
+
+// errFileExists is reported by write_files for existing files when create_only is set.
+var errFileExists = errors.New("file already exists")
+
+func writeFiles(args map[string]any) (map[string]any, error) {
+	files, ok := args["files"].([]any)
+	if !ok || len(files) == 0 {
+		return nil, fmt.Errorf("write_files: files is missing")
+	}
+	createOnly, _ := args["create_only"].(bool)
+
+	results := make([]map[string]any, 0, len(files))
+	written := []string{}
+	for i, file := range files {
+		fields, _ := file.(map[string]any)
+		path, pathOk := fields["path"].(string)
+		contents, contentsOk := fields["contents"].(string)
+		if !pathOk || !contentsOk || path == "" {
+			results = append(results, map[string]any{"index": i, "error": "missing path or contents"})
+			continue
+		}
+		if err := writeOneOfFiles(path, contents, createOnly); err != nil {
+			results = append(results, map[string]any{"path": path, "error": err.Error()})
+			continue
+		}
+		results = append(results, map[string]any{"path": path, "wrote": true})
+		written = append(written, path)
+	}
+
+	return map[string]any{
+		"results": results,
+		"written": written,
+		"summary": fmt.Sprintf("wrote %d of %d files", len(written), len(files)),
+	}, nil
+}
+
+// writeOneOfFiles writes a single file for write_files, applying the same checks as write_file.
+func writeOneOfFiles(path, contents string, createOnly bool) error {
+	if createOnly {
+		if _, err := os.Lstat(path); err == nil {
+			return errFileExists
+		}
+	}
+	_, err := writeFile(map[string]any{"filepath": path, "content": contents})
+	return err
+}
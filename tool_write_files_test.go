@@ -0,0 +1,42 @@
+package smolcode
+
+import (
+	"os"
+	"testing"
+)
+
+func TestWriteFilesReportsPartialSuccess(t *testing.T) {
+	t.Chdir(t.TempDir())
+	os.WriteFile("existing.go", []byte("package existing"), 0644)
+
+	result, err := writeFiles(map[string]any{
+		"create_only": true,
+		"files": []any{
+			map[string]any{"path": "cmd/app/main.go", "contents": "package main"},
+			map[string]any{"path": "../escaped.go", "contents": "package escaped"},
+			map[string]any{"path": "existing.go", "contents": "package replaced"},
+			map[string]any{"path": "README.md", "contents": "# App"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("writeFiles failed: %v", err)
+	}
+
+	written, _ := result["written"].([]string)
+	if len(written) != 2 || written[0] != "cmd/app/main.go" || written[1] != "README.md" {
+		t.Errorf("expected the two new files to be written, got %v", written)
+	}
+	results, _ := result["results"].([]map[string]any)
+	if len(results) != 4 || results[1]["error"] == nil || results[2]["error"] == nil {
+		t.Errorf("expected failures to be reported per file, got %v", results)
+	}
+	if contents, _ := os.ReadFile("cmd/app/main.go"); string(contents) != "package main" {
+		t.Errorf("expected cmd/app/main.go to be written, got %q", contents)
+	}
+	if contents, _ := os.ReadFile("existing.go"); string(contents) != "package existing" {
+		t.Errorf("expected the existing file to be left untouched, got %q", contents)
+	}
+	if _, err := os.Stat("../escaped.go"); !os.IsNotExist(err) {
+		t.Errorf("expected no file to be written outside the root")
+	}
+}
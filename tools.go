@@ -2,7 +2,11 @@ package smolcode
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
 
 	"google.golang.org/genai"
 )
@@ -10,6 +14,42 @@ import (
 type ToolDefinition struct {
 	Tool     *genai.Tool
 	Function func(map[string]any) (map[string]any, error)
+
+	// RichFunction is used instead of Function by tools that return content beyond
+	// their structured output, such as screenshots or charts.
+	RichFunction func(map[string]any) (*ToolResult, error)
+
+	cached bool // Set by CachedTool; running a tool that is not cached invalidates all cached results.
+}
+
+// ToolResult is the result of a tool call.
+type ToolResult struct {
+	// Output is sent to the model as the function response.
+	Output map[string]any
+
+	// Attachments are sent to the model as inline data following the function response.
+	Attachments []*genai.Blob
+}
+
+// Call runs the tool with args, preferring RichFunction over Function.
+func (def *ToolDefinition) Call(args map[string]any) (*ToolResult, error) {
+	if def.RichFunction != nil {
+		return def.RichFunction(args)
+	}
+	output, err := def.Function(args)
+	if err != nil {
+		return nil, err
+	}
+	return &ToolResult{Output: output}, nil
+}
+
+// Content converts the result of calling the tool name into the content sent back to the model.
+func (result *ToolResult) Content(name string) *genai.Content {
+	content := genai.NewContentFromFunctionResponse(name, result.Output, "tool")
+	for _, attachment := range result.Attachments {
+		content.Parts = append(content.Parts, &genai.Part{InlineData: attachment})
+	}
+	return content
 }
 
 func (def *ToolDefinition) Name() string {
@@ -18,6 +58,9 @@ func (def *ToolDefinition) Name() string {
 
 type ToolBox map[string]*ToolDefinition
 
+// ErrDuplicateTool is returned by AddChecked when a tool with the same name is already registered.
+var ErrDuplicateTool = errors.New("duplicate tool name")
+
 func NewToolBox() ToolBox { return ToolBox{} }
 
 func (tools ToolBox) Add(def *ToolDefinition) ToolBox {
@@ -25,6 +68,34 @@ func (tools ToolBox) Add(def *ToolDefinition) ToolBox {
 	return tools
 }
 
+// AddChecked adds def like Add, but refuses to replace a tool that is already registered under the same name.
+func (tools ToolBox) AddChecked(def *ToolDefinition) error {
+	if _, exists := tools[def.Name()]; exists {
+		return fmt.Errorf("%w: %s", ErrDuplicateTool, def.Name())
+	}
+	tools[def.Name()] = def
+	return nil
+}
+
+// ErrUnknownTool is returned by Remove when asked to remove a tool that is not registered.
+var ErrUnknownTool = errors.New("unknown tool")
+
+// Remove removes the tools with the given names.
+// If any name is unknown, it returns ErrUnknownTool and leaves the toolbox unchanged.
+func (tools ToolBox) Remove(names ...string) error {
+	for _, name := range names {
+		if _, exists := tools[name]; !exists {
+			available := tools.Names()
+			sort.Strings(available)
+			return fmt.Errorf("%w: %s, available tools are: %s", ErrUnknownTool, name, strings.Join(available, ", "))
+		}
+	}
+	for _, name := range names {
+		delete(tools, name)
+	}
+	return nil
+}
+
 func (tools ToolBox) Names() []string {
 	names := []string{}
 	for _, tool := range tools {
@@ -39,6 +110,33 @@ func (tools ToolBox) Get(name string) (def *ToolDefinition, found bool) {
 	return
 }
 
+// ToolInfo describes a tool for consumers outside of a session.
+type ToolInfo struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	InputSchema json.RawMessage `json:"inputSchema,omitempty"`
+}
+
+// Catalog describes every function declared by the tools in the toolbox, sorted by name.
+func (tools ToolBox) Catalog() ([]ToolInfo, error) {
+	catalog := []ToolInfo{}
+	for _, tool := range tools {
+		for _, declaration := range tool.Tool.FunctionDeclarations {
+			info := ToolInfo{Name: declaration.Name, Description: declaration.Description}
+			if declaration.Parameters != nil {
+				schema, err := json.Marshal(declaration.Parameters)
+				if err != nil {
+					return nil, fmt.Errorf("failed to marshal input schema of tool %s: %w", declaration.Name, err)
+				}
+				info.InputSchema = schema
+			}
+			catalog = append(catalog, info)
+		}
+	}
+	sort.Slice(catalog, func(i, j int) bool { return catalog[i].Name < catalog[j].Name })
+	return catalog, nil
+}
+
 func (tools ToolBox) List() *genai.Tool {
 	result := &genai.Tool{}
 	for _, tool := range tools {
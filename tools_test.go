@@ -0,0 +1,123 @@
+package smolcode
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"google.golang.org/genai"
+)
+
+func testTool(name, description string) *ToolDefinition {
+	return &ToolDefinition{
+		Tool: &genai.Tool{
+			FunctionDeclarations: []*genai.FunctionDeclaration{{Name: name, Description: description}},
+		},
+	}
+}
+
+func TestToolBoxAddCheckedRejectsDuplicateNames(t *testing.T) {
+	tools := NewToolBox()
+	if err := tools.AddChecked(testTool("read_file", "first")); err != nil {
+		t.Fatalf("AddChecked failed for the first tool: %v", err)
+	}
+
+	err := tools.AddChecked(testTool("read_file", "second"))
+
+	if !errors.Is(err, ErrDuplicateTool) {
+		t.Fatalf("expected ErrDuplicateTool, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "read_file") {
+		t.Errorf("expected the error to name the colliding tool, got %q", err)
+	}
+	if tool, _ := tools.Get("read_file"); tool.Tool.FunctionDeclarations[0].Description != "first" {
+		t.Errorf("expected the first tool to stay registered, got %q", tool.Tool.FunctionDeclarations[0].Description)
+	}
+}
+
+func TestToolBoxCatalogDescribesEveryTool(t *testing.T) {
+	tools := NewToolBox().Add(testTool("write_file", "Writes a file.")).Add(testTool("read_file", "Reads a file."))
+	tools["read_file"].Tool.FunctionDeclarations[0].Parameters = &genai.Schema{
+		Type:       genai.TypeObject,
+		Properties: map[string]*genai.Schema{"path": {Type: genai.TypeString}},
+	}
+
+	catalog, err := tools.Catalog()
+	if err != nil {
+		t.Fatalf("Catalog failed: %v", err)
+	}
+
+	if len(catalog) != 2 || catalog[0].Name != "read_file" || catalog[1].Name != "write_file" {
+		t.Fatalf("expected read_file and write_file sorted by name, got %+v", catalog)
+	}
+	if catalog[0].Description != "Reads a file." {
+		t.Errorf("expected description of read_file, got %q", catalog[0].Description)
+	}
+	if !strings.Contains(string(catalog[0].InputSchema), `"path"`) {
+		t.Errorf("expected the input schema of read_file to contain its parameters, got %s", catalog[0].InputSchema)
+	}
+	if catalog[1].InputSchema != nil {
+		t.Errorf("expected no input schema for write_file, got %s", catalog[1].InputSchema)
+	}
+}
+
+func TestToolDefinitionCallForwardsAttachments(t *testing.T) {
+	screenshot := &genai.Blob{Data: []byte("\x89PNG"), MIMEType: "image/png"}
+	tool := testTool("screenshot", "Takes a screenshot.")
+	tool.RichFunction = func(map[string]any) (*ToolResult, error) {
+		return &ToolResult{Output: map[string]any{"output": "captured"}, Attachments: []*genai.Blob{screenshot}}, nil
+	}
+
+	result, err := tool.Call(nil)
+	if err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+	content := result.Content("screenshot")
+
+	if len(content.Parts) != 2 {
+		t.Fatalf("expected a function response and an inline data part, got %d parts", len(content.Parts))
+	}
+	if response := content.Parts[0].FunctionResponse; response == nil || response.Response["output"] != "captured" {
+		t.Errorf("expected the function response to carry the output, got %+v", content.Parts[0])
+	}
+	if content.Parts[1].InlineData != screenshot {
+		t.Errorf("expected the screenshot to be attached, got %+v", content.Parts[1])
+	}
+}
+
+func TestToolDefinitionCallWrapsMapResults(t *testing.T) {
+	tool := testTool("read_file", "Reads a file.")
+	tool.Function = func(map[string]any) (map[string]any, error) {
+		return map[string]any{"content": "hello"}, nil
+	}
+
+	result, err := tool.Call(nil)
+	if err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+	content := result.Content("read_file")
+
+	if len(content.Parts) != 1 || content.Parts[0].FunctionResponse.Response["content"] != "hello" {
+		t.Errorf("expected a single function response with the map result, got %+v", content.Parts)
+	}
+}
+
+func TestToolBoxRemoveRejectsUnknownNames(t *testing.T) {
+	tools := NewToolBox().Add(testTool("read_file", "")).Add(testTool("run_command", ""))
+
+	err := tools.Remove("run_command", "rm_rf")
+
+	if !errors.Is(err, ErrUnknownTool) || !strings.Contains(err.Error(), "rm_rf") {
+		t.Fatalf("expected ErrUnknownTool naming rm_rf, got %v", err)
+	}
+	if len(tools) != 2 {
+		t.Errorf("expected the toolbox to be unchanged, got %v", tools.Names())
+	}
+
+	if err := tools.Remove("run_command"); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if _, found := tools.Get("run_command"); found {
+		t.Errorf("expected run_command to be removed")
+	}
+}